@@ -17,6 +17,7 @@ from fastapi.exceptions import RequestValidationError
 from fastapi.responses import JSONResponse, StreamingResponse
 from fastapi.middleware.cors import CORSMiddleware
 import json
+import re
 from pydantic import BaseModel, Field
 from minio import Minio
 
@@ -39,8 +40,13 @@ class SummarizeRequest(BaseModel):
     storage_path: str = Field(..., description="Path to file in MinIO storage")
     style: str = Field(default="bullet_points", description="Summary style")
     custom_instructions: Optional[str] = Field(None, max_length=500)
-    language: str = Field(default="en", description="Summary language: 'en' or 'id'")
+    language: str = Field(default="en", description="Summary language code, e.g. 'en' or 'id'")
     callback_url: Optional[str] = Field(None, description="URL to callback when complete")
+    title_hint: Optional[str] = Field(None, max_length=300, description="Title from the PDF metadata")
+    language_hint: Optional[str] = Field(None, description="Language detected in the document text")
+    model: Optional[str] = Field(None, description="Gemini model to use instead of the configured default")
+    continue_from: Optional[str] = Field(None, description="Partial summary of an interrupted attempt to continue")
+    include_source_text: bool = Field(default=False, description="Return the extracted text with the result")
 
 
 class SummarizeResponse(BaseModel):
@@ -64,6 +70,7 @@ class SummaryResult(BaseModel):
     language: str = "en"
     status: str  # "completed" or "failed"
     error_message: Optional[str] = None
+    source_text: Optional[str] = None  # Extracted text, when include_source_text was set
 
 
 class HealthResponse(BaseModel):
@@ -81,8 +88,13 @@ class GuestSummaryResponse(BaseModel):
     language: str
     processing_duration_ms: int
     model_used: str = "gemini-2.0-flash-exp"
+    prompt_tokens: int = 0
+    completion_tokens: int = 0
 
 
+# A BCP 47 style language code such as "en" or "pt-br"
+LANGUAGE_CODE = re.compile(r"^[a-z]{2,3}(-[a-z0-9]{2,8})?$")
+
 # Initialize services
 settings = get_settings()
 pdf_extractor = PDFExtractor()
@@ -184,8 +196,11 @@ async def get_styles():
 async def summarize_sync(
     file: UploadFile = File(..., description="PDF file to summarize"),
     style: str = Form(default="bullet_points", description="Summary style"),
-    language: str = Form(default="en", description="Summary language: 'en' or 'id'"),
-    custom_instructions: Optional[str] = Form(default=None, max_length=500)
+    language: str = Form(default="en", description="Summary language code, e.g. 'en' or 'id'"),
+    custom_instructions: Optional[str] = Form(default=None, max_length=500),
+    title_hint: Optional[str] = Form(default=None, max_length=300, description="Title from the PDF metadata"),
+    language_hint: Optional[str] = Form(default=None, description="Language detected in the document text"),
+    model: Optional[str] = Form(default=None, description="Gemini model to use instead of the configured default")
 ):
     """
     Synchronous PDF summarization for guest users.
@@ -210,9 +225,9 @@ async def summarize_sync(
             detail=f"Invalid style. Must be one of: {', '.join(valid_styles)}"
         )
     
-    # Validate language
-    if language not in ["en", "id"]:
-        raise HTTPException(status_code=400, detail="Language must be 'en' or 'id'")
+    # Validate language; the backend keeps the list of supported languages
+    if not LANGUAGE_CODE.match(language):
+        raise HTTPException(status_code=400, detail="Language must be a language code such as 'en' or 'id'")
     
     try:
         # Read PDF bytes directly from upload
@@ -240,7 +255,10 @@ async def summarize_sync(
             text=text,
             style=style,
             custom_instructions=custom_instructions,
-            language=language
+            title_hint=title_hint,
+            language=language,
+            language_hint=language_hint,
+            model=model
         )
         
         processing_time_ms = int((time.time() - start_time) * 1000)
@@ -251,7 +269,10 @@ async def summarize_sync(
             content=content,
             style=style,
             language=language,
-            processing_duration_ms=processing_time_ms
+            processing_duration_ms=processing_time_ms,
+            model_used=model or settings.gemini_model,
+            prompt_tokens=prompt_tokens or 0,
+            completion_tokens=completion_tokens or 0
         )
         
     except HTTPException:
@@ -265,8 +286,11 @@ async def summarize_sync(
 async def summarize_stream(
     file: UploadFile = File(..., description="PDF file to summarize"),
     style: str = Form(default="bullet_points", description="Summary style"),
-    language: str = Form(default="en", description="Summary language: 'en' or 'id'"),
-    custom_instructions: Optional[str] = Form(default=None, max_length=500)
+    language: str = Form(default="en", description="Summary language code, e.g. 'en' or 'id'"),
+    custom_instructions: Optional[str] = Form(default=None, max_length=500),
+    title_hint: Optional[str] = Form(default=None, max_length=300, description="Title from the PDF metadata"),
+    language_hint: Optional[str] = Form(default=None, description="Language detected in the document text"),
+    include_source_text: bool = Form(default=False, description="Send the extracted text as a source_text event")
 ):
     """
     Streamed PDF summarization for guest users (SSE).
@@ -304,12 +328,17 @@ async def summarize_stream(
                  yield f"data: {json.dumps({'error': f'Text extraction failed: {str(e)}'})}\n\n"
                  return
 
+            if include_source_text:
+                yield f"data: {json.dumps({'source_text': text})}\n\n"
+
             # 5. Run Recursive Summarization
             async for event in summarizer.generate_summary_stream(
                 text=text,
                 style=style,
                 custom_instructions=custom_instructions,
-                language=language
+                language=language,
+                title_hint=title_hint,
+                language_hint=language_hint
             ):
                 # event is a dict like {'log': 'MSG'} or {'result': {...}} or {'error': 'MSG'}
                 yield f"data: {json.dumps(event)}\n\n"
@@ -345,7 +374,12 @@ async def summarize(request: SummarizeRequest, background_tasks: BackgroundTasks
         request.style,
         request.custom_instructions,
         request.language,
-        request.callback_url
+        request.callback_url,
+        request.title_hint,
+        request.language_hint,
+        request.model,
+        request.continue_from,
+        request.include_source_text
     )
     
     return SummarizeResponse(
@@ -361,7 +395,12 @@ async def process_summary(
     style: str,
     custom_instructions: Optional[str],
     language: str,
-    callback_url: Optional[str]
+    callback_url: Optional[str],
+    title_hint: Optional[str] = None,
+    language_hint: Optional[str] = None,
+    model: Optional[str] = None,
+    continue_from: Optional[str] = None,
+    include_source_text: bool = False
 ):
     """Background task to process PDF and generate summary"""
     start_time = time.time()
@@ -394,13 +433,26 @@ async def process_summary(
         
         logger.info(f"Extracted text: {len(text)} characters")
         
-        # Generate summary with language
-        title, content, prompt_tokens, completion_tokens = summarizer.generate_summary(
+        # Generate summary with language, continuing an interrupted attempt
+        # when given one and starting over if that fails
+        summary_args = dict(
             text=text,
             style=style,
             custom_instructions=custom_instructions,
-            language=language
+            title_hint=title_hint,
+            language=language,
+            language_hint=language_hint,
+            model=model
         )
+        try:
+            title, content, prompt_tokens, completion_tokens = summarizer.generate_summary(
+                **summary_args, continue_from=continue_from
+            )
+        except Exception as e:
+            if not continue_from:
+                raise
+            logger.warning(f"Could not continue partial summary for {file_id}, regenerating: {e}")
+            title, content, prompt_tokens, completion_tokens = summarizer.generate_summary(**summary_args)
         
         processing_time_ms = int((time.time() - start_time) * 1000)
         logger.info(f"Summary generated in {processing_time_ms}ms")
@@ -412,11 +464,13 @@ async def process_summary(
             content=content,
             style=style,
             custom_instructions=custom_instructions,
+            model_used=model or settings.gemini_model,
             prompt_tokens=prompt_tokens,
             completion_tokens=completion_tokens,
             processing_duration_ms=processing_time_ms,
             language=language,
-            status="completed"
+            status="completed",
+            source_text=text if include_source_text else None
         )
         
         await send_callback(callback_url, result)
@@ -104,6 +104,13 @@ LANGUAGE_INSTRUCTIONS = {
     "id": "Tulis ringkasan dalam Bahasa Indonesia yang baik dan benar."
 }
 
+
+def language_instruction(language: str) -> str:
+    """Instruction for the output language; the backend decides which codes are allowed."""
+    if language in LANGUAGE_INSTRUCTIONS:
+        return LANGUAGE_INSTRUCTIONS[language]
+    return f"Write the summary in the language with ISO 639-1 code '{language}'."
+
 # For backward compatibility
 STYLE_PROMPTS = STYLE_PROMPTS_EN
 
@@ -171,7 +178,10 @@ class Summarizer:
         style: str = "bullet_points",
         custom_instructions: Optional[str] = None,
         title_hint: Optional[str] = None,
-        language: str = "en"
+        language: str = "en",
+        language_hint: Optional[str] = None,
+        model: Optional[str] = None,
+        continue_from: Optional[str] = None
     ) -> Tuple[str, str, int, int]:
         """Synchronous wrapper for backward compatibility"""
         logger.warning("Using synchronous generate_summary wrapper. Use stream for parallel processing.")
@@ -179,7 +189,7 @@ class Summarizer:
         loop = asyncio.new_event_loop()
         try:
             return loop.run_until_complete(
-                self._generate_summary_async(text, style, custom_instructions, title_hint, language)
+                self._generate_summary_async(text, style, custom_instructions, title_hint, language, language_hint, model, continue_from)
             )
         finally:
             loop.close()
@@ -190,24 +200,30 @@ class Summarizer:
         style: str,
         custom_instructions: Optional[str],
         title_hint: Optional[str],
-        language: str
+        language: str,
+        language_hint: Optional[str] = None,
+        model: Optional[str] = None,
+        continue_from: Optional[str] = None
     ) -> Tuple[str, str, int, int]:
         """Async version of simple summary (legacy path, not used by stream)"""
         # This is a fallback or for non-stream uses
         prompts = STYLE_PROMPTS_ID if language == "id" else STYLE_PROMPTS_EN
         style_prompt = prompts.get(style, prompts["bullet_points"])
-        lang_instruction = LANGUAGE_INSTRUCTIONS.get(language, LANGUAGE_INSTRUCTIONS["en"])
+        lang_instruction = language_instruction(language)
+        hints = self._hints_prompt(title_hint, language_hint)
+        continuation = self._continuation_prompt(continue_from)
         
         full_prompt = f"""
 LANGUAGE REQUIREMENT: {lang_instruction}
 STYLE: {style_prompt}
 {f"INSTRUCTIONS: {custom_instructions}" if custom_instructions else ""}
+{hints}
 
 DOCUMENT CONTENT:
 ---
 {text}
 ---
-
+{continuation}
 TASK:
 1. Analyze the document.
 2. Provide a title and summary.
@@ -217,7 +233,9 @@ TITLE: [Concise Title]
 SUMMARY:
 [Summary Content]
 """
-        response = await self.model.generate_content_async(
+        # A requested model overrides the configured one for this call only
+        generative_model = genai.GenerativeModel(model) if model else self.model
+        response = await generative_model.generate_content_async(
             full_prompt,
             generation_config=self.generation_config
         )
@@ -238,7 +256,9 @@ SUMMARY:
         text: str,
         style: str = "bullet_points",
         custom_instructions: Optional[str] = None,
-        language: str = "en"
+        language: str = "en",
+        title_hint: Optional[str] = None,
+        language_hint: Optional[str] = None
     ) -> AsyncGenerator[dict, None]:
         """
         Generate summary with streaming logs and parallel processing.
@@ -265,6 +285,7 @@ SUMMARY:
             return
 
         total_tokens = {"prompt": 0, "completion": 0}
+        hints = self._hints_prompt(title_hint, language_hint)
         
         try:
             yield {"log": f"Analyzing document ({len(text)} chars)..."}
@@ -272,13 +293,13 @@ SUMMARY:
             async def process_text(current_text: str, depth: int = 1):
                 if depth > MAX_RECURSIVE_DEPTH:
                     yield {"log": f"Max recursive depth reached at level {depth}. Summarizing directly."}
-                    res = await self._summarize_single_async(current_text, style, custom_instructions, language, total_tokens)
+                    res = await self._summarize_single_async(current_text, style, custom_instructions, language, total_tokens, hints)
                     yield {"final_text": res}
                     return
 
                 if len(current_text) <= MAX_SINGLE_CHUNK_SIZE:
                     yield {"log": "Processing single chunk..."}
-                    res = await self._summarize_single_async(current_text, style, custom_instructions, language, total_tokens)
+                    res = await self._summarize_single_async(current_text, style, custom_instructions, language, total_tokens, hints)
                     yield {"final_text": res}
                     return
                 
@@ -313,7 +334,7 @@ SUMMARY:
                     return
                 
                 yield {"log": "Finalizing merged summary..."}
-                res = await self._merge_chunk_summaries_async(chunk_summaries, style, language, total_tokens)
+                res = await self._merge_chunk_summaries_async(chunk_summaries, style, language, total_tokens, hints)
                 yield {"final_text": res}
 
             final_summary = ""
@@ -323,7 +344,7 @@ SUMMARY:
                 else:
                     yield event
             
-            title = "Document Summary"
+            title = title_hint or "Document Summary"
             if "TITLE:" in final_summary:
                 parts = final_summary.split("SUMMARY:", 1)
                 if len(parts) == 2:
@@ -353,17 +374,19 @@ SUMMARY:
         style: str,
         custom_instructions: Optional[str],
         language: str,
-        tokens_dict: dict
+        tokens_dict: dict,
+        hints: str = ""
     ) -> str:
         """Async version of single chunk summary"""
         prompts = STYLE_PROMPTS_ID if language == "id" else STYLE_PROMPTS_EN
         style_prompt = prompts.get(style, prompts["bullet_points"])
-        lang_instruction = LANGUAGE_INSTRUCTIONS.get(language, LANGUAGE_INSTRUCTIONS["en"])
+        lang_instruction = language_instruction(language)
         
         full_prompt = f"""
 LANGUAGE REQUIREMENT: {lang_instruction}
 STYLE: {style_prompt}
 {f"INSTRUCTIONS: {custom_instructions}" if custom_instructions else ""}
+{hints}
 
 DOCUMENT CONTENT:
 ---
@@ -403,7 +426,7 @@ TASK:
         async with semaphore:
             prompts = STYLE_PROMPTS_ID if language == "id" else STYLE_PROMPTS_EN
             style_prompt = prompts.get(style, prompts["bullet_points"])
-            lang_instruction = LANGUAGE_INSTRUCTIONS.get(language, LANGUAGE_INSTRUCTIONS["en"])
+            lang_instruction = language_instruction(language)
 
             prompt = f"""
 Part {index + 1}/{total} of document.
@@ -453,14 +476,15 @@ Provide a summary of this section.
                         raise e
             return ""
 
-    async def _merge_chunk_summaries_async(self, summaries: List[str], style: str, language: str, tokens_dict: dict) -> str:
+    async def _merge_chunk_summaries_async(self, summaries: List[str], style: str, language: str, tokens_dict: dict, hints: str = "") -> str:
         """Merge summaries asynchronously"""
         combined = "\n\n".join(summaries)
-        lang_instruction = LANGUAGE_INSTRUCTIONS.get(language, LANGUAGE_INSTRUCTIONS["en"])
+        lang_instruction = language_instruction(language)
         
         prompt = f"""
 Merge these summaries into one cohesive {style} summary.
 LANGUAGE: {lang_instruction}
+{hints}
 
 SUMMARIES:
 {combined}
@@ -481,6 +505,31 @@ SUMMARY:
              
         return response.text
 
+    @staticmethod
+    def _hints_prompt(title_hint: Optional[str], language_hint: Optional[str]) -> str:
+        """Describe the backend's document hints for a prompt; empty when there are none"""
+        lines = []
+        if title_hint:
+            lines.append(f"HINT: The document metadata gives its title as \"{title_hint}\". Prefer it for TITLE if it matches the content.")
+        if language_hint:
+            lines.append(f"HINT: The document appears to be written in '{language_hint}'. The LANGUAGE requirement above still applies to the output.")
+        return "\n".join(lines)
+
+    @staticmethod
+    def _continuation_prompt(continue_from: Optional[str]) -> str:
+        """Prompt section asking to finish an interrupted summary"""
+        if not continue_from:
+            return ""
+        return f"""
+PARTIAL SUMMARY:
+A previous attempt was interrupted after writing the text below. Keep it as the
+beginning of the summary and continue from where it stops, without repeating it.
+Return the complete summary.
+---
+{continue_from}
+---
+"""
+
     def _parse_response(self, response_text: str, title_hint: Optional[str] = None) -> Tuple[str, str]:
         """Parse the model response to extract title and summary"""
         title = title_hint or "Document Summary"
@@ -47,8 +47,15 @@ def main():
             file_id = task.get("file_id")
             
             # Helper to publish events
-            def publish_event(status, data=None):
+            # "status" is kept for older consumers; "stage" and "progress" drive the UI
+            def publish_event(status, data=None, stage=None, progress=None):
                 payload = {"file_id": file_id, "status": status}
+                if stage:
+                    payload["stage"] = stage
+                if progress is not None:
+                    payload["progress"] = progress
+                if data and "log" in data:
+                    payload["message"] = data["log"]
                 if data:
                     payload.update(data)
                 
@@ -58,7 +65,7 @@ def main():
                     body=json.dumps(payload)
                 )
 
-            publish_event("processing", {"log": "Worker received task"})
+            publish_event("processing", {"log": "Worker received task"}, stage="extracting", progress=5)
 
             async def process_task():
                 # Download File
@@ -69,30 +76,34 @@ def main():
                 
                 # Validate
                 if not await summarizer.validate_pdf(pdf_bytes):
-                    publish_event("failed", {"error": "Invalid PDF file signature"})
+                    publish_event("failed", {"error": "Invalid PDF file signature"}, stage="failed")
                     return
 
                 # Extract Text
-                publish_event("processing", {"log": "Extracting text..."})
+                publish_event("processing", {"log": "Extracting text..."}, stage="extracting", progress=10)
                 text = pdf_extractor.extract_text(pdf_bytes)
                 if not text.strip():
-                     publish_event("failed", {"error": "No text extracted"})
+                     publish_event("failed", {"error": "No text extracted"}, stage="failed")
                      return
 
-                # Summarize
+                # Summarize; progress creeps from 30 towards 90 with each log line
+                progress = 30
                 async for event in summarizer.generate_summary_stream(
                     text=text,
                     style=task.get("style", "bullet_points"),
                     custom_instructions=task.get("custom_instructions"),
-                    language=task.get("language", "en")
+                    language=task.get("language", "en"),
+                    title_hint=task.get("title_hint") or None,
+                    language_hint=task.get("language_hint") or None
                 ):
                     # event contains "log", "result", or "error"
                     if "result" in event:
-                         publish_event("completed", {"result": event["result"]})
+                         publish_event("completed", {"result": event["result"]}, stage="completed", progress=100)
                     elif "error" in event:
-                         publish_event("failed", {"error": event["error"]})
+                         publish_event("failed", {"error": event["error"]}, stage="failed")
                     elif "log" in event:
-                         publish_event("processing", {"log": event["log"]})
+                         publish_event("processing", {"log": event["log"]}, stage="generating", progress=progress)
+                         progress = min(progress + 10, 90)
 
             asyncio.run(process_task())
 
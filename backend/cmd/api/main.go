@@ -2,12 +2,14 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	dbfiles "github.com/nextpdf/backend/db"
 	"github.com/nextpdf/backend/internal/config"
 	"github.com/nextpdf/backend/internal/database"
 	"github.com/nextpdf/backend/internal/server"
@@ -15,6 +17,11 @@ import (
 )
 
 func main() {
+	migrateCmd := flag.String("migrate", "", "run a migration command and exit: up, down, version, force")
+	migrateSteps := flag.Int("steps", 1, "number of migrations to revert with -migrate down")
+	forceVersion := flag.Uint("version", 0, "version to record with -migrate force")
+	flag.Parse()
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -28,16 +35,27 @@ func main() {
 	}
 	defer db.Close()
 
+	// Apply or inspect migrations; with -migrate the process exits afterwards
+	migrator, err := database.NewMigrator(db.Pool, dbfiles.Migrations)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+	if *migrateCmd != "" {
+		runMigrate(migrator, *migrateCmd, *migrateSteps, *forceVersion)
+		return
+	}
+	checkSchemaVersion(migrator, cfg.Database.AutoMigrate)
+
 	// Initialize MinIO storage
 	store, err := storage.New(cfg.MinIO)
 	if err != nil {
 		log.Fatalf("Failed to initialize storage: %v", err)
 	}
 
-	// Create buckets if not exist
+	// Create buckets if not exist, waiting for MinIO if it is still starting
 	ctx := context.Background()
 	if err := store.EnsureBuckets(ctx); err != nil {
-		log.Fatalf("Failed to ensure buckets: %v", err)
+		log.Fatalf("Storage is not usable: %v", err)
 	}
 
 	// Create and start server
@@ -68,3 +86,64 @@ func main() {
 
 	log.Println("Server exited properly")
 }
+
+func runMigrate(m *database.Migrator, cmd string, steps int, version uint) {
+	ctx := context.Background()
+
+	switch cmd {
+	case "up":
+		n, err := m.Up(ctx)
+		if err != nil {
+			log.Fatalf("Migrate up failed after %d migration(s): %v", n, err)
+		}
+		log.Printf("Applied %d migration(s)", n)
+	case "down":
+		n, err := m.Down(ctx, steps)
+		if err != nil {
+			log.Fatalf("Migrate down failed after %d migration(s): %v", n, err)
+		}
+		log.Printf("Reverted %d migration(s)", n)
+	case "force":
+		if err := m.Force(ctx, version); err != nil {
+			log.Fatalf("Migrate force failed: %v", err)
+		}
+		log.Printf("Forced schema version to %d", version)
+	case "version":
+	default:
+		log.Fatalf("Unknown -migrate command %q (want up, down, version, force)", cmd)
+	}
+
+	current, dirty, err := m.Version(ctx)
+	if err != nil {
+		log.Fatalf("Failed to read schema version: %v", err)
+	}
+	log.Printf("Schema version %d (latest %d, dirty %t)", current, m.Latest(), dirty)
+}
+
+// checkSchemaVersion refuses to start on a dirty schema and either applies or
+// warns about pending migrations
+func checkSchemaVersion(m *database.Migrator, autoMigrate bool) {
+	ctx := context.Background()
+
+	current, dirty, err := m.Version(ctx)
+	if err != nil {
+		log.Fatalf("Failed to read schema version: %v", err)
+	}
+	if dirty {
+		log.Fatalf("Schema version %d is dirty; repair it and run with -migrate force -version N", current)
+	}
+	if current >= m.Latest() {
+		return
+	}
+
+	if !autoMigrate {
+		log.Printf("Warning: schema version %d is behind latest migration %d; run with -migrate up", current, m.Latest())
+		return
+	}
+
+	n, err := m.Up(ctx)
+	if err != nil {
+		log.Fatalf("Auto-migrate failed after %d migration(s): %v", n, err)
+	}
+	log.Printf("Auto-migrate applied %d migration(s)", n)
+}
@@ -0,0 +1,8 @@
+// Package db embeds the SQL migrations so the API binary can apply them
+// without the migrate CLI.
+package db
+
+import "embed"
+
+//go:embed migrations/*.sql
+var Migrations embed.FS
@@ -0,0 +1,14 @@
+-- Revert workspaces
+DROP INDEX IF EXISTS idx_files_workspace_id;
+DROP INDEX IF EXISTS idx_folders_workspace_id;
+
+ALTER TABLE files DROP CONSTRAINT IF EXISTS fk_files_workspace;
+ALTER TABLE folders DROP CONSTRAINT IF EXISTS fk_folders_workspace;
+ALTER TABLE pending_uploads DROP CONSTRAINT IF EXISTS fk_pending_uploads_workspace;
+
+ALTER TABLE pending_uploads DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE files DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE folders DROP COLUMN IF EXISTS workspace_id;
+
+DROP TABLE IF EXISTS workspace_members;
+DROP TABLE IF EXISTS workspaces;
@@ -0,0 +1,2 @@
+-- Cache the SHA-256 of each stored object for integrity checks
+ALTER TABLE files ADD COLUMN IF NOT EXISTS checksum_sha256 VARCHAR(64);
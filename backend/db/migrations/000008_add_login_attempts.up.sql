@@ -0,0 +1,7 @@
+-- Failed login tracking per email, for locking out brute-force attempts
+CREATE TABLE IF NOT EXISTS login_attempts (
+    email VARCHAR(255) PRIMARY KEY,
+    failed_count INTEGER NOT NULL DEFAULT 0,
+    window_started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMPTZ
+);
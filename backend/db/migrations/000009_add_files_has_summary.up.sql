@@ -0,0 +1,7 @@
+-- Denormalized flag so file listings no longer join summaries on every call.
+-- Set when a summary is created; summaries are only removed with their file.
+ALTER TABLE files ADD COLUMN IF NOT EXISTS has_summary BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE files f
+SET has_summary = TRUE
+WHERE EXISTS (SELECT 1 FROM summaries s WHERE s.file_id = f.id);
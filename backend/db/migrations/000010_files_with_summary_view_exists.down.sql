@@ -0,0 +1,19 @@
+CREATE OR REPLACE VIEW files_with_summary_view AS
+SELECT 
+    f.id,
+    f.user_id,
+    f.folder_id,
+    f.filename,
+    f.original_filename,
+    f.file_size,
+    f.page_count,
+    f.status,
+    f.uploaded_at,
+    s.id AS summary_id,
+    s.title AS summary_title,
+    s.style AS summary_style,
+    s.processing_duration_ms,
+    s.version AS summary_version,
+    CASE WHEN s.id IS NOT NULL THEN TRUE ELSE FALSE END AS has_summary
+FROM files f
+LEFT JOIN summaries s ON s.file_id = f.id AND s.is_current = TRUE;
@@ -0,0 +1,27 @@
+-- Pick at most one current summary per file, so a broken is_current invariant
+-- can no longer list a file twice, and derive has_summary with EXISTS
+CREATE OR REPLACE VIEW files_with_summary_view AS
+SELECT 
+    f.id,
+    f.user_id,
+    f.folder_id,
+    f.filename,
+    f.original_filename,
+    f.file_size,
+    f.page_count,
+    f.status,
+    f.uploaded_at,
+    s.id AS summary_id,
+    s.title AS summary_title,
+    s.style AS summary_style,
+    s.processing_duration_ms,
+    s.version AS summary_version,
+    EXISTS (SELECT 1 FROM summaries sx WHERE sx.file_id = f.id) AS has_summary
+FROM files f
+LEFT JOIN LATERAL (
+    SELECT id, title, style, processing_duration_ms, version
+    FROM summaries
+    WHERE file_id = f.id AND is_current = TRUE
+    ORDER BY version DESC
+    LIMIT 1
+) s ON TRUE;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_files_search_vector;
+ALTER TABLE files DROP COLUMN IF EXISTS search_vector;
+DROP INDEX IF EXISTS idx_files_original_filename_trgm;
+DROP INDEX IF EXISTS idx_files_filename_trgm;
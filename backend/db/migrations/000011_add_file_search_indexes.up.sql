@@ -0,0 +1,13 @@
+-- Trigram indexes make the ILIKE filename search index-usable, including the
+-- leading-wildcard "contains" mode
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_files_filename_trgm ON files USING GIN (filename gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_files_original_filename_trgm ON files USING GIN (original_filename gin_trgm_ops);
+
+-- Word-level search over the display name; separators are turned into spaces
+-- so "annual_report-2024.pdf" yields the words annual, report, 2024 and pdf
+ALTER TABLE files ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', translate(original_filename, '._-', '   '))) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_files_search_vector ON files USING GIN (search_vector);
@@ -0,0 +1 @@
+-- Paths are derived data; nothing to undo
@@ -0,0 +1,16 @@
+-- Folders created by the application were stored with an empty path and
+-- moves never refreshed their subtree; rebuild every path and depth from
+-- parent_id
+WITH RECURSIVE folder_tree AS (
+    SELECT id, '/' || id::text AS path, 0 AS depth
+    FROM folders
+    WHERE parent_id IS NULL
+    UNION ALL
+    SELECT f.id, ft.path || '/' || f.id::text, ft.depth + 1
+    FROM folders f
+    JOIN folder_tree ft ON f.parent_id = ft.id
+)
+UPDATE folders f
+SET path = ft.path, depth = ft.depth
+FROM folder_tree ft
+WHERE f.id = ft.id AND (f.path IS DISTINCT FROM ft.path OR f.depth IS DISTINCT FROM ft.depth);
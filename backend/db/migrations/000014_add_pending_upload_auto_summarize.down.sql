@@ -0,0 +1,4 @@
+ALTER TABLE pending_uploads
+    DROP COLUMN IF EXISTS summary_language,
+    DROP COLUMN IF EXISTS summary_style,
+    DROP COLUMN IF EXISTS auto_summarize;
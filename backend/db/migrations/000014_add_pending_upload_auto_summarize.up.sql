@@ -0,0 +1,5 @@
+-- Opt-in summarization right after an upload is confirmed
+ALTER TABLE pending_uploads
+    ADD COLUMN IF NOT EXISTS auto_summarize BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS summary_style summary_style,
+    ADD COLUMN IF NOT EXISTS summary_language VARCHAR(10);
@@ -0,0 +1,3 @@
+ALTER TABLE workspaces
+    DROP COLUMN IF EXISTS default_language,
+    DROP COLUMN IF EXISTS default_style;
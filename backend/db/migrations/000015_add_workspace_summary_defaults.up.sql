@@ -0,0 +1,4 @@
+-- Workspace-wide defaults for summaries of workspace files
+ALTER TABLE workspaces
+    ADD COLUMN IF NOT EXISTS default_style summary_style,
+    ADD COLUMN IF NOT EXISTS default_language VARCHAR(10);
@@ -0,0 +1,21 @@
+-- Anonymous usage records of guest summaries for capacity planning; no IP,
+-- filename or content is kept
+CREATE TABLE IF NOT EXISTS guest_summary_metrics (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    mode VARCHAR(10) NOT NULL,
+    style VARCHAR(20) NOT NULL,
+    language VARCHAR(10) NOT NULL,
+    file_size BIGINT NOT NULL,
+    page_count INTEGER,
+    success BOOLEAN NOT NULL,
+    total_duration_ms INTEGER NOT NULL,
+    ai_duration_ms INTEGER,
+    prompt_tokens INTEGER,
+    completion_tokens INTEGER,
+    model_used VARCHAR(100),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT guest_summary_metrics_mode_check CHECK (mode IN ('sync', 'stream'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_guest_summary_metrics_created_at ON guest_summary_metrics(created_at);
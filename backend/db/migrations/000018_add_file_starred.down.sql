@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_files_user_starred;
+
+ALTER TABLE files DROP COLUMN IF EXISTS starred;
@@ -0,0 +1,4 @@
+-- Favorites for quick access, listed with GET /files?starred=true
+ALTER TABLE files ADD COLUMN IF NOT EXISTS starred BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_files_user_starred ON files(user_id) WHERE starred;
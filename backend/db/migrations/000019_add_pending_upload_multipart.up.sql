@@ -0,0 +1,2 @@
+-- S3 upload ID of a resumable multipart upload; NULL for single-request uploads
+ALTER TABLE pending_uploads ADD COLUMN IF NOT EXISTS multipart_upload_id VARCHAR(255);
@@ -0,0 +1,3 @@
+-- Text produced by a summary attempt that did not finish, continued by
+-- POST /summaries/:file_id/resume; cleared once a summary is saved
+ALTER TABLE files ADD COLUMN IF NOT EXISTS partial_summary TEXT;
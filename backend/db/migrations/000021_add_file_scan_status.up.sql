@@ -0,0 +1,2 @@
+-- Malware scan result of the upload; NULL when no scanner was configured
+ALTER TABLE files ADD COLUMN IF NOT EXISTS scan_status VARCHAR(20);
@@ -0,0 +1 @@
+ALTER TABLE summaries DROP COLUMN IF EXISTS source_text;
@@ -0,0 +1,3 @@
+-- Text the AI service extracted for a summary; only kept when
+-- STORE_SUMMARY_SOURCE_TEXT is enabled
+ALTER TABLE summaries ADD COLUMN IF NOT EXISTS source_text TEXT;
@@ -0,0 +1,3 @@
+-- Duplicate lookups on upload confirm and GET /files?checksum=
+CREATE INDEX IF NOT EXISTS idx_files_user_checksum ON files(user_id, checksum_sha256)
+    WHERE checksum_sha256 IS NOT NULL AND deleted_at IS NULL;
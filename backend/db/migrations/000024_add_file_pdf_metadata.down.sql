@@ -0,0 +1,3 @@
+ALTER TABLE files DROP COLUMN IF EXISTS pdf_created_at;
+ALTER TABLE files DROP COLUMN IF EXISTS pdf_author;
+ALTER TABLE files DROP COLUMN IF EXISTS pdf_title;
@@ -0,0 +1,4 @@
+-- Document info read from the PDF after upload; NULL when missing or unreadable
+ALTER TABLE files ADD COLUMN IF NOT EXISTS pdf_title TEXT;
+ALTER TABLE files ADD COLUMN IF NOT EXISTS pdf_author TEXT;
+ALTER TABLE files ADD COLUMN IF NOT EXISTS pdf_created_at TIMESTAMPTZ;
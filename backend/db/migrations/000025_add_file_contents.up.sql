@@ -0,0 +1,9 @@
+-- Plain text extracted from each PDF after upload, for GET /files/search
+CREATE TABLE IF NOT EXISTS file_contents (
+    file_id UUID PRIMARY KEY REFERENCES files(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    search_vector tsvector GENERATED ALWAYS AS (to_tsvector('simple', content)) STORED,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_contents_search_vector ON file_contents USING GIN (search_vector);
@@ -0,0 +1,10 @@
+-- Workspaces a file is shared into besides its home workspace (files.workspace_id)
+CREATE TABLE IF NOT EXISTS file_workspace_shares (
+    file_id UUID NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    shared_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (file_id, workspace_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_workspace_shares_workspace_id ON file_workspace_shares(workspace_id);
@@ -0,0 +1,12 @@
+-- One rating per user per summary version, with an optional comment
+CREATE TABLE IF NOT EXISTS summary_feedback (
+    summary_id UUID NOT NULL REFERENCES summaries(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    rating SMALLINT NOT NULL CONSTRAINT summary_feedback_rating_range CHECK (rating BETWEEN 1 AND 5),
+    comment TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (summary_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_summary_feedback_user_id ON summary_feedback(user_id);
@@ -0,0 +1,13 @@
+-- Reusable summary styles a user defines on top of a built-in one
+CREATE TABLE IF NOT EXISTS user_summary_styles (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    base_style summary_style NOT NULL DEFAULT 'bullet_points',
+    instructions TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    CONSTRAINT user_summary_styles_name_unique UNIQUE (user_id, name),
+    CONSTRAINT user_summary_styles_name_not_empty CHECK (LENGTH(TRIM(name)) > 0),
+    CONSTRAINT user_summary_styles_instructions_length CHECK (LENGTH(instructions) BETWEEN 1 AND 500)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE workspaces DROP CONSTRAINT IF EXISTS fk_workspaces_owner;
+ALTER TABLE workspaces
+    ADD CONSTRAINT workspaces_owner_id_fkey FOREIGN KEY (owner_id) REFERENCES users(id);
@@ -0,0 +1,9 @@
+-- Deleting a user removes the workspaces they own. 000001 created owner_id
+-- without ON DELETE, so account deletion failed for every workspace owner.
+-- UserRepository.Delete refuses owners of workspaces with other members and
+-- moves other people's files out first, so the cascade only takes workspaces
+-- the user had to themselves.
+ALTER TABLE workspaces DROP CONSTRAINT IF EXISTS workspaces_owner_id_fkey;
+ALTER TABLE workspaces DROP CONSTRAINT IF EXISTS fk_workspaces_owner;
+ALTER TABLE workspaces
+    ADD CONSTRAINT fk_workspaces_owner FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE;
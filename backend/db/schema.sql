@@ -7,6 +7,8 @@
 -- Enable UUID extension for generating UUIDs
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 CREATE EXTENSION IF NOT EXISTS "pgcrypto";
+-- Trigram indexes for filename search
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
 
 -- ============================================================================
 -- 1. USERS TABLE
@@ -21,6 +23,9 @@ CREATE TABLE users (
     avatar_url TEXT,
     is_active BOOLEAN DEFAULT TRUE,
     email_verified_at TIMESTAMPTZ,
+    totp_secret VARCHAR(64),           -- Base32 TOTP secret (set during 2FA setup)
+    totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    totp_last_step BIGINT,             -- Last accepted TOTP step, prevents code replay
     created_at TIMESTAMPTZ DEFAULT NOW(),
     updated_at TIMESTAMPTZ DEFAULT NOW(),
     
@@ -43,6 +48,7 @@ CREATE TABLE refresh_tokens (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     user_id UUID NOT NULL,
     token_hash VARCHAR(64) NOT NULL,  -- SHA-256 hash of the refresh token
+    family_id UUID NOT NULL,           -- Shared by tokens rotated from the same login
     device_info TEXT,                  -- Optional: browser/device identifier
     ip_address INET,                   -- Optional: IP address for security
     expires_at TIMESTAMPTZ NOT NULL,
@@ -60,6 +66,7 @@ CREATE TABLE refresh_tokens (
 -- Indexes for token validation and cleanup
 CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens(user_id);
 CREATE INDEX idx_refresh_tokens_token_hash ON refresh_tokens(token_hash);
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens(family_id);
 CREATE INDEX idx_refresh_tokens_expires_at ON refresh_tokens(expires_at);
 CREATE INDEX idx_refresh_tokens_active ON refresh_tokens(user_id, revoked_at) 
     WHERE revoked_at IS NULL;
@@ -141,8 +148,18 @@ CREATE TABLE files (
     mime_type VARCHAR(100) DEFAULT 'application/pdf',
     file_size BIGINT NOT NULL,         -- Size in bytes
     page_count INTEGER,                -- Number of pages (extracted after upload)
+    checksum_sha256 VARCHAR(64),       -- SHA-256 of the stored object, set on upload or first request
     status processing_status DEFAULT 'uploaded',
     error_message TEXT,                -- Error details if status = 'failed'
+    has_summary BOOLEAN NOT NULL DEFAULT FALSE, -- Set when the first summary is saved
+    starred BOOLEAN NOT NULL DEFAULT FALSE,     -- Favorite of the owner
+    partial_summary TEXT,              -- Output of an unfinished summary attempt, for resume
+    scan_status VARCHAR(20),           -- Malware scan result ('clean'); NULL when not scanned
+    pdf_title TEXT,                    -- Document info of the PDF, read after upload
+    pdf_author TEXT,
+    pdf_created_at TIMESTAMPTZ,
+    -- Words of the display name for ?search_mode=fulltext
+    search_vector tsvector GENERATED ALWAYS AS (to_tsvector('simple', translate(original_filename, '._-', '   '))) STORED,
     -- Latest summary cache fields (synced from summaries table via trigger)
     latest_summary_title VARCHAR(500),
     latest_summary TEXT,
@@ -155,6 +172,7 @@ CREATE TABLE files (
     processed_at TIMESTAMPTZ,          -- When processing completed
     created_at TIMESTAMPTZ DEFAULT NOW(),
     updated_at TIMESTAMPTZ DEFAULT NOW(),
+    deleted_at TIMESTAMPTZ,            -- Set while the file is in the trash
     
     -- Foreign Keys
     CONSTRAINT fk_files_user 
@@ -175,6 +193,30 @@ CREATE INDEX idx_files_status ON files(status);
 CREATE INDEX idx_files_pending ON files(status) WHERE status = 'pending';
 CREATE INDEX idx_files_uploaded ON files(status) WHERE status = 'uploaded';
 CREATE INDEX idx_files_uploaded_at ON files(uploaded_at DESC);
+CREATE INDEX idx_files_filename_trgm ON files USING GIN (filename gin_trgm_ops);
+CREATE INDEX idx_files_original_filename_trgm ON files USING GIN (original_filename gin_trgm_ops);
+CREATE INDEX idx_files_search_vector ON files USING GIN (search_vector);
+CREATE INDEX idx_files_deleted_at ON files(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_files_user_starred ON files(user_id) WHERE starred;
+CREATE INDEX idx_files_user_checksum ON files(user_id, checksum_sha256)
+    WHERE checksum_sha256 IS NOT NULL AND deleted_at IS NULL;
+
+-- ============================================================================
+-- 6. FILE CONTENTS TABLE
+-- Plain text extracted from each PDF after upload, for GET /files/search
+-- BCNF: file_id → all other attributes (one row per file)
+-- ============================================================================
+CREATE TABLE file_contents (
+    file_id UUID PRIMARY KEY,
+    content TEXT NOT NULL,
+    search_vector tsvector GENERATED ALWAYS AS (to_tsvector('simple', content)) STORED,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT fk_file_contents_file
+        FOREIGN KEY (file_id) REFERENCES files(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_file_contents_search_vector ON file_contents USING GIN (search_vector);
 
 -- ============================================================================
 -- 7. SUMMARIES TABLE
@@ -197,6 +239,7 @@ CREATE TABLE summaries (
     processing_completed_at TIMESTAMPTZ,
     processing_duration_ms INTEGER,    -- Duration in milliseconds
     language VARCHAR(10) DEFAULT 'en', -- Summary language (en/id)
+    source_text TEXT,                  -- Extracted text sent to the AI (STORE_SUMMARY_SOURCE_TEXT)
     version INTEGER DEFAULT 1,         -- Summary version (for regeneration)
     is_current BOOLEAN DEFAULT TRUE,   -- Flag for current/latest summary
     created_at TIMESTAMPTZ DEFAULT NOW(),
@@ -321,6 +364,8 @@ CREATE TABLE workspaces (
     name VARCHAR(255) NOT NULL,
     invite_code VARCHAR(20) UNIQUE NOT NULL,
     owner_id UUID NOT NULL,
+    default_style summary_style,        -- Used when a summary request omits style
+    default_language VARCHAR(10),       -- Used when a summary request omits language
     created_at TIMESTAMPTZ DEFAULT NOW(),
     updated_at TIMESTAMPTZ DEFAULT NOW(),
     
@@ -527,7 +572,8 @@ FROM folders f
 LEFT JOIN files ON files.folder_id = f.id
 GROUP BY f.id, f.user_id, f.parent_id, f.name, f.path, f.depth, f.sort_order, f.created_at, f.updated_at;
 
--- View: Files with their current summary status
+-- View: Files with their current summary status (one row per file, even if
+-- several summaries are wrongly marked current)
 CREATE OR REPLACE VIEW files_with_summary_view AS
 SELECT 
     f.id,
@@ -544,9 +590,15 @@ SELECT
     s.style AS summary_style,
     s.processing_duration_ms,
     s.version AS summary_version,
-    CASE WHEN s.id IS NOT NULL THEN TRUE ELSE FALSE END AS has_summary
+    EXISTS (SELECT 1 FROM summaries sx WHERE sx.file_id = f.id) AS has_summary
 FROM files f
-LEFT JOIN summaries s ON s.file_id = f.id AND s.is_current = TRUE;
+LEFT JOIN LATERAL (
+    SELECT id, title, style, processing_duration_ms, version
+    FROM summaries
+    WHERE file_id = f.id AND is_current = TRUE
+    ORDER BY version DESC
+    LIMIT 1
+) s ON TRUE;
 
 -- ============================================================================
 -- 13. INITIAL SEED DATA (Optional)
@@ -597,6 +649,10 @@ CREATE TABLE pending_uploads (
     file_size BIGINT NOT NULL,
     content_type VARCHAR(100) NOT NULL,
     storage_path TEXT NOT NULL,
+    auto_summarize BOOLEAN NOT NULL DEFAULT FALSE,  -- Enqueue a summary on confirm
+    summary_style summary_style,                    -- Style for the automatic summary
+    summary_language VARCHAR(10),                   -- Language for the automatic summary
+    multipart_upload_id VARCHAR(255),               -- S3 upload ID of a multipart upload
     expires_at TIMESTAMPTZ NOT NULL,
     created_at TIMESTAMPTZ DEFAULT NOW(),
     
@@ -611,4 +667,241 @@ CREATE TABLE pending_uploads (
 
 -- Indexes for pending uploads
 CREATE INDEX idx_pending_uploads_user ON pending_uploads(user_id);
-CREATE INDEX idx_pending_uploads_expires ON pending_uploads(expires_at);
\ No newline at end of file
+CREATE INDEX idx_pending_uploads_expires ON pending_uploads(expires_at);
+
+-- ============================================================================
+-- 16. EMAIL VERIFICATION TOKENS TABLE
+-- Single-use tokens (stored hashed) for confirming a user's email address
+-- ============================================================================
+CREATE TABLE email_verification_tokens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    token_hash VARCHAR(64) NOT NULL,  -- SHA-256 hash of the emailed token
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,               -- Set once the token is redeemed
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    
+    -- Foreign Keys
+    CONSTRAINT fk_email_verification_tokens_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    
+    -- Constraints
+    CONSTRAINT email_verification_tokens_hash_unique UNIQUE (token_hash)
+);
+
+CREATE INDEX idx_email_verification_tokens_user_id ON email_verification_tokens(user_id);
+
+-- ============================================================================
+-- 17. PASSWORD RESET TOKENS TABLE
+-- Single-use, short-lived tokens (stored hashed) for resetting a password
+-- ============================================================================
+CREATE TABLE password_reset_tokens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    token_hash VARCHAR(64) NOT NULL,  -- SHA-256 hash of the emailed token
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,               -- Set once the token is redeemed
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    
+    -- Foreign Keys
+    CONSTRAINT fk_password_reset_tokens_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    
+    -- Constraints
+    CONSTRAINT password_reset_tokens_hash_unique UNIQUE (token_hash)
+);
+
+CREATE INDEX idx_password_reset_tokens_user_id ON password_reset_tokens(user_id);
+
+-- ============================================================================
+-- 18. TOTP RECOVERY CODES TABLE
+-- One-time codes (stored hashed) for signing in without the authenticator
+-- ============================================================================
+CREATE TABLE totp_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,   -- SHA-256 hash of the recovery code
+    used_at TIMESTAMPTZ,               -- Set once the code is redeemed
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    
+    -- Foreign Keys
+    CONSTRAINT fk_totp_recovery_codes_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    
+    -- Constraints
+    CONSTRAINT totp_recovery_codes_user_hash_unique UNIQUE (user_id, code_hash)
+);
+
+-- ============================================================================
+-- 19. LOGIN ATTEMPTS TABLE
+-- Failed logins per email; keyed by email so unknown accounts are throttled too.
+-- Failed second-factor codes are counted under "2fa:<user id>".
+-- ============================================================================
+CREATE TABLE login_attempts (
+    email VARCHAR(255) PRIMARY KEY,    -- Lowercased login email, or 2fa:<user id>
+    failed_count INTEGER NOT NULL DEFAULT 0,
+    window_started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMPTZ           -- Logins are refused until this time
+);
+
+-- ============================================================================
+-- 20. WORKSPACE INVITES TABLE
+-- Invite links (stored hashed) with optional expiry and use limit; they
+-- coexist with the static invite_code on workspaces
+-- ============================================================================
+CREATE TABLE workspace_invites (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    workspace_id UUID NOT NULL,
+    created_by UUID NOT NULL,
+    token_hash VARCHAR(64) NOT NULL,  -- SHA-256 hash of the invite token
+    expires_at TIMESTAMPTZ,            -- NULL = never expires
+    max_uses INTEGER,                  -- NULL = unlimited
+    use_count INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    
+    -- Foreign Keys
+    CONSTRAINT fk_workspace_invites_workspace
+        FOREIGN KEY (workspace_id) REFERENCES workspaces(id) ON DELETE CASCADE,
+    CONSTRAINT fk_workspace_invites_creator
+        FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE,
+    
+    -- Constraints
+    CONSTRAINT workspace_invites_hash_unique UNIQUE (token_hash),
+    CONSTRAINT workspace_invites_max_uses_positive CHECK (max_uses IS NULL OR max_uses > 0)
+);
+
+CREATE INDEX idx_workspace_invites_workspace_id ON workspace_invites(workspace_id);
+
+-- ============================================================================
+-- 21. GUEST SUMMARY METRICS TABLE
+-- One anonymous row per guest summary (no IP, filename or content) for
+-- capacity planning; aggregated on /admin/guest-metrics
+-- ============================================================================
+CREATE TABLE guest_summary_metrics (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    mode VARCHAR(10) NOT NULL,         -- 'sync' or 'stream'
+    style VARCHAR(20) NOT NULL,
+    language VARCHAR(10) NOT NULL,
+    file_size BIGINT NOT NULL,
+    page_count INTEGER,                -- NULL when the PDF could not be parsed
+    success BOOLEAN NOT NULL,
+    total_duration_ms INTEGER NOT NULL, -- Measured by the backend, end to end
+    ai_duration_ms INTEGER,            -- As reported by the AI service
+    prompt_tokens INTEGER,
+    completion_tokens INTEGER,
+    model_used VARCHAR(100),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    
+    -- Constraints
+    CONSTRAINT guest_summary_metrics_mode_check CHECK (mode IN ('sync', 'stream'))
+);
+
+CREATE INDEX idx_guest_summary_metrics_created_at ON guest_summary_metrics(created_at);
+
+-- ============================================================================
+-- 22. FILE WORKSPACE SHARES TABLE
+-- Workspaces a file is shared into besides its home workspace (files.workspace_id)
+-- BCNF: (file_id, workspace_id) → shared_by, created_at
+-- ============================================================================
+CREATE TABLE file_workspace_shares (
+    file_id UUID NOT NULL,
+    workspace_id UUID NOT NULL,
+    shared_by UUID NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+
+    PRIMARY KEY (file_id, workspace_id),
+
+    -- Foreign Keys
+    CONSTRAINT fk_file_workspace_shares_file
+        FOREIGN KEY (file_id) REFERENCES files(id) ON DELETE CASCADE,
+    CONSTRAINT fk_file_workspace_shares_workspace
+        FOREIGN KEY (workspace_id) REFERENCES workspaces(id) ON DELETE CASCADE,
+    CONSTRAINT fk_file_workspace_shares_user
+        FOREIGN KEY (shared_by) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_file_workspace_shares_workspace_id ON file_workspace_shares(workspace_id);
+
+-- ============================================================================
+-- 23. SUMMARY FEEDBACK TABLE
+-- One rating (1-5) per user per summary version, with an optional comment
+-- BCNF: (summary_id, user_id) → rating, comment, created_at, updated_at
+-- ============================================================================
+CREATE TABLE summary_feedback (
+    summary_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    rating SMALLINT NOT NULL,
+    comment TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+
+    PRIMARY KEY (summary_id, user_id),
+
+    -- Foreign Keys
+    CONSTRAINT fk_summary_feedback_summary
+        FOREIGN KEY (summary_id) REFERENCES summaries(id) ON DELETE CASCADE,
+    CONSTRAINT fk_summary_feedback_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+
+    -- Constraints
+    CONSTRAINT summary_feedback_rating_range CHECK (rating BETWEEN 1 AND 5)
+);
+
+CREATE INDEX idx_summary_feedback_user_id ON summary_feedback(user_id);
+
+-- ============================================================================
+-- 24. USER SUMMARY STYLES TABLE
+-- Reusable styles a user defines: a built-in style plus instructions that are
+-- sent as custom_instructions
+-- BCNF: id → user_id, name, base_style, instructions, created_at, updated_at
+--       (user_id, name) → id
+-- ============================================================================
+CREATE TABLE user_summary_styles (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    base_style summary_style NOT NULL DEFAULT 'bullet_points',
+    instructions TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+
+    -- Foreign Keys
+    CONSTRAINT fk_user_summary_styles_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+
+    -- Constraints
+    CONSTRAINT user_summary_styles_name_unique UNIQUE (user_id, name),
+    CONSTRAINT user_summary_styles_name_not_empty CHECK (LENGTH(TRIM(name)) > 0),
+    CONSTRAINT user_summary_styles_instructions_length CHECK (LENGTH(instructions) BETWEEN 1 AND 500)
+);
+
+-- ============================================================================
+-- 25. TWO-FACTOR CHALLENGES TABLE
+-- Login challenges issued after the password step of a 2FA login; each is
+-- redeemed at most once and burned after too many wrong codes
+-- ============================================================================
+CREATE TABLE two_factor_challenges (
+    id UUID PRIMARY KEY,               -- The challenge token's jti
+    user_id UUID NOT NULL,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,               -- Set once redeemed or burned
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+
+    -- Foreign Keys
+    CONSTRAINT fk_two_factor_challenges_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_two_factor_challenges_user_id ON two_factor_challenges(user_id);
+
+-- ============================================================================
+-- 26. SCHEMA MIGRATIONS
+-- This file already includes every migration in db/migrations, so record the
+-- latest version for the migration runner. Bump it with each new migration.
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT NOT NULL PRIMARY KEY,
+    dirty BOOLEAN NOT NULL
+);
+INSERT INTO schema_migrations (version, dirty) VALUES (30, false);
@@ -0,0 +1,219 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Migrations use golang-migrate's file naming (000001_name.up.sql) and its
+// schema_migrations table, so the embedded runner and the migrate CLI used by
+// the Makefile can be mixed on the same database.
+var migrationFile = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+var ErrDirtyDatabase = errors.New("database is in a dirty migration state")
+
+type migration struct {
+	version uint
+	name    string
+	up      string
+	down    string
+}
+
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []migration
+}
+
+func NewMigrator(pool *pgxpool.Pool, fsys fs.FS) (*Migrator, error) {
+	files, err := fs.Glob(fsys, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint]*migration)
+	for _, path := range files {
+		name := path[len("migrations/"):]
+		m := migrationFile.FindStringSubmatch(name)
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration filename %s", name)
+		}
+
+		version, err := strconv.ParseUint(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", name, err)
+		}
+
+		body, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[uint(version)]
+		if !ok {
+			mig = &migration{version: uint(version), name: m[2]}
+			byVersion[uint(version)] = mig
+		}
+		if m[3] == "up" {
+			mig.up = string(body)
+		} else {
+			mig.down = string(body)
+		}
+	}
+
+	migrator := &Migrator{pool: pool}
+	for _, mig := range byVersion {
+		if mig.up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", mig.version, mig.name)
+		}
+		migrator.migrations = append(migrator.migrations, *mig)
+	}
+	sort.Slice(migrator.migrations, func(i, j int) bool {
+		return migrator.migrations[i].version < migrator.migrations[j].version
+	})
+
+	return migrator, nil
+}
+
+// Latest returns the highest embedded migration version
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].version
+}
+
+// Version returns the applied version; 0 means no migration has run
+func (m *Migrator) Version(ctx context.Context) (uint, bool, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return 0, false, err
+	}
+
+	var version int64
+	var dirty bool
+	err := m.pool.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+
+	return uint(version), dirty, nil
+}
+
+// Up applies every migration newer than the current version. Each migration
+// runs in its own transaction together with the version bump.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	current, err := m.cleanVersion(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, mig := range m.migrations {
+		if mig.version <= current {
+			continue
+		}
+		if err := m.apply(ctx, mig.up, int64(mig.version)); err != nil {
+			return applied, fmt.Errorf("migration %d_%s up: %w", mig.version, mig.name, err)
+		}
+		applied++
+	}
+
+	return applied, nil
+}
+
+// Down reverts up to steps migrations, newest first
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	current, err := m.cleanVersion(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	reverted := 0
+	for i := len(m.migrations) - 1; i >= 0 && reverted < steps; i-- {
+		mig := m.migrations[i]
+		if mig.version > current {
+			continue
+		}
+		if mig.down == "" {
+			return reverted, fmt.Errorf("migration %d_%s has no down file", mig.version, mig.name)
+		}
+
+		var previous int64 = -1
+		if i > 0 {
+			previous = int64(m.migrations[i-1].version)
+		}
+		if err := m.apply(ctx, mig.down, previous); err != nil {
+			return reverted, fmt.Errorf("migration %d_%s down: %w", mig.version, mig.name, err)
+		}
+		reverted++
+	}
+
+	return reverted, nil
+}
+
+// Force records version as applied and clean without running any SQL. Use it
+// to adopt a database created from schema.sql or to recover from a dirty state.
+func (m *Migrator) Force(ctx context.Context, version uint) error {
+	if err := m.ensureTable(ctx); err != nil {
+		return err
+	}
+	return m.apply(ctx, "", int64(version))
+}
+
+func (m *Migrator) cleanVersion(ctx context.Context) (uint, error) {
+	version, dirty, err := m.Version(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, fmt.Errorf("%w at version %d; fix it manually and use -migrate force", ErrDirtyDatabase, version)
+	}
+	return version, nil
+}
+
+// apply runs sql and sets the recorded version atomically. A negative version
+// clears the table, matching golang-migrate after the first migration is reverted.
+func (m *Migrator) apply(ctx context.Context, sql string, version int64) error {
+	tx, err := m.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if sql != "" {
+		if _, err := tx.Exec(ctx, sql); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `TRUNCATE schema_migrations`); err != nil {
+		return err
+	}
+	if version >= 0 {
+		if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)`, version); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	_, err := m.pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT NOT NULL PRIMARY KEY,
+			dirty BOOLEAN NOT NULL
+		)
+	`)
+	return err
+}
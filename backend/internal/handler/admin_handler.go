@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/nextpdf/backend/internal/models"
+	"github.com/nextpdf/backend/internal/repository"
+	"github.com/nextpdf/backend/internal/service"
+	"github.com/nextpdf/backend/internal/storage"
+)
+
+// AdminHandler serves troubleshooting endpoints restricted to admins
+type AdminHandler struct {
+	summaryService     *service.SummaryService
+	fileService        *service.FileService
+	maintenanceService *service.MaintenanceService
+	guestMetrics       *service.GuestMetricsService
+}
+
+func NewAdminHandler(summaryService *service.SummaryService, fileService *service.FileService, maintenanceService *service.MaintenanceService, guestMetrics *service.GuestMetricsService) *AdminHandler {
+	return &AdminHandler{
+		summaryService:     summaryService,
+		fileService:        fileService,
+		maintenanceService: maintenanceService,
+		guestMetrics:       guestMetrics,
+	}
+}
+
+// GetSummaryDebug returns the parameters behind a summary
+// GET /api/v1/admin/summaries/:id/debug?dry_run=true
+func (h *AdminHandler) GetSummaryDebug(c *fiber.Ctx) error {
+	summaryID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid summary ID",
+		))
+	}
+
+	debug, err := h.summaryService.GetDebugInfo(c.Context(), summaryID, c.QueryBool("dry_run"))
+	if err != nil {
+		if errors.Is(err, repository.ErrSummaryNotFound) || errors.Is(err, repository.ErrFileNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"SUMMARY_NOT_FOUND",
+				"Summary not found",
+			))
+		}
+		if errors.Is(err, service.ErrAIServiceStatus) {
+			return c.Status(fiber.StatusBadGateway).JSON(models.NewErrorResponse(
+				"AI_SERVICE_ERROR",
+				err.Error(),
+			))
+		}
+		log.Printf("Summary debug failed for %s: %v", summaryID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to get summary debug info",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(debug, ""))
+}
+
+// ReconcileStorage cross-checks the files bucket against the files table
+// POST /api/v1/admin/storage/reconcile
+func (h *AdminHandler) ReconcileStorage(c *fiber.Ctx) error {
+	var req models.StorageReconcileRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"VALIDATION_ERROR",
+				"Invalid request body",
+			))
+		}
+	}
+
+	report, err := h.maintenanceService.ReconcileStorage(c.Context(), req.DeleteOrphans)
+	if err != nil {
+		if errors.Is(err, storage.ErrStorageUnavailable) {
+			return storageUnavailable(c)
+		}
+		log.Printf("Storage reconcile failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to reconcile storage",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(report, ""))
+}
+
+// BackfillPageCounts fills in missing page counts across all users
+// POST /api/v1/admin/files/page-count/backfill?limit=50
+func (h *AdminHandler) BackfillPageCounts(c *fiber.Ctx) error {
+	report, err := h.fileService.BackfillPageCounts(c.Context(), nil, backfillLimit(c))
+	if err != nil {
+		if errors.Is(err, storage.ErrStorageUnavailable) {
+			return storageUnavailable(c)
+		}
+		log.Printf("Page count backfill failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to backfill page counts",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(report, ""))
+}
+
+// GetGuestMetrics aggregates anonymous guest summary metrics
+// GET /api/v1/admin/guest-metrics?days=30
+func (h *AdminHandler) GetGuestMetrics(c *fiber.Ctx) error {
+	days := c.QueryInt("days", 30)
+	if days < 1 || days > 365 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "days", Message: "Days must be between 1 and 365"},
+		}))
+	}
+
+	metrics, err := h.guestMetrics.Summary(c.Context(), days)
+	if err != nil {
+		log.Printf("Guest metrics failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to get guest metrics",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(metrics, ""))
+}
+
+// GetFileCacheStats reports hits and misses of the file metadata cache
+// GET /api/v1/admin/cache/files
+func (h *AdminHandler) GetFileCacheStats(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(h.fileService.CacheStats(), ""))
+}
@@ -2,6 +2,9 @@ package handler
 
 import (
 	"errors"
+	"log"
+	"math"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -88,12 +91,26 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 				"Your account has been deactivated. Please contact support.",
 			))
 		}
+		var locked *service.AccountLockedError
+		if errors.As(err, &locked) {
+			retryAfter := int(math.Ceil(time.Until(locked.Until).Seconds()))
+			c.Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+			return c.Status(fiber.StatusTooManyRequests).JSON(models.NewErrorResponse(
+				"ACCOUNT_LOCKED",
+				"Too many failed login attempts. Please try again later or reset your password.",
+			))
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
 			"INTERNAL_ERROR",
 			"Failed to login",
 		))
 	}
 
+	// No session yet; the client must complete /auth/2fa/verify
+	if response.TwoFactorRequired {
+		return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(response, "Two-factor authentication required"))
+	}
+
 	// Set refresh token cookie
 	c.Cookie(&fiber.Cookie{
 		Name:     "refresh_token",
@@ -119,12 +136,18 @@ func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
 
 	response, newRefreshToken, err := h.authService.RefreshToken(c.Context(), refreshToken)
 	if err != nil {
-		if errors.Is(err, service.ErrInvalidToken) {
+		if errors.Is(err, service.ErrTokenReuse) {
 			return c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(
 				"TOKEN_REUSE_DETECTED",
 				"Suspicious activity detected. All sessions have been terminated. Please login again.",
 			))
 		}
+		if errors.Is(err, service.ErrInvalidToken) {
+			return c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(
+				"INVALID_TOKEN",
+				"Refresh token is invalid. Please login again.",
+			))
+		}
 		if errors.Is(err, service.ErrTokenExpired) {
 			return c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(
 				"TOKEN_EXPIRED",
@@ -171,6 +194,20 @@ func (h *AuthHandler) Logout(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(nil, "Successfully logged out"))
 }
 
+// StreamToken mints a 60-second token for ?token= on streaming endpoints
+// POST /api/v1/auth/stream-token
+func (h *AuthHandler) StreamToken(c *fiber.Ctx) error {
+	response, err := h.authService.IssueStreamToken(middleware.GetUserID(c), middleware.GetUserEmail(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to issue stream token",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(response, ""))
+}
+
 func (h *AuthHandler) LogoutAll(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 
@@ -198,3 +235,276 @@ func (h *AuthHandler) LogoutAll(c *fiber.Ctx) error {
 		"Successfully logged out from all devices",
 	))
 }
+
+func (h *AuthHandler) VerifyEmail(c *fiber.Ctx) error {
+	var req models.VerifyEmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid request body",
+		))
+	}
+
+	if req.Token == "" {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "token", Message: "Token is required"},
+		}))
+	}
+
+	if err := h.authService.VerifyEmail(c.Context(), req.Token); err != nil {
+		if errors.Is(err, service.ErrInvalidToken) {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"INVALID_TOKEN",
+				"This verification link is invalid or has already been used",
+			))
+		}
+		if errors.Is(err, service.ErrTokenExpired) {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"TOKEN_EXPIRED",
+				"This verification link has expired. Please request a new one.",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to verify email",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(nil, "Email verified successfully"))
+}
+
+func (h *AuthHandler) ResendVerification(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	if err := h.authService.SendVerificationEmail(c.Context(), userID); err != nil {
+		if errors.Is(err, service.ErrEmailAlreadyVerified) {
+			return c.Status(fiber.StatusConflict).JSON(models.NewErrorResponse(
+				"EMAIL_ALREADY_VERIFIED",
+				"Your email address is already verified",
+			))
+		}
+		if errors.Is(err, service.ErrVerificationCooldown) {
+			return c.Status(fiber.StatusTooManyRequests).JSON(models.NewErrorResponse(
+				"VERIFICATION_RECENTLY_SENT",
+				"A verification email was sent recently. Please wait a minute before requesting another.",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to send verification email",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(nil, "Verification email sent"))
+}
+
+func (h *AuthHandler) ForgotPassword(c *fiber.Ctx) error {
+	var req models.ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid request body",
+		))
+	}
+
+	if req.Email == "" {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "email", Message: "Email is required"},
+		}))
+	}
+
+	// Same answer whether or not the account exists, to avoid account enumeration
+	if err := h.authService.RequestPasswordReset(c.Context(), req.Email); err != nil {
+		log.Printf("Password reset request failed: %v", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(
+		nil,
+		"If an account exists for this email, a password reset link has been sent.",
+	))
+}
+
+func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
+	var req models.ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid request body",
+		))
+	}
+
+	var validationErrors []models.ValidationError
+	if req.Token == "" {
+		validationErrors = append(validationErrors, models.ValidationError{
+			Field:   "token",
+			Message: "Token is required",
+		})
+	}
+	if len(req.NewPassword) < 8 {
+		validationErrors = append(validationErrors, models.ValidationError{
+			Field:   "new_password",
+			Message: "Password must be at least 8 characters",
+		})
+	}
+	if len(validationErrors) > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse(validationErrors))
+	}
+
+	if err := h.authService.ResetPassword(c.Context(), req.Token, req.NewPassword); err != nil {
+		if errors.Is(err, service.ErrInvalidToken) {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"INVALID_TOKEN",
+				"This reset link is invalid or has already been used",
+			))
+		}
+		if errors.Is(err, service.ErrTokenExpired) {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"TOKEN_EXPIRED",
+				"This reset link has expired. Please request a new one.",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to reset password",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(nil, "Password has been reset. Please login with your new password."))
+}
+
+func (h *AuthHandler) VerifyTwoFactor(c *fiber.Ctx) error {
+	var req models.TwoFactorVerifyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid request body",
+		))
+	}
+
+	if req.ChallengeToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "challenge_token", Message: "Challenge token is required"},
+			{Field: "code", Message: "A code or recovery_code is required"},
+		}))
+	}
+
+	response, refreshToken, err := h.authService.VerifyTwoFactor(c.Context(), &req, c.Get("User-Agent"), c.IP())
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidToken) || errors.Is(err, service.ErrTokenExpired) {
+			return c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(
+				"INVALID_CHALLENGE",
+				"Login challenge is invalid or has expired. Please login again.",
+			))
+		}
+		if errors.Is(err, service.ErrInvalidTwoFactorCode) {
+			return c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(
+				"INVALID_2FA_CODE",
+				"The two-factor code is invalid",
+			))
+		}
+		var locked *service.AccountLockedError
+		if errors.As(err, &locked) {
+			retryAfter := int(math.Ceil(time.Until(locked.Until).Seconds()))
+			c.Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+			return c.Status(fiber.StatusTooManyRequests).JSON(models.NewErrorResponse(
+				"ACCOUNT_LOCKED",
+				"Too many invalid two-factor codes. Please try again later.",
+			))
+		}
+		if errors.Is(err, service.ErrAccountDisabled) {
+			return c.Status(fiber.StatusForbidden).JSON(models.NewErrorResponse(
+				"ACCOUNT_DISABLED",
+				"Your account has been deactivated. Please contact support.",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to verify two-factor code",
+		))
+	}
+
+	// Set refresh token cookie
+	c.Cookie(&fiber.Cookie{
+		Name:     "refresh_token",
+		Value:    refreshToken,
+		Path:     "/api/v1/auth",
+		Expires:  time.Now().Add(7 * 24 * time.Hour),
+		HTTPOnly: true,
+		Secure:   true,
+		SameSite: "Strict",
+	})
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(response, ""))
+}
+
+func (h *AuthHandler) SetupTwoFactor(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	setup, err := h.authService.SetupTwoFactor(c.Context(), userID)
+	if err != nil {
+		if errors.Is(err, service.ErrTwoFactorEnabled) {
+			return c.Status(fiber.StatusConflict).JSON(models.NewErrorResponse(
+				"2FA_ALREADY_ENABLED",
+				"Two-factor authentication is already enabled",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to set up two-factor authentication",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(
+		setup,
+		"Scan the code with your authenticator app, then confirm with a code to enable",
+	))
+}
+
+func (h *AuthHandler) EnableTwoFactor(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	var req models.TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid request body",
+		))
+	}
+
+	if req.Code == "" {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "code", Message: "Code is required"},
+		}))
+	}
+
+	result, err := h.authService.EnableTwoFactor(c.Context(), userID, req.Code)
+	if err != nil {
+		if errors.Is(err, service.ErrTwoFactorEnabled) {
+			return c.Status(fiber.StatusConflict).JSON(models.NewErrorResponse(
+				"2FA_ALREADY_ENABLED",
+				"Two-factor authentication is already enabled",
+			))
+		}
+		if errors.Is(err, service.ErrTwoFactorNotSetUp) {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"2FA_NOT_SET_UP",
+				"Call /me/2fa/setup before enabling two-factor authentication",
+			))
+		}
+		if errors.Is(err, service.ErrInvalidTwoFactorCode) {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"INVALID_2FA_CODE",
+				"The two-factor code is invalid",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to enable two-factor authentication",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(
+		result,
+		"Two-factor authentication enabled. Store these recovery codes somewhere safe.",
+	))
+}
@@ -4,15 +4,13 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
-	"mime/multipart"
 	"net/http"
-	"net/textproto"
-	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -20,36 +18,73 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/nextpdf/backend/internal/config"
 	"github.com/nextpdf/backend/internal/infrastructure"
 	"github.com/nextpdf/backend/internal/middleware"
 	"github.com/nextpdf/backend/internal/models"
+	"github.com/nextpdf/backend/internal/netguard"
 	"github.com/nextpdf/backend/internal/repository"
 	"github.com/nextpdf/backend/internal/service"
+	"github.com/nextpdf/backend/internal/storage"
 )
 
 type FileHandler struct {
 	fileService      *service.FileService
 	workspaceService *service.WorkspaceService
-	httpClient       *http.Client
-	aiServiceURL     string
+	aiStream         *service.AIStreamClient
+	maxStream        time.Duration
 	rabbitMQ         *infrastructure.RabbitMQClient
+	saver            *streamSaver
+	languages        *service.Languages
 }
 
-func NewFileHandler(fileService *service.FileService, workspaceService *service.WorkspaceService, rabbitMQ *infrastructure.RabbitMQClient) *FileHandler {
-	aiURL := os.Getenv("AI_SERVICE_URL")
-	if aiURL == "" {
-		aiURL = "http://localhost:8000"
-	}
-
+func NewFileHandler(fileService *service.FileService, workspaceService *service.WorkspaceService, rabbitMQ *infrastructure.RabbitMQClient, aiConfig config.AIConfig, aiLimiter *service.AILimiter, aiTransport http.RoundTripper, languages *service.Languages) *FileHandler {
 	return &FileHandler{
 		fileService:      fileService,
 		workspaceService: workspaceService,
-		httpClient:       &http.Client{Timeout: 30 * time.Minute},
-		aiServiceURL:     aiURL,
+		aiStream:         service.NewAIStreamClient(aiConfig.ServiceURL, aiConfig.StreamTimeout, aiLimiter, aiTransport),
+		maxStream:        aiConfig.MaxStreamDuration,
 		rabbitMQ:         rabbitMQ,
+		saver:            newStreamSaver(),
+		languages:        languages,
 	}
 }
 
+// unsupportedLanguage answers a request for a language missing from
+// SUPPORTED_LANGUAGES. Every endpoint taking a language answers the same way:
+// 422 with a validation error on field.
+func unsupportedLanguage(c *fiber.Ctx, field, message string) error {
+	return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+		{Field: field, Message: message},
+	}))
+}
+
+// DrainStreamSaves waits for summaries of finished streams to be saved. Call
+// it on shutdown, before the database is closed.
+func (h *FileHandler) DrainStreamSaves() error {
+	return h.saver.Drain()
+}
+
+// storageRetryAfter is the Retry-After hint, in seconds, sent when storage is down
+const storageRetryAfter = "10"
+
+// storageUnavailable answers a transient storage failure with 503 so clients retry
+func storageUnavailable(c *fiber.Ctx) error {
+	c.Set("Retry-After", storageRetryAfter)
+	return c.Status(fiber.StatusServiceUnavailable).JSON(models.NewErrorResponse(
+		"STORAGE_UNAVAILABLE",
+		"File storage is temporarily unavailable. Please retry shortly.",
+	))
+}
+
+// blockedFilename rejects a name that matches the configured upload policy
+func blockedFilename(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+		"FILENAME_BLOCKED",
+		"This filename is not allowed by the upload policy",
+	))
+}
+
 func (h *FileHandler) SummarizeStream(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 
@@ -58,8 +93,16 @@ func (h *FileHandler) SummarizeStream(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse("INVALID_ID", "Invalid file ID"))
 	}
 
+	requestedLanguage := c.Query("language", c.FormValue("language"))
+	if !h.languages.Allows(requestedLanguage) {
+		return unsupportedLanguage(c, "language", "Language must be one of: "+h.languages.Codes()+", auto")
+	}
+
 	startTime := time.Now()
 
+	// Ephemeral summaries are streamed to the client but never saved
+	ephemeral := c.QueryBool("ephemeral")
+
 	// 1. Get file content from storage
 	content, file, err := h.fileService.GetFileContent(c.Context(), userID, fileID)
 	if err != nil {
@@ -83,124 +126,162 @@ func (h *FileHandler) SummarizeStream(c *fiber.Ctx) error {
 	}
 
 	// 2. Smart Check (Magic Numbers)
-	header := make([]byte, 5)
-	n, err := io.ReadFull(content, header)
-	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse("INTERNAL_ERROR", "Failed to validate file"))
-	}
-
-	// Check for %PDF- signature
-	if !bytes.HasPrefix(header[:n], []byte("%PDF-")) {
-		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse("INVALID_FILE_TYPE", "File is not a valid PDF (missing signature)"))
-	}
-
-	// Reconstruct the reader to include the read bytes
-	// We use a custom struct to combine MultiReader with the original Closer
-	content = struct {
-		io.Reader
-		io.Closer
-	}{
-		Reader: io.MultiReader(bytes.NewReader(header[:n]), content),
-		Closer: content,
-	}
-
-	// 2. Prepare request to AI Service
-	var buf bytes.Buffer
-	writer := multipart.NewWriter(&buf)
-
-	// Add fields
-	_ = writer.WriteField("style", c.FormValue("style", "bullet_points"))
-	_ = writer.WriteField("language", c.FormValue("language", "en"))
-	if customInstructions := c.FormValue("custom_instructions"); customInstructions != "" {
-		_ = writer.WriteField("custom_instructions", customInstructions)
-	}
-
-	// Add file part with explicit Content-Type
-	partHeader := make(textproto.MIMEHeader)
-	partHeader.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, file.OriginalFilename))
-	partHeader.Set("Content-Type", "application/pdf")
-
-	part, err := writer.CreatePart(partHeader)
+	content, err = sniffPDF(content)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse("INTERNAL_ERROR", "Failed to create multipart request"))
-	}
-
-	if _, err := io.Copy(part, content); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse("INTERNAL_ERROR", "Failed to write file content"))
+		if errors.Is(err, errMissingPDFSignature) {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse("INVALID_FILE_TYPE", "File is not a valid PDF (missing signature)"))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse("INTERNAL_ERROR", "Failed to validate file"))
 	}
 
-	writer.Close()
-
-	// 3. Send request to AI Service
-	req, err := http.NewRequest("POST", h.aiServiceURL+"/summarize-stream", &buf)
+	// Title and language hints; they also resolve ?language=auto (or form field)
+	content, hints, err := readPDFHints(content)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse("INTERNAL_ERROR", "Failed to create request"))
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse("INTERNAL_ERROR", "Failed to read file content"))
 	}
-
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-
-	resp, err := h.httpClient.Do(req)
+	language := h.languages.Resolve(requestedLanguage, hints)
+
+	// 2. Open stream to AI Service; the deadline covers the whole SSE session
+	ctx, cancel := context.WithTimeout(context.Background(), h.maxStream)
+	events, err := h.aiStream.Stream(ctx, service.AIStreamRequest{
+		Filename:           file.OriginalFilename,
+		Content:            content,
+		Style:              c.FormValue("style", "bullet_points"),
+		Language:           language,
+		CustomInstructions: c.FormValue("custom_instructions"),
+		Hints:              hints,
+		IncludeSourceText:  !ephemeral && h.fileService.StoresSourceText(),
+	})
 	if err != nil {
+		cancel()
+		if errors.Is(err, service.ErrAIBusy) {
+			c.Set("Retry-After", "5")
+			return c.Status(fiber.StatusServiceUnavailable).JSON(models.NewErrorResponse("AI_SERVICE_BUSY", err.Error()))
+		}
+		log.Printf("AI stream failed for file %s: %v", fileID, err)
 		return c.Status(fiber.StatusBadGateway).JSON(models.NewErrorResponse("AI_SERVICE_ERROR", "Failed to connect to AI service"))
 	}
 
-	// 4. Stream response back to client
+	// 3. Stream response back to client
 	c.Set("Content-Type", "text/event-stream")
 	c.Set("Cache-Control", "no-cache")
 	c.Set("Connection", "keep-alive")
 	c.Set("Transfer-Encoding", "chunked")
 
 	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
-		defer resp.Body.Close()
-
-		reader := bufio.NewReader(resp.Body)
+		defer cancel()
+
+		saved := false
+		var partial strings.Builder
+		defer func() {
+			if !saved && !ephemeral && partial.Len() > 0 {
+				text := partial.String()
+				h.saver.Go(func() { h.savePartialSummary(fileID, text) })
+			}
+		}()
 
-		for {
-			line, err := reader.ReadString('\n')
-			if err != nil {
-				if err != io.EOF {
-					// Log error if needed, but don't break flow if possible
-				}
-				break
+		var sourceText string
+		for event := range events {
+			if event.Type == service.AIStreamSource {
+				sourceText = event.SourceText
+				continue
 			}
 
-			// Write to client
-			fmt.Fprint(w, line)
-			w.Flush()
+			partial.WriteString(event.Token)
+			fmt.Fprintf(w, "data: %s\n\n", event.Data)
+			if err := w.Flush(); err != nil {
+				return // Client disconnected
+			}
 
-			// Check for result to save to DB
-			if strings.HasPrefix(line, "data: ") {
-				payload := strings.TrimSpace(strings.TrimPrefix(line, "data: "))
-				if strings.Contains(payload, "\"result\"") {
-					var event struct {
-						Result *models.SummaryCallbackRequest `json:"result"`
-					}
-					// Only try to parse if it looks like a result to avoid overhead
-					if err := json.Unmarshal([]byte(payload), &event); err == nil && event.Result != nil {
-						// Save to DB asynchronously
-						go func(res models.SummaryCallbackRequest) {
-							saveCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-							defer cancel()
-
-							// Calculate duration
-							durationMs := int(time.Since(startTime).Milliseconds())
-							res.ProcessingDurationMs = durationMs
-
-							if err := h.fileService.SaveStreamSummary(saveCtx, userID, fileID, res); err != nil {
-								log.Printf("ERROR: Failed to save summary for file %s: %v", fileID, err)
-							} else {
-								log.Printf("SUCCESS: Saved summary for file %s (Duration: %dms)", fileID, durationMs)
-							}
-						}(*event.Result)
-					}
-				}
+			// Persist only the first structured result of the stream
+			if event.Type != service.AIStreamResult || saved {
+				continue
 			}
+			saved = true
+			if ephemeral {
+				continue
+			}
+
+			// Save to DB in the background, bounded by the saver
+			result := *event.Result
+			result.SourceText = sourceText
+			h.saver.Go(func() { h.saveStreamResult(userID, fileID, startTime, result) })
+		}
+
+		// The upstream request was cancelled by the deadline; tell the client why
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !saved {
+			log.Printf("Summary stream for file %s exceeded %s", fileID, h.maxStream)
+			fmt.Fprintf(w, "event: timeout\ndata: {\"error\":\"Summary generation timed out\"}\n\n")
+			w.Flush()
 		}
 	})
 
 	return nil
 }
 
+var errMissingPDFSignature = errors.New("missing %PDF- signature")
+
+// sniffPDF checks the content starts with the PDF signature and returns a
+// reader that still yields the bytes consumed by the check
+func sniffPDF(content io.ReadCloser) (io.ReadCloser, error) {
+	header := make([]byte, 5)
+	n, err := io.ReadFull(content, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(header[:n], []byte("%PDF-")) {
+		return nil, errMissingPDFSignature
+	}
+
+	// Combine MultiReader with the original Closer
+	return struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: io.MultiReader(bytes.NewReader(header[:n]), content),
+		Closer: content,
+	}, nil
+}
+
+// readPDFHints buffers the content to extract document hints and returns a
+// reader over the same bytes for the AI request
+func readPDFHints(content io.ReadCloser) (io.ReadCloser, service.DocumentHints, error) {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, service.DocumentHints{}, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), service.ExtractPDFHints(data), nil
+}
+
+// saveStreamResult persists a streamed summary. It runs through h.saver,
+// detached from the request, since the client may disconnect as soon as the
+// result arrives.
+func (h *FileHandler) saveStreamResult(userID, fileID uuid.UUID, startTime time.Time, res models.SummaryCallbackRequest) {
+	saveCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Calculate duration
+	durationMs := int(time.Since(startTime).Milliseconds())
+	res.ProcessingDurationMs = durationMs
+
+	if err := h.fileService.SaveStreamSummary(saveCtx, userID, fileID, res); err != nil {
+		log.Printf("ERROR: Failed to save summary for file %s: %v", fileID, err)
+	} else {
+		log.Printf("SUCCESS: Saved summary for file %s (Duration: %dms)", fileID, durationMs)
+	}
+}
+
+// savePartialSummary keeps the tokens of a stream that ended without a result
+func (h *FileHandler) savePartialSummary(fileID uuid.UUID, content string) {
+	saveCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := h.fileService.SavePartialSummary(saveCtx, fileID, content); err != nil {
+		log.Printf("ERROR: Failed to save partial summary for file %s: %v", fileID, err)
+	}
+}
+
 func (h *FileHandler) SummarizeAsync(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	fileID, err := uuid.Parse(c.Params("id"))
@@ -212,19 +293,39 @@ func (h *FileHandler) SummarizeAsync(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusServiceUnavailable).JSON(models.NewErrorResponse("SERVICE_UNAVAILABLE", "Queue service is not available"))
 	}
 
+	requestedLanguage := c.Query("language", c.FormValue("language"))
+	if !h.languages.Allows(requestedLanguage) {
+		return unsupportedLanguage(c, "language", "Language must be one of: "+h.languages.Codes()+", auto")
+	}
+
 	// Verify file access
 	file, err := h.fileService.GetByID(c.Context(), fileID, userID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse("NOT_FOUND", "File not found"))
 	}
 
+	hints := h.fileService.GetFileHints(c.Context(), userID, fileID)
+
 	// Prepare task
 	task := map[string]interface{}{
 		"file_id":             file.ID.String(),
 		"storage_path":        file.StoragePath,
 		"style":               c.FormValue("style", "bullet_points"),
-		"language":            c.FormValue("language", "en"),
+		"language":            h.languages.Resolve(requestedLanguage, hints),
 		"custom_instructions": c.FormValue("custom_instructions"),
+		"title_hint":          hints.Title,
+		"language_hint":       hints.Language,
+	}
+
+	// Announce the queued stage first so a fast worker's extracting event
+	// can never arrive ahead of it
+	queued := models.SummaryProgressEvent{
+		FileID:  file.ID.String(),
+		Stage:   models.StageQueued,
+		Message: "Waiting for a worker",
+	}
+	if err := h.rabbitMQ.PublishEvent(c.Context(), "summary."+file.ID.String(), queued); err != nil {
+		log.Printf("Failed to publish queued event for file %s: %v", fileID, err)
 	}
 
 	// Publish to RabbitMQ
@@ -243,6 +344,10 @@ func (h *FileHandler) SummarizeAsync(c *fiber.Ctx) error {
 func (h *FileHandler) SubscribeEvents(c *fiber.Ctx) error {
 	fileID := c.Params("id")
 
+	if h.rabbitMQ == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(models.NewErrorResponse("SERVICE_UNAVAILABLE", "Queue service is not available"))
+	}
+
 	c.Set("Content-Type", "text/event-stream")
 	c.Set("Cache-Control", "no-cache")
 	c.Set("Connection", "keep-alive")
@@ -256,12 +361,26 @@ func (h *FileHandler) SubscribeEvents(c *fiber.Ctx) error {
 
 	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
 		for msg := range msgs {
-			fmt.Fprintf(w, "data: %s\n\n", msg.Body)
-			w.Flush()
+			event, err := models.ParseSummaryProgressEvent(msg.Body)
+			if err != nil {
+				log.Printf("Dropping malformed event for file %s: %s", fileID, msg.Body)
+				continue
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Stage, data)
+			if err := w.Flush(); err != nil {
+				return // Client disconnected
+			}
 
-			// Should we peek at body to see if it is "completed" and stop?
-			// Client will close connection usually.
-			// But good to stop loop if channel closes.
+			// Nothing follows a terminal stage, so release the connection
+			if event.Stage.IsTerminal() {
+				return
+			}
 		}
 	})
 
@@ -296,9 +415,10 @@ func (h *FileHandler) List(c *fiber.Ctx) error {
 		params.Status = &status
 	}
 
-	// Parse search
+	// Parse search; search_mode is prefix, contains (default) or fulltext
 	if search := c.Query("search"); search != "" {
 		params.Search = &search
+		params.SearchMode = repository.ParseSearchMode(c.Query("search_mode"))
 	}
 
 	// Parse workspace_id
@@ -317,6 +437,23 @@ func (h *FileHandler) List(c *fiber.Ctx) error {
 		}
 	}
 
+	params.Starred = c.QueryBool("starred")
+
+	// checksum finds files with identical content (lowercase hex SHA-256)
+	if checksum := c.Query("checksum"); checksum != "" {
+		if !isSHA256Hex(checksum) {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"VALIDATION_ERROR",
+				"checksum must be a hex-encoded SHA-256",
+			))
+		}
+		checksum = strings.ToLower(checksum)
+		params.Checksum = &checksum
+	}
+
+	// include_trashed adds the caller's own trashed files, marked by deleted_at
+	params.IncludeTrashed = c.QueryBool("include_trashed") && params.WorkspaceID == nil
+
 	files, totalCount, err := h.fileService.List(c.Context(), params)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
@@ -328,6 +465,63 @@ func (h *FileHandler) List(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(models.NewPaginatedResponse(files, params.Page, params.Limit, totalCount))
 }
 
+// Search finds files by the text inside them, best match first, with a
+// highlighted snippet of each match. Filename search stays on GET /files.
+// GET /api/v1/files/search?q=
+func (h *FileHandler) Search(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "q", Message: "Search query is required"},
+		}))
+	}
+
+	params := repository.FileListParams{
+		UserID: userID,
+		Search: &query,
+		Page:   c.QueryInt("page", 1),
+		Limit:  c.QueryInt("limit", 20),
+	}
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.Limit < 1 || params.Limit > 50 {
+		params.Limit = 50
+	}
+
+	if folderIDStr := c.Query("folder_id"); folderIDStr != "" {
+		folderID, err := uuid.Parse(folderIDStr)
+		if err == nil {
+			params.FolderID = &folderID
+		}
+	}
+
+	if workspaceIDStr := c.Query("workspace_id"); workspaceIDStr != "" {
+		workspaceID, err := uuid.Parse(workspaceIDStr)
+		if err == nil {
+			if _, err := h.workspaceService.VerifyMemberAccess(c.Context(), workspaceID, userID); err != nil {
+				return c.Status(fiber.StatusForbidden).JSON(models.NewErrorResponse(
+					"FORBIDDEN",
+					"You do not have access to this workspace",
+				))
+			}
+			params.WorkspaceID = &workspaceID
+		}
+	}
+
+	results, totalCount, err := h.fileService.SearchContent(c.Context(), params)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to search files",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewPaginatedResponse(results, params.Page, params.Limit, totalCount))
+}
+
 func (h *FileHandler) Export(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 
@@ -353,6 +547,7 @@ func (h *FileHandler) Export(c *fiber.Ctx) error {
 	}
 	if search := c.Query("search"); search != "" {
 		params.Search = &search
+		params.SearchMode = repository.ParseSearchMode(c.Query("search_mode"))
 	}
 
 	// Parse file_ids (optional)
@@ -386,15 +581,7 @@ func (h *FileHandler) Export(c *fiber.Ctx) error {
 
 	if len(fileIDs) == 1 {
 		if file, err := h.fileService.GetFile(c.Context(), fileIDs[0]); err == nil {
-			// Sanitize original filename
-			safeName := strings.Map(func(r rune) rune {
-				if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
-					return r
-				}
-				return '_'
-			}, strings.TrimSuffix(file.OriginalFilename, filepath.Ext(file.OriginalFilename)))
-
-			if len(safeName) > 0 {
+			if safeName := safeFilenameBase(file.OriginalFilename); len(safeName) > 0 {
 				filenameBase = safeName
 			}
 		}
@@ -434,130 +621,349 @@ func (h *FileHandler) Export(c *fiber.Ctx) error {
 	return c.SendStream(csvReader)
 }
 
-func (h *FileHandler) GetByID(c *fiber.Ctx) error {
+// Bundle streams the original PDF, its current summary as summary.md and a
+// metadata.json as one ZIP download
+// GET /api/v1/files/:id/bundle
+func (h *FileHandler) Bundle(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 
-	fileIDStr := c.Params("id")
-	fileID, err := uuid.Parse(fileIDStr)
+	fileID, err := uuid.Parse(c.Params("id"))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
-			"VALIDATION_ERROR",
-			"Invalid file ID",
-		))
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse("INVALID_ID", "Invalid file ID"))
 	}
 
-	file, err := h.fileService.GetByID(c.Context(), userID, fileID)
+	bundle, err := h.fileService.OpenBundle(c.Context(), userID, fileID)
 	if err != nil {
 		if errors.Is(err, repository.ErrFileNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
-				"FILE_NOT_FOUND",
-				"File not found",
-			))
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse("FILE_NOT_FOUND", "File not found"))
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
-			"INTERNAL_ERROR",
-			"Failed to get file",
-		))
+		if errors.Is(err, storage.ErrStorageUnavailable) {
+			return storageUnavailable(c)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse("INTERNAL_ERROR", "Failed to prepare bundle"))
 	}
 
-	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(file, ""))
+	filenameBase := safeFilenameBase(bundle.File.OriginalFilename)
+	if filenameBase == "" {
+		filenameBase = "file"
+	}
+	c.Set("Content-Type", "application/zip")
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s_bundle.zip\"", filenameBase))
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer bundle.Content.Close()
+		if err := bundle.WriteZip(w); err != nil {
+			log.Printf("Bundle for file %s failed: %v", fileID, err)
+			return
+		}
+		w.Flush()
+	})
+
+	return nil
 }
 
-func (h *FileHandler) Move(c *fiber.Ctx) error {
+// safeFilenameBase strips the extension and replaces anything outside
+// [A-Za-z0-9_-] so the name is safe in a Content-Disposition header
+func safeFilenameBase(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, strings.TrimSuffix(name, filepath.Ext(name)))
+}
+
+// ListWorkspaces lists the file's home workspace and the workspaces it is shared into
+// GET /api/v1/files/:id/workspaces
+func (h *FileHandler) ListWorkspaces(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 
-	fileIDStr := c.Params("id")
-	fileID, err := uuid.Parse(fileIDStr)
+	fileID, err := uuid.Parse(c.Params("id"))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
-			"VALIDATION_ERROR",
-			"Invalid file ID",
-		))
-	}
-
-	var req models.MoveFileRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
-			"VALIDATION_ERROR",
-			"Invalid request body",
-		))
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse("INVALID_ID", "Invalid file ID"))
 	}
 
-	err = h.fileService.Move(c.Context(), userID, fileID, req.FolderID)
+	workspaces, err := h.fileService.ListWorkspaces(c.Context(), userID, fileID)
 	if err != nil {
 		if errors.Is(err, repository.ErrFileNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
-				"FILE_NOT_FOUND",
-				"File not found",
-			))
-		}
-		if errors.Is(err, repository.ErrFolderNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
-				"FOLDER_NOT_FOUND",
-				"Target folder not found",
-			))
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse("FILE_NOT_FOUND", "File not found"))
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
-			"INTERNAL_ERROR",
-			"Failed to move file",
-		))
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse("INTERNAL_ERROR", "Failed to list file workspaces"))
 	}
 
-	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(
-		map[string]interface{}{
-			"id":         fileID,
-			"folder_id":  req.FolderID,
-			"updated_at": time.Now(),
-		},
-		"File moved successfully",
-	))
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(workspaces, ""))
 }
 
-func (h *FileHandler) Rename(c *fiber.Ctx) error {
+// ShareToWorkspace shares the file into another workspace the owner belongs to
+// POST /api/v1/files/:id/workspaces
+func (h *FileHandler) ShareToWorkspace(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 
-	fileIDStr := c.Params("id")
-	fileID, err := uuid.Parse(fileIDStr)
+	fileID, err := uuid.Parse(c.Params("id"))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
-			"VALIDATION_ERROR",
-			"Invalid file ID",
-		))
-	}
-
-	var req struct {
-		Name string `json:"name"`
-	}
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
-			"VALIDATION_ERROR",
-			"Invalid request body",
-		))
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse("INVALID_ID", "Invalid file ID"))
 	}
 
-	if req.Name == "" {
+	var req models.ShareFileRequest
+	if err := c.BodyParser(&req); err != nil || req.WorkspaceID == uuid.Nil {
 		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
-			{Field: "name", Message: "Name is required"},
+			{Field: "workspace_id", Message: "Workspace ID is required"},
 		}))
 	}
 
-	err = h.fileService.Rename(c.Context(), userID, fileID, req.Name)
-	if err != nil {
+	if err := h.fileService.ShareToWorkspace(c.Context(), userID, fileID, req.WorkspaceID); err != nil {
 		if errors.Is(err, repository.ErrFileNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
-				"FILE_NOT_FOUND",
-				"File not found",
-			))
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse("FILE_NOT_FOUND", "File not found"))
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
-			"INTERNAL_ERROR",
-			"Failed to rename file",
-		))
+		if errors.Is(err, repository.ErrWorkspaceNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse("WORKSPACE_NOT_FOUND", "Workspace not found"))
+		}
+		if errors.Is(err, repository.ErrAlreadyShared) {
+			return c.Status(fiber.StatusConflict).JSON(models.NewErrorResponse("ALREADY_SHARED", "File is already in this workspace"))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse("INTERNAL_ERROR", "Failed to share file"))
 	}
 
-	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(
-		map[string]interface{}{
-			"id":         fileID,
+	workspaces, err := h.fileService.ListWorkspaces(c.Context(), userID, fileID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse("INTERNAL_ERROR", "Failed to list file workspaces"))
+	}
+	return c.Status(fiber.StatusCreated).JSON(models.NewAPIResponse(workspaces, "File shared"))
+}
+
+// UnshareFromWorkspace removes the file from a workspace it was shared into
+// DELETE /api/v1/files/:id/workspaces/:workspace_id
+func (h *FileHandler) UnshareFromWorkspace(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	fileID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse("INVALID_ID", "Invalid file ID"))
+	}
+	workspaceID, err := uuid.Parse(c.Params("workspace_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse("INVALID_ID", "Invalid workspace ID"))
+	}
+
+	if err := h.fileService.UnshareFromWorkspace(c.Context(), userID, fileID, workspaceID); err != nil {
+		if errors.Is(err, repository.ErrFileNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse("FILE_NOT_FOUND", "File not found"))
+		}
+		if errors.Is(err, repository.ErrShareNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse("SHARE_NOT_FOUND", "File is not shared into this workspace"))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse("INTERNAL_ERROR", "Failed to unshare file"))
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *FileHandler) BatchGet(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	var req models.BatchGetFilesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid request body",
+		))
+	}
+
+	if len(req.FileIDs) == 0 || len(req.FileIDs) > service.MaxBatchGetFiles {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "file_ids", Message: fmt.Sprintf("Between 1 and %d file IDs are required", service.MaxBatchGetFiles)},
+		}))
+	}
+
+	files, err := h.fileService.BatchGet(c.Context(), userID, req.FileIDs)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to get files",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(files, ""))
+}
+
+// BulkMove moves the selected files of the file grid to one folder
+// POST /api/v1/files/bulk-move
+func (h *FileHandler) BulkMove(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	var req models.BulkMoveFilesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid request body",
+		))
+	}
+
+	if len(req.FileIDs) == 0 || len(req.FileIDs) > service.MaxBulkMoveFiles {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "file_ids", Message: fmt.Sprintf("Between 1 and %d file IDs are required", service.MaxBulkMoveFiles)},
+		}))
+	}
+
+	moved, err := h.fileService.BulkMove(c.Context(), userID, req.FileIDs, req.FolderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrFolderNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"FOLDER_NOT_FOUND",
+				"Target folder not found",
+			))
+		}
+		if errors.Is(err, service.ErrWorkspaceMismatch) {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"WORKSPACE_MISMATCH",
+				"Files can only be moved to folders of the same workspace",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to move files",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(
+		&models.BulkMoveFilesResponse{FolderID: req.FolderID, Moved: moved},
+		"Files moved successfully",
+	))
+}
+
+func (h *FileHandler) GetByID(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	fileIDStr := c.Params("id")
+	fileID, err := uuid.Parse(fileIDStr)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid file ID",
+		))
+	}
+
+	file, err := h.fileService.GetByID(c.Context(), userID, fileID)
+	if err != nil {
+		if errors.Is(err, repository.ErrFileNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"FILE_NOT_FOUND",
+				"File not found",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to get file",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(file, ""))
+}
+
+func (h *FileHandler) Move(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	fileIDStr := c.Params("id")
+	fileID, err := uuid.Parse(fileIDStr)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid file ID",
+		))
+	}
+
+	var req models.MoveFileRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid request body",
+		))
+	}
+
+	err = h.fileService.Move(c.Context(), userID, fileID, req.FolderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrFileNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"FILE_NOT_FOUND",
+				"File not found",
+			))
+		}
+		if errors.Is(err, repository.ErrFolderNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"FOLDER_NOT_FOUND",
+				"Target folder not found",
+			))
+		}
+		if errors.Is(err, service.ErrWorkspaceMismatch) {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"WORKSPACE_MISMATCH",
+				"Files can only be moved to folders of the same workspace",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to move file",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(
+		map[string]interface{}{
+			"id":         fileID,
+			"folder_id":  req.FolderID,
+			"updated_at": time.Now(),
+		},
+		"File moved successfully",
+	))
+}
+
+func (h *FileHandler) Rename(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	fileIDStr := c.Params("id")
+	fileID, err := uuid.Parse(fileIDStr)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid file ID",
+		))
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid request body",
+		))
+	}
+
+	if req.Name == "" {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "name", Message: "Name is required"},
+		}))
+	}
+
+	err = h.fileService.Rename(c.Context(), userID, fileID, req.Name)
+	if err != nil {
+		if errors.Is(err, repository.ErrFileNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"FILE_NOT_FOUND",
+				"File not found",
+			))
+		}
+		if errors.Is(err, service.ErrBlockedFilename) {
+			return blockedFilename(c)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to rename file",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(
+		map[string]interface{}{
+			"id":         fileID,
 			"name":       req.Name,
 			"updated_at": time.Now(),
 		},
@@ -565,11 +971,165 @@ func (h *FileHandler) Rename(c *fiber.Ctx) error {
 	))
 }
 
-func (h *FileHandler) Delete(c *fiber.Ctx) error {
-	userID := middleware.GetUserID(c)
+func (h *FileHandler) Delete(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	fileIDStr := c.Params("id")
+	fileID, err := uuid.Parse(fileIDStr)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid file ID",
+		))
+	}
+
+	err = h.fileService.Delete(c.Context(), userID, fileID)
+	if err != nil {
+		if errors.Is(err, repository.ErrFileNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"FILE_NOT_FOUND",
+				"File not found",
+			))
+		}
+		if errors.Is(err, storage.ErrStorageUnavailable) {
+			return storageUnavailable(c)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to delete file",
+		))
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Copy duplicates a file in the same folder
+// POST /api/v1/files/:id/copy
+func (h *FileHandler) Copy(c *fiber.Ctx) error {
+	fileID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid file ID",
+		))
+	}
+
+	// The body is optional
+	var req models.CopyFileRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"VALIDATION_ERROR",
+				"Invalid request body",
+			))
+		}
+	}
+
+	file, err := h.fileService.Copy(c.Context(), middleware.GetUserID(c), fileID, req.CopySummaries)
+	if err != nil {
+		if errors.Is(err, repository.ErrFileNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"FILE_NOT_FOUND",
+				"File not found",
+			))
+		}
+		if errors.Is(err, storage.ErrStorageUnavailable) {
+			return storageUnavailable(c)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to copy file",
+		))
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(models.NewAPIResponse(file, "File copied successfully"))
+}
+
+// Star adds a file to the caller's favorites
+// PATCH /api/v1/files/:id/star
+func (h *FileHandler) Star(c *fiber.Ctx) error {
+	return h.setStarred(c, true)
+}
+
+// Unstar removes a file from the caller's favorites
+// PATCH /api/v1/files/:id/unstar
+func (h *FileHandler) Unstar(c *fiber.Ctx) error {
+	return h.setStarred(c, false)
+}
+
+func (h *FileHandler) setStarred(c *fiber.Ctx, starred bool) error {
+	fileID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid file ID",
+		))
+	}
+
+	if err := h.fileService.SetStarred(c.Context(), middleware.GetUserID(c), fileID, starred); err != nil {
+		if errors.Is(err, repository.ErrFileNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"FILE_NOT_FOUND",
+				"File not found",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to update file",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(&models.FileStarResponse{
+		FileID:  fileID,
+		Starred: starred,
+	}, ""))
+}
+
+// ListTrash returns the caller's trashed files
+// GET /api/v1/files/trash
+func (h *FileHandler) ListTrash(c *fiber.Ctx) error {
+	files, err := h.fileService.ListTrash(c.Context(), middleware.GetUserID(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to list trash",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(files, ""))
+}
+
+// Restore takes a file out of the trash
+// POST /api/v1/files/:id/restore
+func (h *FileHandler) Restore(c *fiber.Ctx) error {
+	fileID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid file ID",
+		))
+	}
+
+	if err := h.fileService.Restore(c.Context(), middleware.GetUserID(c), fileID); err != nil {
+		if errors.Is(err, repository.ErrFileNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"FILE_NOT_FOUND",
+				"File not found in trash",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to restore file",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(nil, "File restored successfully"))
+}
 
-	fileIDStr := c.Params("id")
-	fileID, err := uuid.Parse(fileIDStr)
+// Purge permanently deletes a trashed file and its stored object
+// DELETE /api/v1/files/:id/purge
+func (h *FileHandler) Purge(c *fiber.Ctx) error {
+	fileID, err := uuid.Parse(c.Params("id"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
 			"VALIDATION_ERROR",
@@ -577,17 +1137,16 @@ func (h *FileHandler) Delete(c *fiber.Ctx) error {
 		))
 	}
 
-	err = h.fileService.Delete(c.Context(), userID, fileID)
-	if err != nil {
+	if err := h.fileService.Purge(c.Context(), middleware.GetUserID(c), fileID); err != nil {
 		if errors.Is(err, repository.ErrFileNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
 				"FILE_NOT_FOUND",
-				"File not found",
+				"File not found in trash",
 			))
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
 			"INTERNAL_ERROR",
-			"Failed to delete file",
+			"Failed to purge file",
 		))
 	}
 
@@ -597,7 +1156,69 @@ func (h *FileHandler) Delete(c *fiber.Ctx) error {
 func (h *FileHandler) Presign(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 
-	var req models.PresignRequest
+	req, err := h.parsePresignRequest(c)
+	if req == nil {
+		return err
+	}
+
+	response, err := h.fileService.CreatePresignedUpload(c.Context(), userID, req)
+	if err != nil {
+		return presignError(c, err, "Failed to create upload URL")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(response, ""))
+}
+
+// InitMultipartUpload starts a resumable upload for a large PDF
+// POST /api/v1/files/upload/multipart/init
+func (h *FileHandler) InitMultipartUpload(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	req, err := h.parsePresignRequest(c)
+	if req == nil {
+		return err
+	}
+
+	response, err := h.fileService.InitMultipartUpload(c.Context(), userID, req)
+	if err != nil {
+		return presignError(c, err, "Failed to start multipart upload")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(response, ""))
+}
+
+// GetMultipartPartURL presigns the upload of one part
+// POST /api/v1/files/upload/multipart/part-url
+func (h *FileHandler) GetMultipartPartURL(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	var req models.MultipartPartURLRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid request body",
+		))
+	}
+
+	response, err := h.fileService.GetMultipartPartURL(c.Context(), userID, &req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPartNumber) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+				{Field: "part_number", Message: "Part number must be between 1 and the upload's part count"},
+			}))
+		}
+		return confirmUploadError(c, err, "Failed to create part upload URL")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(response, ""))
+}
+
+// CompleteMultipartUpload assembles the uploaded parts and creates the file
+// POST /api/v1/files/upload/multipart/complete
+func (h *FileHandler) CompleteMultipartUpload(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	var req models.ConfirmUploadRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
 			"VALIDATION_ERROR",
@@ -605,43 +1226,88 @@ func (h *FileHandler) Presign(c *fiber.Ctx) error {
 		))
 	}
 
+	file, err := h.fileService.CompleteMultipartUpload(c.Context(), userID, req.UploadID)
+	if err != nil {
+		if errors.Is(err, storage.ErrIncompleteUpload) {
+			return c.Status(fiber.StatusConflict).JSON(models.NewErrorResponse(
+				"UPLOAD_INCOMPLETE",
+				"Some parts have not been uploaded yet. Upload the missing parts and complete again.",
+			))
+		}
+		return confirmUploadError(c, err, "Failed to complete multipart upload")
+	}
+
+	return h.uploadConfirmed(c, userID, file)
+}
+
+// parsePresignRequest reads and validates the body shared by both ways of
+// starting an upload. On failure it returns a nil request and the response.
+func (h *FileHandler) parsePresignRequest(c *fiber.Ctx) (*models.PresignRequest, error) {
+	var req models.PresignRequest
+	if err := c.BodyParser(&req); err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid request body",
+		))
+	}
+
 	// Validation
 	if req.Filename == "" || req.FileSize <= 0 || req.ContentType == "" {
-		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+		return nil, c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
 			{Field: "filename", Message: "Filename is required"},
 			{Field: "file_size", Message: "File size must be greater than 0"},
 			{Field: "content_type", Message: "Content type is required"},
 		}))
 	}
+	if req.AutoSummarize && !h.languages.Allows(req.SummaryLanguage) {
+		return nil, c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "summary_language", Message: "Summary language must be one of: " + h.languages.Codes() + ", auto"},
+		}))
+	}
 
-	response, err := h.fileService.CreatePresignedUpload(c.Context(), userID, &req)
-	if err != nil {
-		errMsg := err.Error()
-		if strings.Contains(errMsg, "only PDF") {
-			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
-				"INVALID_FILE_TYPE",
-				"Only PDF files are allowed",
-			))
-		}
-		if strings.Contains(errMsg, "exceeds maximum") {
-			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
-				"FILE_TOO_LARGE",
-				"File size exceeds the maximum limit of 25 MB",
-			))
-		}
-		if errors.Is(err, repository.ErrFolderNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
-				"FOLDER_NOT_FOUND",
-				"Target folder not found",
-			))
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
-			"INTERNAL_ERROR",
-			"Failed to create upload URL",
+	return &req, nil
+}
+
+// presignError maps a failure to start an upload to its response
+func presignError(c *fiber.Ctx, err error, fallback string) error {
+	errMsg := err.Error()
+	if strings.Contains(errMsg, "only PDF") {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"INVALID_FILE_TYPE",
+			"Only PDF files are allowed",
 		))
 	}
-
-	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(response, ""))
+	if errors.Is(err, service.ErrBlockedFilename) {
+		return blockedFilename(c)
+	}
+	if errors.Is(err, service.ErrInvalidStyle) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"INVALID_STYLE",
+			"Invalid summary style. Valid options: bullet_points, paragraph, detailed, executive, academic",
+		))
+	}
+	if strings.Contains(errMsg, "exceeds maximum") {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"FILE_TOO_LARGE",
+			"File size exceeds the maximum limit of 25 MB",
+		))
+	}
+	if errors.Is(err, repository.ErrFolderNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+			"FOLDER_NOT_FOUND",
+			"Target folder not found",
+		))
+	}
+	if errors.Is(err, repository.ErrWorkspaceNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+			"WORKSPACE_NOT_FOUND",
+			"Workspace not found",
+		))
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+		"INTERNAL_ERROR",
+		fallback,
+	))
 }
 
 func (h *FileHandler) ConfirmUpload(c *fiber.Ctx) error {
@@ -657,30 +1323,84 @@ func (h *FileHandler) ConfirmUpload(c *fiber.Ctx) error {
 
 	file, err := h.fileService.ConfirmUpload(c.Context(), userID, req.UploadID)
 	if err != nil {
-		if errors.Is(err, repository.ErrUploadNotFound) {
-			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
-				"UPLOAD_NOT_FOUND",
-				"Upload session not found or has expired",
-			))
-		}
-		if errors.Is(err, repository.ErrUploadExpired) {
-			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
-				"UPLOAD_NOT_FOUND",
-				"Upload session has expired",
-			))
-		}
-		errMsg := err.Error()
-		if strings.Contains(errMsg, "not found in storage") {
-			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
-				"FILE_NOT_IN_STORAGE",
-				"File was not found in storage. Please retry the upload.",
-			))
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
-			"INTERNAL_ERROR",
-			"Failed to confirm upload",
+		return confirmUploadError(c, err, "Failed to confirm upload")
+	}
+
+	return h.uploadConfirmed(c, userID, file)
+}
+
+// confirmUploadError maps a failure on a pending upload to its response
+func confirmUploadError(c *fiber.Ctx, err error, fallback string) error {
+	if errors.Is(err, repository.ErrUploadNotFound) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"UPLOAD_NOT_FOUND",
+			"Upload session not found or has expired",
+		))
+	}
+	if errors.Is(err, repository.ErrUploadExpired) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"UPLOAD_NOT_FOUND",
+			"Upload session has expired",
+		))
+	}
+	errMsg := err.Error()
+	if strings.Contains(errMsg, "not found in storage") {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"FILE_NOT_IN_STORAGE",
+			"File was not found in storage. Please retry the upload.",
+		))
+	}
+	if errors.Is(err, service.ErrUploadMismatch) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"UPLOAD_MISMATCH",
+			"Uploaded file does not match the requested size or type. Please retry the upload.",
+		))
+	}
+	if errors.Is(err, service.ErrFileRejected) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewErrorResponse(
+			"FILE_REJECTED",
+			"File was rejected by the malware scan",
+		))
+	}
+	if errors.Is(err, service.ErrScanUnavailable) {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(models.NewErrorResponse(
+			"SCAN_UNAVAILABLE",
+			"File could not be scanned right now. Please confirm the upload again later.",
+		))
+	}
+	if errors.Is(err, storage.ErrStorageUnavailable) {
+		return storageUnavailable(c)
+	}
+	if errors.Is(err, service.ErrNotMultipartUpload) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"NOT_MULTIPART_UPLOAD",
+			"This upload was not started as a multipart upload",
 		))
 	}
+	return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+		"INTERNAL_ERROR",
+		fallback,
+	))
+}
+
+// uploadConfirmed answers a successful confirm or multipart complete
+func (h *FileHandler) uploadConfirmed(c *fiber.Ctx, userID uuid.UUID, file *models.File) error {
+	// Usage is advisory, so a failure here must not fail the upload
+	quotaWarning := false
+	if usage, err := h.fileService.GetStorageUsage(c.Context(), userID); err == nil {
+		quotaWarning = usage.QuotaWarning
+	}
+
+	// So is the duplicate lookup; the upload stands either way
+	duplicateOf, err := h.fileService.DuplicateOf(c.Context(), file)
+	if err != nil {
+		log.Printf("Duplicate lookup failed for file %s: %v", file.ID, err)
+	}
+
+	message := "File uploaded successfully. Use POST /summaries/{file_id}/generate to create a summary."
+	if file.Status == models.StatusProcessing {
+		message = "File uploaded successfully. Summary generation started. Check status at GET /summaries/{file_id}"
+	}
 
 	return c.Status(fiber.StatusCreated).JSON(models.NewAPIResponse(
 		&models.FileResponse{
@@ -691,11 +1411,219 @@ func (h *FileHandler) ConfirmUpload(c *fiber.Ctx) error {
 			FileSize:         file.FileSize,
 			Status:           file.Status,
 			UploadedAt:       file.UploadedAt,
+			QuotaWarning:     quotaWarning,
+			DuplicateOf:      duplicateOf,
 		},
-		"File uploaded successfully. Use POST /summaries/{file_id}/generate to create a summary.",
+		message,
+	))
+}
+
+// ImportURLs fetches publicly accessible PDFs and stores them as uploads.
+// Each URL succeeds or fails on its own; the response lists one result per URL.
+// POST /api/v1/files/import-urls
+func (h *FileHandler) ImportURLs(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	var req models.ImportURLsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid request body",
+		))
+	}
+
+	if len(req.URLs) == 0 || len(req.URLs) > service.MaxImportURLs {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "urls", Message: fmt.Sprintf("Between 1 and %d URLs are required", service.MaxImportURLs)},
+		}))
+	}
+
+	results := make([]models.ImportURLResult, 0, len(req.URLs))
+	imported := 0
+	for _, rawURL := range req.URLs {
+		file, err := h.fileService.ImportFromURL(c.Context(), userID, rawURL, &req)
+		if err != nil {
+			results = append(results, models.ImportURLResult{URL: rawURL, Error: importErrorDetail(err)})
+			continue
+		}
+
+		imported++
+		results = append(results, models.ImportURLResult{
+			URL: rawURL,
+			File: &models.FileResponse{
+				ID:               file.ID,
+				Filename:         file.Filename,
+				OriginalFilename: file.OriginalFilename,
+				FolderID:         file.FolderID,
+				FileSize:         file.FileSize,
+				Status:           file.Status,
+				UploadedAt:       file.UploadedAt,
+			},
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(
+		results,
+		fmt.Sprintf("Imported %d of %d URLs", imported, len(req.URLs)),
 	))
 }
 
+// importErrorDetail describes why one URL of an import failed
+func importErrorDetail(err error) *models.ErrorDetail {
+	code, message := "INTERNAL_ERROR", "Failed to import file"
+	switch {
+	case errors.Is(err, netguard.ErrInvalidURL):
+		code, message = "INVALID_URL", "URL must be an absolute http or https URL"
+	case errors.Is(err, netguard.ErrBlockedAddress):
+		code, message = "URL_NOT_ALLOWED", "URL does not point to a public address"
+	case errors.Is(err, netguard.ErrHostNotAllowed):
+		code, message = "URL_NOT_ALLOWED", "URL host is not on the allowlist"
+	case errors.Is(err, service.ErrImportFetch):
+		code, message = "FETCH_FAILED", "File could not be downloaded from the URL"
+	case errors.Is(err, service.ErrImportNotPDF):
+		code, message = "INVALID_FILE_TYPE", "Only PDF files are allowed"
+	case errors.Is(err, service.ErrImportTooLarge):
+		code, message = "FILE_TOO_LARGE", "File size exceeds the maximum upload size"
+	case errors.Is(err, service.ErrBlockedFilename):
+		code, message = "FILENAME_BLOCKED", "This filename is not allowed by the upload policy"
+	case errors.Is(err, repository.ErrFolderNotFound):
+		code, message = "FOLDER_NOT_FOUND", "Target folder not found"
+	case errors.Is(err, repository.ErrWorkspaceNotFound):
+		code, message = "WORKSPACE_NOT_FOUND", "Workspace not found"
+	case errors.Is(err, service.ErrFileRejected):
+		code, message = "FILE_REJECTED", "File was rejected by the malware scan"
+	case errors.Is(err, service.ErrScanUnavailable):
+		code, message = "SCAN_UNAVAILABLE", "File could not be scanned right now. Please import it again later."
+	case errors.Is(err, storage.ErrStorageUnavailable):
+		code, message = "STORAGE_UNAVAILABLE", "File storage is temporarily unavailable. Please retry shortly."
+	}
+	return &models.ErrorDetail{Code: code, Message: message}
+}
+
+// isSHA256Hex reports whether s is a hex-encoded SHA-256 digest
+func isSHA256Hex(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// BackfillPageCounts fills in missing page counts across the caller's library
+// POST /api/v1/files/page-count/backfill?limit=50
+func (h *FileHandler) BackfillPageCounts(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	report, err := h.fileService.BackfillPageCounts(c.Context(), &userID, backfillLimit(c))
+	if err != nil {
+		if errors.Is(err, storage.ErrStorageUnavailable) {
+			return storageUnavailable(c)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to backfill page counts",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(report, ""))
+}
+
+// backfillLimit reads ?limit= for page count backfills, capped to keep a
+// single request bounded
+func backfillLimit(c *fiber.Ctx) int {
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+	return limit
+}
+
+func (h *FileHandler) GetUsage(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	usage, err := h.fileService.GetStorageUsage(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to get storage usage",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(usage, ""))
+}
+
+func (h *FileHandler) ListPendingUploads(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	uploads, err := h.fileService.ListPendingUploads(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to list pending uploads",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(uploads, ""))
+}
+
+func (h *FileHandler) AbandonUpload(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	uploadID, err := uuid.Parse(c.Params("upload_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid upload ID",
+		))
+	}
+
+	if err := h.fileService.AbandonUpload(c.Context(), userID, uploadID); err != nil {
+		if errors.Is(err, repository.ErrUploadNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"UPLOAD_NOT_FOUND",
+				"Upload session not found",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to cancel upload",
+		))
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *FileHandler) GetChecksum(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	fileID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid file ID",
+		))
+	}
+
+	checksum, err := h.fileService.GetChecksum(c.Context(), userID, fileID)
+	if err != nil {
+		if errors.Is(err, repository.ErrFileNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"FILE_NOT_FOUND",
+				"File not found",
+			))
+		}
+		if errors.Is(err, storage.ErrStorageUnavailable) {
+			return storageUnavailable(c)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to compute checksum",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(checksum, ""))
+}
+
 func (h *FileHandler) GetDownloadURL(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 
@@ -723,6 +1651,9 @@ func (h *FileHandler) GetDownloadURL(c *fiber.Ctx) error {
 				"File not found",
 			))
 		}
+		if errors.Is(err, storage.ErrStorageUnavailable) {
+			return storageUnavailable(c)
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
 			"INTERNAL_ERROR",
 			"Failed to generate download URL",
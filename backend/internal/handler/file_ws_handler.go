@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+	"github.com/google/uuid"
+	"github.com/nextpdf/backend/internal/middleware"
+	"github.com/nextpdf/backend/internal/models"
+	"github.com/nextpdf/backend/internal/service"
+)
+
+const (
+	// wsFileIDKey carries the checked file ID from the upgrade to the socket
+	wsFileIDKey = "wsFileID"
+
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = wsPongWait * 9 / 10
+)
+
+// wsErrorMessage is sent before the server closes a socket because of a failure
+type wsErrorMessage struct {
+	Type    string `json:"type"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// SummarizeWSUpgrade checks access before switching protocols, so a bad
+// request still gets a regular HTTP error response
+func (h *FileHandler) SummarizeWSUpgrade(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return c.Status(fiber.StatusUpgradeRequired).JSON(models.NewErrorResponse(
+			"UPGRADE_REQUIRED",
+			"This endpoint only accepts WebSocket connections",
+		))
+	}
+
+	fileID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse("INVALID_ID", "Invalid file ID"))
+	}
+
+	if _, err := h.fileService.GetOwnedFile(c.Context(), middleware.GetUserID(c), fileID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse("FILE_NOT_FOUND", "File not found"))
+	}
+
+	c.Locals(wsFileIDKey, fileID)
+	return c.Next()
+}
+
+// SummarizeWS is the WebSocket counterpart of SummarizeStream for clients
+// whose proxies cut long-lived SSE responses. Every AI stream event is sent
+// as one text message with the same JSON payload as the SSE data lines, and
+// the server closes the socket once the stream ends.
+// GET /api/v1/files/:id/summarize-ws?style=&language=&custom_instructions=&ephemeral=
+func (h *FileHandler) SummarizeWS(conn *websocket.Conn) {
+	userID, _ := conn.Locals(middleware.UserIDKey).(uuid.UUID)
+	fileID, _ := conn.Locals(wsFileIDKey).(uuid.UUID)
+	ephemeral := conn.Query("ephemeral") == "true"
+	startTime := time.Now()
+
+	// The deadline covers the whole session, as for SSE
+	ctx, cancel := context.WithTimeout(context.Background(), h.maxStream)
+	defer cancel()
+
+	fail := func(closeCode int, code, message string) {
+		payload, _ := json.Marshal(wsErrorMessage{Type: "error", Code: code, Message: message})
+		_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+		_ = conn.WriteMessage(websocket.TextMessage, payload)
+		_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(closeCode, code), time.Now().Add(wsWriteWait))
+	}
+
+	requestedLanguage := conn.Query("language")
+	if !h.languages.Allows(requestedLanguage) {
+		fail(websocket.ClosePolicyViolation, "VALIDATION_ERROR", "Language must be one of: "+h.languages.Codes()+", auto")
+		return
+	}
+
+	content, file, err := h.fileService.GetFileContent(ctx, userID, fileID)
+	if err != nil {
+		fail(websocket.CloseInternalServerErr, "INTERNAL_ERROR", "Failed to retrieve file content")
+		return
+	}
+	defer content.Close()
+
+	if file.MimeType != "application/pdf" {
+		fail(websocket.CloseUnsupportedData, "INVALID_FILE_TYPE", "Only PDF files can be summarized")
+		return
+	}
+	content, err = sniffPDF(content)
+	if err != nil {
+		if errors.Is(err, errMissingPDFSignature) {
+			fail(websocket.CloseUnsupportedData, "INVALID_FILE_TYPE", "File is not a valid PDF (missing signature)")
+			return
+		}
+		fail(websocket.CloseInternalServerErr, "INTERNAL_ERROR", "Failed to validate file")
+		return
+	}
+
+	content, hints, err := readPDFHints(content)
+	if err != nil {
+		fail(websocket.CloseInternalServerErr, "INTERNAL_ERROR", "Failed to read file content")
+		return
+	}
+
+	events, err := h.aiStream.Stream(ctx, service.AIStreamRequest{
+		Filename:           file.OriginalFilename,
+		Content:            content,
+		Style:              conn.Query("style", "bullet_points"),
+		Language:           h.languages.Resolve(requestedLanguage, hints),
+		CustomInstructions: conn.Query("custom_instructions"),
+		Hints:              hints,
+		IncludeSourceText:  !ephemeral && h.fileService.StoresSourceText(),
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrAIBusy) {
+			fail(websocket.CloseTryAgainLater, "AI_SERVICE_BUSY", err.Error())
+			return
+		}
+		log.Printf("AI stream failed for file %s: %v", fileID, err)
+		fail(websocket.CloseInternalServerErr, "AI_SERVICE_ERROR", "Failed to connect to AI service")
+		return
+	}
+
+	// Reading is what processes pongs and the client's close frame; a read
+	// error means the client is gone, so stop the upstream stream too
+	go func() {
+		_ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				cancel()
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(wsPingPeriod)
+	defer ping.Stop()
+
+	saved := false
+	var partial strings.Builder
+	var sourceText string
+	defer func() {
+		if !saved && !ephemeral && partial.Len() > 0 {
+			text := partial.String()
+			h.saver.Go(func() { h.savePartialSummary(fileID, text) })
+		}
+	}()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) && !saved {
+					log.Printf("Summary socket for file %s exceeded %s", fileID, h.maxStream)
+					fail(websocket.CloseNormalClosure, "TIMEOUT", "Summary generation timed out")
+					return
+				}
+				_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(wsWriteWait))
+				return
+			}
+			if event.Type == service.AIStreamSource {
+				sourceText = event.SourceText
+				continue
+			}
+
+			partial.WriteString(event.Token)
+			_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.TextMessage, event.Data); err != nil {
+				return // Client disconnected
+			}
+
+			// Persist only the first structured result of the stream
+			if event.Type == service.AIStreamResult && !saved {
+				saved = true
+				if !ephemeral {
+					result := *event.Result
+					result.SourceText = sourceText
+					h.saver.Go(func() { h.saveStreamResult(userID, fileID, startTime, result) })
+				}
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/nextpdf/backend/internal/config"
+	"github.com/nextpdf/backend/internal/middleware"
+	"github.com/nextpdf/backend/internal/repository"
+	"github.com/nextpdf/backend/internal/service"
+	"github.com/nextpdf/backend/internal/testdb"
+)
+
+func TestSummarizeWSUpgradeLetsOwnerThrough(t *testing.T) {
+	pool := testdb.New(t)
+	ownerID := testdb.CreateUser(t, pool)
+	fileID := testdb.CreateFile(t, pool, ownerID, nil)
+	otherID := testdb.CreateUser(t, pool)
+
+	fileService := service.NewFileService(repository.NewFileRepository(pool, nil),
+		nil, nil, nil, nil, nil, nil, nil, nil, nil, config.UploadConfig{})
+	h := &FileHandler{fileService: fileService}
+
+	// The route as mounted by the server, with the socket handler stubbed
+	app := fiber.New()
+	var caller uuid.UUID
+	app.Get("/files/:id/summarize-ws",
+		func(c *fiber.Ctx) error {
+			c.Locals(middleware.UserIDKey, caller)
+			return c.Next()
+		},
+		h.SummarizeWSUpgrade,
+		func(c *fiber.Ctx) error {
+			if c.Locals(wsFileIDKey) != fileID {
+				t.Errorf("upgrade passed file %v, want %s", c.Locals(wsFileIDKey), fileID)
+			}
+			return c.SendStatus(fiber.StatusSwitchingProtocols)
+		},
+	)
+
+	tests := []struct {
+		name   string
+		caller uuid.UUID
+		want   int
+	}{
+		{"owner", ownerID, fiber.StatusSwitchingProtocols},
+		{"other user", otherID, fiber.StatusNotFound},
+	}
+	for _, tt := range tests {
+		caller = tt.caller
+		req := httptest.NewRequest(fiber.MethodGet, "/files/"+fileID.String()+"/summarize-ws", nil)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, resp.StatusCode, tt.want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"bytes"
 	"errors"
 
 	"github.com/gofiber/fiber/v2"
@@ -63,6 +64,37 @@ func (h *FolderHandler) GetTree(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(tree, ""))
 }
 
+func (h *FolderHandler) GetWorkspaceTree(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	workspaceID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid workspace ID",
+		))
+	}
+
+	includeFiles := c.QueryBool("include_files", false)
+	includeCounts := c.QueryBool("include_counts", true)
+
+	tree, err := h.folderService.GetWorkspaceTree(c.Context(), userID, workspaceID, includeFiles, includeCounts)
+	if err != nil {
+		if errors.Is(err, service.ErrNotMember) {
+			return c.Status(fiber.StatusForbidden).JSON(models.NewErrorResponse(
+				"FORBIDDEN",
+				"You do not have access to this workspace",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to get folder tree",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(tree, ""))
+}
+
 func (h *FolderHandler) Create(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 
@@ -80,8 +112,24 @@ func (h *FolderHandler) Create(c *fiber.Ctx) error {
 		}))
 	}
 
+	// The query parameter wins so bulk importers can set it without touching bodies
+	if onConflict := c.Query("on_conflict"); onConflict != "" {
+		req.OnConflict = onConflict
+	}
+	if req.OnConflict != "" && req.OnConflict != models.ConflictReject && req.OnConflict != models.ConflictRename {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "on_conflict", Message: "on_conflict must be reject or rename"},
+		}))
+	}
+
 	folder, err := h.folderService.Create(c.Context(), userID, &req)
 	if err != nil {
+		if errors.Is(err, service.ErrFolderLimitExceeded) {
+			return c.Status(fiber.StatusForbidden).JSON(models.NewErrorResponse(
+				"FOLDER_LIMIT_EXCEEDED",
+				"You have reached the maximum number of folders",
+			))
+		}
 		if errors.Is(err, repository.ErrFolderExists) {
 			return c.Status(fiber.StatusConflict).JSON(models.NewErrorResponse(
 				"FOLDER_EXISTS",
@@ -207,6 +255,114 @@ func (h *FolderHandler) Move(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(folder, "Folder moved successfully"))
 }
 
+// Reorder accepts either {parent_id, ordered_ids} to order one set of siblings,
+// or an array of {id, sort_order, parent_id} applied as one batch
+func (h *FolderHandler) Reorder(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	if body := bytes.TrimSpace(c.Body()); len(body) > 0 && body[0] == '[' {
+		return h.batchReorder(c, userID)
+	}
+
+	var req models.ReorderFoldersRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid request body",
+		))
+	}
+
+	if len(req.OrderedIDs) == 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "ordered_ids", Message: "At least one folder ID is required"},
+		}))
+	}
+
+	err := h.folderService.Reorder(c.Context(), userID, &req)
+	if err != nil {
+		if errors.Is(err, repository.ErrFolderNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"FOLDER_NOT_FOUND",
+				"Parent folder not found",
+			))
+		}
+		if errors.Is(err, repository.ErrInvalidReorder) {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"INVALID_REORDER",
+				"All folders must be distinct siblings under the given parent",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to reorder folders",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(
+		map[string]interface{}{
+			"parent_id":   req.ParentID,
+			"ordered_ids": req.OrderedIDs,
+		},
+		"Folders reordered successfully",
+	))
+}
+
+func (h *FolderHandler) batchReorder(c *fiber.Ctx, userID uuid.UUID) error {
+	var items []models.FolderReorderItem
+	if err := c.BodyParser(&items); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid request body",
+		))
+	}
+
+	if len(items) == 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "items", Message: "At least one folder is required"},
+		}))
+	}
+
+	folders, err := h.folderService.BatchReorder(c.Context(), userID, items)
+	if err != nil {
+		if errors.Is(err, repository.ErrFolderNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"FOLDER_NOT_FOUND",
+				"Folder or parent folder not found",
+			))
+		}
+		if errors.Is(err, repository.ErrInvalidReorder) {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"INVALID_REORDER",
+				"Each folder may appear once, with a sort order of 0 or more",
+			))
+		}
+		if errors.Is(err, repository.ErrInvalidMove) {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"INVALID_MOVE",
+				"Cannot move folder into itself or its descendants",
+			))
+		}
+		if errors.Is(err, repository.ErrCircularReference) {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"CIRCULAR_REFERENCE",
+				"Moving this folder would create a circular reference",
+			))
+		}
+		if errors.Is(err, repository.ErrFolderExists) {
+			return c.Status(fiber.StatusConflict).JSON(models.NewErrorResponse(
+				"FOLDER_EXISTS",
+				"A folder with this name already exists in the target location",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to reorder folders",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(folders, "Folders reordered successfully"))
+}
+
 func (h *FolderHandler) Delete(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 
@@ -219,7 +375,19 @@ func (h *FolderHandler) Delete(c *fiber.Ctx) error {
 		))
 	}
 
-	err = h.folderService.Delete(c.Context(), userID, folderID)
+	// ?reassign_to=<id> keeps the files by moving them there first
+	if reassignTo := c.Query("reassign_to"); reassignTo != "" {
+		targetID, err := uuid.Parse(reassignTo)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"VALIDATION_ERROR",
+				"Invalid reassign_to folder ID",
+			))
+		}
+		return h.deleteAndReassign(c, userID, folderID, targetID)
+	}
+
+	failedObjects, err := h.folderService.Delete(c.Context(), userID, folderID)
 	if err != nil {
 		if errors.Is(err, repository.ErrFolderNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
@@ -233,5 +401,157 @@ func (h *FolderHandler) Delete(c *fiber.Ctx) error {
 		))
 	}
 
+	// The folder is gone either way; say so when storage cleanup was partial
+	if failedObjects > 0 {
+		return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(&models.FolderDeleteResponse{
+			FolderID:      folderID,
+			FailedObjects: failedObjects,
+			Warning:       "Some stored files could not be deleted; they are left for the storage reconcile",
+		}, "Folder deleted with storage cleanup errors"))
+	}
+
 	return c.SendStatus(fiber.StatusNoContent)
 }
+
+// deleteAndReassign handles DELETE /folders/:id?reassign_to=<id>&keep_subfolders=true
+func (h *FolderHandler) deleteAndReassign(c *fiber.Ctx, userID, folderID, targetID uuid.UUID) error {
+	err := h.folderService.DeleteAndReassign(c.Context(), userID, folderID, targetID, c.QueryBool("keep_subfolders"))
+	if err != nil {
+		if errors.Is(err, repository.ErrFolderNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"FOLDER_NOT_FOUND",
+				"Folder not found",
+			))
+		}
+		if errors.Is(err, service.ErrTargetFolderNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"TARGET_FOLDER_NOT_FOUND",
+				"Target folder not found",
+			))
+		}
+		if errors.Is(err, repository.ErrInvalidMove) || errors.Is(err, repository.ErrCircularReference) {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"INVALID_MOVE",
+				"Files cannot be reassigned to the deleted folder or its descendants",
+			))
+		}
+		if errors.Is(err, repository.ErrFolderExists) {
+			return c.Status(fiber.StatusConflict).JSON(models.NewErrorResponse(
+				"FOLDER_EXISTS",
+				"A subfolder with the same name already exists in the target folder",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to delete folder",
+		))
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetBreadcrumbs returns the ancestor chain of a folder, root first
+// GET /api/v1/folders/:id/breadcrumbs
+func (h *FolderHandler) GetBreadcrumbs(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	folderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid folder ID",
+		))
+	}
+
+	breadcrumbs, err := h.folderService.GetBreadcrumbs(c.Context(), userID, folderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrFolderNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"FOLDER_NOT_FOUND",
+				"Folder not found",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to get breadcrumbs",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(breadcrumbs, ""))
+}
+
+// GetSummaryStats reports how many files of a folder have summaries, the
+// pages summarized and the average processing time
+// GET /api/v1/folders/:id/summary-stats?include_subfolders=
+func (h *FolderHandler) GetSummaryStats(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	folderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid folder ID",
+		))
+	}
+
+	stats, err := h.folderService.GetSummaryStats(c.Context(), userID, folderID, c.QueryBool("include_subfolders", false))
+	if err != nil {
+		if errors.Is(err, repository.ErrFolderNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"FOLDER_NOT_FOUND",
+				"Folder not found",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to get folder summary stats",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(stats, ""))
+}
+
+func (h *FolderHandler) Duplicate(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	folderIDStr := c.Params("id")
+	folderID, err := uuid.Parse(folderIDStr)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid folder ID",
+		))
+	}
+
+	var req models.DuplicateFolderRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"VALIDATION_ERROR",
+				"Invalid request body",
+			))
+		}
+	}
+
+	folder, err := h.folderService.Duplicate(c.Context(), userID, folderID, req.IncludeFiles)
+	if err != nil {
+		if errors.Is(err, service.ErrFolderLimitExceeded) {
+			return c.Status(fiber.StatusForbidden).JSON(models.NewErrorResponse(
+				"FOLDER_LIMIT_EXCEEDED",
+				"You have reached the maximum number of folders",
+			))
+		}
+		if errors.Is(err, repository.ErrFolderNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"FOLDER_NOT_FOUND",
+				"Folder not found",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to duplicate folder",
+		))
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(models.NewAPIResponse(folder, "Folder duplicated successfully"))
+}
@@ -3,39 +3,48 @@ package handler
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"mime/multipart"
 	"net/http"
 	"net/textproto"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/nextpdf/backend/internal/config"
 	"github.com/nextpdf/backend/internal/models"
+	"github.com/nextpdf/backend/internal/service"
 )
 
 // GuestHandler handles guest (unauthenticated) operations
 type GuestHandler struct {
 	aiServiceURL string
 	httpClient   *http.Client
+	aiStream     *service.AIStreamClient
+	maxStream    time.Duration
+	aiLimiter    *service.AILimiter
+	metrics      *service.GuestMetricsService
+	languages    *service.Languages
 }
 
 // NewGuestHandler creates a new guest handler
-func NewGuestHandler() *GuestHandler {
-	aiURL := os.Getenv("AI_SERVICE_URL")
-	if aiURL == "" {
-		aiURL = "http://localhost:8000"
-	}
-
+func NewGuestHandler(aiConfig config.AIConfig, aiLimiter *service.AILimiter, aiTransport http.RoundTripper, metrics *service.GuestMetricsService, languages *service.Languages) *GuestHandler {
 	return &GuestHandler{
-		aiServiceURL: aiURL,
+		aiServiceURL: aiConfig.ServiceURL,
 		httpClient: &http.Client{
-			Timeout: 120 * time.Second, // Long timeout for AI processing
+			Transport: aiTransport,
+			Timeout:   aiConfig.GuestTimeout, // Long timeout for AI processing
 		},
+		aiStream:  service.NewAIStreamClient(aiConfig.ServiceURL, aiConfig.GuestTimeout, aiLimiter, aiTransport),
+		maxStream: aiConfig.MaxStreamDuration,
+		aiLimiter: aiLimiter,
+		metrics:   metrics,
+		languages: languages,
 	}
 }
 
@@ -47,11 +56,18 @@ type GuestSummaryResponse struct {
 	Language             string `json:"language"`
 	ProcessingDurationMs int    `json:"processing_duration_ms"`
 	ModelUsed            string `json:"model_used"`
+	PromptTokens         int    `json:"prompt_tokens"`
+	CompletionTokens     int    `json:"completion_tokens"`
+	// TotalDurationMs is measured by the backend and includes upload handling
+	// and any wait for an AI slot
+	TotalDurationMs int `json:"total_duration_ms"`
 }
 
 // Summarize handles guest PDF summarization
 // POST /api/v1/guest/summarize
 func (h *GuestHandler) Summarize(c *fiber.Ctx) error {
+	startTime := time.Now()
+
 	// Get uploaded file
 	fileHeader, err := c.FormFile("file")
 	if err != nil {
@@ -80,7 +96,7 @@ func (h *GuestHandler) Summarize(c *fiber.Ctx) error {
 
 	// Get form fields
 	style := c.FormValue("style", "bullet_points")
-	language := c.FormValue("language", "en")
+	language := c.FormValue("language", h.languages.Default())
 	customInstructions := c.FormValue("custom_instructions", "")
 
 	// Validate style
@@ -99,11 +115,8 @@ func (h *GuestHandler) Summarize(c *fiber.Ctx) error {
 	}
 
 	// Validate language
-	if language != "en" && language != "id" {
-		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
-			"VALIDATION_ERROR",
-			"Language must be 'en' or 'id'",
-		))
+	if !h.languages.Has(language) {
+		return unsupportedLanguage(c, "language", "Language must be one of: "+h.languages.Codes())
 	}
 
 	// Open uploaded file
@@ -127,7 +140,29 @@ func (h *GuestHandler) Summarize(c *fiber.Ctx) error {
 
 	// Forward to AI service
 	summary, err := h.callAIService(fileBytes, fileHeader.Filename, style, language, customInstructions)
+
+	metric := models.GuestSummaryMetric{
+		Mode:            "sync",
+		Style:           style,
+		Language:        language,
+		FileSize:        fileHeader.Size,
+		Success:         err == nil,
+		TotalDurationMs: int(time.Since(startTime).Milliseconds()),
+	}
+	if err == nil {
+		summary.TotalDurationMs = metric.TotalDurationMs
+		metric.AIDurationMs = &summary.ProcessingDurationMs
+		metric.PromptTokens = &summary.PromptTokens
+		metric.CompletionTokens = &summary.CompletionTokens
+		metric.ModelUsed = &summary.ModelUsed
+	}
+	h.metrics.Record(metric, fileBytes)
+
 	if err != nil {
+		if errors.Is(err, service.ErrAIBusy) {
+			c.Set("Retry-After", "5")
+			return c.Status(fiber.StatusServiceUnavailable).JSON(models.NewErrorResponse("AI_SERVICE_BUSY", err.Error()))
+		}
 		log.Printf("ERROR: Guest summarize failed: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
 			"AI_SERVICE_ERROR",
@@ -141,6 +176,8 @@ func (h *GuestHandler) Summarize(c *fiber.Ctx) error {
 // SummarizeStream handles guest PDF summarization with streaming response (SSE)
 // POST /api/v1/guest/summarize-stream
 func (h *GuestHandler) SummarizeStream(c *fiber.Ctx) error {
+	startTime := time.Now()
+
 	// Get uploaded file
 	fileHeader, err := c.FormFile("file")
 	if err != nil {
@@ -159,9 +196,13 @@ func (h *GuestHandler) SummarizeStream(c *fiber.Ctx) error {
 
 	// Get form fields
 	style := c.FormValue("style", "bullet_points")
-	language := c.FormValue("language", "en")
+	language := c.FormValue("language", h.languages.Default())
 	customInstructions := c.FormValue("custom_instructions", "")
 
+	if !h.languages.Has(language) {
+		return unsupportedLanguage(c, "language", "Language must be one of: "+h.languages.Codes())
+	}
+
 	// Open uploaded file
 	file, err := fileHeader.Open()
 	if err != nil {
@@ -169,43 +210,37 @@ func (h *GuestHandler) SummarizeStream(c *fiber.Ctx) error {
 	}
 	defer file.Close()
 
+	// Buffered so the page count for metrics can be read after the stream
 	fileBytes, err := io.ReadAll(file)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse("INTERNAL_ERROR", "Failed to read file content"))
 	}
 
-	// Prepare request to AI Service
-	var buf bytes.Buffer
-	writer := multipart.NewWriter(&buf)
-
-	// Add file part
-	header := make(textproto.MIMEHeader)
-	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, fileHeader.Filename))
-	header.Set("Content-Type", "application/pdf")
-	part, err := writer.CreatePart(header)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse("INTERNAL_ERROR", "Failed to create request"))
-	}
-	part.Write(fileBytes)
-
-	// Add fields
-	writer.WriteField("style", style)
-	writer.WriteField("language", language)
-	if customInstructions != "" {
-		writer.WriteField("custom_instructions", customInstructions)
-	}
-	writer.Close()
-
-	// Create HTTP Request
-	req, err := http.NewRequest("POST", h.aiServiceURL+"/summarize-stream", &buf)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse("INTERNAL_ERROR", "Failed to create request"))
+	metric := models.GuestSummaryMetric{
+		Mode:     "stream",
+		Style:    style,
+		Language: language,
+		FileSize: fileHeader.Size,
 	}
-	req.Header.Set("Content-Type", writer.FormDataContentType())
 
-	// Execute Request (do not read body yet)
-	resp, err := h.httpClient.Do(req)
+	// Open stream to AI Service; the deadline covers the whole SSE session
+	ctx, cancel := context.WithTimeout(context.Background(), h.maxStream)
+	events, err := h.aiStream.Stream(ctx, service.AIStreamRequest{
+		Filename:           fileHeader.Filename,
+		Content:            bytes.NewReader(fileBytes),
+		Style:              style,
+		Language:           language,
+		CustomInstructions: customInstructions,
+	})
 	if err != nil {
+		cancel()
+		metric.TotalDurationMs = int(time.Since(startTime).Milliseconds())
+		h.metrics.Record(metric, fileBytes)
+		if errors.Is(err, service.ErrAIBusy) {
+			c.Set("Retry-After", "5")
+			return c.Status(fiber.StatusServiceUnavailable).JSON(models.NewErrorResponse("AI_SERVICE_BUSY", err.Error()))
+		}
+		log.Printf("Guest AI stream failed: %v", err)
 		return c.Status(fiber.StatusBadGateway).JSON(models.NewErrorResponse("AI_SERVICE_ERROR", "Failed to connect to AI service"))
 	}
 
@@ -215,11 +250,36 @@ func (h *GuestHandler) SummarizeStream(c *fiber.Ctx) error {
 	c.Set("Connection", "keep-alive")
 	c.Set("Transfer-Encoding", "chunked")
 
-	// Stream response body
+	// Relay events to the client
 	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
-		defer resp.Body.Close()
-		io.Copy(w, resp.Body)
-		w.Flush()
+		defer cancel()
+		defer func() {
+			metric.TotalDurationMs = int(time.Since(startTime).Milliseconds())
+			h.metrics.Record(metric, fileBytes)
+		}()
+
+		for event := range events {
+			if event.Type == service.AIStreamResult && !metric.Success {
+				res := event.Result
+				metric.Success = true
+				metric.AIDurationMs = &res.ProcessingDurationMs
+				metric.PromptTokens = &res.PromptTokens
+				metric.CompletionTokens = &res.CompletionTokens
+				metric.ModelUsed = &res.ModelUsed
+			}
+
+			fmt.Fprintf(w, "data: %s\n\n", event.Data)
+			if err := w.Flush(); err != nil {
+				return // Client disconnected
+			}
+		}
+
+		// The upstream request was cancelled by the deadline; tell the client why
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !metric.Success {
+			log.Printf("Guest summary stream exceeded %s", h.maxStream)
+			fmt.Fprintf(w, "event: timeout\ndata: {\"error\":\"Summary generation timed out\"}\n\n")
+			w.Flush()
+		}
 	})
 
 	return nil
@@ -270,7 +330,12 @@ func (h *GuestHandler) callAIService(fileBytes []byte, filename, style, language
 	}
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
-	// Send request
+	// Send request once a concurrency slot is free
+	if err := h.aiLimiter.Acquire(context.Background()); err != nil {
+		return nil, err
+	}
+	defer h.aiLimiter.Release()
+
 	resp, err := h.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call AI service: %w", err)
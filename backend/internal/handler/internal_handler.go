@@ -36,7 +36,7 @@ func (h *InternalHandler) SummaryCallback(c *fiber.Ctx) error {
 	if req.Status == "completed" {
 		err = h.summaryService.ProcessCallback(c.Context(), fileID, &req)
 	} else {
-		err = h.summaryService.ProcessErrorCallback(c.Context(), fileID, req.ErrorMessage)
+		err = h.summaryService.ProcessErrorCallback(c.Context(), fileID, req.ErrorMessage, req.Content)
 	}
 
 	if err != nil {
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// maxConcurrentStreamSaves caps the background saves of streamed summaries
+	maxConcurrentStreamSaves = 8
+
+	// streamSaveDrainTimeout bounds how long shutdown waits for saves in flight
+	streamSaveDrainTimeout = 15 * time.Second
+)
+
+// streamSaver runs the saves of streamed summaries in the background. The
+// stream has already delivered the result, so the client does not wait for
+// the database, but at most maxConcurrentStreamSaves saves run at once and
+// shutdown waits for them before the pool is closed.
+type streamSaver struct {
+	slots chan struct{}
+	wg    sync.WaitGroup
+}
+
+func newStreamSaver() *streamSaver {
+	return &streamSaver{slots: make(chan struct{}, maxConcurrentStreamSaves)}
+}
+
+// Go runs save once a slot is free. It blocks the caller, the stream loop,
+// while all slots are taken; the result is the last useful event anyway.
+func (s *streamSaver) Go(save func()) {
+	s.slots <- struct{}{}
+	s.wg.Add(1)
+	go func() {
+		defer func() {
+			<-s.slots
+			s.wg.Done()
+		}()
+		save()
+	}()
+}
+
+// Drain waits for the saves in flight. It is registered as a shutdown hook,
+// which runs before main closes the database pool.
+func (s *streamSaver) Drain() error {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), streamSaveDrainTimeout)
+	defer cancel()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Printf("WARNING: %d stream summary save(s) still running at shutdown; they may be lost", len(s.slots))
+	}
+	return nil
+}
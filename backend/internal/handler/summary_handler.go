@@ -1,9 +1,14 @@
 package handler
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"log"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -41,8 +46,16 @@ func (h *SummaryHandler) GetByFileID(c *fiber.Ctx) error {
 		}
 	}
 
-	summary, status, err := h.summaryService.GetByFileID(c.Context(), userID, fileID, version)
+	format := models.SummaryFormat(c.Query("format", string(models.FormatMarkdown)))
+
+	summary, status, err := h.summaryService.GetByFileID(c.Context(), userID, fileID, version, format)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidFormat) {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"VALIDATION_ERROR",
+				"Invalid format. Use markdown, html, or plain",
+			))
+		}
 		if errors.Is(err, repository.ErrFileNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
 				"FILE_NOT_FOUND",
@@ -63,6 +76,50 @@ func (h *SummaryHandler) GetByFileID(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(summary, ""))
 }
 
+// GetLatest returns the current summary, or 404 if the file has none yet
+// GET /api/v1/summaries/:file_id/latest (also /files/:id/summaries/latest)
+func (h *SummaryHandler) GetLatest(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	fileID, err := uuid.Parse(c.Params("file_id", c.Params("id")))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid file ID",
+		))
+	}
+
+	format := models.SummaryFormat(c.Query("format", string(models.FormatMarkdown)))
+
+	summary, err := h.summaryService.GetLatest(c.Context(), userID, fileID, format)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidFormat) {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"VALIDATION_ERROR",
+				"Invalid format. Use markdown, html, or plain",
+			))
+		}
+		if errors.Is(err, repository.ErrFileNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"FILE_NOT_FOUND",
+				"File not found",
+			))
+		}
+		if errors.Is(err, repository.ErrSummaryNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"SUMMARY_NOT_FOUND",
+				"No summary has been generated for this file yet",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to get summary",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(summary, ""))
+}
+
 func (h *SummaryHandler) GetHistory(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 
@@ -89,9 +146,294 @@ func (h *SummaryHandler) GetHistory(c *fiber.Ctx) error {
 		))
 	}
 
+	// Lets clients warn that older versions will be pruned
+	c.Set("X-Summary-Max-Versions", strconv.Itoa(h.summaryService.MaxVersions()))
 	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(history, ""))
 }
 
+// GetSourceText returns the text extracted from the PDF and sent to the AI
+// for a summary version, when source text storage is enabled
+// GET /api/v1/summaries/:file_id/source-text?version=N
+func (h *SummaryHandler) GetSourceText(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	fileID, err := uuid.Parse(c.Params("file_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid file ID",
+		))
+	}
+
+	var version *int
+	if versionStr := c.Query("version"); versionStr != "" {
+		v, err := strconv.Atoi(versionStr)
+		if err != nil || v < 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"VALIDATION_ERROR",
+				"version must be a positive integer",
+			))
+		}
+		version = &v
+	}
+
+	source, err := h.summaryService.GetSourceText(c.Context(), userID, fileID, version)
+	if err != nil {
+		if errors.Is(err, service.ErrSourceTextDisabled) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"SOURCE_TEXT_DISABLED",
+				"Source text is not stored on this server",
+			))
+		}
+		if errors.Is(err, repository.ErrFileNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"FILE_NOT_FOUND",
+				"File not found",
+			))
+		}
+		if errors.Is(err, repository.ErrSummaryNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"SUMMARY_NOT_FOUND",
+				"Summary version not found",
+			))
+		}
+		if errors.Is(err, service.ErrSourceTextNotStored) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"SOURCE_TEXT_NOT_FOUND",
+				"No source text was stored for this summary",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to get summary source text",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(source, ""))
+}
+
+// Export downloads a summary version as a document
+// GET /api/v1/summaries/:file_id/export?format=docx&version=N
+func (h *SummaryHandler) Export(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	fileID, err := uuid.Parse(c.Params("file_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid file ID",
+		))
+	}
+
+	var version *int
+	if versionStr := c.Query("version"); versionStr != "" {
+		v, err := strconv.Atoi(versionStr)
+		if err != nil || v < 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"VALIDATION_ERROR",
+				"version must be a positive integer",
+			))
+		}
+		version = &v
+	}
+
+	export, err := h.summaryService.OpenExport(c.Context(), userID, fileID, version, c.Query("format", service.ExportFormatDOCX))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidExportFormat) {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"VALIDATION_ERROR",
+				"Invalid format. Use docx, md or pdf",
+			))
+		}
+		if errors.Is(err, repository.ErrFileNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"FILE_NOT_FOUND",
+				"File not found",
+			))
+		}
+		if errors.Is(err, repository.ErrSummaryNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"SUMMARY_NOT_FOUND",
+				"Summary version not found",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to export summary",
+		))
+	}
+
+	// Render before answering so a failure can still become a JSON error
+	var doc bytes.Buffer
+	if err := export.Write(&doc); err != nil {
+		if errors.Is(err, service.ErrPDFUnsupportedText) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+				{Field: "format", Message: "The summary has characters PDF export cannot show. Export as docx or md instead"},
+			}))
+		}
+		log.Printf("Summary export for file %s failed: %v", fileID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to export summary",
+		))
+	}
+
+	filenameBase := safeFilenameBase(export.File.OriginalFilename)
+	if filenameBase == "" {
+		filenameBase = "file"
+	}
+	c.Set("Content-Type", export.ContentType())
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s_summary_v%d.%s\"", filenameBase, export.Summary.Version, export.Format))
+	return c.Send(doc.Bytes())
+}
+
+// Diff compares two summary versions line by line
+// GET /api/v1/summaries/:file_id/diff?from=1&to=3
+func (h *SummaryHandler) Diff(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	fileID, err := uuid.Parse(c.Params("file_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid file ID",
+		))
+	}
+
+	from, errFrom := strconv.Atoi(c.Query("from"))
+	to, errTo := strconv.Atoi(c.Query("to"))
+	if errFrom != nil || errTo != nil || from < 1 || to < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"from and to must be positive integers",
+		))
+	}
+
+	diff, err := h.summaryService.DiffVersions(c.Context(), userID, fileID, from, to)
+	if err != nil {
+		if errors.Is(err, repository.ErrFileNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"FILE_NOT_FOUND",
+				"File not found",
+			))
+		}
+		if errors.Is(err, service.ErrDiffVersionNotFound) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewErrorResponse(
+				"SUMMARY_NOT_FOUND",
+				"Both summary versions must exist",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to diff summaries",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(diff, ""))
+}
+
+// SubmitFeedback rates a summary version from 1 to 5 with an optional comment
+// POST /api/v1/summaries/:file_id/feedback
+func (h *SummaryHandler) SubmitFeedback(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	fileID, err := uuid.Parse(c.Params("file_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid file ID",
+		))
+	}
+
+	var req models.SummaryFeedbackRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid request body",
+		))
+	}
+
+	var errs []models.ValidationError
+	if req.Rating < 1 || req.Rating > 5 {
+		errs = append(errs, models.ValidationError{Field: "rating", Message: "Rating must be between 1 and 5"})
+	}
+	if req.Comment != nil && len(*req.Comment) > 2000 {
+		errs = append(errs, models.ValidationError{Field: "comment", Message: "Comment must not exceed 2000 characters"})
+	}
+	if req.Version != nil && *req.Version < 1 {
+		errs = append(errs, models.ValidationError{Field: "version", Message: "Version must be a positive integer"})
+	}
+	if len(errs) > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse(errs))
+	}
+
+	feedback, err := h.summaryService.SubmitFeedback(c.Context(), userID, fileID, &req)
+	if err != nil {
+		if errors.Is(err, repository.ErrFileNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"FILE_NOT_FOUND",
+				"File not found",
+			))
+		}
+		if errors.Is(err, repository.ErrSummaryNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"SUMMARY_NOT_FOUND",
+				"Summary not found",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to save feedback",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(feedback, "Feedback saved"))
+}
+
+// SetCurrentVersion makes an earlier (or later) version the current summary
+// POST /api/v1/summaries/:file_id/versions/:version/set-current
+func (h *SummaryHandler) SetCurrentVersion(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	fileID, err := uuid.Parse(c.Params("file_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid file ID",
+		))
+	}
+
+	version, err := strconv.Atoi(c.Params("version"))
+	if err != nil || version < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"version must be a positive integer",
+		))
+	}
+
+	summary, err := h.summaryService.SetCurrentVersion(c.Context(), userID, fileID, version)
+	if err != nil {
+		if errors.Is(err, repository.ErrFileNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"FILE_NOT_FOUND",
+				"File not found",
+			))
+		}
+		if errors.Is(err, repository.ErrSummaryNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"SUMMARY_NOT_FOUND",
+				"Summary version not found",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to set the current summary",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(summary, "Summary version is now current"))
+}
+
 func (h *SummaryHandler) Generate(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 
@@ -112,21 +454,112 @@ func (h *SummaryHandler) Generate(c *fiber.Ctx) error {
 		))
 	}
 
-	// Validate style
-	if req.Style == "" {
+	// Validate custom instructions length
+	if req.CustomInstructions != nil && utf8.RuneCountInString(*req.CustomInstructions) > service.MaxCustomInstructionsLength {
 		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
-			{Field: "style", Message: "Summary style is required"},
+			{Field: "custom_instructions", Message: "Custom instructions must not exceed 500 characters"},
 		}))
 	}
 
-	// Validate custom instructions length
-	if req.CustomInstructions != nil && len(*req.CustomInstructions) > 500 {
+	// ?ephemeral=true answers synchronously and keeps the result out of history
+	if c.QueryBool("ephemeral") {
+		return h.generateEphemeral(c, userID, fileID, &req)
+	}
+
+	response, err := h.summaryService.Generate(c.Context(), userID, fileID, &req)
+	if err != nil {
+		return generateError(c, fileID, err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(models.NewAPIResponse(response, ""))
+}
+
+// Resume continues an interrupted summary from its stored partial output, or
+// regenerates it in full when there is nothing to continue
+// POST /api/v1/summaries/:file_id/resume
+func (h *SummaryHandler) Resume(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	fileID, err := uuid.Parse(c.Params("file_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid file ID",
+		))
+	}
+
+	var req models.GenerateSummaryRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"VALIDATION_ERROR",
+				"Invalid request body",
+			))
+		}
+	}
+	if req.CustomInstructions != nil && utf8.RuneCountInString(*req.CustomInstructions) > service.MaxCustomInstructionsLength {
 		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
 			{Field: "custom_instructions", Message: "Custom instructions must not exceed 500 characters"},
 		}))
 	}
 
-	response, err := h.summaryService.Generate(c.Context(), userID, fileID, &req)
+	response, err := h.summaryService.Resume(c.Context(), userID, fileID, &req)
+	if err != nil {
+		return generateError(c, fileID, err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(models.NewAPIResponse(response, ""))
+}
+
+// generateError maps a failure to start a summary to its response
+func generateError(c *fiber.Ctx, fileID uuid.UUID, err error) error {
+	if errors.Is(err, repository.ErrFileNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+			"FILE_NOT_FOUND",
+			"File not found",
+		))
+	}
+	if errors.Is(err, service.ErrAlreadyProcessing) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"ALREADY_PROCESSING",
+			"A summary is already being generated for this file",
+		))
+	}
+	if errors.Is(err, service.ErrInvalidStyle) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"INVALID_STYLE",
+			"Invalid summary style. Valid options: bullet_points, paragraph, detailed, executive, academic, or custom with custom_style_id",
+		))
+	}
+	if errors.Is(err, service.ErrUnknownModel) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"INVALID_MODEL",
+			"Unknown model. See GET /summary-models for the available models",
+		))
+	}
+	if errors.Is(err, service.ErrUnsupportedLanguage) {
+		return unsupportedLanguage(c, "language", "Unsupported language. See GET /summary-languages for the available languages")
+	}
+	if errors.Is(err, repository.ErrCustomStyleNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+			"CUSTOM_STYLE_NOT_FOUND",
+			"Custom style not found",
+		))
+	}
+	if errors.Is(err, service.ErrInstructionsTooLong) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "custom_instructions", Message: "Custom style and request instructions together must not exceed 500 characters"},
+		}))
+	}
+	log.Printf("ERROR: Failed to generate summary for file %s: %v", fileID, err)
+	return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+		"INTERNAL_ERROR",
+		"Failed to generate summary",
+	))
+}
+
+func (h *SummaryHandler) generateEphemeral(c *fiber.Ctx, userID, fileID uuid.UUID, req *models.GenerateSummaryRequest) error {
+	response, err := h.summaryService.GenerateEphemeral(c.Context(), userID, fileID, req)
 	if err != nil {
 		if errors.Is(err, repository.ErrFileNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
@@ -134,10 +567,65 @@ func (h *SummaryHandler) Generate(c *fiber.Ctx) error {
 				"File not found",
 			))
 		}
-		if errors.Is(err, service.ErrAlreadyProcessing) {
+		if errors.Is(err, service.ErrInvalidStyle) {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"INVALID_STYLE",
+				"Invalid summary style. Valid options: bullet_points, paragraph, detailed, executive, academic, or custom with custom_style_id",
+			))
+		}
+		if errors.Is(err, service.ErrUnknownModel) {
 			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
-				"ALREADY_PROCESSING",
-				"A summary is already being generated for this file",
+				"INVALID_MODEL",
+				"Unknown model. See GET /summary-models for the available models",
+			))
+		}
+		if errors.Is(err, service.ErrUnsupportedLanguage) {
+			return unsupportedLanguage(c, "language", "Unsupported language. See GET /summary-languages for the available languages")
+		}
+		if errors.Is(err, repository.ErrCustomStyleNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"CUSTOM_STYLE_NOT_FOUND",
+				"Custom style not found",
+			))
+		}
+		if errors.Is(err, service.ErrInstructionsTooLong) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+				{Field: "custom_instructions", Message: "Custom style and request instructions together must not exceed 500 characters"},
+			}))
+		}
+		if errors.Is(err, service.ErrAIBusy) {
+			c.Set("Retry-After", "5")
+			return c.Status(fiber.StatusServiceUnavailable).JSON(models.NewErrorResponse("AI_SERVICE_BUSY", err.Error()))
+		}
+		log.Printf("ERROR: Failed to generate ephemeral summary for file %s: %v", fileID, err)
+		return c.Status(fiber.StatusBadGateway).JSON(models.NewErrorResponse(
+			"AI_SERVICE_ERROR",
+			"Failed to generate summary",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(response, ""))
+}
+
+func (h *SummaryHandler) Estimate(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	fileID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid file ID",
+		))
+	}
+
+	style := models.SummaryStyle(c.Query("style"))
+
+	estimate, err := h.summaryService.Estimate(c.Context(), userID, fileID, style)
+	if err != nil {
+		if errors.Is(err, repository.ErrFileNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+				"FILE_NOT_FOUND",
+				"File not found",
 			))
 		}
 		if errors.Is(err, service.ErrInvalidStyle) {
@@ -146,17 +634,236 @@ func (h *SummaryHandler) Generate(c *fiber.Ctx) error {
 				"Invalid summary style. Valid options: bullet_points, paragraph, detailed, executive, academic",
 			))
 		}
-		log.Printf("ERROR: Failed to generate summary for file %s: %v", fileIDStr, err)
+		if errors.Is(err, service.ErrPageCountUnknown) {
+			return c.Status(fiber.StatusConflict).JSON(models.NewErrorResponse(
+				"PAGE_COUNT_UNKNOWN",
+				"The page count for this file is not known yet, so no estimate is available",
+			))
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
 			"INTERNAL_ERROR",
-			"Failed to generate summary",
+			"Failed to estimate summary",
 		))
 	}
 
-	return c.Status(fiber.StatusAccepted).JSON(models.NewAPIResponse(response, ""))
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(estimate, ""))
 }
 
+// GetTimeseries reports how many summaries the user generated per day, week or month
+// GET /api/v1/me/stats/summaries-timeseries?from=&to=&bucket=
+func (h *SummaryHandler) GetTimeseries(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	from, err := parseDateQuery(c, "from")
+	if err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "from", Message: "Must be a date in YYYY-MM-DD format"},
+		}))
+	}
+	to, err := parseDateQuery(c, "to")
+	if err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "to", Message: "Must be a date in YYYY-MM-DD format"},
+		}))
+	}
+
+	timeseries, err := h.summaryService.SummaryTimeseries(c.Context(), userID, from, to, c.Query("bucket"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidBucket) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+				{Field: "bucket", Message: "Must be one of: day, week, month"},
+			}))
+		}
+		if errors.Is(err, service.ErrInvalidRange) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewErrorResponse(
+				"INVALID_RANGE",
+				"from must not be after to, and the range is limited to 1 year of days, 5 years of weeks or 10 years of months",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to get summary statistics",
+		))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(timeseries, ""))
+}
+
+// parseDateQuery reads an optional YYYY-MM-DD query parameter; absent yields the zero time
+func parseDateQuery(c *fiber.Ctx, name string) (time.Time, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", raw)
+}
+
+// GetStyles lists the built-in styles, followed by the caller's custom styles
+// on the authenticated route
+// GET /api/v1/summary-styles
 func (h *SummaryHandler) GetStyles(c *fiber.Ctx) error {
-	styles := h.summaryService.GetStyles()
+	styles, err := h.summaryService.GetStyles(c.Context(), middleware.GetUserID(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to list summary styles",
+		))
+	}
 	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(styles, ""))
 }
+
+// ListCustomStyles lists the caller's custom summary styles
+// GET /api/v1/summary-styles/custom
+func (h *SummaryHandler) ListCustomStyles(c *fiber.Ctx) error {
+	styles, err := h.summaryService.ListCustomStyles(c.Context(), middleware.GetUserID(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to list custom styles",
+		))
+	}
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(styles, ""))
+}
+
+// CreateCustomStyle saves a reusable style: a built-in base style plus instructions
+// POST /api/v1/summary-styles/custom
+func (h *SummaryHandler) CreateCustomStyle(c *fiber.Ctx) error {
+	var req models.CustomSummaryStyleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid request body",
+		))
+	}
+
+	errs := validateCustomStyle(&req)
+	if req.Name == nil {
+		errs = append(errs, models.ValidationError{Field: "name", Message: "Name is required"})
+	}
+	if req.Instructions == nil {
+		errs = append(errs, models.ValidationError{Field: "instructions", Message: "Instructions are required"})
+	}
+	if len(errs) > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse(errs))
+	}
+
+	style, err := h.summaryService.CreateCustomStyle(c.Context(), middleware.GetUserID(c), &req)
+	if err != nil {
+		return customStyleError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(models.NewAPIResponse(style, "Custom style created"))
+}
+
+// UpdateCustomStyle changes the name, base style or instructions of a custom style
+// PATCH /api/v1/summary-styles/custom/:id
+func (h *SummaryHandler) UpdateCustomStyle(c *fiber.Ctx) error {
+	styleID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid style ID",
+		))
+	}
+
+	var req models.CustomSummaryStyleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid request body",
+		))
+	}
+
+	errs := validateCustomStyle(&req)
+	if req.Name == nil && req.BaseStyle == nil && req.Instructions == nil {
+		errs = append(errs, models.ValidationError{Field: "name", Message: "Nothing to update"})
+	}
+	if len(errs) > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse(errs))
+	}
+
+	style, err := h.summaryService.UpdateCustomStyle(c.Context(), middleware.GetUserID(c), styleID, &req)
+	if err != nil {
+		return customStyleError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(style, "Custom style updated"))
+}
+
+// DeleteCustomStyle removes a custom style
+// DELETE /api/v1/summary-styles/custom/:id
+func (h *SummaryHandler) DeleteCustomStyle(c *fiber.Ctx) error {
+	styleID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid style ID",
+		))
+	}
+
+	if err := h.summaryService.DeleteCustomStyle(c.Context(), middleware.GetUserID(c), styleID); err != nil {
+		return customStyleError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// validateCustomStyle checks the fields of a custom style request that are set
+func validateCustomStyle(req *models.CustomSummaryStyleRequest) []models.ValidationError {
+	var errs []models.ValidationError
+	if req.Name != nil {
+		if name := strings.TrimSpace(*req.Name); name == "" || utf8.RuneCountInString(name) > 100 {
+			errs = append(errs, models.ValidationError{Field: "name", Message: "Name must be 1 to 100 characters"})
+		}
+	}
+	if req.BaseStyle != nil && !req.BaseStyle.IsBuiltIn() {
+		errs = append(errs, models.ValidationError{Field: "base_style", Message: "Base style must be one of: bullet_points, paragraph, detailed, executive, academic"})
+	}
+	if req.Instructions != nil {
+		if instructions := strings.TrimSpace(*req.Instructions); instructions == "" || utf8.RuneCountInString(instructions) > service.MaxCustomInstructionsLength {
+			errs = append(errs, models.ValidationError{Field: "instructions", Message: "Instructions must be 1 to 500 characters"})
+		}
+	}
+	return errs
+}
+
+// customStyleError maps custom style service errors to responses
+func customStyleError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, repository.ErrCustomStyleNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(
+			"CUSTOM_STYLE_NOT_FOUND",
+			"Custom style not found",
+		))
+	}
+	if errors.Is(err, repository.ErrCustomStyleExists) {
+		return c.Status(fiber.StatusConflict).JSON(models.NewErrorResponse(
+			"CUSTOM_STYLE_EXISTS",
+			"A custom style with this name already exists",
+		))
+	}
+	if errors.Is(err, service.ErrCustomStyleLimit) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewErrorResponse(
+			"CUSTOM_STYLE_LIMIT",
+			"Custom style limit reached. Delete a style to add another",
+		))
+	}
+	if errors.Is(err, service.ErrInvalidStyle) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"INVALID_STYLE",
+			"Invalid base style. Valid options: bullet_points, paragraph, detailed, executive, academic",
+		))
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+		"INTERNAL_ERROR",
+		"Failed to save custom style",
+	))
+}
+
+// GetLanguages lists the languages that GenerateSummaryRequest.language accepts
+// GET /api/v1/summary-languages
+func (h *SummaryHandler) GetLanguages(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(h.summaryService.GetLanguages(), ""))
+}
+
+// GetModels lists the models that GenerateSummaryRequest.model accepts
+// GET /api/v1/summary-models
+func (h *SummaryHandler) GetModels(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(h.summaryService.GetModels(), ""))
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"errors"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -116,6 +117,58 @@ func (h *UserHandler) ChangePassword(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(nil, "Password changed successfully"))
 }
 
+// DeleteMe permanently deletes the authenticated user's account
+// DELETE /api/v1/me
+func (h *UserHandler) DeleteMe(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	var req models.DeleteAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+			"VALIDATION_ERROR",
+			"Invalid request body",
+		))
+	}
+
+	if req.Password == "" {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "password", Message: "Password is required"},
+		}))
+	}
+
+	if err := h.userService.DeleteAccount(c.Context(), userID, req.Password); err != nil {
+		if errors.Is(err, service.ErrInvalidPassword) {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"INVALID_PASSWORD",
+				"Password is incorrect",
+			))
+		}
+		if errors.Is(err, repository.ErrOwnsSharedWorkspace) {
+			return c.Status(fiber.StatusConflict).JSON(models.NewErrorResponse(
+				"WORKSPACE_OWNER",
+				"You own a workspace with other members. Transfer ownership or remove them before deleting your account",
+			))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
+			"INTERNAL_ERROR",
+			"Failed to delete account",
+		))
+	}
+
+	// Clear cookie
+	c.Cookie(&fiber.Cookie{
+		Name:     "refresh_token",
+		Value:    "",
+		Path:     "/api/v1/auth",
+		Expires:  time.Now().Add(-time.Hour),
+		HTTPOnly: true,
+		Secure:   true,
+		SameSite: "Strict",
+	})
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(nil, "Account deleted successfully"))
+}
+
 func (h *UserHandler) GetSessions(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 
@@ -1,6 +1,9 @@
 package handler
 
 import (
+	"errors"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/nextpdf/backend/internal/middleware"
@@ -10,10 +13,11 @@ import (
 
 type WorkspaceHandler struct {
 	workspaceService *service.WorkspaceService
+	languages        *service.Languages
 }
 
-func NewWorkspaceHandler(workspaceService *service.WorkspaceService) *WorkspaceHandler {
-	return &WorkspaceHandler{workspaceService: workspaceService}
+func NewWorkspaceHandler(workspaceService *service.WorkspaceService, languages *service.Languages) *WorkspaceHandler {
+	return &WorkspaceHandler{workspaceService: workspaceService, languages: languages}
 }
 
 func (h *WorkspaceHandler) Create(c *fiber.Ctx) error {
@@ -37,6 +41,25 @@ func (h *WorkspaceHandler) Create(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusCreated).JSON(models.NewAPIResponse(workspace.ToResponse("owner"), "Workspace created successfully"))
 }
 
+// Get returns one workspace, including its summary defaults, to a member
+func (h *WorkspaceHandler) Get(c *fiber.Ctx) error {
+	workspaceID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse("INVALID_ID", "Invalid workspace ID"))
+	}
+
+	userID := middleware.GetUserID(c)
+	workspace, err := h.workspaceService.GetWorkspaceDetail(c.Context(), userID, workspaceID)
+	if err != nil {
+		if errors.Is(err, service.ErrNotMember) || errors.Is(err, service.ErrWorkspaceNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse("WORKSPACE_NOT_FOUND", "Workspace not found"))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse("INTERNAL_ERROR", "Failed to get workspace"))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(workspace, ""))
+}
+
 func (h *WorkspaceHandler) Update(c *fiber.Ctx) error {
 	workspaceIDStr := c.Params("id")
 	workspaceID, err := uuid.Parse(workspaceIDStr)
@@ -44,30 +67,51 @@ func (h *WorkspaceHandler) Update(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse("INVALID_ID", "Invalid workspace ID"))
 	}
 
-	var req struct {
-		Name string `json:"name"`
-	}
+	var req models.UpdateWorkspaceRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse("VALIDATION_ERROR", "Invalid request body"))
 	}
 
-	if req.Name == "" {
-		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
-			{Field: "name", Message: "Workspace name is required"},
-		}))
+	var validationErrors []models.ValidationError
+	if req.Name == nil && req.DefaultStyle == nil && req.DefaultLanguage == nil {
+		validationErrors = append(validationErrors, models.ValidationError{Field: "name", Message: "Nothing to update"})
+	}
+	if req.Name != nil && *req.Name == "" {
+		validationErrors = append(validationErrors, models.ValidationError{Field: "name", Message: "Workspace name is required"})
+	}
+	if req.DefaultLanguage != nil && !h.languages.Allows(*req.DefaultLanguage) {
+		validationErrors = append(validationErrors, models.ValidationError{Field: "default_language", Message: "Default language must be one of: " + h.languages.Codes() + ", auto"})
+	}
+	if len(validationErrors) > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse(validationErrors))
 	}
 
 	userID := middleware.GetUserID(c)
-	workspace, err := h.workspaceService.UpdateWorkspace(c.Context(), userID, workspaceID, req.Name)
+	workspace, err := h.workspaceService.UpdateWorkspace(c.Context(), userID, workspaceID, &req)
 	if err != nil {
-		if errStr := err.Error(); errStr == "FORBIDDEN" { // Assuming service returns this or we check struct
-			return c.Status(fiber.StatusForbidden).JSON(models.NewErrorResponse("FORBIDDEN", "Only the owner can update the workspace"))
+		if errors.Is(err, service.ErrWorkspaceNotFound) || errors.Is(err, service.ErrNotMember) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse("WORKSPACE_NOT_FOUND", "Workspace not found"))
+		}
+		if errors.Is(err, service.ErrNotOwner) {
+			return c.Status(fiber.StatusForbidden).JSON(models.NewErrorResponse("FORBIDDEN", "Only the owner can rename the workspace"))
+		}
+		if errors.Is(err, service.ErrNotManager) {
+			return c.Status(fiber.StatusForbidden).JSON(models.NewErrorResponse("FORBIDDEN", "Only the owner or an admin can change summary defaults"))
+		}
+		if errors.Is(err, service.ErrInvalidStyle) {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
+				"INVALID_STYLE",
+				"Invalid summary style. Valid options: bullet_points, paragraph, detailed, executive, academic",
+			))
 		}
-		// Better error handling for custom errors would be ideal, but for now generic
 		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse("INTERNAL_ERROR", "Failed to update workspace"))
 	}
 
-	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(workspace.ToResponse("owner"), "Workspace updated successfully"))
+	role := models.WorkspaceRoleAdmin
+	if workspace.OwnerID == userID {
+		role = models.WorkspaceRoleOwner
+	}
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(workspace.ToResponse(role), "Workspace updated successfully"))
 }
 
 func (h *WorkspaceHandler) Join(c *fiber.Ctx) error {
@@ -97,6 +141,75 @@ func (h *WorkspaceHandler) Join(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(workspace.ToResponse("member"), "Joined workspace successfully"))
 }
 
+func (h *WorkspaceHandler) CreateInvite(c *fiber.Ctx) error {
+	workspaceID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse("INVALID_ID", "Invalid workspace ID"))
+	}
+
+	var req models.CreateWorkspaceInviteRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse("VALIDATION_ERROR", "Invalid request body"))
+		}
+	}
+
+	var validationErrors []models.ValidationError
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		validationErrors = append(validationErrors, models.ValidationError{Field: "expires_at", Message: "Expiry must be in the future"})
+	}
+	if req.MaxUses != nil && *req.MaxUses < 1 {
+		validationErrors = append(validationErrors, models.ValidationError{Field: "max_uses", Message: "Max uses must be at least 1"})
+	}
+	if len(validationErrors) > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse(validationErrors))
+	}
+
+	userID := middleware.GetUserID(c)
+	invite, err := h.workspaceService.CreateInvite(c.Context(), userID, workspaceID, req.ExpiresAt, req.MaxUses)
+	if err != nil {
+		if errors.Is(err, service.ErrNotMember) || errors.Is(err, service.ErrNotManager) {
+			return c.Status(fiber.StatusForbidden).JSON(models.NewErrorResponse("FORBIDDEN", "Only the owner or an admin can create invites"))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse("INTERNAL_ERROR", "Failed to create invite"))
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(models.NewAPIResponse(invite, "Invite created successfully"))
+}
+
+func (h *WorkspaceHandler) JoinByInvite(c *fiber.Ctx) error {
+	var req models.JoinByInviteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse("VALIDATION_ERROR", "Invalid request body"))
+	}
+
+	if req.Token == "" {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "token", Message: "Invite token is required"},
+		}))
+	}
+
+	userID := middleware.GetUserID(c)
+	workspace, err := h.workspaceService.JoinByInvite(c.Context(), userID, req.Token)
+	if err != nil {
+		if errors.Is(err, service.ErrInviteNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse("INVALID_INVITE", "Invalid invite link"))
+		}
+		if errors.Is(err, service.ErrInviteExpired) {
+			return c.Status(fiber.StatusGone).JSON(models.NewErrorResponse("INVITE_EXPIRED", "This invite link has expired"))
+		}
+		if errors.Is(err, service.ErrInviteExhausted) {
+			return c.Status(fiber.StatusGone).JSON(models.NewErrorResponse("INVITE_EXHAUSTED", "This invite link has already been used the maximum number of times"))
+		}
+		if errors.Is(err, service.ErrAlreadyMember) {
+			return c.Status(fiber.StatusConflict).JSON(models.NewErrorResponse("ALREADY_MEMBER", "You are already a member of this workspace"))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse("INTERNAL_ERROR", "Failed to join workspace"))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(workspace.ToResponse(models.WorkspaceRoleMember), "Joined workspace successfully"))
+}
+
 func (h *WorkspaceHandler) List(c *fiber.Ctx) error {
 	userID := middleware.GetUserID(c)
 	workspaces, err := h.workspaceService.GetUserWorkspaces(c.Context(), userID)
@@ -114,15 +227,142 @@ func (h *WorkspaceHandler) GetMembers(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse("INVALID_ID", "Invalid workspace ID"))
 	}
 
-	// Verify access (User must be a member to see other members)
+	// User must be a member to see other members
+	userID := middleware.GetUserID(c)
+	members, err := h.workspaceService.GetMembers(c.Context(), userID, workspaceID)
+	if err != nil {
+		if errors.Is(err, service.ErrNotMember) {
+			return c.Status(fiber.StatusForbidden).JSON(models.NewErrorResponse("FORBIDDEN", "You do not have access to this workspace"))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse("INTERNAL_ERROR", "Failed to list workspace members"))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(members, ""))
+}
+
+func (h *WorkspaceHandler) RemoveMember(c *fiber.Ctx) error {
+	workspaceID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse("INVALID_ID", "Invalid workspace ID"))
+	}
+	memberID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse("INVALID_ID", "Invalid user ID"))
+	}
+
+	userID := middleware.GetUserID(c)
+	if err := h.workspaceService.RemoveMember(c.Context(), userID, workspaceID, memberID); err != nil {
+		if errors.Is(err, service.ErrWorkspaceNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse("WORKSPACE_NOT_FOUND", "Workspace not found"))
+		}
+		if errors.Is(err, service.ErrNotOwner) {
+			return c.Status(fiber.StatusForbidden).JSON(models.NewErrorResponse("FORBIDDEN", "Only the owner or an admin can remove members"))
+		}
+		if errors.Is(err, service.ErrInsufficientRole) {
+			return c.Status(fiber.StatusForbidden).JSON(models.NewErrorResponse("FORBIDDEN", "Admins can only remove members"))
+		}
+		if errors.Is(err, service.ErrCannotRemoveOwner) {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse("CANNOT_REMOVE_OWNER", "The workspace owner cannot be removed"))
+		}
+		if errors.Is(err, service.ErrMemberNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse("MEMBER_NOT_FOUND", "User is not a member of this workspace"))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse("INTERNAL_ERROR", "Failed to remove member"))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(nil, "Member removed successfully"))
+}
+
+func (h *WorkspaceHandler) UpdateMemberRole(c *fiber.Ctx) error {
+	workspaceID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse("INVALID_ID", "Invalid workspace ID"))
+	}
+	memberID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse("INVALID_ID", "Invalid user ID"))
+	}
+
+	var req models.UpdateMemberRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse("VALIDATION_ERROR", "Invalid request body"))
+	}
+
+	userID := middleware.GetUserID(c)
+	member, err := h.workspaceService.ChangeMemberRole(c.Context(), userID, workspaceID, memberID, req.Role)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRole) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+				{Field: "role", Message: "Role must be 'admin' or 'member'"},
+			}))
+		}
+		if errors.Is(err, service.ErrWorkspaceNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse("WORKSPACE_NOT_FOUND", "Workspace not found"))
+		}
+		if errors.Is(err, service.ErrNotOwner) {
+			return c.Status(fiber.StatusForbidden).JSON(models.NewErrorResponse("FORBIDDEN", "Only the owner can change member roles"))
+		}
+		if errors.Is(err, service.ErrCannotChangeOwner) {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse("CANNOT_CHANGE_OWNER", "The workspace owner's role cannot be changed"))
+		}
+		if errors.Is(err, service.ErrMemberNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse("MEMBER_NOT_FOUND", "User is not a member of this workspace"))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse("INTERNAL_ERROR", "Failed to update member role"))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(member, "Member role updated successfully"))
+}
+
+func (h *WorkspaceHandler) TransferOwnership(c *fiber.Ctx) error {
+	workspaceID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse("INVALID_ID", "Invalid workspace ID"))
+	}
+
+	var req models.TransferOwnershipRequest
+	if err := c.BodyParser(&req); err != nil || req.UserID == uuid.Nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "user_id", Message: "User ID is required"},
+		}))
+	}
+
 	userID := middleware.GetUserID(c)
-	_, err = h.workspaceService.VerifyMemberAccess(c.Context(), workspaceID, userID)
+	if err := h.workspaceService.TransferOwnership(c.Context(), userID, workspaceID, req.UserID); err != nil {
+		if errors.Is(err, service.ErrWorkspaceNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse("WORKSPACE_NOT_FOUND", "Workspace not found"))
+		}
+		if errors.Is(err, service.ErrNotOwner) {
+			return c.Status(fiber.StatusForbidden).JSON(models.NewErrorResponse("FORBIDDEN", "Only the owner can transfer ownership"))
+		}
+		if errors.Is(err, service.ErrAlreadyOwner) {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse("ALREADY_OWNER", "You already own this workspace"))
+		}
+		if errors.Is(err, service.ErrMemberNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse("MEMBER_NOT_FOUND", "User is not a member of this workspace"))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse("INTERNAL_ERROR", "Failed to transfer ownership"))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(nil, "Ownership transferred successfully"))
+}
+
+func (h *WorkspaceHandler) Leave(c *fiber.Ctx) error {
+	workspaceID, err := uuid.Parse(c.Params("id"))
 	if err != nil {
-		return c.Status(fiber.StatusForbidden).JSON(models.NewErrorResponse("FORBIDDEN", "You do not have access to this workspace"))
+		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse("INVALID_ID", "Invalid workspace ID"))
+	}
+
+	userID := middleware.GetUserID(c)
+	if err := h.workspaceService.LeaveWorkspace(c.Context(), userID, workspaceID); err != nil {
+		if errors.Is(err, service.ErrWorkspaceNotFound) || errors.Is(err, service.ErrNotMember) {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse("WORKSPACE_NOT_FOUND", "Workspace not found"))
+		}
+		if errors.Is(err, service.ErrOwnerCannotLeave) {
+			return c.Status(fiber.StatusConflict).JSON(models.NewErrorResponse("OWNER_CANNOT_LEAVE", "Transfer ownership before leaving the workspace"))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse("INTERNAL_ERROR", "Failed to leave workspace"))
 	}
 
-	// For now, implementing simple member count logic or list logic could be added here
-	// This endpoint was planned but ListByUserID is the primary one for now.
-	// Returning not implemented or simple success for now to unblock.
-	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(nil, "Member list coming soon"))
+	return c.Status(fiber.StatusOK).JSON(models.NewAPIResponse(nil, "Left workspace successfully"))
 }
@@ -0,0 +1,74 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/nextpdf/backend/internal/config"
+)
+
+// Mailer sends plain-text email
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// NewMailer returns an SMTP mailer, or a log-only mailer when no SMTP host is configured
+func NewMailer(cfg config.MailConfig) Mailer {
+	if cfg.SMTPHost == "" {
+		return &logMailer{}
+	}
+	return &smtpMailer{cfg: cfg}
+}
+
+type smtpMailer struct {
+	cfg config.MailConfig
+}
+
+func (m *smtpMailer) Send(ctx context.Context, to, subject, body string) error {
+	from, err := mail.ParseAddress(m.cfg.From)
+	if err != nil {
+		return fmt.Errorf("invalid MAIL_FROM: %w", err)
+	}
+
+	var auth smtp.Auth
+	if m.cfg.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", m.cfg.SMTPUsername, m.cfg.SMTPPassword, m.cfg.SMTPHost)
+	}
+
+	msg := strings.Join([]string{
+		"From: " + from.String(),
+		"To: " + to,
+		"Subject: " + subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+
+	// net/smtp has no context support; run it aside so callers can still give up
+	addr := fmt.Sprintf("%s:%d", m.cfg.SMTPHost, m.cfg.SMTPPort)
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, from.Address, []string{to}, []byte(msg))
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type logMailer struct{}
+
+func (m *logMailer) Send(ctx context.Context, to, subject, body string) error {
+	log.Printf("Mail (SMTP not configured) to=%s subject=%q\n%s", to, subject, body)
+	return nil
+}
@@ -88,6 +88,25 @@ func (c *RabbitMQClient) PublishTask(ctx context.Context, task interface{}) erro
 	)
 }
 
+func (c *RabbitMQClient) PublishEvent(ctx context.Context, routingKey string, event interface{}) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	return c.channel.PublishWithContext(ctx,
+		"ai.events", // exchange
+		routingKey,  // routing key
+		false,       // mandatory
+		false,       // immediate
+		amqp.Publishing{
+			ContentType: "application/json",
+			Body:        body,
+			Timestamp:   time.Now(),
+		},
+	)
+}
+
 func (c *RabbitMQClient) SubscribeEvents(routingKey string) (<-chan amqp.Delivery, error) {
 	q, err := c.channel.QueueDeclare(
 		"",    // name (random)
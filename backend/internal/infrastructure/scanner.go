@@ -0,0 +1,111 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/nextpdf/backend/internal/config"
+	"github.com/nextpdf/backend/internal/models"
+)
+
+// maxScanResponseSize bounds the scanner reply read for the signature name
+const maxScanResponseSize = 64 << 10
+
+// ScanResult is the outcome of scanning one file. Status is empty when no
+// scan ran; Signature names what was found in an infected file.
+type ScanResult struct {
+	Status    models.ScanStatus
+	Signature string
+}
+
+// Scanner checks uploaded content for malware
+type Scanner interface {
+	Scan(ctx context.Context, filename string, content io.Reader) (ScanResult, error)
+}
+
+// NewScanner returns an HTTP scanner, or a no-op scanner when no scanner URL is configured
+func NewScanner(cfg config.ScanConfig) Scanner {
+	if cfg.URL == "" {
+		return noopScanner{}
+	}
+	return &httpScanner{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+}
+
+type noopScanner struct{}
+
+func (noopScanner) Scan(ctx context.Context, filename string, content io.Reader) (ScanResult, error) {
+	return ScanResult{}, nil
+}
+
+type httpScanner struct {
+	cfg    config.ScanConfig
+	client *http.Client
+}
+
+func (s *httpScanner) Scan(ctx context.Context, filename string, content io.Reader) (ScanResult, error) {
+	// Stream the object into the request body instead of buffering it
+	body, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	go func() {
+		part, err := writer.CreateFormFile("file", filename)
+		if err == nil {
+			_, err = io.Copy(part, content)
+		}
+		if err == nil {
+			err = writer.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL, body)
+	if err != nil {
+		body.Close()
+		return ScanResult{}, err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		body.Close()
+		return ScanResult{}, fmt.Errorf("scanner request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return ScanResult{Status: models.ScanClean}, nil
+	case http.StatusNotAcceptable:
+		reply, _ := io.ReadAll(io.LimitReader(resp.Body, maxScanResponseSize))
+		return ScanResult{Status: models.ScanInfected, Signature: scanSignature(reply)}, nil
+	default:
+		return ScanResult{}, fmt.Errorf("scanner returned status %d", resp.StatusCode)
+	}
+}
+
+// scanSignature pulls the signature names out of a ClamAV REST reply, which
+// lists one result per file; anything unexpected is reported as unknown
+func scanSignature(reply []byte) string {
+	var results []struct {
+		Description string `json:"Description"`
+	}
+	if json.Unmarshal(reply, &results) != nil {
+		return "unknown"
+	}
+
+	var names []string
+	for _, r := range results {
+		if r.Description != "" {
+			names = append(names, r.Description)
+		}
+	}
+	if len(names) == 0 {
+		return "unknown"
+	}
+	return strings.Join(names, ", ")
+}
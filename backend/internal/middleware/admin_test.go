@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nextpdf/backend/internal/repository"
+	"github.com/nextpdf/backend/internal/service"
+	"github.com/nextpdf/backend/internal/testdb"
+)
+
+func TestAdminRequiresVerifiedEmail(t *testing.T) {
+	pool := testdb.New(t)
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(pool, nil)
+
+	userID := testdb.CreateUser(t, pool)
+	const email = "admin@example.com"
+	if _, err := pool.Exec(ctx, "UPDATE users SET email = $2 WHERE id = $1", userID, email); err != nil {
+		t.Fatalf("set email: %v", err)
+	}
+
+	app := fiber.New()
+	app.Get("/admin",
+		func(c *fiber.Ctx) error {
+			c.Locals(UserIDKey, userID)
+			c.Locals(UserEmailKey, email)
+			return c.Next()
+		},
+		AdminMiddleware(service.NewUserService(userRepo, nil, nil), "Admin@Example.com"),
+		func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) },
+	)
+
+	status := func() int {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/admin", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+
+	if got := status(); got != fiber.StatusForbidden {
+		t.Errorf("unverified admin email: status %d, want %d", got, fiber.StatusForbidden)
+	}
+	if err := userRepo.MarkEmailVerified(ctx, userID); err != nil {
+		t.Fatalf("verify email: %v", err)
+	}
+	if got := status(); got != fiber.StatusOK {
+		t.Errorf("verified admin email: status %d, want %d", got, fiber.StatusOK)
+	}
+}
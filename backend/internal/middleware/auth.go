@@ -14,7 +14,11 @@ const (
 	UserEmailKey = "userEmail"
 )
 
-func AuthMiddleware(authService *service.AuthService) fiber.Handler {
+// AuthMiddleware authenticates requests with the access token in the
+// Authorization header. Requests whose path ends in one of streamPaths may
+// instead carry a stream token in ?token=; everywhere else the query token is
+// ignored.
+func AuthMiddleware(authService *service.AuthService, streamPaths ...string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var token string
 
@@ -27,9 +31,22 @@ func AuthMiddleware(authService *service.AuthService) fiber.Handler {
 			}
 		}
 
-		// 2. Try Query Param (for SSE/WS)
-		if token == "" {
-			token = c.Query("token")
+		// 2. Try Query Param (for SSE/WS) on the streaming endpoints only. The
+		// URL may be logged, so only a short-lived stream token from
+		// POST /auth/stream-token is accepted there.
+		if token == "" && c.Query("token") != "" && isStreamPath(c.Path(), streamPaths) {
+			claims, err := authService.ValidateStreamToken(c.Query("token"))
+			if err != nil {
+				return c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(
+					"UNAUTHORIZED",
+					"Invalid or expired stream token",
+				))
+			}
+
+			c.Locals(UserIDKey, claims.UserID)
+			c.Locals(UserEmailKey, claims.Email)
+
+			return c.Next()
 		}
 
 		if token == "" {
@@ -59,6 +76,48 @@ func AuthMiddleware(authService *service.AuthService) fiber.Handler {
 	}
 }
 
+func isStreamPath(path string, streamPaths []string) bool {
+	for _, suffix := range streamPaths {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// AdminMiddleware restricts a route to the configured admin emails. It must run
+// after AuthMiddleware. The email is read from the user's account rather than
+// the token, and must be verified: registration does not require
+// verification, so anyone could otherwise sign up with an admin address that
+// has no account yet. An empty list disables admin access entirely.
+func AdminMiddleware(userService *service.UserService, adminEmails string) fiber.Handler {
+	admins := make(map[string]bool)
+	for _, email := range strings.Split(adminEmails, ",") {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			admins[email] = true
+		}
+	}
+
+	forbidden := func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusForbidden).JSON(models.NewErrorResponse(
+			"FORBIDDEN",
+			"Admin access required",
+		))
+	}
+
+	return func(c *fiber.Ctx) error {
+		if !admins[strings.ToLower(GetUserEmail(c))] {
+			return forbidden(c)
+		}
+
+		user, err := userService.GetByID(c.Context(), GetUserID(c))
+		if err != nil || !user.IsActive || user.EmailVerifiedAt == nil || !admins[strings.ToLower(user.Email)] {
+			return forbidden(c)
+		}
+		return c.Next()
+	}
+}
+
 func GetUserID(c *fiber.Ctx) uuid.UUID {
 	userID, ok := c.Locals(UserIDKey).(uuid.UUID)
 	if !ok {
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/nextpdf/backend/internal/config"
+	"github.com/nextpdf/backend/internal/service"
+)
+
+func newStreamTestApp(t *testing.T) (*fiber.App, string) {
+	t.Helper()
+
+	authService := service.NewAuthService(nil, nil, nil, nil, nil, nil, nil, nil, nil,
+		config.JWTConfig{AccessSecret: "test-secret", AccessExpiryMins: time.Minute},
+		config.MailConfig{}, config.LockoutConfig{})
+
+	streamToken, err := authService.IssueStreamToken(uuid.New(), "user@example.com")
+	if err != nil {
+		t.Fatalf("IssueStreamToken: %v", err)
+	}
+
+	app := fiber.New()
+	files := app.Group("/files", AuthMiddleware(authService, "/summarize-stream", "/summarize-ws", "/events"))
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+	files.Get("/:id", ok)
+	files.Get("/:id/download", ok)
+	files.Get("/:id/events", ok)
+	files.Get("/:id/summarize-ws", ok)
+	files.Post("/:id/summarize-stream", ok)
+
+	return app, streamToken.StreamToken
+}
+
+func TestStreamTokenOnlyOnStreamingPaths(t *testing.T) {
+	app, token := newStreamTestApp(t)
+	id := uuid.New().String()
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{fiber.MethodGet, "/files/" + id + "/events", fiber.StatusOK},
+		{fiber.MethodGet, "/files/" + id + "/summarize-ws", fiber.StatusOK},
+		{fiber.MethodPost, "/files/" + id + "/summarize-stream", fiber.StatusOK},
+		{fiber.MethodGet, "/files/" + id, fiber.StatusUnauthorized},
+		{fiber.MethodGet, "/files/" + id + "/download", fiber.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest(tt.method, tt.path+"?token="+token, nil))
+		if err != nil {
+			t.Fatalf("%s %s: %v", tt.method, tt.path, err)
+		}
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s %s: status %d, want %d", tt.method, tt.path, resp.StatusCode, tt.want)
+		}
+	}
+}
+
+func TestStreamTokenRejectedAsBearer(t *testing.T) {
+	app, token := newStreamTestApp(t)
+
+	req := httptest.NewRequest(fiber.MethodGet, "/files/"+uuid.New().String(), nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("status %d, want %d", resp.StatusCode, fiber.StatusUnauthorized)
+	}
+}
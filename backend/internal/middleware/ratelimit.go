@@ -5,10 +5,12 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/limiter"
+	"github.com/google/uuid"
 	"github.com/nextpdf/backend/internal/config"
 	"github.com/nextpdf/backend/internal/models"
 )
 
+// RateLimitMiddleware is the per-IP ceiling applied to every request
 func RateLimitMiddleware(cfg config.RateLimitConfig) fiber.Handler {
 	return limiter.New(limiter.Config{
 		Max:        cfg.Max,
@@ -25,3 +27,27 @@ func RateLimitMiddleware(cfg config.RateLimitConfig) fiber.Handler {
 		SkipFailedRequests: false,
 	})
 }
+
+// RateLimitByUser limits requests per authenticated user, falling back to the
+// client IP on public routes. Each call creates an independent bucket, so
+// handlers sharing one instance share a budget. It must run after
+// AuthMiddleware to see the user; the X-RateLimit-* headers it sets replace
+// those of the global per-IP limiter.
+func RateLimitByUser(rule config.RateLimitRule) fiber.Handler {
+	return limiter.New(limiter.Config{
+		Max:        rule.Max,
+		Expiration: rule.Expiry,
+		KeyGenerator: func(c *fiber.Ctx) string {
+			if userID := GetUserID(c); userID != uuid.Nil {
+				return "user:" + userID.String()
+			}
+			return "ip:" + c.IP()
+		},
+		LimitReached: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusTooManyRequests).JSON(models.NewErrorResponse(
+				"RATE_LIMIT_EXCEEDED",
+				"Too many requests. Please try again later.",
+			))
+		},
+	})
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"log"
+	"runtime/debug"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/google/uuid"
+)
+
+// RecoverMiddleware turns a panic into the generic 500 of the error handler
+// and logs it with its stack trace and the request it happened in. It must run
+// after requestid so the log line carries the request ID.
+func RecoverMiddleware() fiber.Handler {
+	return recover.New(recover.Config{
+		EnableStackTrace:  true,
+		StackTraceHandler: logPanic,
+	})
+}
+
+func logPanic(c *fiber.Ctx, e interface{}) {
+	requestID, _ := c.Locals(requestid.ConfigDefault.ContextKey).(string)
+	userID := "-"
+	if id, ok := c.Locals(UserIDKey).(uuid.UUID); ok {
+		userID = id.String()
+	}
+
+	log.Printf("PANIC: %v [request_id=%s method=%s path=%s user_id=%s]\n%s",
+		e, requestID, c.Method(), c.Path(), userID, debug.Stack())
+}
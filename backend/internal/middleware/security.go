@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nextpdf/backend/internal/config"
+)
+
+// SecurityHeaders sets the configured security headers on every response.
+// The defaults suit a JSON API that is never framed: file previews load from
+// presigned storage URLs, not from this server, so no route needs framing.
+func SecurityHeaders(cfg config.SecurityConfig, production bool) fiber.Handler {
+	hsts := ""
+	if production && cfg.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(cfg.HSTSMaxAge.Seconds())) + "; includeSubDomains"
+	}
+
+	headers := map[string]string{
+		fiber.HeaderXContentTypeOptions:     cfg.ContentTypeOptions,
+		fiber.HeaderXFrameOptions:           cfg.FrameOptions,
+		fiber.HeaderReferrerPolicy:          cfg.ReferrerPolicy,
+		fiber.HeaderContentSecurityPolicy:   cfg.ContentSecurityPolicy,
+		fiber.HeaderStrictTransportSecurity: hsts,
+	}
+
+	return func(c *fiber.Ctx) error {
+		for name, value := range headers {
+			if value != "" {
+				c.Set(name, value)
+			}
+		}
+		return c.Next()
+	}
+}
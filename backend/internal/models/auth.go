@@ -13,11 +13,47 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=8"`
+}
+
+// LoginResponse carries either tokens or, for accounts with 2FA, a challenge
+// token to exchange at /auth/2fa/verify together with a code
 type LoginResponse struct {
-	AccessToken string        `json:"access_token"`
-	TokenType   string        `json:"token_type"`
-	ExpiresIn   int           `json:"expires_in"`
-	User        *UserResponse `json:"user"`
+	AccessToken       string        `json:"access_token,omitempty"`
+	TokenType         string        `json:"token_type,omitempty"`
+	ExpiresIn         int           `json:"expires_in"`
+	User              *UserResponse `json:"user,omitempty"`
+	TwoFactorRequired bool          `json:"two_factor_required,omitempty"`
+	ChallengeToken    string        `json:"challenge_token,omitempty"`
+}
+
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type TwoFactorSetupResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type TwoFactorEnableResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
 }
 
 type RefreshResponse struct {
@@ -26,6 +62,13 @@ type RefreshResponse struct {
 	ExpiresIn   int    `json:"expires_in"`
 }
 
+// StreamTokenResponse carries a short-lived token for ?token= on streaming
+// endpoints, which cannot send an Authorization header
+type StreamTokenResponse struct {
+	StreamToken string `json:"stream_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
 type LogoutAllResponse struct {
 	SessionsTerminated int `json:"sessions_terminated"`
 }
@@ -35,6 +78,10 @@ type UpdateProfileRequest struct {
 	AvatarURL *string `json:"avatar_url" validate:"omitempty,url"`
 }
 
+type DeleteAccountRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
 type ChangePasswordRequest struct {
 	CurrentPassword         string `json:"current_password" validate:"required"`
 	NewPassword             string `json:"new_password" validate:"required,min=8"`
@@ -16,6 +16,22 @@ const (
 	StatusFailed     ProcessingStatus = "failed"
 )
 
+// ScanStatus is the malware scan result recorded for an upload
+type ScanStatus string
+
+const (
+	ScanClean    ScanStatus = "clean"
+	ScanInfected ScanStatus = "infected"
+)
+
+// PDFMetadata is read from a PDF's document info dictionary after upload.
+// Fields are nil when the entry is missing or the file could not be parsed.
+type PDFMetadata struct {
+	Title     *string    `json:"title"`
+	Author    *string    `json:"author"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
 type File struct {
 	ID               uuid.UUID        `json:"id"`
 	UserID           uuid.UUID        `json:"user_id"`
@@ -27,12 +43,17 @@ type File struct {
 	MimeType         string           `json:"mime_type"`
 	FileSize         int64            `json:"file_size"`
 	PageCount        *int             `json:"page_count"`
+	ChecksumSHA256   *string          `json:"checksum_sha256,omitempty"`
 	Status           ProcessingStatus `json:"status"`
 	ErrorMessage     *string          `json:"error_message"`
+	Starred          bool             `json:"starred"`
+	ScanStatus       *ScanStatus      `json:"scan_status"` // NULL when no scanner was configured at upload
+	PDFMetadata      PDFMetadata      `json:"pdf_metadata"`
 	UploadedAt       time.Time        `json:"uploaded_at"`
 	ProcessedAt      *time.Time       `json:"processed_at"`
 	CreatedAt        time.Time        `json:"created_at"`
 	UpdatedAt        time.Time        `json:"updated_at"`
+	DeletedAt        *time.Time       `json:"deleted_at,omitempty"` // Set while the file is in the trash
 }
 
 type FileResponse struct {
@@ -44,9 +65,62 @@ type FileResponse struct {
 	PageCount        *int             `json:"page_count,omitempty"`
 	Status           ProcessingStatus `json:"status"`
 	HasSummary       bool             `json:"has_summary"`
+	Starred          bool             `json:"starred"`
 	MimeType         string           `json:"mime_type"`
 	UploadedAt       time.Time        `json:"uploaded_at"`
 	ProcessedAt      *time.Time       `json:"processed_at,omitempty"`
+	DeletedAt        *time.Time       `json:"deleted_at,omitempty"`
+	QuotaWarning     bool             `json:"quota_warning,omitempty"`
+	// DuplicateOf is set on a confirmed upload whose content matches a file
+	// the user already has
+	DuplicateOf *uuid.UUID `json:"duplicate_of,omitempty"`
+}
+
+// FileSearchResult is a file whose extracted text matched GET /files/search.
+// Snippet quotes the best passages with the matches wrapped in <mark> tags;
+// the rest of it is document text, not HTML.
+type FileSearchResult struct {
+	FileResponse
+	Rank    float32 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// PageCountBackfillReport summarizes one backfill run. HasMore means files
+// beyond this run's limit still lack a page count.
+type PageCountBackfillReport struct {
+	Scanned int  `json:"scanned"`
+	Updated int  `json:"updated"`
+	Failed  int  `json:"failed"`
+	HasMore bool `json:"has_more"`
+}
+
+// FileCacheStats reports the file metadata cache for GET /admin/cache/files.
+// Hits are database round trips saved.
+type FileCacheStats struct {
+	Enabled    bool    `json:"enabled"`
+	TTLSeconds float64 `json:"ttl_seconds"`
+	Entries    int     `json:"entries"`
+	Hits       int64   `json:"hits"`
+	Misses     int64   `json:"misses"`
+	HitRatio   float64 `json:"hit_ratio"`
+}
+
+// FileChecksumResponse reports the SHA-256 of a stored file. Cached is true
+// when the value came from the row rather than being computed for this request.
+type FileChecksumResponse struct {
+	FileID    uuid.UUID `json:"file_id"`
+	Algorithm string    `json:"algorithm"`
+	Checksum  string    `json:"checksum"`
+	Cached    bool      `json:"cached"`
+}
+
+// StorageUsageResponse reports a user's storage consumption against their quota.
+// QuotaBytes is 0 when quotas are disabled.
+type StorageUsageResponse struct {
+	UsedBytes    int64   `json:"used_bytes"`
+	QuotaBytes   int64   `json:"quota_bytes"`
+	UsedPercent  float64 `json:"used_percent"`
+	QuotaWarning bool    `json:"quota_warning"`
 }
 
 type FileDetailResponse struct {
@@ -61,12 +135,28 @@ type FileDetailResponse struct {
 	PageCount        *int             `json:"page_count,omitempty"`
 	Status           ProcessingStatus `json:"status"`
 	ErrorMessage     *string          `json:"error_message,omitempty"`
+	Starred          bool             `json:"starred"`
 	UploadedAt       time.Time        `json:"uploaded_at"`
 	ProcessedAt      *time.Time       `json:"processed_at,omitempty"`
 	CreatedAt        time.Time        `json:"created_at"`
 	UpdatedAt        time.Time        `json:"updated_at"`
 	DownloadURL      string           `json:"download_url,omitempty"`
 	Summary          *SummaryBrief    `json:"summary,omitempty"`
+	PDFMetadata      PDFMetadata      `json:"pdf_metadata"`
+	// SuggestedFilename is the embedded PDF title as a display name, offered
+	// when it differs from the current name
+	SuggestedFilename *string `json:"suggested_filename,omitempty"`
+}
+
+// CopyFileRequest is the optional body of POST /files/:id/copy
+type CopyFileRequest struct {
+	CopySummaries bool `json:"copy_summaries"`
+}
+
+// FileStarResponse is returned by PATCH /files/:id/star and /unstar
+type FileStarResponse struct {
+	FileID  uuid.UUID `json:"file_id"`
+	Starred bool      `json:"starred"`
 }
 
 type FolderInfo struct {
@@ -74,6 +164,10 @@ type FolderInfo struct {
 	Name string    `json:"name"`
 }
 
+type BatchGetFilesRequest struct {
+	FileIDs []uuid.UUID `json:"file_ids"`
+}
+
 type SummaryBrief struct {
 	ID                   uuid.UUID `json:"id"`
 	Title                *string   `json:"title"`
@@ -86,27 +180,69 @@ type MoveFileRequest struct {
 	FolderID *uuid.UUID `json:"folder_id"`
 }
 
+// BulkMoveFilesRequest moves several files to one folder (nil for the root)
+type BulkMoveFilesRequest struct {
+	FileIDs  []uuid.UUID `json:"file_ids"`
+	FolderID *uuid.UUID  `json:"folder_id"`
+}
+
+// BulkMoveFilesResponse reports how many of the requested files were moved;
+// IDs that are not the caller's files are skipped
+type BulkMoveFilesResponse struct {
+	FolderID *uuid.UUID `json:"folder_id"`
+	Moved    int64      `json:"moved"`
+}
+
 type PendingUpload struct {
-	ID          uuid.UUID  `json:"id"`
-	UserID      uuid.UUID  `json:"user_id"`
-	WorkspaceID *uuid.UUID `json:"workspace_id"`
-	FolderID    *uuid.UUID `json:"folder_id"`
-	Filename    string     `json:"filename"`
-	FileSize    int64      `json:"file_size"`
-	ContentType string     `json:"content_type"`
-	StoragePath string     `json:"storage_path"`
-	ExpiresAt   time.Time  `json:"expires_at"`
-	CreatedAt   time.Time  `json:"created_at"`
+	ID              uuid.UUID     `json:"id"`
+	UserID          uuid.UUID     `json:"user_id"`
+	WorkspaceID     *uuid.UUID    `json:"workspace_id"`
+	FolderID        *uuid.UUID    `json:"folder_id"`
+	Filename        string        `json:"filename"`
+	FileSize        int64         `json:"file_size"`
+	ContentType     string        `json:"content_type"`
+	StoragePath     string        `json:"storage_path"`
+	AutoSummarize   bool          `json:"auto_summarize"`
+	SummaryStyle    *SummaryStyle `json:"summary_style"`
+	SummaryLanguage *string       `json:"summary_language"`
+	// MultipartUploadID is set for uploads started with POST /files/upload/multipart/init
+	MultipartUploadID *string   `json:"multipart_upload_id"`
+	ExpiresAt         time.Time `json:"expires_at"`
+	CreatedAt         time.Time `json:"created_at"`
 }
 
+// PresignRequest starts an upload. With auto_summarize set, confirming the
+// upload also queues a summary in summary_style and summary_language, which
+// default like an omitted field of POST /summaries/{file_id}/generate.
 type PresignRequest struct {
-	Filename    string     `json:"filename" validate:"required"`
-	FileSize    int64      `json:"file_size" validate:"required,gt=0"`
-	ContentType string     `json:"content_type" validate:"required"`
+	Filename        string       `json:"filename" validate:"required"`
+	FileSize        int64        `json:"file_size" validate:"required,gt=0"`
+	ContentType     string       `json:"content_type" validate:"required"`
+	FolderID        *uuid.UUID   `json:"folder_id"`
+	WorkspaceID     *uuid.UUID   `json:"workspace_id"`
+	AutoSummarize   bool         `json:"auto_summarize"`
+	SummaryStyle    SummaryStyle `json:"summary_style"`
+	SummaryLanguage string       `json:"summary_language"` // One of GET /summary-languages or "auto"
+}
+
+// ImportURLsRequest is the body of POST /files/import-urls
+type ImportURLsRequest struct {
+	URLs        []string   `json:"urls"`
 	FolderID    *uuid.UUID `json:"folder_id"`
 	WorkspaceID *uuid.UUID `json:"workspace_id"`
 }
 
+// ImportURLResult is the outcome of importing one URL: the created file, or
+// the error that stopped it
+type ImportURLResult struct {
+	URL   string        `json:"url"`
+	File  *FileResponse `json:"file,omitempty"`
+	Error *ErrorDetail  `json:"error,omitempty"`
+}
+
+// PresignResponse is a presigned POST policy: the client uploads with a
+// multipart/form-data POST to PresignedURL, sending every Headers entry as a
+// form field before the file field
 type PresignResponse struct {
 	UploadID     uuid.UUID         `json:"upload_id"`
 	PresignedURL string            `json:"presigned_url"`
@@ -115,6 +251,42 @@ type PresignResponse struct {
 	Headers      map[string]string `json:"headers"`
 }
 
+// MultipartInitResponse starts a resumable upload. The client PUTs PartCount
+// parts of PartSize bytes (the last may be shorter), each to a URL from
+// POST /files/upload/multipart/part-url, then calls
+// POST /files/upload/multipart/complete. A failed part is simply sent again.
+type MultipartInitResponse struct {
+	UploadID    uuid.UUID `json:"upload_id"`
+	PartSize    int64     `json:"part_size"`
+	PartCount   int       `json:"part_count"`
+	StoragePath string    `json:"storage_path"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type MultipartPartURLRequest struct {
+	UploadID   uuid.UUID `json:"upload_id"`
+	PartNumber int       `json:"part_number"`
+}
+
+type MultipartPartURLResponse struct {
+	PartNumber   int       `json:"part_number"`
+	PresignedURL string    `json:"presigned_url"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// PendingUploadResponse describes an upload that was presigned but never confirmed
+type PendingUploadResponse struct {
+	UploadID    uuid.UUID  `json:"upload_id"`
+	Filename    string     `json:"filename"`
+	FileSize    int64      `json:"file_size"`
+	ContentType string     `json:"content_type"`
+	FolderID    *uuid.UUID `json:"folder_id"`
+	WorkspaceID *uuid.UUID `json:"workspace_id"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	IsExpired   bool       `json:"is_expired"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
 type ConfirmUploadRequest struct {
 	UploadID uuid.UUID `json:"upload_id" validate:"required"`
 }
@@ -127,9 +299,10 @@ type AvatarPresignRequest struct {
 }
 
 type AvatarPresignResponse struct {
-	UploadID     uuid.UUID `json:"upload_id"`
-	PresignedURL string    `json:"presigned_url"`
-	ExpiresAt    time.Time `json:"expires_at"`
+	UploadID     uuid.UUID         `json:"upload_id"`
+	PresignedURL string            `json:"presigned_url"`
+	ExpiresAt    time.Time         `json:"expires_at"`
+	Headers      map[string]string `json:"headers"`
 }
 
 type AvatarConfirmRequest struct {
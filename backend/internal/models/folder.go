@@ -24,22 +24,35 @@ type FolderWithCounts struct {
 	TotalSize int64 `json:"total_size"`
 }
 
+// FolderTreeNode is one folder of GET /folders/tree. FileCount and TotalSize
+// cover the files directly in the folder; TotalFileCount and
+// TotalSizeRecursive also include every subfolder.
 type FolderTreeNode struct {
-	ID        uuid.UUID         `json:"id"`
-	Name      string            `json:"name"`
-	ParentID  *uuid.UUID        `json:"parent_id"`
-	Depth     int               `json:"depth"`
-	SortOrder int               `json:"sort_order"`
-	FileCount int64             `json:"file_count,omitempty"`
-	TotalSize int64             `json:"total_size,omitempty"`
-	CreatedAt time.Time         `json:"created_at"`
-	Children  []*FolderTreeNode `json:"children"`
-	Files     []*FileResponse   `json:"files,omitempty"`
+	ID                 uuid.UUID         `json:"id"`
+	Name               string            `json:"name"`
+	ParentID           *uuid.UUID        `json:"parent_id"`
+	Depth              int               `json:"depth"`
+	SortOrder          int               `json:"sort_order"`
+	FileCount          int64             `json:"file_count,omitempty"`
+	TotalSize          int64             `json:"total_size,omitempty"`
+	TotalFileCount     int64             `json:"total_file_count,omitempty"`
+	TotalSizeRecursive int64             `json:"total_size_recursive,omitempty"`
+	CreatedAt          time.Time         `json:"created_at"`
+	Children           []*FolderTreeNode `json:"children"`
+	Files              []*FileResponse   `json:"files,omitempty"`
 }
 
+// OnConflict values decide what creating a folder does when a sibling has the same name
+const (
+	ConflictReject = "reject"
+	ConflictRename = "rename"
+)
+
 type CreateFolderRequest struct {
 	Name     string     `json:"name" validate:"required,min=1,max=255"`
 	ParentID *uuid.UUID `json:"parent_id"`
+	// OnConflict is "reject" (default) or "rename", which appends " (2)", " (3)", ...
+	OnConflict string `json:"on_conflict"`
 }
 
 type UpdateFolderRequest struct {
@@ -50,3 +63,48 @@ type MoveFolderRequest struct {
 	ParentID  *uuid.UUID `json:"parent_id"`
 	SortOrder *int       `json:"sort_order"`
 }
+
+type ReorderFoldersRequest struct {
+	ParentID   *uuid.UUID  `json:"parent_id"`
+	OrderedIDs []uuid.UUID `json:"ordered_ids"`
+}
+
+// FolderReorderItem is one entry of a batch reorder: the folder ends up under
+// ParentID (nil for the root) at SortOrder
+type FolderReorderItem struct {
+	ID        uuid.UUID  `json:"id"`
+	SortOrder int        `json:"sort_order"`
+	ParentID  *uuid.UUID `json:"parent_id"`
+}
+
+type DuplicateFolderRequest struct {
+	IncludeFiles bool `json:"include_files"`
+}
+
+// FolderBreadcrumb is one step of the navigation path above a folder
+type FolderBreadcrumb struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+// FolderDeleteResponse is returned when a folder was deleted but some of its
+// stored files could not be removed. Their rows are already gone, so the
+// objects are orphans that POST /admin/storage/reconcile with delete_orphans
+// removes.
+type FolderDeleteResponse struct {
+	FolderID      uuid.UUID `json:"folder_id"`
+	FailedObjects int       `json:"failed_objects"`
+	Warning       string    `json:"warning"`
+}
+
+// FolderSummaryStats shows how much of a folder has been summarized. Pages and
+// processing time only count each file's current summary; AvgProcessingMs is
+// nil when no summary recorded a duration.
+type FolderSummaryStats struct {
+	FolderID          uuid.UUID `json:"folder_id"`
+	IncludeSubfolders bool      `json:"include_subfolders"`
+	TotalFiles        int       `json:"total_files"`
+	SummarizedFiles   int       `json:"summarized_files"`
+	PagesSummarized   int64     `json:"pages_summarized"`
+	AvgProcessingMs   *float64  `json:"avg_processing_ms"`
+}
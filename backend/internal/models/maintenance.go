@@ -0,0 +1,22 @@
+package models
+
+import "github.com/google/uuid"
+
+type StorageReconcileRequest struct {
+	DeleteOrphans bool `json:"delete_orphans"`
+}
+
+// MissingObject is a file row whose storage object no longer exists
+type MissingObject struct {
+	FileID      uuid.UUID `json:"file_id"`
+	StoragePath string    `json:"storage_path"`
+}
+
+// StorageReconcileReport compares the files bucket with files.storage_path
+type StorageReconcileReport struct {
+	ObjectsScanned int             `json:"objects_scanned"`
+	RowsScanned    int             `json:"rows_scanned"`
+	OrphanObjects  []string        `json:"orphan_objects"`
+	MissingObjects []MissingObject `json:"missing_objects"`
+	DeletedObjects int             `json:"deleted_objects"`
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,9 +16,20 @@ const (
 	StyleDetailed     SummaryStyle = "detailed"
 	StyleExecutive    SummaryStyle = "executive"
 	StyleAcademic     SummaryStyle = "academic"
+
+	// StyleCustom selects a user's custom style, named by custom_style_id. It
+	// is expanded into a built-in style before a summary is requested, so it
+	// is never stored.
+	StyleCustom SummaryStyle = "custom"
 )
 
+// IsValid reports whether s may be requested, including StyleCustom
 func (s SummaryStyle) IsValid() bool {
+	return s.IsBuiltIn() || s == StyleCustom
+}
+
+// IsBuiltIn reports whether s is one of the styles the AI service knows
+func (s SummaryStyle) IsBuiltIn() bool {
 	switch s {
 	case StyleBulletPoints, StyleParagraph, StyleDetailed, StyleExecutive, StyleAcademic:
 		return true
@@ -24,6 +37,23 @@ func (s SummaryStyle) IsValid() bool {
 	return false
 }
 
+// SummaryFormat selects how summary content is rendered in responses
+type SummaryFormat string
+
+const (
+	FormatMarkdown SummaryFormat = "markdown"
+	FormatHTML     SummaryFormat = "html"
+	FormatPlain    SummaryFormat = "plain"
+)
+
+func (f SummaryFormat) IsValid() bool {
+	switch f {
+	case FormatMarkdown, FormatHTML, FormatPlain:
+		return true
+	}
+	return false
+}
+
 type Summary struct {
 	ID                    uuid.UUID    `json:"id"`
 	FileID                uuid.UUID    `json:"file_id"`
@@ -44,22 +74,50 @@ type Summary struct {
 }
 
 type SummaryResponse struct {
-	ID                    uuid.UUID    `json:"id"`
-	FileID                uuid.UUID    `json:"file_id"`
-	Title                 *string      `json:"title,omitempty"`
-	Content               string       `json:"content"`
-	Style                 SummaryStyle `json:"style"`
-	CustomInstructions    *string      `json:"custom_instructions,omitempty"`
-	ModelUsed             *string      `json:"model_used,omitempty"`
-	PromptTokens          *int         `json:"prompt_tokens,omitempty"`
-	CompletionTokens      *int         `json:"completion_tokens,omitempty"`
-	ProcessingStartedAt   *time.Time   `json:"processing_started_at,omitempty"`
-	ProcessingCompletedAt *time.Time   `json:"processing_completed_at,omitempty"`
-	ProcessingDurationMs  *int         `json:"processing_duration_ms,omitempty"`
-	Language              string       `json:"language"`
-	Version               int          `json:"version"`
-	IsCurrent             bool         `json:"is_current"`
-	CreatedAt             time.Time    `json:"created_at"`
+	ID                    uuid.UUID     `json:"id"`
+	FileID                uuid.UUID     `json:"file_id"`
+	Title                 *string       `json:"title,omitempty"`
+	Content               string        `json:"content"`
+	Format                SummaryFormat `json:"format"`
+	Style                 SummaryStyle  `json:"style"`
+	CustomInstructions    *string       `json:"custom_instructions,omitempty"`
+	ModelUsed             *string       `json:"model_used,omitempty"`
+	PromptTokens          *int          `json:"prompt_tokens,omitempty"`
+	CompletionTokens      *int          `json:"completion_tokens,omitempty"`
+	ProcessingStartedAt   *time.Time    `json:"processing_started_at,omitempty"`
+	ProcessingCompletedAt *time.Time    `json:"processing_completed_at,omitempty"`
+	ProcessingDurationMs  *int          `json:"processing_duration_ms,omitempty"`
+	Language              string        `json:"language"`
+	Version               int           `json:"version"`
+	LatestVersion         int           `json:"latest_version"`
+	IsLatest              bool          `json:"is_latest"`
+	IsCurrent             bool          `json:"is_current"`
+	Rating                SummaryRating `json:"rating"`
+	CreatedAt             time.Time     `json:"created_at"`
+}
+
+// SummaryRating aggregates the feedback given on one summary version
+type SummaryRating struct {
+	Average float64 `json:"average"`
+	Count   int     `json:"count"`
+}
+
+// SummaryFeedbackRequest rates a summary version, the current one when
+// Version is omitted
+type SummaryFeedbackRequest struct {
+	Rating  int     `json:"rating" validate:"required,min=1,max=5"`
+	Comment *string `json:"comment" validate:"omitempty,max=2000"`
+	Version *int    `json:"version"`
+}
+
+// SummaryFeedbackResponse is the caller's feedback with the updated aggregate
+type SummaryFeedbackResponse struct {
+	SummaryID uuid.UUID     `json:"summary_id"`
+	Version   int           `json:"version"`
+	Rating    int           `json:"rating"`
+	Comment   *string       `json:"comment,omitempty"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	Summary   SummaryRating `json:"summary_rating"`
 }
 
 type SummaryHistoryItem struct {
@@ -76,9 +134,30 @@ type SummaryHistoryItem struct {
 }
 
 type GenerateSummaryRequest struct {
-	Style              SummaryStyle `json:"style" validate:"required"`
+	Style              SummaryStyle `json:"style"` // Workspace default or bullet_points when empty
 	CustomInstructions *string      `json:"custom_instructions" validate:"omitempty,max=500"`
-	Language           string       `json:"language" validate:"omitempty,oneof=en id"`
+	Language           string       `json:"language"`        // One of GET /summary-languages or "auto"; workspace default or English when empty
+	Model              string       `json:"model"`           // One of GET /summary-models; empty for the AI service default
+	CustomStyleID      *uuid.UUID   `json:"custom_style_id"` // Required when style is "custom"
+}
+
+// SummaryLanguage is a language summaries can be written in
+type SummaryLanguage struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// SummaryModelsResponse lists the models a summary request may pick
+type SummaryModelsResponse struct {
+	Models []string `json:"models"`
+}
+
+// EphemeralSummaryResponse carries a one-off summary that was not saved.
+// Summary is the AI service's response, passed through unchanged.
+type EphemeralSummaryResponse struct {
+	FileID    uuid.UUID       `json:"file_id"`
+	Ephemeral bool            `json:"ephemeral"`
+	Summary   json.RawMessage `json:"summary"`
 }
 
 type SummaryStatusResponse struct {
@@ -94,7 +173,10 @@ type GenerateSummaryResponse struct {
 	JobID              uuid.UUID    `json:"job_id"`
 	Style              SummaryStyle `json:"style"`
 	CustomInstructions *string      `json:"custom_instructions,omitempty"`
-	Message            string       `json:"message"`
+	// Resumed is set by POST /summaries/:file_id/resume when the AI service
+	// was asked to continue a partial summary rather than start over
+	Resumed bool   `json:"resumed,omitempty"`
+	Message string `json:"message"`
 }
 
 type SummaryStyleInfo struct {
@@ -102,6 +184,28 @@ type SummaryStyleInfo struct {
 	Name          string       `json:"name"`
 	Description   string       `json:"description"`
 	ExampleOutput string       `json:"example_output"`
+	// Set for a user's custom style, whose ID is "custom"
+	CustomStyleID *uuid.UUID `json:"custom_style_id,omitempty"`
+}
+
+// CustomSummaryStyle is a reusable style a user saved: a built-in style plus
+// instructions sent as custom_instructions
+type CustomSummaryStyle struct {
+	ID           uuid.UUID    `json:"id"`
+	UserID       uuid.UUID    `json:"-"`
+	Name         string       `json:"name"`
+	BaseStyle    SummaryStyle `json:"base_style"`
+	Instructions string       `json:"instructions"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+// CustomSummaryStyleRequest creates a custom style, or updates the fields
+// that are set. BaseStyle defaults to bullet_points on create.
+type CustomSummaryStyleRequest struct {
+	Name         *string       `json:"name"`
+	BaseStyle    *SummaryStyle `json:"base_style"`
+	Instructions *string       `json:"instructions"`
 }
 
 func GetSummaryStyles() []SummaryStyleInfo {
@@ -153,6 +257,8 @@ type SummaryCallbackRequest struct {
 	Language             string       `json:"language"`
 	Status               string       `json:"status"`
 	ErrorMessage         string       `json:"error_message,omitempty"`
+	// SourceText is the text the AI service extracted, sent only on request
+	SourceText string `json:"source_text,omitempty"`
 }
 
 // AIServiceRequest is the request to send to AI service
@@ -163,4 +269,203 @@ type AIServiceRequest struct {
 	CustomInstructions *string `json:"custom_instructions,omitempty"`
 	Language           string  `json:"language"`
 	CallbackURL        string  `json:"callback_url,omitempty"`
+	TitleHint          string  `json:"title_hint,omitempty"`
+	LanguageHint       string  `json:"language_hint,omitempty"`
+	Model              string  `json:"model,omitempty"`
+	// ContinueFrom is partial output of an interrupted attempt for the AI
+	// service to continue instead of starting over
+	ContinueFrom string `json:"continue_from,omitempty"`
+	// IncludeSourceText asks for the extracted text in the callback
+	IncludeSourceText bool `json:"include_source_text,omitempty"`
+}
+
+// SummarySourceTextResponse is the text that was summarized for one version
+type SummarySourceTextResponse struct {
+	FileID     uuid.UUID `json:"file_id"`
+	Version    int       `json:"version"`
+	SourceText string    `json:"source_text"`
+}
+
+// Diff segment operations
+const (
+	DiffUnchanged = "unchanged"
+	DiffAdded     = "added"
+	DiffRemoved   = "removed"
+)
+
+// SummaryDiffSegment is a run of consecutive lines with the same operation
+type SummaryDiffSegment struct {
+	Op    string   `json:"op"`
+	Lines []string `json:"lines"`
+}
+
+// SummaryDiffResponse is a line-based diff from one summary version to another
+type SummaryDiffResponse struct {
+	FileID      uuid.UUID            `json:"file_id"`
+	FromVersion int                  `json:"from_version"`
+	ToVersion   int                  `json:"to_version"`
+	Added       int                  `json:"added"`
+	Removed     int                  `json:"removed"`
+	Segments    []SummaryDiffSegment `json:"segments"`
+}
+
+// SummaryEstimateResponse predicts the cost of summarizing a file. Basis tells
+// which history the numbers came from: similar_pages, style, all or default.
+type SummaryEstimateResponse struct {
+	FileID                    uuid.UUID    `json:"file_id"`
+	Style                     SummaryStyle `json:"style"`
+	PageCount                 int          `json:"page_count"`
+	EstimatedDurationMs       int64        `json:"estimated_duration_ms"`
+	EstimatedPromptTokens     int64        `json:"estimated_prompt_tokens"`
+	EstimatedCompletionTokens int64        `json:"estimated_completion_tokens"`
+	SampleSize                int          `json:"sample_size"`
+	Basis                     string       `json:"basis"`
+}
+
+// TimeseriesPoint is one bucket of a usage chart; Date is the bucket start
+type TimeseriesPoint struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+// SummaryTimeseriesResponse counts summaries generated per bucket in [From, To]
+type SummaryTimeseriesResponse struct {
+	Bucket string            `json:"bucket"`
+	From   string            `json:"from"`
+	To     string            `json:"to"`
+	Total  int               `json:"total"`
+	Points []TimeseriesPoint `json:"points"`
+}
+
+// GuestSummaryMetric is the anonymous record kept for one guest summary.
+// Nothing in it identifies the guest or the document.
+type GuestSummaryMetric struct {
+	Mode             string // "sync" or "stream"
+	Style            string
+	Language         string
+	FileSize         int64
+	PageCount        *int
+	Success          bool
+	TotalDurationMs  int
+	AIDurationMs     *int
+	PromptTokens     *int
+	CompletionTokens *int
+	ModelUsed        *string
+}
+
+// GuestMetricsResponse aggregates guest summaries created since From.
+// Durations and token averages only cover successful summaries.
+type GuestMetricsResponse struct {
+	From                string  `json:"from"`
+	Total               int     `json:"total"`
+	Succeeded           int     `json:"succeeded"`
+	Failed              int     `json:"failed"`
+	Streamed            int     `json:"streamed"`
+	AvgDurationMs       float64 `json:"avg_duration_ms"`
+	P95DurationMs       float64 `json:"p95_duration_ms"`
+	AvgPageCount        float64 `json:"avg_page_count"`
+	AvgFileSize         float64 `json:"avg_file_size"`
+	AvgPromptTokens     float64 `json:"avg_prompt_tokens"`
+	AvgCompletionTokens float64 `json:"avg_completion_tokens"`
+}
+
+// SummaryDebugResponse exposes what a summary was generated from, for admins
+// diagnosing poor output. DryRun holds the raw AI response of a re-run, if requested.
+type SummaryDebugResponse struct {
+	SummaryID            uuid.UUID       `json:"summary_id"`
+	FileID               uuid.UUID       `json:"file_id"`
+	Version              int             `json:"version"`
+	Style                SummaryStyle    `json:"style"`
+	CustomInstructions   *string         `json:"custom_instructions"`
+	Language             string          `json:"language"`
+	ModelUsed            *string         `json:"model_used"`
+	PromptTokens         *int            `json:"prompt_tokens"`
+	CompletionTokens     *int            `json:"completion_tokens"`
+	ProcessingDurationMs *int            `json:"processing_duration_ms"`
+	CreatedAt            time.Time       `json:"created_at"`
+	DryRun               json.RawMessage `json:"dry_run,omitempty"`
+}
+
+// SummaryStage is a step of asynchronous summary generation reported over the events channel
+type SummaryStage string
+
+const (
+	StageQueued     SummaryStage = "queued"
+	StageExtracting SummaryStage = "extracting"
+	StageGenerating SummaryStage = "generating"
+	StageCompleted  SummaryStage = "completed"
+	StageFailed     SummaryStage = "failed"
+)
+
+// stageProgress is the default percentage reported for a stage when the publisher omits one
+var stageProgress = map[SummaryStage]int{
+	StageQueued:     0,
+	StageExtracting: 10,
+	StageGenerating: 30,
+	StageCompleted:  100,
+	StageFailed:     100,
+}
+
+func (s SummaryStage) IsValid() bool {
+	_, ok := stageProgress[s]
+	return ok
+}
+
+// IsTerminal reports whether no further events follow this stage
+func (s SummaryStage) IsTerminal() bool {
+	return s == StageCompleted || s == StageFailed
+}
+
+// SummaryProgressEvent is published on "summary.<file_id>" at each stage of generation
+type SummaryProgressEvent struct {
+	FileID   string                  `json:"file_id"`
+	Stage    SummaryStage            `json:"stage"`
+	Progress int                     `json:"progress"`
+	Message  string                  `json:"message,omitempty"`
+	Result   *SummaryCallbackRequest `json:"result,omitempty"`
+	Error    string                  `json:"error,omitempty"`
+}
+
+var ErrInvalidProgressEvent = errors.New("invalid summary progress event")
+
+// ParseSummaryProgressEvent decodes and normalizes an event body. Legacy worker
+// payloads ({"status": "processing", "log": ...}) are mapped onto stages.
+func ParseSummaryProgressEvent(body []byte) (*SummaryProgressEvent, error) {
+	var raw struct {
+		SummaryProgressEvent
+		Status string `json:"status"`
+		Log    string `json:"log"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, ErrInvalidProgressEvent
+	}
+
+	event := raw.SummaryProgressEvent
+	if event.Stage == "" {
+		switch raw.Status {
+		case "queued":
+			event.Stage = StageQueued
+		case "processing":
+			event.Stage = StageGenerating
+		case "completed":
+			event.Stage = StageCompleted
+		case "failed":
+			event.Stage = StageFailed
+		}
+	}
+	if !event.Stage.IsValid() {
+		return nil, ErrInvalidProgressEvent
+	}
+
+	if event.Message == "" {
+		event.Message = raw.Log
+	}
+	if event.Progress <= 0 {
+		event.Progress = stageProgress[event.Stage]
+	}
+	if event.Progress > 100 || event.Stage.IsTerminal() {
+		event.Progress = 100
+	}
+
+	return &event, nil
 }
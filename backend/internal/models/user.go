@@ -14,6 +14,7 @@ type User struct {
 	AvatarURL       *string    `json:"avatar_url"`
 	IsActive        bool       `json:"is_active"`
 	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	TOTPEnabled     bool       `json:"totp_enabled"`
 	CreatedAt       time.Time  `json:"created_at"`
 	UpdatedAt       time.Time  `json:"updated_at"`
 }
@@ -25,6 +26,7 @@ type UserResponse struct {
 	AvatarURL       *string    `json:"avatar_url,omitempty"`
 	IsActive        bool       `json:"is_active,omitempty"`
 	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	TOTPEnabled     bool       `json:"totp_enabled"`
 	CreatedAt       time.Time  `json:"created_at"`
 	UpdatedAt       time.Time  `json:"updated_at,omitempty"`
 }
@@ -37,6 +39,7 @@ func (u *User) ToResponse() *UserResponse {
 		AvatarURL:       u.AvatarURL,
 		IsActive:        u.IsActive,
 		EmailVerifiedAt: u.EmailVerifiedAt,
+		TOTPEnabled:     u.TOTPEnabled,
 		CreatedAt:       u.CreatedAt,
 		UpdatedAt:       u.UpdatedAt,
 	}
@@ -46,6 +49,7 @@ type RefreshToken struct {
 	ID         uuid.UUID  `json:"id"`
 	UserID     uuid.UUID  `json:"user_id"`
 	TokenHash  string     `json:"-"`
+	FamilyID   uuid.UUID  `json:"-"`
 	DeviceInfo *string    `json:"device_info"`
 	IPAddress  *string    `json:"ip_address"`
 	ExpiresAt  time.Time  `json:"expires_at"`
@@ -53,6 +57,24 @@ type RefreshToken struct {
 	CreatedAt  time.Time  `json:"created_at"`
 }
 
+type EmailVerificationToken struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type PasswordResetToken struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
 type UserSession struct {
 	ID             uuid.UUID  `json:"id"`
 	UserID         uuid.UUID  `json:"user_id"`
@@ -6,13 +6,22 @@ import (
 	"github.com/google/uuid"
 )
 
+// Workspace member roles
+const (
+	WorkspaceRoleOwner  = "owner"
+	WorkspaceRoleAdmin  = "admin"
+	WorkspaceRoleMember = "member"
+)
+
 type Workspace struct {
-	ID         uuid.UUID `json:"id"`
-	Name       string    `json:"name"`
-	InviteCode string    `json:"invite_code"`
-	OwnerID    uuid.UUID `json:"owner_id"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID              uuid.UUID     `json:"id"`
+	Name            string        `json:"name"`
+	InviteCode      string        `json:"invite_code"`
+	OwnerID         uuid.UUID     `json:"owner_id"`
+	DefaultStyle    *SummaryStyle `json:"default_style"`
+	DefaultLanguage *string       `json:"default_language"`
+	CreatedAt       time.Time     `json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
 }
 
 type WorkspaceMember struct {
@@ -35,27 +44,104 @@ type JoinWorkspaceRequest struct {
 	InviteCode string `json:"invite_code"`
 }
 
+// UpdateWorkspaceRequest changes only the fields that are present. An empty
+// default_style or default_language clears that default.
 type UpdateWorkspaceRequest struct {
-	Name string `json:"name"`
+	Name            *string       `json:"name"`
+	DefaultStyle    *SummaryStyle `json:"default_style"`
+	DefaultLanguage *string       `json:"default_language"`
+}
+
+// WorkspaceInvite is a dedicated invite link. Only the token's hash is kept;
+// the token itself is returned once, when the invite is created.
+type WorkspaceInvite struct {
+	ID          uuid.UUID  `json:"id"`
+	WorkspaceID uuid.UUID  `json:"workspace_id"`
+	CreatedBy   uuid.UUID  `json:"created_by"`
+	TokenHash   string     `json:"-"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	MaxUses     *int       `json:"max_uses"`
+	UseCount    int        `json:"use_count"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type CreateWorkspaceInviteRequest struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxUses   *int       `json:"max_uses"`
+}
+
+type JoinByInviteRequest struct {
+	Token string `json:"token"`
+}
+
+// WorkspaceInviteResponse is returned when an invite is created
+type WorkspaceInviteResponse struct {
+	*WorkspaceInvite
+	Token string `json:"token"`
+}
+
+// UpdateMemberRoleRequest sets a member's role to "admin" or "member"
+type UpdateMemberRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// TransferOwnershipRequest is the body of POST /workspaces/:id/transfer
+type TransferOwnershipRequest struct {
+	UserID uuid.UUID `json:"user_id"`
 }
 
 type WorkspaceResponse struct {
-	ID          uuid.UUID `json:"id"`
-	Name        string    `json:"name"`
-	InviteCode  string    `json:"invite_code,omitempty"` // Only show if admin/owner
-	Role        string    `json:"role"`
-	IsOwner     bool      `json:"is_owner"`
-	MemberCount int       `json:"member_count,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID              uuid.UUID     `json:"id"`
+	Name            string        `json:"name"`
+	InviteCode      string        `json:"invite_code,omitempty"` // Only show if admin/owner
+	Role            string        `json:"role"`
+	IsOwner         bool          `json:"is_owner"`
+	MemberCount     int           `json:"member_count,omitempty"`
+	DefaultStyle    *SummaryStyle `json:"default_style"`
+	DefaultLanguage *string       `json:"default_language"`
+	CreatedAt       time.Time     `json:"created_at"`
 }
 
 func (w *Workspace) ToResponse(role string) *WorkspaceResponse {
 	return &WorkspaceResponse{
-		ID:         w.ID,
-		Name:       w.Name,
-		InviteCode: w.InviteCode,
-		Role:       role,
-		IsOwner:    role == "owner",
-		CreatedAt:  w.CreatedAt,
+		ID:              w.ID,
+		Name:            w.Name,
+		InviteCode:      w.InviteCode,
+		Role:            role,
+		IsOwner:         role == "owner",
+		DefaultStyle:    w.DefaultStyle,
+		DefaultLanguage: w.DefaultLanguage,
+		CreatedAt:       w.CreatedAt,
 	}
 }
+
+// WorkspaceMemberResponse describes one member of a workspace
+type WorkspaceMemberResponse struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	FullName  *string   `json:"full_name"`
+	AvatarURL *string   `json:"avatar_url"`
+	Role      string    `json:"role"`
+	JoinedAt  time.Time `json:"joined_at"`
+}
+
+type WorkspaceMembersResponse struct {
+	Members     []*WorkspaceMemberResponse `json:"members"`
+	MemberCount int                        `json:"member_count"`
+}
+
+// FileWorkspaceShare is a workspace a file appears in. Home marks the file's
+// own workspace (files.workspace_id); the others are shares, with who made
+// them and when.
+type FileWorkspaceShare struct {
+	WorkspaceID   uuid.UUID  `json:"workspace_id"`
+	WorkspaceName string     `json:"workspace_name"`
+	Home          bool       `json:"home"`
+	SharedBy      *uuid.UUID `json:"shared_by,omitempty"`
+	SharedAt      *time.Time `json:"shared_at,omitempty"`
+}
+
+// ShareFileRequest is the body of POST /files/:id/workspaces
+type ShareFileRequest struct {
+	WorkspaceID uuid.UUID `json:"workspace_id"`
+}
@@ -0,0 +1,154 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/nextpdf/backend/internal/config"
+)
+
+var (
+	// ErrInvalidURL is returned for a URL that is not absolute http(s)
+	ErrInvalidURL = errors.New("URL must be an absolute http or https URL")
+	// ErrBlockedAddress is returned when a host resolves to a loopback,
+	// private, link-local or otherwise non-public address
+	ErrBlockedAddress = errors.New("URL resolves to a non-public address")
+	// ErrHostNotAllowed is returned for a host missing from a configured allowlist
+	ErrHostNotAllowed = errors.New("URL host is not on the allowlist")
+)
+
+// dialTimeout bounds connecting to a checked address
+const dialTimeout = 10 * time.Second
+
+// cgnatBlock is the carrier-grade NAT range, which net.IP.IsPrivate does not cover
+var cgnatBlock = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// Guard vets URLs the server fetches on a user's behalf (URL import,
+// webhooks) so they cannot reach the server itself or the internal network.
+// Use CheckURL to reject a URL up front and HTTPClient for the fetch, which
+// repeats the checks on every redirect and on the address actually dialed.
+type Guard struct {
+	allowedHosts []string
+}
+
+// New returns a guard for cfg. With no allowed hosts, any public host passes.
+func New(cfg config.OutboundConfig) *Guard {
+	hosts := make([]string, 0, len(cfg.AllowedHosts))
+	for _, h := range cfg.AllowedHosts {
+		hosts = append(hosts, strings.ToLower(strings.TrimSuffix(h, ".")))
+	}
+	return &Guard{allowedHosts: hosts}
+}
+
+// CheckURL parses raw and returns it when it is an http(s) URL whose host is
+// allowed and resolves only to public addresses
+func (g *Guard) CheckURL(ctx context.Context, raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, ErrInvalidURL
+	}
+	if err := g.checkTarget(u); err != nil {
+		return nil, err
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", u.Hostname(), err)
+	}
+	for _, addr := range addrs {
+		if !IsPublicIP(addr.IP) {
+			return nil, ErrBlockedAddress
+		}
+	}
+	return u, nil
+}
+
+// checkTarget validates the scheme and host of u without resolving it
+func (g *Guard) checkTarget(u *url.URL) error {
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrInvalidURL
+	}
+	if !g.hostAllowed(u.Hostname()) {
+		return ErrHostNotAllowed
+	}
+	return nil
+}
+
+// hostAllowed matches host against the allowlist, where "example.com" allows
+// that host only and "*.example.com" allows its subdomains
+func (g *Guard) hostAllowed(host string) bool {
+	if len(g.allowedHosts) == 0 {
+		return true
+	}
+
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, allowed := range g.allowedHosts {
+		if suffix, ok := strings.CutPrefix(allowed, "*"); ok {
+			if strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+				return true
+			}
+		} else if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// HTTPClient returns a client that only connects to public addresses and
+// follows at most maxRedirects redirects, each checked like the original URL.
+// The address is checked when connecting, after DNS resolution, so a host
+// cannot pass CheckURL and then resolve somewhere internal. Proxies from the
+// environment are ignored for the same reason.
+func (g *Guard) HTTPClient(timeout time.Duration, maxRedirects int) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: dialTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !IsPublicIP(ip) {
+				return ErrBlockedAddress
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   dialTimeout,
+			ResponseHeaderTimeout: timeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return g.checkTarget(req.URL)
+		},
+	}
+}
+
+// IsRefused reports whether err is the guard refusing a URL, as opposed to a
+// failure to resolve or reach it
+func IsRefused(err error) bool {
+	return errors.Is(err, ErrInvalidURL) || errors.Is(err, ErrBlockedAddress) || errors.Is(err, ErrHostNotAllowed)
+}
+
+// IsPublicIP reports whether ip is a globally routable unicast address
+func IsPublicIP(ip net.IP) bool {
+	return ip.IsGlobalUnicast() &&
+		!ip.IsPrivate() &&
+		!ip.IsLoopback() &&
+		!ip.IsLinkLocalUnicast() &&
+		!cgnatBlock.Contains(ip)
+}
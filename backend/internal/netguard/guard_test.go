@@ -0,0 +1,126 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nextpdf/backend/internal/config"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"127.255.0.9", false},
+		{"10.0.0.1", false},
+		{"10.255.255.255", false},
+		{"172.16.5.4", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"100.127.255.254", false},
+		{"100.128.0.1", true},
+		{"0.0.0.0", false},
+		{"255.255.255.255", false},
+		{"224.0.0.1", false},
+		{"::", false},
+		{"::1", false},
+		{"fc00::1", false},
+		{"fd12:3456::1", false},
+		{"fe80::1", false},
+		{"ff02::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.1.2.3", false},
+		{"::ffff:169.254.169.254", false},
+		{"::ffff:100.64.0.1", false},
+		{"::ffff:8.8.8.8", true},
+	}
+
+	for _, tt := range tests {
+		ip := net.ParseIP(tt.ip)
+		if ip == nil {
+			t.Fatalf("bad test address %q", tt.ip)
+		}
+		if got := IsPublicIP(ip); got != tt.want {
+			t.Errorf("IsPublicIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestHostAllowed(t *testing.T) {
+	guard := New(config.OutboundConfig{AllowedHosts: []string{"Example.com.", "*.files.example.org"}})
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"example.com", true},
+		{"EXAMPLE.COM.", true},
+		{"www.example.com", false},
+		{"notexample.com", false},
+		{"a.files.example.org", true},
+		{"a.b.files.example.org", true},
+		{"files.example.org", false},
+		{"evilfiles.example.org", false},
+		{"files.example.org.evil.com", false},
+	}
+	for _, tt := range tests {
+		if got := guard.hostAllowed(tt.host); got != tt.want {
+			t.Errorf("hostAllowed(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+
+	if !New(config.OutboundConfig{}).hostAllowed("anything.test") {
+		t.Error("an empty allowlist refused a host")
+	}
+}
+
+func TestHTTPClientRefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client := New(config.OutboundConfig{}).HTTPClient(5*time.Second, 3)
+	resp, err := client.Get(server.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("client connected to a loopback server")
+	}
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("err = %v, want ErrBlockedAddress", err)
+	}
+}
+
+func TestHTTPClientRefusesDisallowedRedirect(t *testing.T) {
+	guard := New(config.OutboundConfig{AllowedHosts: []string{"files.example.com"}})
+	client := guard.HTTPClient(5*time.Second, 3)
+
+	// Only the redirect policy is under test, so nothing is dialed
+	client.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		resp := &http.Response{StatusCode: http.StatusFound, Header: http.Header{}, Body: http.NoBody, Request: req}
+		resp.Header.Set("Location", "http://internal.example.net/admin")
+		return resp, nil
+	})
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://files.example.com/a.pdf", nil)
+	resp, err := client.Do(req)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("redirect to a host off the allowlist was followed")
+	}
+	if !errors.Is(err, ErrHostNotAllowed) {
+		t.Errorf("err = %v, want ErrHostNotAllowed", err)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
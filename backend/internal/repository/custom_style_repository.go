@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nextpdf/backend/internal/models"
+)
+
+var (
+	ErrCustomStyleNotFound = errors.New("custom summary style not found")
+	ErrCustomStyleExists   = errors.New("custom summary style with this name already exists")
+)
+
+// CustomStyleRepository stores the summary styles users define
+type CustomStyleRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCustomStyleRepository(db *pgxpool.Pool) *CustomStyleRepository {
+	return &CustomStyleRepository{db: db}
+}
+
+func (r *CustomStyleRepository) Create(ctx context.Context, style *models.CustomSummaryStyle) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO user_summary_styles (user_id, name, base_style, instructions)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at
+	`, style.UserID, style.Name, style.BaseStyle, style.Instructions).Scan(&style.ID, &style.CreatedAt, &style.UpdatedAt)
+	if isDuplicateKeyError(err) {
+		return ErrCustomStyleExists
+	}
+	return err
+}
+
+// GetByID returns one of userID's styles; another user's style is not found
+func (r *CustomStyleRepository) GetByID(ctx context.Context, id, userID uuid.UUID) (*models.CustomSummaryStyle, error) {
+	style := &models.CustomSummaryStyle{}
+	err := r.db.QueryRow(ctx, `
+		SELECT id, user_id, name, base_style, instructions, created_at, updated_at
+		FROM user_summary_styles
+		WHERE id = $1 AND user_id = $2
+	`, id, userID).Scan(&style.ID, &style.UserID, &style.Name, &style.BaseStyle, &style.Instructions, &style.CreatedAt, &style.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrCustomStyleNotFound
+	}
+	return style, err
+}
+
+// ListByUser returns userID's styles by name
+func (r *CustomStyleRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.CustomSummaryStyle, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, user_id, name, base_style, instructions, created_at, updated_at
+		FROM user_summary_styles
+		WHERE user_id = $1
+		ORDER BY LOWER(name), created_at
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var styles []*models.CustomSummaryStyle
+	for rows.Next() {
+		style := &models.CustomSummaryStyle{}
+		if err := rows.Scan(&style.ID, &style.UserID, &style.Name, &style.BaseStyle, &style.Instructions, &style.CreatedAt, &style.UpdatedAt); err != nil {
+			return nil, err
+		}
+		styles = append(styles, style)
+	}
+
+	return styles, rows.Err()
+}
+
+// CountByUser returns how many styles userID has saved
+func (r *CustomStyleRepository) CountByUser(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM user_summary_styles WHERE user_id = $1", userID).Scan(&count)
+	return count, err
+}
+
+func (r *CustomStyleRepository) Update(ctx context.Context, style *models.CustomSummaryStyle) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE user_summary_styles
+		SET name = $3, base_style = $4, instructions = $5, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING updated_at
+	`, style.ID, style.UserID, style.Name, style.BaseStyle, style.Instructions).Scan(&style.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrCustomStyleNotFound
+	}
+	if isDuplicateKeyError(err) {
+		return ErrCustomStyleExists
+	}
+	return err
+}
+
+func (r *CustomStyleRepository) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	result, err := r.db.Exec(ctx, "DELETE FROM user_summary_styles WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrCustomStyleNotFound
+	}
+	return nil
+}
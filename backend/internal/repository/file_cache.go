@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nextpdf/backend/internal/models"
+)
+
+// maxFileCacheEntries bounds the cache; when it fills up, expired entries are
+// dropped and, failing that, the whole cache is reset
+const maxFileCacheEntries = 10000
+
+// FileCache keeps recently read file rows for GetByID, which status polling
+// and ownership checks call over and over while a summary is generated.
+// Every repository that writes to files shares the cache and drops the
+// affected entries after the write; bulk writes drop all of them. Each drop
+// bumps a generation, and a row read before the drop is never stored after
+// it. A nil *FileCache caches nothing.
+type FileCache struct {
+	ttl     time.Duration
+	mu      sync.RWMutex
+	entries map[uuid.UUID]fileCacheEntry
+	gen     uint64
+	hits    atomic.Int64
+	misses  atomic.Int64
+}
+
+type fileCacheEntry struct {
+	file      models.File
+	expiresAt time.Time
+}
+
+// NewFileCache returns a cache holding rows for ttl, or nil (no caching) when
+// ttl is not positive
+func NewFileCache(ttl time.Duration) *FileCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &FileCache{ttl: ttl, entries: make(map[uuid.UUID]fileCacheEntry)}
+}
+
+// get returns a copy of the cached row, or nil on a miss
+func (c *FileCache) get(id uuid.UUID) *models.File {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.RLock()
+	entry, ok := c.entries[id]
+	c.mu.RUnlock()
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		c.misses.Add(1)
+		return nil
+	}
+	c.hits.Add(1)
+	file := entry.file
+	return &file
+}
+
+// generation is taken before reading a row and handed to put with it
+func (c *FileCache) generation() uint64 {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.gen
+}
+
+// put stores a row read at generation gen, unless an invalidation happened
+// since: the row may predate that write
+func (c *FileCache) put(file *models.File, gen uint64) {
+	if c == nil {
+		return
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.gen != gen {
+		return
+	}
+
+	if len(c.entries) >= maxFileCacheEntries {
+		for id, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, id)
+			}
+		}
+		if len(c.entries) >= maxFileCacheEntries {
+			c.entries = make(map[uuid.UUID]fileCacheEntry)
+		}
+	}
+	c.entries[file.ID] = fileCacheEntry{file: *file, expiresAt: now.Add(c.ttl)}
+}
+
+func (c *FileCache) invalidate(ids ...uuid.UUID) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.gen++
+	for _, id := range ids {
+		delete(c.entries, id)
+	}
+	c.mu.Unlock()
+}
+
+// invalidateAll empties the cache, for writes that touch an unknown set of files
+func (c *FileCache) invalidateAll() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.gen++
+	c.entries = make(map[uuid.UUID]fileCacheEntry)
+	c.mu.Unlock()
+}
+
+// Stats reports hits and misses since startup; every hit is a database round
+// trip saved
+func (c *FileCache) Stats() models.FileCacheStats {
+	if c == nil {
+		return models.FileCacheStats{}
+	}
+
+	c.mu.RLock()
+	entries := len(c.entries)
+	c.mu.RUnlock()
+
+	stats := models.FileCacheStats{
+		Enabled:    true,
+		TTLSeconds: c.ttl.Seconds(),
+		Entries:    entries,
+		Hits:       c.hits.Load(),
+		Misses:     c.misses.Load(),
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
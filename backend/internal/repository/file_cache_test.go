@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nextpdf/backend/internal/models"
+)
+
+func TestFileCachePutAfterInvalidateIsDropped(t *testing.T) {
+	cache := NewFileCache(time.Minute)
+	file := &models.File{ID: uuid.New(), Filename: "old.pdf"}
+
+	// A reader takes the generation and reads the row, then a writer
+	// commits and invalidates before the reader stores what it read
+	gen := cache.generation()
+	cache.invalidate(file.ID)
+	cache.put(file, gen)
+
+	if got := cache.get(file.ID); got != nil {
+		t.Fatalf("stale row %q was cached after invalidation", got.Filename)
+	}
+
+	cache.put(file, cache.generation())
+	if got := cache.get(file.ID); got == nil || got.Filename != "old.pdf" {
+		t.Fatalf("get = %v, want the row stored at the current generation", got)
+	}
+}
+
+func TestFileCacheInvalidateAll(t *testing.T) {
+	cache := NewFileCache(time.Minute)
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+	for _, id := range ids {
+		cache.put(&models.File{ID: id}, cache.generation())
+	}
+
+	gen := cache.generation()
+	cache.invalidateAll()
+	for _, id := range ids {
+		if cache.get(id) != nil {
+			t.Errorf("entry %s survived invalidateAll", id)
+		}
+	}
+
+	cache.put(&models.File{ID: ids[0]}, gen)
+	if cache.get(ids[0]) != nil {
+		t.Error("row read before invalidateAll was cached")
+	}
+}
+
+func TestFileCacheExpires(t *testing.T) {
+	cache := NewFileCache(time.Millisecond)
+	id := uuid.New()
+	cache.put(&models.File{ID: id}, cache.generation())
+
+	time.Sleep(5 * time.Millisecond)
+	if cache.get(id) != nil {
+		t.Fatal("expired entry was returned")
+	}
+}
+
+func TestNilFileCache(t *testing.T) {
+	var cache *FileCache
+	id := uuid.New()
+
+	cache.put(&models.File{ID: id}, cache.generation())
+	cache.invalidate(id)
+	cache.invalidateAll()
+	if cache.get(id) != nil {
+		t.Fatal("nil cache returned an entry")
+	}
+	if cache.Stats().Enabled {
+		t.Fatal("nil cache reports itself enabled")
+	}
+}
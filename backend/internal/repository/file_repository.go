@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,7 +17,8 @@ import (
 var ErrFileNotFound = errors.New("file not found")
 
 type FileRepository struct {
-	db *pgxpool.Pool
+	db    *pgxpool.Pool
+	cache *FileCache
 }
 
 type ExportRow struct {
@@ -37,31 +39,45 @@ type ExportRow struct {
 	SummaryProcessingDuration *int
 }
 
-func NewFileRepository(db *pgxpool.Pool) *FileRepository {
-	return &FileRepository{db: db}
+// NewFileRepository returns a file repository; GetByID is served from cache
+// when one is given (nil disables caching)
+func NewFileRepository(db *pgxpool.Pool, cache *FileCache) *FileRepository {
+	return &FileRepository{db: db, cache: cache}
+}
+
+// CacheStats reports the GetByID cache
+func (r *FileRepository) CacheStats() models.FileCacheStats {
+	return r.cache.Stats()
 }
 
 func (r *FileRepository) Create(ctx context.Context, file *models.File) error {
 	query := `
 		INSERT INTO files (user_id, workspace_id, folder_id, filename, original_filename, storage_path, 
-		                   mime_type, file_size, page_count, status)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		                   mime_type, file_size, page_count, status, scan_status, checksum_sha256)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id, uploaded_at, created_at, updated_at
 	`
 
 	return r.db.QueryRow(ctx, query,
 		file.UserID, file.WorkspaceID, file.FolderID, file.Filename, file.OriginalFilename,
-		file.StoragePath, file.MimeType, file.FileSize, file.PageCount, file.Status,
+		file.StoragePath, file.MimeType, file.FileSize, file.PageCount, file.Status, file.ScanStatus,
+		file.ChecksumSHA256,
 	).Scan(&file.ID, &file.UploadedAt, &file.CreatedAt, &file.UpdatedAt)
 }
 
 func (r *FileRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.File, error) {
+	if file := r.cache.get(id); file != nil {
+		return file, nil
+	}
+	gen := r.cache.generation()
+
 	query := `
 		SELECT id, user_id, workspace_id, folder_id, filename, original_filename, storage_path,
 		       mime_type, file_size, page_count, status, error_message,
-		       uploaded_at, processed_at, created_at, updated_at
+		       uploaded_at, processed_at, created_at, updated_at, starred, scan_status, checksum_sha256,
+		       pdf_title, pdf_author, pdf_created_at
 		FROM files
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
 	file := &models.File{}
@@ -69,7 +85,8 @@ func (r *FileRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Fil
 		&file.ID, &file.UserID, &file.WorkspaceID, &file.FolderID, &file.Filename, &file.OriginalFilename,
 		&file.StoragePath, &file.MimeType, &file.FileSize, &file.PageCount,
 		&file.Status, &file.ErrorMessage, &file.UploadedAt, &file.ProcessedAt,
-		&file.CreatedAt, &file.UpdatedAt,
+		&file.CreatedAt, &file.UpdatedAt, &file.Starred, &file.ScanStatus, &file.ChecksumSHA256,
+		&file.PDFMetadata.Title, &file.PDFMetadata.Author, &file.PDFMetadata.CreatedAt,
 	)
 
 	if err != nil {
@@ -79,18 +96,60 @@ func (r *FileRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Fil
 		return nil, err
 	}
 
+	r.cache.put(file, gen)
 	return file, nil
 }
 
+// GetByIDsForUser returns the files among ids owned by userID; others are skipped
+func (r *FileRepository) GetByIDsForUser(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) ([]*models.File, error) {
+	query := `
+		SELECT id, user_id, workspace_id, folder_id, filename, original_filename, storage_path,
+		       mime_type, file_size, page_count, status, error_message,
+		       uploaded_at, processed_at, created_at, updated_at, starred
+		FROM files
+		WHERE id = ANY($1) AND user_id = $2 AND deleted_at IS NULL
+	`
+
+	rows, err := r.db.Query(ctx, query, ids, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []*models.File
+	for rows.Next() {
+		file := &models.File{}
+		err := rows.Scan(
+			&file.ID, &file.UserID, &file.WorkspaceID, &file.FolderID, &file.Filename, &file.OriginalFilename,
+			&file.StoragePath, &file.MimeType, &file.FileSize, &file.PageCount,
+			&file.Status, &file.ErrorMessage, &file.UploadedAt, &file.ProcessedAt,
+			&file.CreatedAt, &file.UpdatedAt, &file.Starred,
+		)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return files, rows.Err()
+}
+
 type FileListParams struct {
 	UserID      uuid.UUID
 	WorkspaceID *uuid.UUID
 	FolderID    *uuid.UUID
 	Status      *models.ProcessingStatus
 	Search      *string
+	SearchMode  SearchMode
 	Sort        string
 	Page        int
 	Limit       int
+	// IncludeTrashed lists trashed files next to active ones (List only)
+	IncludeTrashed bool
+	// Starred keeps only the owner's favorites
+	Starred bool
+	// Checksum keeps only files whose content has this SHA-256
+	Checksum *string
 }
 
 type FileWithSummary struct {
@@ -99,10 +158,10 @@ type FileWithSummary struct {
 }
 
 func (r *FileRepository) List(ctx context.Context, params FileListParams) ([]*FileWithSummary, int64, error) {
-	// Base query joins files with summaries to check existence
+	// has_summary is a column kept up to date by SummaryRepository.Create, so
+	// listing never has to touch the summaries table
 	baseQuery := `
 		FROM files f
-		LEFT JOIN summaries s ON s.file_id = f.id AND s.is_current = true
 		WHERE 1=1
 	`
 	args := []interface{}{}
@@ -116,11 +175,17 @@ func (r *FileRepository) List(ctx context.Context, params FileListParams) ([]*Fi
 		argIndex++
 	} else {
 		// Legacy/Private Fallback: Filter by UserID if no workspace context exists.
-		baseQuery += " AND f.user_id = " + placeholder(argIndex)
+		// Uploads into a workspace the user has since left stay with the workspace.
+		baseQuery += " AND f.user_id = " + placeholder(argIndex) + " AND " + memberWorkspaceClause(placeholder(argIndex))
 		args = append(args, params.UserID)
 		argIndex++
 	}
 
+	// Trashed files only show up when explicitly requested
+	if !params.IncludeTrashed {
+		baseQuery += " AND f.deleted_at IS NULL"
+	}
+
 	// 2. Folder Navigation: Filter by specific folder (or root).
 	if params.FolderID != nil {
 		baseQuery += " AND f.folder_id = " + placeholder(argIndex)
@@ -135,10 +200,23 @@ func (r *FileRepository) List(ctx context.Context, params FileListParams) ([]*Fi
 		argIndex++
 	}
 
-	// 4. Search Functionality: Case-insensitive ILIKE search on filename OR original_filename.
+	// Favorites only
+	if params.Starred {
+		baseQuery += " AND f.starred"
+	}
+
+	// Identical content
+	if params.Checksum != nil {
+		baseQuery += " AND f.checksum_sha256 = " + placeholder(argIndex)
+		args = append(args, *params.Checksum)
+		argIndex++
+	}
+
+	// 4. Search Functionality: filename match per SearchMode, all served by indexes.
 	if params.Search != nil && *params.Search != "" {
-		baseQuery += " AND (f.filename ILIKE " + placeholder(argIndex) + " OR f.original_filename ILIKE " + placeholder(argIndex) + ")"
-		args = append(args, "%"+*params.Search+"%")
+		clause, arg := searchClause(params.SearchMode, *params.Search, placeholder(argIndex))
+		baseQuery += " AND " + clause
+		args = append(args, arg)
 		argIndex++
 	}
 
@@ -183,8 +261,7 @@ func (r *FileRepository) List(ctx context.Context, params FileListParams) ([]*Fi
 	selectQuery := `
 		SELECT f.id, f.user_id, f.workspace_id, f.folder_id, f.filename, f.original_filename, f.storage_path,
 		       f.mime_type, f.file_size, f.page_count, f.status, f.error_message,
-		       f.uploaded_at, f.processed_at, f.created_at, f.updated_at,
-		       CASE WHEN s.id IS NOT NULL THEN true ELSE false END as has_summary
+		       f.uploaded_at, f.processed_at, f.created_at, f.updated_at, f.has_summary, f.starred, f.deleted_at
 	` + baseQuery + orderBy + pagination
 
 	rows, err := r.db.Query(ctx, selectQuery, args...)
@@ -200,7 +277,7 @@ func (r *FileRepository) List(ctx context.Context, params FileListParams) ([]*Fi
 			&file.ID, &file.UserID, &file.WorkspaceID, &file.FolderID, &file.Filename, &file.OriginalFilename,
 			&file.StoragePath, &file.MimeType, &file.FileSize, &file.PageCount,
 			&file.Status, &file.ErrorMessage, &file.UploadedAt, &file.ProcessedAt,
-			&file.CreatedAt, &file.UpdatedAt, &file.HasSummary,
+			&file.CreatedAt, &file.UpdatedAt, &file.HasSummary, &file.Starred, &file.DeletedAt,
 		)
 		if err != nil {
 			return nil, 0, err
@@ -217,7 +294,7 @@ func (r *FileRepository) GetByFolderID(ctx context.Context, folderID uuid.UUID)
 		       mime_type, file_size, page_count, status, error_message,
 		       uploaded_at, processed_at, created_at, updated_at
 		FROM files
-		WHERE folder_id = $1
+		WHERE folder_id = $1 AND deleted_at IS NULL
 		ORDER BY filename
 	`
 
@@ -245,11 +322,53 @@ func (r *FileRepository) GetByFolderID(ctx context.Context, folderID uuid.UUID)
 	return files, nil
 }
 
+// GetByFolderIDs loads the files of several folders in one query, ordered by
+// folder and then by filename like GetByFolderID
+func (r *FileRepository) GetByFolderIDs(ctx context.Context, folderIDs []uuid.UUID) ([]*models.File, error) {
+	if len(folderIDs) == 0 {
+		return nil, nil
+	}
+
+	query := `
+		SELECT id, user_id, workspace_id, folder_id, filename, original_filename, storage_path,
+		       mime_type, file_size, page_count, status, error_message,
+		       uploaded_at, processed_at, created_at, updated_at
+		FROM files
+		WHERE folder_id = ANY($1) AND deleted_at IS NULL
+		ORDER BY folder_id, filename
+	`
+
+	rows, err := r.db.Query(ctx, query, folderIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []*models.File
+	for rows.Next() {
+		file := &models.File{}
+		err := rows.Scan(
+			&file.ID, &file.UserID, &file.WorkspaceID, &file.FolderID, &file.Filename, &file.OriginalFilename,
+			&file.StoragePath, &file.MimeType, &file.FileSize, &file.PageCount,
+			&file.Status, &file.ErrorMessage, &file.UploadedAt, &file.ProcessedAt,
+			&file.CreatedAt, &file.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return files, rows.Err()
+}
+
 func (r *FileRepository) Move(ctx context.Context, fileID, userID uuid.UUID, folderID *uuid.UUID) error {
+	defer r.cache.invalidate(fileID)
+
 	query := `
 		UPDATE files
 		SET folder_id = $2, updated_at = NOW()
-		WHERE id = $1 AND user_id = $3
+		WHERE id = $1 AND user_id = $3 AND deleted_at IS NULL
 	`
 
 	result, err := r.db.Exec(ctx, query, fileID, folderID, userID)
@@ -264,6 +383,25 @@ func (r *FileRepository) Move(ctx context.Context, fileID, userID uuid.UUID, fol
 	return nil
 }
 
+// BulkMove moves the user's files among fileIDs to folderID in one statement
+// and returns how many rows changed
+func (r *FileRepository) BulkMove(ctx context.Context, userID uuid.UUID, fileIDs []uuid.UUID, folderID *uuid.UUID) (int64, error) {
+	defer r.cache.invalidate(fileIDs...)
+
+	query := `
+		UPDATE files
+		SET folder_id = $1, updated_at = NOW()
+		WHERE id = ANY($2) AND user_id = $3 AND deleted_at IS NULL
+	`
+
+	result, err := r.db.Exec(ctx, query, folderID, fileIDs, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
+
 func (r *FileRepository) Export(ctx context.Context, params FileListParams, fileIDs []uuid.UUID) ([]ExportRow, error) {
 	query := `
 		SELECT 
@@ -274,7 +412,7 @@ func (r *FileRepository) Export(ctx context.Context, params FileListParams, file
 		LEFT JOIN folders fo ON f.folder_id = fo.id
 		LEFT JOIN workspaces w ON f.workspace_id = w.id
 		LEFT JOIN summaries s ON f.id = s.file_id
-		WHERE 1=1
+		WHERE f.deleted_at IS NULL
 	`
 	args := []interface{}{}
 	argIdx := 1
@@ -298,8 +436,9 @@ func (r *FileRepository) Export(ctx context.Context, params FileListParams, file
 		}
 
 		if params.Search != nil && *params.Search != "" {
-			query += fmt.Sprintf(" AND f.original_filename ILIKE $%d", argIdx)
-			args = append(args, "%"+*params.Search+"%")
+			clause, arg := searchClause(params.SearchMode, *params.Search, placeholder(argIdx))
+			query += " AND " + clause
+			args = append(args, arg)
 			argIdx++
 		}
 
@@ -311,7 +450,7 @@ func (r *FileRepository) Export(ctx context.Context, params FileListParams, file
 
 		// Fallback: filter by user_id if no workspace is specified
 		if params.WorkspaceID == nil {
-			query += fmt.Sprintf(" AND f.user_id = $%d", argIdx)
+			query += fmt.Sprintf(" AND f.user_id = $%d AND ", argIdx) + memberWorkspaceClause(placeholder(argIdx))
 			args = append(args, params.UserID)
 			argIdx++
 		}
@@ -357,10 +496,12 @@ func (r *FileRepository) Export(ctx context.Context, params FileListParams, file
 }
 
 func (r *FileRepository) Rename(ctx context.Context, fileID, userID uuid.UUID, newName string) error {
+	defer r.cache.invalidate(fileID)
+
 	query := `
 		UPDATE files
 		SET original_filename = $2, updated_at = NOW()
-		WHERE id = $1 AND user_id = $3
+		WHERE id = $1 AND user_id = $3 AND deleted_at IS NULL
 	`
 
 	result, err := r.db.Exec(ctx, query, fileID, newName, userID)
@@ -376,6 +517,8 @@ func (r *FileRepository) Rename(ctx context.Context, fileID, userID uuid.UUID, n
 }
 
 func (r *FileRepository) UpdateStatus(ctx context.Context, fileID uuid.UUID, status models.ProcessingStatus, errorMsg *string) error {
+	defer r.cache.invalidate(fileID)
+
 	statusStr := string(status)
 	updateProcessedAt := statusStr == "completed" || statusStr == "failed"
 
@@ -406,8 +549,59 @@ func (r *FileRepository) UpdateStatus(ctx context.Context, fileID uuid.UUID, sta
 	return nil
 }
 
-func (r *FileRepository) Delete(ctx context.Context, fileID, userID uuid.UUID) error {
-	query := `DELETE FROM files WHERE id = $1 AND user_id = $2`
+// SetStarred stars or unstars one of the user's active files
+func (r *FileRepository) SetStarred(ctx context.Context, fileID, userID uuid.UUID, starred bool) error {
+	defer r.cache.invalidate(fileID)
+
+	query := `
+		UPDATE files
+		SET starred = $3, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+	`
+
+	result, err := r.db.Exec(ctx, query, fileID, userID, starred)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrFileNotFound
+	}
+
+	return nil
+}
+
+// Trash moves an active file to the trash by setting deleted_at
+func (r *FileRepository) Trash(ctx context.Context, fileID, userID uuid.UUID) error {
+	defer r.cache.invalidate(fileID)
+
+	query := `
+		UPDATE files
+		SET deleted_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+	`
+
+	result, err := r.db.Exec(ctx, query, fileID, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrFileNotFound
+	}
+
+	return nil
+}
+
+// Restore takes a file out of the trash
+func (r *FileRepository) Restore(ctx context.Context, fileID, userID uuid.UUID) error {
+	defer r.cache.invalidate(fileID)
+
+	query := `
+		UPDATE files
+		SET deleted_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+	`
 
 	result, err := r.db.Exec(ctx, query, fileID, userID)
 	if err != nil {
@@ -421,6 +615,407 @@ func (r *FileRepository) Delete(ctx context.Context, fileID, userID uuid.UUID) e
 	return nil
 }
 
+// ListTrashed returns the user's trashed files, most recently trashed first
+func (r *FileRepository) ListTrashed(ctx context.Context, userID uuid.UUID) ([]*FileWithSummary, error) {
+	query := `
+		SELECT id, user_id, workspace_id, folder_id, filename, original_filename, storage_path,
+		       mime_type, file_size, page_count, status, error_message,
+		       uploaded_at, processed_at, created_at, updated_at, has_summary, starred, deleted_at
+		FROM files
+		WHERE user_id = $1 AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []*FileWithSummary
+	for rows.Next() {
+		file := &FileWithSummary{}
+		if err := rows.Scan(
+			&file.ID, &file.UserID, &file.WorkspaceID, &file.FolderID, &file.Filename, &file.OriginalFilename,
+			&file.StoragePath, &file.MimeType, &file.FileSize, &file.PageCount,
+			&file.Status, &file.ErrorMessage, &file.UploadedAt, &file.ProcessedAt,
+			&file.CreatedAt, &file.UpdatedAt, &file.HasSummary, &file.Starred, &file.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return files, rows.Err()
+}
+
+// ListTrashedBefore returns files trashed before cutoff across all users,
+// oldest first, for the periodic purge
+func (r *FileRepository) ListTrashedBefore(ctx context.Context, cutoff time.Time, limit int) ([]*models.File, error) {
+	query := `
+		SELECT id, user_id, storage_path, deleted_at
+		FROM files
+		WHERE deleted_at < $1
+		ORDER BY deleted_at ASC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, cutoff, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []*models.File
+	for rows.Next() {
+		file := &models.File{}
+		if err := rows.Scan(&file.ID, &file.UserID, &file.StoragePath, &file.DeletedAt); err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return files, rows.Err()
+}
+
+// DeletePurged removes a trashed file's row for good (cascading to summaries)
+// and returns its storage path. The deleted_at check keeps a file restored in
+// the meantime from being purged.
+func (r *FileRepository) DeletePurged(ctx context.Context, fileID, userID uuid.UUID) (string, error) {
+	defer r.cache.invalidate(fileID)
+
+	query := `
+		DELETE FROM files
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+		RETURNING storage_path
+	`
+
+	var storagePath string
+	if err := r.db.QueryRow(ctx, query, fileID, userID).Scan(&storagePath); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrFileNotFound
+		}
+		return "", err
+	}
+
+	return storagePath, nil
+}
+
+// ListMissingPageCount returns PDFs whose page_count was never recorded, oldest
+// first. userID restricts the scan to one user; nil scans every user.
+func (r *FileRepository) ListMissingPageCount(ctx context.Context, userID *uuid.UUID, limit int) ([]*models.File, error) {
+	query := `
+		SELECT id, user_id, workspace_id, folder_id, filename, original_filename, storage_path,
+		       mime_type, file_size, page_count, status, error_message,
+		       uploaded_at, processed_at, created_at, updated_at
+		FROM files
+		WHERE page_count IS NULL
+		  AND mime_type LIKE 'application/pdf%'
+		  AND ($1::uuid IS NULL OR user_id = $1)
+		ORDER BY uploaded_at ASC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []*models.File
+	for rows.Next() {
+		file := &models.File{}
+		if err := rows.Scan(
+			&file.ID, &file.UserID, &file.WorkspaceID, &file.FolderID, &file.Filename, &file.OriginalFilename,
+			&file.StoragePath, &file.MimeType, &file.FileSize, &file.PageCount,
+			&file.Status, &file.ErrorMessage, &file.UploadedAt, &file.ProcessedAt,
+			&file.CreatedAt, &file.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return files, rows.Err()
+}
+
+// SetPartialSummary stores the output of an unfinished summary attempt, or
+// clears it when content is nil
+func (r *FileRepository) SetPartialSummary(ctx context.Context, fileID uuid.UUID, content *string) error {
+	query := `UPDATE files SET partial_summary = $2 WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, fileID, content)
+	return err
+}
+
+// GetPartialSummary returns the stored output of an unfinished summary
+// attempt, or an empty string when there is none
+func (r *FileRepository) GetPartialSummary(ctx context.Context, fileID uuid.UUID) (string, error) {
+	query := `SELECT COALESCE(partial_summary, '') FROM files WHERE id = $1 AND deleted_at IS NULL`
+
+	var content string
+	if err := r.db.QueryRow(ctx, query, fileID).Scan(&content); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrFileNotFound
+		}
+		return "", err
+	}
+	return content, nil
+}
+
+func (r *FileRepository) UpdatePageCount(ctx context.Context, fileID uuid.UUID, pageCount int) error {
+	defer r.cache.invalidate(fileID)
+
+	query := `UPDATE files SET page_count = $2, updated_at = NOW() WHERE id = $1`
+
+	result, err := r.db.Exec(ctx, query, fileID, pageCount)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrFileNotFound
+	}
+
+	return nil
+}
+
+// UpdatePDFMetadata saves the metadata read from a file's PDF
+func (r *FileRepository) UpdatePDFMetadata(ctx context.Context, fileID uuid.UUID, meta models.PDFMetadata) error {
+	defer r.cache.invalidate(fileID)
+
+	_, err := r.db.Exec(ctx, `
+		UPDATE files SET pdf_title = $2, pdf_author = $3, pdf_created_at = $4
+		WHERE id = $1
+	`, fileID, meta.Title, meta.Author, meta.CreatedAt)
+	return err
+}
+
+// GetByChecksum returns the user's active files whose content has the given
+// SHA-256, oldest first
+func (r *FileRepository) GetByChecksum(ctx context.Context, userID uuid.UUID, checksum string) ([]*models.File, error) {
+	query := `
+		SELECT id, user_id, workspace_id, folder_id, filename, original_filename, storage_path,
+		       mime_type, file_size, page_count, status, error_message,
+		       uploaded_at, processed_at, created_at, updated_at, starred
+		FROM files
+		WHERE user_id = $1 AND checksum_sha256 = $2 AND deleted_at IS NULL
+		ORDER BY uploaded_at
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, checksum)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []*models.File
+	for rows.Next() {
+		file := &models.File{}
+		err := rows.Scan(
+			&file.ID, &file.UserID, &file.WorkspaceID, &file.FolderID, &file.Filename, &file.OriginalFilename,
+			&file.StoragePath, &file.MimeType, &file.FileSize, &file.PageCount,
+			&file.Status, &file.ErrorMessage, &file.UploadedAt, &file.ProcessedAt,
+			&file.CreatedAt, &file.UpdatedAt, &file.Starred,
+		)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return files, rows.Err()
+}
+
+// GetChecksum returns the cached SHA-256, or nil if it was never computed
+func (r *FileRepository) GetChecksum(ctx context.Context, fileID uuid.UUID) (*string, error) {
+	var checksum *string
+	err := r.db.QueryRow(ctx, `SELECT checksum_sha256 FROM files WHERE id = $1`, fileID).Scan(&checksum)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrFileNotFound
+		}
+		return nil, err
+	}
+	return checksum, nil
+}
+
+func (r *FileRepository) SetChecksum(ctx context.Context, fileID uuid.UUID, checksum string) error {
+	defer r.cache.invalidate(fileID)
+
+	_, err := r.db.Exec(ctx, `UPDATE files SET checksum_sha256 = $2 WHERE id = $1`, fileID, checksum)
+	return err
+}
+
+// GetTotalSizeByUser returns the bytes stored across all of a user's files
+func (r *FileRepository) GetTotalSizeByUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var total int64
+	err := r.db.QueryRow(ctx, `SELECT COALESCE(SUM(file_size), 0) FROM files WHERE user_id = $1`, userID).Scan(&total)
+	return total, err
+}
+
+// ListStoragePaths maps every file's storage path to its ID, for reconciling
+// the database against the files bucket
+func (r *FileRepository) ListStoragePaths(ctx context.Context) (map[string]uuid.UUID, error) {
+	rows, err := r.db.Query(ctx, `SELECT id, storage_path FROM files`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	paths := make(map[string]uuid.UUID)
+	for rows.Next() {
+		var id uuid.UUID
+		var path string
+		if err := rows.Scan(&id, &path); err != nil {
+			return nil, err
+		}
+		paths[path] = id
+	}
+
+	return paths, rows.Err()
+}
+
+// SaveContent stores the plain text extracted from a file, replacing any
+// earlier extraction
+func (r *FileRepository) SaveContent(ctx context.Context, fileID uuid.UUID, content string) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO file_contents (file_id, content) VALUES ($1, $2)
+		ON CONFLICT (file_id) DO UPDATE SET content = EXCLUDED.content, created_at = NOW()
+	`, fileID, content)
+	return err
+}
+
+// CopyContent gives a copied file the extracted text of its source
+func (r *FileRepository) CopyContent(ctx context.Context, srcFileID, dstFileID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO file_contents (file_id, content)
+		SELECT $2, content FROM file_contents WHERE file_id = $1
+		ON CONFLICT (file_id) DO NOTHING
+	`, srcFileID, dstFileID)
+	return err
+}
+
+// FullTextSearch matches params.Search against the extracted text of active
+// files, best match first. Only UserID, WorkspaceID, FolderID, Search, Page
+// and Limit are read. Each result carries a snippet around the matches,
+// delimited by <mark> and </mark>.
+func (r *FileRepository) FullTextSearch(ctx context.Context, params FileListParams) ([]*models.FileSearchResult, int64, error) {
+	baseQuery := `
+		FROM files f
+		JOIN file_contents fc ON fc.file_id = f.id,
+		     websearch_to_tsquery('simple', $1) q
+		WHERE fc.search_vector @@ q AND f.deleted_at IS NULL
+	`
+	args := []interface{}{*params.Search}
+	argIndex := 2
+
+	// Same scoping as List: the workspace, or the user's private files
+	if params.WorkspaceID != nil {
+		baseQuery += " AND f.workspace_id = " + placeholder(argIndex)
+		args = append(args, *params.WorkspaceID)
+	} else {
+		baseQuery += " AND f.user_id = " + placeholder(argIndex) + " AND " + memberWorkspaceClause(placeholder(argIndex))
+		args = append(args, params.UserID)
+	}
+	argIndex++
+
+	if params.FolderID != nil {
+		baseQuery += " AND f.folder_id = " + placeholder(argIndex)
+		args = append(args, *params.FolderID)
+		argIndex++
+	}
+
+	var totalCount int64
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) "+baseQuery, args...).Scan(&totalCount); err != nil {
+		return nil, 0, err
+	}
+
+	offset := (params.Page - 1) * params.Limit
+	args = append(args, params.Limit, offset)
+
+	// ts_headline reparses the whole text, so it only runs on the page returned
+	query := `
+		SELECT id, folder_id, filename, original_filename, file_size, page_count, status,
+		       has_summary, starred, mime_type, uploaded_at, processed_at, rank,
+		       ts_headline('simple', content, q,
+		                   'StartSel=<mark>, StopSel=</mark>, MaxWords=30, MinWords=10, MaxFragments=2')
+		FROM (
+			SELECT f.id, f.folder_id, f.filename, f.original_filename, f.file_size, f.page_count, f.status,
+			       f.has_summary, f.starred, f.mime_type, f.uploaded_at, f.processed_at,
+			       ts_rank(fc.search_vector, q) AS rank, fc.content, q
+	` + baseQuery + `
+			ORDER BY rank DESC, f.uploaded_at DESC
+			LIMIT ` + placeholder(argIndex) + ` OFFSET ` + placeholder(argIndex+1) + `
+		) matches
+		ORDER BY rank DESC, uploaded_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var results []*models.FileSearchResult
+	for rows.Next() {
+		res := &models.FileSearchResult{}
+		err := rows.Scan(
+			&res.ID, &res.FolderID, &res.Filename, &res.OriginalFilename, &res.FileSize, &res.PageCount, &res.Status,
+			&res.HasSummary, &res.Starred, &res.MimeType, &res.UploadedAt, &res.ProcessedAt, &res.Rank,
+			&res.Snippet,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		results = append(results, res)
+	}
+
+	return results, totalCount, rows.Err()
+}
+
+// SearchMode selects how FileListParams.Search matches filenames
+type SearchMode string
+
+const (
+	// SearchModeContains matches the term anywhere in the name (trigram index)
+	SearchModeContains SearchMode = "contains"
+	// SearchModePrefix matches names starting with the term (trigram index)
+	SearchModePrefix SearchMode = "prefix"
+	// SearchModeFullText matches whole words of the display name (tsvector index)
+	SearchModeFullText SearchMode = "fulltext"
+)
+
+// ParseSearchMode maps a query value to a SearchMode, defaulting to contains
+func ParseSearchMode(s string) SearchMode {
+	switch mode := SearchMode(s); mode {
+	case SearchModePrefix, SearchModeFullText:
+		return mode
+	}
+	return SearchModeContains
+}
+
+// likeEscaper makes user input match literally inside a LIKE pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// searchClause returns the filename filter for a search term bound to param
+// together with the argument to bind
+func searchClause(mode SearchMode, term, param string) (string, interface{}) {
+	switch mode {
+	case SearchModeFullText:
+		return "f.search_vector @@ websearch_to_tsquery('simple', " + param + ")", term
+	case SearchModePrefix:
+		return "(f.filename ILIKE " + param + " OR f.original_filename ILIKE " + param + ")", likeEscaper.Replace(term) + "%"
+	default:
+		return "(f.filename ILIKE " + param + " OR f.original_filename ILIKE " + param + ")", "%" + likeEscaper.Replace(term) + "%"
+	}
+}
+
+// memberWorkspaceClause keeps files that are private or in a workspace the
+// user identified by userParam still belongs to
+func memberWorkspaceClause(userParam string) string {
+	return "(f.workspace_id IS NULL OR EXISTS (SELECT 1 FROM workspace_members wm WHERE wm.workspace_id = f.workspace_id AND wm.user_id = " + userParam + "))"
+}
+
 // placeholder returns a PostgreSQL placeholder like $1, $2, etc.
 func placeholder(i int) string {
 	return "$" + strconv.Itoa(i)
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nextpdf/backend/internal/testdb"
+)
+
+func TestListShowsFileWithManySummariesOnce(t *testing.T) {
+	pool := testdb.New(t)
+	ctx := context.Background()
+	userID := testdb.CreateUser(t, pool)
+	fileID := testdb.CreateFile(t, pool, userID, nil)
+	testdb.CreateFile(t, pool, userID, nil)
+
+	summaries := NewSummaryRepository(pool, nil, 0, false)
+	for _, content := range []string{"v1", "v2", "v3"} {
+		createSummary(t, summaries, fileID, content)
+	}
+
+	files, total, err := NewFileRepository(pool, nil).List(ctx, FileListParams{UserID: userID, Page: 1, Limit: 20})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if total != 2 || len(files) != 2 {
+		t.Fatalf("list = %d rows, total %d, want 2 and 2", len(files), total)
+	}
+	rows := 0
+	for _, f := range files {
+		if f.ID == fileID {
+			rows++
+			if !f.HasSummary {
+				t.Error("summarized file listed without has_summary")
+			}
+		}
+	}
+	if rows != 1 {
+		t.Errorf("summarized file listed %d times, want once", rows)
+	}
+
+	// The view joins only the current version, so it must not repeat the file either
+	var viewRows int
+	err = pool.QueryRow(ctx, "SELECT COUNT(*) FROM files_with_summary_view WHERE id = $1", fileID).Scan(&viewRows)
+	if err != nil {
+		t.Fatalf("query view: %v", err)
+	}
+	if viewRows != 1 {
+		t.Errorf("view has %d rows for the file, want 1", viewRows)
+	}
+}
+
+func TestDeletePurgedOnlyRemovesTrashedRows(t *testing.T) {
+	pool := testdb.New(t)
+	ctx := context.Background()
+	userID := testdb.CreateUser(t, pool)
+	fileID := testdb.CreateFile(t, pool, userID, nil)
+	createSummary(t, NewSummaryRepository(pool, nil, 0, false), fileID, "v1")
+	repo := NewFileRepository(pool, nil)
+
+	// An active file, or a file of someone else, is never purged
+	if _, err := repo.DeletePurged(ctx, fileID, userID); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("purge of an active file = %v, want ErrFileNotFound", err)
+	}
+	if err := repo.Trash(ctx, fileID, userID); err != nil {
+		t.Fatalf("trash: %v", err)
+	}
+	if _, err := repo.DeletePurged(ctx, fileID, testdb.CreateUser(t, pool)); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("purge by another user = %v, want ErrFileNotFound", err)
+	}
+
+	// The row goes first and hands back the path of the object to delete
+	path, err := repo.DeletePurged(ctx, fileID, userID)
+	if err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if want := userID.String() + "/"; !strings.HasPrefix(path, want) {
+		t.Errorf("storage path = %q, want it under %q", path, want)
+	}
+
+	var rows int
+	err = pool.QueryRow(ctx, `
+		SELECT (SELECT COUNT(*) FROM files WHERE id = $1) + (SELECT COUNT(*) FROM summaries WHERE file_id = $1)
+	`, fileID).Scan(&rows)
+	if err != nil {
+		t.Fatalf("count rows: %v", err)
+	}
+	if rows != 0 {
+		t.Errorf("%d file or summary row(s) left after purge", rows)
+	}
+
+	if _, err := repo.DeletePurged(ctx, fileID, userID); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("second purge = %v, want ErrFileNotFound", err)
+	}
+}
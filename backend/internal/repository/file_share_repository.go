@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nextpdf/backend/internal/models"
+)
+
+var (
+	ErrAlreadyShared = errors.New("file is already shared into this workspace")
+	ErrShareNotFound = errors.New("file is not shared into this workspace")
+)
+
+// FileShareRepository stores the workspaces a file is shared into besides its
+// home workspace
+type FileShareRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewFileShareRepository(db *pgxpool.Pool) *FileShareRepository {
+	return &FileShareRepository{db: db}
+}
+
+// Create shares a file into a workspace, or returns ErrAlreadyShared
+func (r *FileShareRepository) Create(ctx context.Context, fileID, workspaceID, sharedBy uuid.UUID) error {
+	result, err := r.db.Exec(ctx, `
+		INSERT INTO file_workspace_shares (file_id, workspace_id, shared_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (file_id, workspace_id) DO NOTHING
+	`, fileID, workspaceID, sharedBy)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrAlreadyShared
+	}
+	return nil
+}
+
+// Delete removes a share, or returns ErrShareNotFound
+func (r *FileShareRepository) Delete(ctx context.Context, fileID, workspaceID uuid.UUID) error {
+	result, err := r.db.Exec(ctx, "DELETE FROM file_workspace_shares WHERE file_id = $1 AND workspace_id = $2", fileID, workspaceID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrShareNotFound
+	}
+	return nil
+}
+
+// ListByFile returns the workspaces a file is shared into, oldest share first.
+// The home workspace is not included.
+func (r *FileShareRepository) ListByFile(ctx context.Context, fileID uuid.UUID) ([]*models.FileWorkspaceShare, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT s.workspace_id, w.name, s.shared_by, s.created_at
+		FROM file_workspace_shares s
+		JOIN workspaces w ON w.id = s.workspace_id
+		WHERE s.file_id = $1
+		ORDER BY s.created_at, w.name
+	`, fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shares []*models.FileWorkspaceShare
+	for rows.Next() {
+		share := &models.FileWorkspaceShare{}
+		if err := rows.Scan(&share.WorkspaceID, &share.WorkspaceName, &share.SharedBy, &share.SharedAt); err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+
+	return shares, rows.Err()
+}
@@ -15,25 +15,36 @@ var (
 	ErrFolderExists      = errors.New("folder with this name already exists")
 	ErrInvalidMove       = errors.New("cannot move folder into itself or its descendants")
 	ErrCircularReference = errors.New("moving this folder would create a circular reference")
+	ErrInvalidReorder    = errors.New("folders to reorder must be distinct siblings owned by the user")
 )
 
 type FolderRepository struct {
-	db *pgxpool.Pool
+	db    *pgxpool.Pool
+	cache *FileCache
 }
 
-func NewFolderRepository(db *pgxpool.Pool) *FolderRepository {
-	return &FolderRepository{db: db}
+// NewFolderRepository returns a folder repository; cache is the file cache
+// to invalidate when folder operations delete or move files
+func NewFolderRepository(db *pgxpool.Pool, cache *FileCache) *FolderRepository {
+	return &FolderRepository{db: db, cache: cache}
 }
 
+// Create inserts a folder with its materialized path: the parent's path
+// followed by "/<id>", or just "/<id>" at the root
 func (r *FolderRepository) Create(ctx context.Context, folder *models.Folder) error {
 	query := `
-		INSERT INTO folders (user_id, parent_id, name, path, sort_order)
-		VALUES ($1, $2, $3, '', $4)
+		INSERT INTO folders (id, user_id, parent_id, name, path, depth, sort_order)
+		VALUES (
+			$1::uuid, $2, $3, $4,
+			COALESCE((SELECT path FROM folders WHERE id = $3), '') || '/' || $1::uuid::text,
+			COALESCE((SELECT depth + 1 FROM folders WHERE id = $3), 0),
+			$5
+		)
 		RETURNING id, path, depth, created_at, updated_at
 	`
 
 	err := r.db.QueryRow(ctx, query,
-		folder.UserID, folder.ParentID, folder.Name, folder.SortOrder,
+		uuid.New(), folder.UserID, folder.ParentID, folder.Name, folder.SortOrder,
 	).Scan(&folder.ID, &folder.Path, &folder.Depth, &folder.CreatedAt, &folder.UpdatedAt)
 
 	if err != nil {
@@ -77,7 +88,7 @@ func (r *FolderRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([
 		       COUNT(DISTINCT files.id) AS file_count,
 		       COALESCE(SUM(files.file_size), 0) AS total_size
 		FROM folders f
-		LEFT JOIN files ON files.folder_id = f.id
+		LEFT JOIN files ON files.folder_id = f.id AND files.deleted_at IS NULL
 		WHERE f.user_id = $1
 		GROUP BY f.id
 		ORDER BY f.sort_order, f.name
@@ -139,7 +150,7 @@ func (r *FolderRepository) Move(ctx context.Context, folderID, userID uuid.UUID,
 
 	// Check for circular reference
 	if parentID != nil {
-		isDescendant, err := r.isDescendant(ctx, *parentID, folderID)
+		isDescendant, err := r.isDescendant(ctx, r.db, *parentID, folderID)
 		if err != nil {
 			return nil, err
 		}
@@ -148,15 +159,24 @@ func (r *FolderRepository) Move(ctx context.Context, folderID, userID uuid.UUID,
 		}
 	}
 
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
 	query := `
 		UPDATE folders
-		SET parent_id = $2, sort_order = COALESCE($3, sort_order), updated_at = NOW()
+		SET parent_id = $2, sort_order = COALESCE($3, sort_order),
+		    path = COALESCE((SELECT p.path FROM folders p WHERE p.id = $2), '') || '/' || id::text,
+		    depth = COALESCE((SELECT p.depth + 1 FROM folders p WHERE p.id = $2), 0),
+		    updated_at = NOW()
 		WHERE id = $1 AND user_id = $4
 		RETURNING id, user_id, parent_id, name, path, depth, sort_order, created_at, updated_at
 	`
 
 	folder := &models.Folder{}
-	err := r.db.QueryRow(ctx, query, folderID, parentID, sortOrder, userID).Scan(
+	err = tx.QueryRow(ctx, query, folderID, parentID, sortOrder, userID).Scan(
 		&folder.ID, &folder.UserID, &folder.ParentID, &folder.Name,
 		&folder.Path, &folder.Depth, &folder.SortOrder,
 		&folder.CreatedAt, &folder.UpdatedAt,
@@ -172,10 +192,371 @@ func (r *FolderRepository) Move(ctx context.Context, folderID, userID uuid.UUID,
 		return nil, err
 	}
 
+	// Rebuild the path and depth of the whole subtree below the moved folder
+	_, err = tx.Exec(ctx, `
+		WITH RECURSIVE folder_tree AS (
+			SELECT id, path, depth FROM folders WHERE id = $1
+			UNION ALL
+			SELECT f.id, ft.path || '/' || f.id::text, ft.depth + 1 FROM folders f
+			JOIN folder_tree ft ON f.parent_id = ft.id
+		)
+		UPDATE folders f
+		SET path = ft.path, depth = ft.depth
+		FROM folder_tree ft
+		WHERE f.id = ft.id AND f.id <> $1
+	`, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
 	return folder, nil
 }
 
-func (r *FolderRepository) isDescendant(ctx context.Context, potentialDescendant, ancestor uuid.UUID) (bool, error) {
+// Reorder assigns sort_order 0..n-1 following orderedIDs. Every ID must be a
+// folder of userID directly under parentID (nil for root), else nothing changes.
+func (r *FolderRepository) Reorder(ctx context.Context, userID uuid.UUID, parentID *uuid.UUID, orderedIDs []uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the siblings so a concurrent move cannot slip in between check and update
+	rows, err := tx.Query(ctx, `
+		SELECT id FROM folders
+		WHERE id = ANY($1) AND user_id = $2 AND parent_id IS NOT DISTINCT FROM $3
+		FOR UPDATE
+	`, orderedIDs, userID, parentID)
+	if err != nil {
+		return err
+	}
+	matched := 0
+	for rows.Next() {
+		matched++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if matched != len(orderedIDs) {
+		return ErrInvalidReorder
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE folders f
+		SET sort_order = o.position - 1, updated_at = NOW()
+		FROM unnest($1::uuid[]) WITH ORDINALITY AS o(id, position)
+		WHERE f.id = o.id
+	`, orderedIDs)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// BatchReorder places every folder of items under its parent at its
+// sort_order in one transaction, with the same checks as Move. It returns the
+// updated folders in the order of items.
+func (r *FolderRepository) BatchReorder(ctx context.Context, userID uuid.UUID, items []models.FolderReorderItem) ([]*models.Folder, error) {
+	ids := make([]uuid.UUID, len(items))
+	var parentIDs []uuid.UUID
+	for i, item := range items {
+		ids[i] = item.ID
+		if item.ParentID != nil {
+			parentIDs = append(parentIDs, *item.ParentID)
+		}
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the folders being moved; every one must belong to the user
+	var locked int
+	err = tx.QueryRow(ctx, `
+		WITH locked AS (
+			SELECT id FROM folders WHERE id = ANY($1) AND user_id = $2 FOR UPDATE
+		)
+		SELECT COUNT(*) FROM locked
+	`, ids, userID).Scan(&locked)
+	if err != nil {
+		return nil, err
+	}
+	if locked != len(ids) {
+		return nil, ErrFolderNotFound
+	}
+
+	if len(parentIDs) > 0 {
+		var missing bool
+		err = tx.QueryRow(ctx, `
+			SELECT EXISTS(
+				SELECT 1 FROM unnest($1::uuid[]) AS p(id)
+				WHERE NOT EXISTS (SELECT 1 FROM folders f WHERE f.id = p.id AND f.user_id = $2)
+			)
+		`, parentIDs, userID).Scan(&missing)
+		if err != nil {
+			return nil, err
+		}
+		if missing {
+			return nil, ErrFolderNotFound
+		}
+	}
+
+	for _, item := range items {
+		if item.ParentID != nil {
+			if *item.ParentID == item.ID {
+				return nil, ErrInvalidMove
+			}
+			// Checked against the tree as updated by the earlier items
+			isDescendant, err := r.isDescendant(ctx, tx, *item.ParentID, item.ID)
+			if err != nil {
+				return nil, err
+			}
+			if isDescendant {
+				return nil, ErrCircularReference
+			}
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE folders
+			SET parent_id = $2, sort_order = $3,
+			    path = COALESCE((SELECT p.path FROM folders p WHERE p.id = $2), '') || '/' || id::text,
+			    depth = COALESCE((SELECT p.depth + 1 FROM folders p WHERE p.id = $2), 0),
+			    updated_at = NOW()
+			WHERE id = $1
+		`, item.ID, item.ParentID, item.SortOrder)
+		if err != nil {
+			if isDuplicateKeyError(err) {
+				return nil, ErrFolderExists
+			}
+			return nil, err
+		}
+
+		_, err = tx.Exec(ctx, `
+			WITH RECURSIVE folder_tree AS (
+				SELECT id, path, depth FROM folders WHERE id = $1
+				UNION ALL
+				SELECT f.id, ft.path || '/' || f.id::text, ft.depth + 1 FROM folders f
+				JOIN folder_tree ft ON f.parent_id = ft.id
+			)
+			UPDATE folders f
+			SET path = ft.path, depth = ft.depth
+			FROM folder_tree ft
+			WHERE f.id = ft.id AND f.id <> $1
+		`, item.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT f.id, f.user_id, f.parent_id, f.name, f.path, f.depth, f.sort_order, f.created_at, f.updated_at
+		FROM unnest($1::uuid[]) WITH ORDINALITY AS o(id, position)
+		JOIN folders f ON f.id = o.id
+		ORDER BY o.position
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	folders := make([]*models.Folder, 0, len(ids))
+	for rows.Next() {
+		folder := &models.Folder{}
+		if err := rows.Scan(
+			&folder.ID, &folder.UserID, &folder.ParentID, &folder.Name,
+			&folder.Path, &folder.Depth, &folder.SortOrder,
+			&folder.CreatedAt, &folder.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		folders = append(folders, folder)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return folders, nil
+}
+
+// GetChildren returns the direct subfolders of the given folder.
+func (r *FolderRepository) GetChildren(ctx context.Context, parentID uuid.UUID) ([]*models.Folder, error) {
+	query := `
+		SELECT id, user_id, parent_id, name, path, depth, sort_order, created_at, updated_at
+		FROM folders
+		WHERE parent_id = $1
+		ORDER BY sort_order, name
+	`
+
+	rows, err := r.db.Query(ctx, query, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var folders []*models.Folder
+	for rows.Next() {
+		folder := &models.Folder{}
+		err := rows.Scan(
+			&folder.ID, &folder.UserID, &folder.ParentID, &folder.Name,
+			&folder.Path, &folder.Depth, &folder.SortOrder,
+			&folder.CreatedAt, &folder.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		folders = append(folders, folder)
+	}
+
+	return folders, nil
+}
+
+// CountByUser returns how many folders the user owns
+func (r *FolderRepository) CountByUser(ctx context.Context, userID uuid.UUID) (int, error) {
+	query := `SELECT COUNT(*) FROM folders WHERE user_id = $1`
+
+	var count int
+	err := r.db.QueryRow(ctx, query, userID).Scan(&count)
+	return count, err
+}
+
+// NameExists reports whether the user already has a folder with the given name under parentID.
+// A nil parentID checks root-level folders, which the unique constraint does not cover.
+func (r *FolderRepository) NameExists(ctx context.Context, userID uuid.UUID, parentID *uuid.UUID, name string) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM folders
+			WHERE user_id = $1 AND parent_id IS NOT DISTINCT FROM $2 AND name = $3
+		)
+	`
+
+	var exists bool
+	err := r.db.QueryRow(ctx, query, userID, parentID, name).Scan(&exists)
+	return exists, err
+}
+
+// GetAncestors returns the chain of folders from the root down to and
+// including folderID, following parent_id rather than the stored path
+func (r *FolderRepository) GetAncestors(ctx context.Context, folderID uuid.UUID) ([]*models.Folder, error) {
+	query := `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id, 0 AS hops FROM folders WHERE id = $1
+			UNION ALL
+			SELECT f.id, f.parent_id, a.hops + 1 FROM folders f
+			JOIN ancestors a ON f.id = a.parent_id
+		)
+		SELECT f.id, f.user_id, f.parent_id, f.name, f.path, f.depth, f.sort_order, f.created_at, f.updated_at
+		FROM ancestors a
+		JOIN folders f ON f.id = a.id
+		ORDER BY a.hops DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, folderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var folders []*models.Folder
+	for rows.Next() {
+		folder := &models.Folder{}
+		err := rows.Scan(
+			&folder.ID, &folder.UserID, &folder.ParentID, &folder.Name,
+			&folder.Path, &folder.Depth, &folder.SortOrder,
+			&folder.CreatedAt, &folder.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		folders = append(folders, folder)
+	}
+
+	if len(folders) == 0 {
+		return nil, ErrFolderNotFound
+	}
+
+	return folders, rows.Err()
+}
+
+// GetSummaryStats aggregates the current summaries of the files in a folder,
+// and in all of its subfolders when includeSubfolders is set. Trashed files
+// are not counted.
+func (r *FolderRepository) GetSummaryStats(ctx context.Context, folderID uuid.UUID, includeSubfolders bool) (*models.FolderSummaryStats, error) {
+	query := `
+		WITH RECURSIVE folder_tree AS (
+			SELECT id FROM folders WHERE id = $1
+			UNION ALL
+			SELECT f.id FROM folders f
+			JOIN folder_tree ft ON f.parent_id = ft.id
+			WHERE $2
+		)
+		SELECT
+			COUNT(fi.id),
+			COUNT(s.id),
+			COALESCE(SUM(fi.page_count) FILTER (WHERE s.id IS NOT NULL), 0),
+			AVG(s.processing_duration_ms)::float8
+		FROM folder_tree ft
+		JOIN files fi ON fi.folder_id = ft.id AND fi.deleted_at IS NULL
+		LEFT JOIN summaries s ON s.file_id = fi.id AND s.is_current = TRUE
+	`
+
+	stats := &models.FolderSummaryStats{FolderID: folderID, IncludeSubfolders: includeSubfolders}
+	err := r.db.QueryRow(ctx, query, folderID, includeSubfolders).Scan(
+		&stats.TotalFiles, &stats.SummarizedFiles, &stats.PagesSummarized, &stats.AvgProcessingMs,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// MatchesWorkspace reports whether a folder may hold files of the given
+// workspace (nil for personal files). A workspace folder only matches its own
+// workspace; a personal folder matches personal files and, as in
+// GetByWorkspaceID, the workspaces its owner is a member of.
+func (r *FolderRepository) MatchesWorkspace(ctx context.Context, folderID uuid.UUID, workspaceID *uuid.UUID) (bool, error) {
+	query := `
+		SELECT CASE
+			WHEN f.workspace_id IS NOT NULL THEN f.workspace_id = $2
+			WHEN $2::uuid IS NULL THEN TRUE
+			ELSE EXISTS (
+				SELECT 1 FROM workspace_members wm
+				WHERE wm.workspace_id = $2 AND wm.user_id = f.user_id
+			)
+		END
+		FROM folders f
+		WHERE f.id = $1
+	`
+
+	var matches *bool
+	if err := r.db.QueryRow(ctx, query, folderID, workspaceID).Scan(&matches); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, ErrFolderNotFound
+		}
+		return false, err
+	}
+
+	return matches != nil && *matches, nil
+}
+
+// rowQuerier is satisfied by both the pool and a transaction
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func (r *FolderRepository) isDescendant(ctx context.Context, q rowQuerier, potentialDescendant, ancestor uuid.UUID) (bool, error) {
 	query := `
 		WITH RECURSIVE folder_tree AS (
 			SELECT id, parent_id FROM folders WHERE id = $1
@@ -187,23 +568,153 @@ func (r *FolderRepository) isDescendant(ctx context.Context, potentialDescendant
 	`
 
 	var isDescendant bool
-	err := r.db.QueryRow(ctx, query, potentialDescendant, ancestor).Scan(&isDescendant)
+	err := q.QueryRow(ctx, query, potentialDescendant, ancestor).Scan(&isDescendant)
 	return isDescendant, err
 }
 
-func (r *FolderRepository) Delete(ctx context.Context, folderID, userID uuid.UUID) error {
-	query := `DELETE FROM folders WHERE id = $1 AND user_id = $2`
+// Delete removes a folder, its subfolders and the files in them in one
+// transaction, and returns the storage paths of the deleted files. files.folder_id
+// is ON DELETE SET NULL, so the files have to be deleted explicitly; trashed
+// files are kept and come back to the root when restored.
+func (r *FolderRepository) Delete(ctx context.Context, folderID, userID uuid.UUID) ([]string, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
 
-	result, err := r.db.Exec(ctx, query, folderID, userID)
+	query := `
+		WITH RECURSIVE folder_tree AS (
+			SELECT id FROM folders WHERE id = $1 AND user_id = $2
+			UNION ALL
+			SELECT f.id FROM folders f
+			JOIN folder_tree ft ON f.parent_id = ft.id
+		)
+		DELETE FROM files
+		WHERE folder_id IN (SELECT id FROM folder_tree) AND deleted_at IS NULL
+		RETURNING id, storage_path
+	`
+	rows, err := tx.Query(ctx, query, folderID, userID)
+	if err != nil {
+		return nil, err
+	}
+	var ids []uuid.UUID
+	var paths []string
+	for rows.Next() {
+		var id uuid.UUID
+		var path string
+		if err := rows.Scan(&id, &path); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+		paths = append(paths, path)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec(ctx, `DELETE FROM folders WHERE id = $1 AND user_id = $2`, folderID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if result.RowsAffected() == 0 {
+		return nil, ErrFolderNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	r.cache.invalidate(ids...)
+	return paths, nil
+}
+
+// DeleteAndReassign deletes a folder after moving its files into targetID, all
+// in one transaction. With keepSubfolders the direct subfolders are moved
+// under the target intact; otherwise the files of the whole subtree land in
+// the target and every folder of the subtree is deleted. Storage is untouched.
+func (r *FolderRepository) DeleteAndReassign(ctx context.Context, folderID, userID, targetID uuid.UUID, keepSubfolders bool) error {
+	if targetID == folderID {
+		return ErrInvalidMove
+	}
+	isDescendant, err := r.isDescendant(ctx, r.db, targetID, folderID)
+	if err != nil {
+		return err
+	}
+	if isDescendant {
+		return ErrCircularReference
+	}
+
+	// Files of the whole subtree may move
+	defer r.cache.invalidateAll()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if keepSubfolders {
+		_, err = tx.Exec(ctx, `
+			UPDATE folders
+			SET parent_id = $2, updated_at = NOW()
+			WHERE parent_id = $1 AND user_id = $3
+		`, folderID, targetID, userID)
+		if err != nil {
+			if isDuplicateKeyError(err) {
+				return ErrFolderExists
+			}
+			return err
+		}
+
+		// Rebuild the path and depth of everything now below the target
+		_, err = tx.Exec(ctx, `
+			WITH RECURSIVE folder_tree AS (
+				SELECT id, path, depth FROM folders WHERE id = $1
+				UNION ALL
+				SELECT f.id, ft.path || '/' || f.id::text, ft.depth + 1 FROM folders f
+				JOIN folder_tree ft ON f.parent_id = ft.id
+			)
+			UPDATE folders f
+			SET path = ft.path, depth = ft.depth
+			FROM folder_tree ft
+			WHERE f.id = ft.id AND f.id <> $1
+		`, targetID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE files SET folder_id = $2, updated_at = NOW()
+			WHERE folder_id = $1
+		`, folderID, targetID)
+	} else {
+		_, err = tx.Exec(ctx, `
+			WITH RECURSIVE folder_tree AS (
+				SELECT id FROM folders WHERE id = $1
+				UNION ALL
+				SELECT f.id FROM folders f
+				JOIN folder_tree ft ON f.parent_id = ft.id
+			)
+			UPDATE files SET folder_id = $2, updated_at = NOW()
+			WHERE folder_id IN (SELECT id FROM folder_tree)
+		`, folderID, targetID)
+	}
 	if err != nil {
 		return err
 	}
 
+	// Whatever folders remain below are empty now, so the cascade only removes folders
+	result, err := tx.Exec(ctx, `DELETE FROM folders WHERE id = $1 AND user_id = $2`, folderID, userID)
+	if err != nil {
+		return err
+	}
 	if result.RowsAffected() == 0 {
 		return ErrFolderNotFound
 	}
 
-	return nil
+	return tx.Commit(ctx)
 }
 
 func (r *FolderRepository) GetDescendantIDs(ctx context.Context, folderID uuid.UUID) ([]uuid.UUID, error) {
@@ -244,7 +755,7 @@ func (r *FolderRepository) GetByWorkspaceID(ctx context.Context, workspaceID uui
 		       COUNT(DISTINCT files.id) AS file_count,
 		       COALESCE(SUM(files.file_size), 0) AS total_size
 		FROM folders f
-		LEFT JOIN files ON files.folder_id = f.id
+		LEFT JOIN files ON files.folder_id = f.id AND files.deleted_at IS NULL
 		WHERE f.user_id IN (
 			SELECT user_id FROM workspace_members WHERE workspace_id = $1
 		)
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nextpdf/backend/internal/models"
+	"github.com/nextpdf/backend/internal/testdb"
+)
+
+func createFolder(t *testing.T, repo *FolderRepository, userID uuid.UUID, parent *models.Folder, name string) *models.Folder {
+	t.Helper()
+
+	folder := &models.Folder{UserID: userID, Name: name}
+	if parent != nil {
+		folder.ParentID = &parent.ID
+	}
+	if err := repo.Create(context.Background(), folder); err != nil {
+		t.Fatalf("create folder %q: %v", name, err)
+	}
+	return folder
+}
+
+func TestFolderMoveIntoDescendantIsRejected(t *testing.T) {
+	pool := testdb.New(t)
+	userID := testdb.CreateUser(t, pool)
+	repo := NewFolderRepository(pool, nil)
+
+	root := createFolder(t, repo, userID, nil, "root")
+	child := createFolder(t, repo, userID, root, "child")
+	grandchild := createFolder(t, repo, userID, child, "grandchild")
+
+	ctx := context.Background()
+	if _, err := repo.Move(ctx, root.ID, userID, &root.ID, nil); !errors.Is(err, ErrInvalidMove) {
+		t.Errorf("move into itself = %v, want ErrInvalidMove", err)
+	}
+	for _, target := range []*models.Folder{child, grandchild} {
+		if _, err := repo.Move(ctx, root.ID, userID, &target.ID, nil); !errors.Is(err, ErrCircularReference) {
+			t.Errorf("move under %s = %v, want ErrCircularReference", target.Name, err)
+		}
+	}
+
+	got, err := repo.GetByID(ctx, root.ID)
+	if err != nil {
+		t.Fatalf("get root: %v", err)
+	}
+	if got.ParentID != nil || got.Path != root.Path {
+		t.Errorf("rejected move changed root: parent %v, path %q", got.ParentID, got.Path)
+	}
+}
+
+func TestFolderMoveRewritesSubtree(t *testing.T) {
+	pool := testdb.New(t)
+	userID := testdb.CreateUser(t, pool)
+	repo := NewFolderRepository(pool, nil)
+	ctx := context.Background()
+
+	// a/b/c/d moved under x: b becomes x/b at depth 1, d lands at depth 3
+	a := createFolder(t, repo, userID, nil, "a")
+	b := createFolder(t, repo, userID, a, "b")
+	c := createFolder(t, repo, userID, b, "c")
+	d := createFolder(t, repo, userID, c, "d")
+	x := createFolder(t, repo, userID, nil, "x")
+	fileID := testdb.CreateFile(t, pool, userID, &d.ID)
+
+	moved, err := repo.Move(ctx, b.ID, userID, &x.ID, nil)
+	if err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	if moved.ParentID == nil || *moved.ParentID != x.ID {
+		t.Errorf("moved parent = %v, want %s", moved.ParentID, x.ID)
+	}
+
+	want := map[uuid.UUID]struct {
+		path  string
+		depth int
+	}{
+		b.ID: {"/" + x.ID.String() + "/" + b.ID.String(), 1},
+		c.ID: {"/" + x.ID.String() + "/" + b.ID.String() + "/" + c.ID.String(), 2},
+		d.ID: {"/" + x.ID.String() + "/" + b.ID.String() + "/" + c.ID.String() + "/" + d.ID.String(), 3},
+	}
+	for id, w := range want {
+		got, err := repo.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("get %s: %v", id, err)
+		}
+		if got.Path != w.path || got.Depth != w.depth {
+			t.Errorf("folder %s: path %q depth %d, want %q depth %d", got.Name, got.Path, got.Depth, w.path, w.depth)
+		}
+	}
+
+	// Files travel with their folder
+	var folderID uuid.UUID
+	if err := pool.QueryRow(ctx, "SELECT folder_id FROM files WHERE id = $1", fileID).Scan(&folderID); err != nil {
+		t.Fatalf("get file: %v", err)
+	}
+	if folderID != d.ID {
+		t.Errorf("file folder_id = %s, want %s", folderID, d.ID)
+	}
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type LoginAttemptRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLoginAttemptRepository(db *pgxpool.Pool) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+// GetLockedUntil returns when the email's lockout ends, or nil if it is not locked
+func (r *LoginAttemptRepository) GetLockedUntil(ctx context.Context, email string) (*time.Time, error) {
+	query := `
+		SELECT locked_until FROM login_attempts
+		WHERE email = $1 AND locked_until > NOW()
+	`
+
+	var lockedUntil *time.Time
+	err := r.db.QueryRow(ctx, query, email).Scan(&lockedUntil)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return lockedUntil, nil
+}
+
+// RecordFailure counts a failed login. Failures older than window start a
+// fresh count; reaching maxAttempts locks the email for window and returns
+// the end of the lockout.
+func (r *LoginAttemptRepository) RecordFailure(ctx context.Context, email string, maxAttempts int, window time.Duration) (*time.Time, error) {
+	query := `
+		INSERT INTO login_attempts (email, failed_count, window_started_at)
+		VALUES ($1, 1, NOW())
+		ON CONFLICT (email) DO UPDATE SET
+			failed_count = CASE
+				WHEN login_attempts.window_started_at < NOW() - $2 * INTERVAL '1 second' THEN 1
+				ELSE login_attempts.failed_count + 1
+			END,
+			window_started_at = CASE
+				WHEN login_attempts.window_started_at < NOW() - $2 * INTERVAL '1 second' THEN NOW()
+				ELSE login_attempts.window_started_at
+			END
+		RETURNING failed_count
+	`
+
+	windowSecs := int64(window.Seconds())
+
+	var failedCount int
+	if err := r.db.QueryRow(ctx, query, email, windowSecs).Scan(&failedCount); err != nil {
+		return nil, err
+	}
+	if failedCount < maxAttempts {
+		return nil, nil
+	}
+
+	lockQuery := `
+		UPDATE login_attempts
+		SET locked_until = NOW() + $2 * INTERVAL '1 second', failed_count = 0, window_started_at = NOW()
+		WHERE email = $1
+		RETURNING locked_until
+	`
+
+	var lockedUntil time.Time
+	if err := r.db.QueryRow(ctx, lockQuery, email, windowSecs).Scan(&lockedUntil); err != nil {
+		return nil, err
+	}
+
+	return &lockedUntil, nil
+}
+
+// Clear forgets the email's failures and any lockout
+func (r *LoginAttemptRepository) Clear(ctx context.Context, email string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM login_attempts WHERE email = $1`, email)
+	return err
+}
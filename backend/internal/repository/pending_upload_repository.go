@@ -26,20 +26,25 @@ func NewPendingUploadRepository(db *pgxpool.Pool) *PendingUploadRepository {
 
 func (r *PendingUploadRepository) Create(ctx context.Context, upload *models.PendingUpload) error {
 	query := `
-		INSERT INTO pending_uploads (user_id, workspace_id, folder_id, filename, file_size, content_type, storage_path, expires_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO pending_uploads (
+			user_id, workspace_id, folder_id, filename, file_size, content_type, storage_path,
+			auto_summarize, summary_style, summary_language, multipart_upload_id, expires_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id, created_at
 	`
 
 	return r.db.QueryRow(ctx, query,
 		upload.UserID, upload.WorkspaceID, upload.FolderID, upload.Filename, upload.FileSize,
-		upload.ContentType, upload.StoragePath, upload.ExpiresAt,
+		upload.ContentType, upload.StoragePath,
+		upload.AutoSummarize, upload.SummaryStyle, upload.SummaryLanguage, upload.MultipartUploadID, upload.ExpiresAt,
 	).Scan(&upload.ID, &upload.CreatedAt)
 }
 
 func (r *PendingUploadRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.PendingUpload, error) {
 	query := `
-		SELECT id, user_id, workspace_id, folder_id, filename, file_size, content_type, storage_path, expires_at, created_at
+		SELECT id, user_id, workspace_id, folder_id, filename, file_size, content_type, storage_path,
+		       auto_summarize, summary_style, summary_language, multipart_upload_id, expires_at, created_at
 		FROM pending_uploads
 		WHERE id = $1
 	`
@@ -48,6 +53,7 @@ func (r *PendingUploadRepository) GetByID(ctx context.Context, id uuid.UUID) (*m
 	err := r.db.QueryRow(ctx, query, id).Scan(
 		&upload.ID, &upload.UserID, &upload.WorkspaceID, &upload.FolderID, &upload.Filename,
 		&upload.FileSize, &upload.ContentType, &upload.StoragePath,
+		&upload.AutoSummarize, &upload.SummaryStyle, &upload.SummaryLanguage, &upload.MultipartUploadID,
 		&upload.ExpiresAt, &upload.CreatedAt,
 	)
 
@@ -65,6 +71,61 @@ func (r *PendingUploadRepository) GetByID(ctx context.Context, id uuid.UUID) (*m
 	return upload, nil
 }
 
+// ListByUserID returns a user's unconfirmed file uploads, newest first, including
+// expired ones. Avatar uploads share the table but live under avatars/ and are excluded.
+func (r *PendingUploadRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]*models.PendingUpload, error) {
+	query := `
+		SELECT id, user_id, workspace_id, folder_id, filename, file_size, content_type, storage_path,
+		       auto_summarize, summary_style, summary_language, multipart_upload_id, expires_at, created_at
+		FROM pending_uploads
+		WHERE user_id = $1 AND storage_path LIKE 'users/%'
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var uploads []*models.PendingUpload
+	for rows.Next() {
+		upload := &models.PendingUpload{}
+		if err := rows.Scan(
+			&upload.ID, &upload.UserID, &upload.WorkspaceID, &upload.FolderID, &upload.Filename,
+			&upload.FileSize, &upload.ContentType, &upload.StoragePath,
+			&upload.AutoSummarize, &upload.SummaryStyle, &upload.SummaryLanguage, &upload.MultipartUploadID,
+			&upload.ExpiresAt, &upload.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, upload)
+	}
+
+	return uploads, rows.Err()
+}
+
+// DeleteByUser removes one of the user's file uploads regardless of expiry.
+// The returned upload only carries its storage path and multipart upload ID,
+// so the caller can remove what was already uploaded.
+func (r *PendingUploadRepository) DeleteByUser(ctx context.Context, id, userID uuid.UUID) (*models.PendingUpload, error) {
+	query := `
+		DELETE FROM pending_uploads
+		WHERE id = $1 AND user_id = $2 AND storage_path LIKE 'users/%'
+		RETURNING storage_path, multipart_upload_id
+	`
+
+	upload := &models.PendingUpload{ID: id, UserID: userID}
+	if err := r.db.QueryRow(ctx, query, id, userID).Scan(&upload.StoragePath, &upload.MultipartUploadID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUploadNotFound
+		}
+		return nil, err
+	}
+
+	return upload, nil
+}
+
 func (r *PendingUploadRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM pending_uploads WHERE id = $1`
 	_, err := r.db.Exec(ctx, query, id)
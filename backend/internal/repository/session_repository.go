@@ -114,6 +114,38 @@ func (r *SessionRepository) Delete(ctx context.Context, sessionID uuid.UUID) err
 	return nil
 }
 
+// Revoke deletes a session and revokes its refresh token in one transaction,
+// so the device can no longer refresh. The whole token family is revoked,
+// since the session's token may since have been rotated.
+func (r *SessionRepository) Revoke(ctx context.Context, sessionID uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var tokenID uuid.UUID
+	err = tx.QueryRow(ctx, `DELETE FROM user_sessions WHERE id = $1 RETURNING refresh_token_id`, sessionID).Scan(&tokenID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE revoked_at IS NULL
+		  AND family_id = (SELECT family_id FROM refresh_tokens WHERE id = $1)
+	`
+	if _, err := tx.Exec(ctx, query, tokenID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
 func (r *SessionRepository) DeleteByRefreshTokenID(ctx context.Context, tokenID uuid.UUID) error {
 	query := `DELETE FROM user_sessions WHERE refresh_token_id = $1`
 	_, err := r.db.Exec(ctx, query, tokenID)
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nextpdf/backend/internal/models"
+)
+
+// SummaryAverages holds per-page processing averages over completed summaries
+type SummaryAverages struct {
+	Samples                 int
+	DurationMsPerPage       float64
+	PromptTokensPerPage     float64
+	CompletionTokensPerPage float64
+}
+
+// TimeseriesBucket is the number of events in the bucket starting at Start (UTC)
+type TimeseriesBucket struct {
+	Start time.Time
+	Count int
+}
+
+type StatsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewStatsRepository(db *pgxpool.Pool) *StatsRepository {
+	return &StatsRepository{db: db}
+}
+
+// EstimateForPages averages the cost per page of past summaries on files with
+// minPages..maxPages pages. A nil style or a maxPages of 0 lifts that filter.
+func (r *StatsRepository) EstimateForPages(ctx context.Context, style *models.SummaryStyle, minPages, maxPages int) (*SummaryAverages, error) {
+	query := `
+		SELECT COUNT(*),
+		       COALESCE(AVG(s.processing_duration_ms::float8 / f.page_count), 0),
+		       COALESCE(AVG(s.prompt_tokens::float8 / f.page_count), 0),
+		       COALESCE(AVG(s.completion_tokens::float8 / f.page_count), 0)
+		FROM summaries s
+		JOIN files f ON f.id = s.file_id
+		WHERE f.page_count > 0
+		  AND s.processing_duration_ms IS NOT NULL
+		  AND ($1::summary_style IS NULL OR s.style = $1)
+		  AND f.page_count >= $2
+		  AND ($3 = 0 OR f.page_count <= $3)
+	`
+
+	avg := &SummaryAverages{}
+	err := r.db.QueryRow(ctx, query, style, minPages, maxPages).Scan(
+		&avg.Samples, &avg.DurationMsPerPage, &avg.PromptTokensPerPage, &avg.CompletionTokensPerPage,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return avg, nil
+}
+
+// SummaryTimeseries counts summaries of the user's files created in
+// [from, to), grouped by bucket ("day", "week" or "month"). Empty buckets are
+// included with a zero count so charts get a continuous series.
+func (r *StatsRepository) SummaryTimeseries(ctx context.Context, userID uuid.UUID, bucket string, from, to time.Time) ([]TimeseriesBucket, error) {
+	query := `
+		WITH counts AS (
+			SELECT date_trunc($2, s.created_at AT TIME ZONE 'UTC') AS bucket, COUNT(*) AS total
+			FROM summaries s
+			JOIN files f ON f.id = s.file_id
+			WHERE f.user_id = $1
+			  AND s.created_at >= $3::timestamptz
+			  AND s.created_at < $4::timestamptz
+			GROUP BY 1
+		)
+		SELECT b.bucket, COALESCE(c.total, 0)
+		FROM generate_series(
+			date_trunc($2, $3::timestamptz AT TIME ZONE 'UTC'),
+			date_trunc($2, ($4::timestamptz - INTERVAL '1 microsecond') AT TIME ZONE 'UTC'),
+			('1 ' || $2)::interval
+		) AS b(bucket)
+		LEFT JOIN counts c ON c.bucket = b.bucket
+		ORDER BY b.bucket
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, bucket, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var buckets []TimeseriesBucket
+	for rows.Next() {
+		var b TimeseriesBucket
+		if err := rows.Scan(&b.Start, &b.Count); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, b)
+	}
+
+	return buckets, rows.Err()
+}
+
+// RecordGuestSummary stores the anonymous metrics of one guest summary
+func (r *StatsRepository) RecordGuestSummary(ctx context.Context, m *models.GuestSummaryMetric) error {
+	query := `
+		INSERT INTO guest_summary_metrics (
+			mode, style, language, file_size, page_count, success, total_duration_ms,
+			ai_duration_ms, prompt_tokens, completion_tokens, model_used
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	_, err := r.db.Exec(ctx, query,
+		m.Mode, m.Style, m.Language, m.FileSize, m.PageCount, m.Success, m.TotalDurationMs,
+		m.AIDurationMs, m.PromptTokens, m.CompletionTokens, m.ModelUsed,
+	)
+	return err
+}
+
+// GuestSummaryStats aggregates the guest summaries recorded since from
+func (r *StatsRepository) GuestSummaryStats(ctx context.Context, from time.Time) (*models.GuestMetricsResponse, error) {
+	query := `
+		SELECT COUNT(*),
+		       COUNT(*) FILTER (WHERE success),
+		       COUNT(*) FILTER (WHERE mode = 'stream'),
+		       COALESCE(AVG(total_duration_ms) FILTER (WHERE success), 0),
+		       COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY total_duration_ms) FILTER (WHERE success), 0),
+		       COALESCE(AVG(page_count), 0),
+		       COALESCE(AVG(file_size), 0),
+		       COALESCE(AVG(prompt_tokens) FILTER (WHERE success), 0),
+		       COALESCE(AVG(completion_tokens) FILTER (WHERE success), 0)
+		FROM guest_summary_metrics
+		WHERE created_at >= $1
+	`
+
+	stats := &models.GuestMetricsResponse{}
+	err := r.db.QueryRow(ctx, query, from).Scan(
+		&stats.Total, &stats.Succeeded, &stats.Streamed,
+		&stats.AvgDurationMs, &stats.P95DurationMs, &stats.AvgPageCount, &stats.AvgFileSize,
+		&stats.AvgPromptTokens, &stats.AvgCompletionTokens,
+	)
+	if err != nil {
+		return nil, err
+	}
+	stats.Failed = stats.Total - stats.Succeeded
+
+	return stats, nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -13,11 +14,28 @@ import (
 var ErrSummaryNotFound = errors.New("summary not found")
 
 type SummaryRepository struct {
-	db *pgxpool.Pool
+	db              *pgxpool.Pool
+	cache           *FileCache
+	maxVersions     int
+	storeSourceText bool
 }
 
-func NewSummaryRepository(db *pgxpool.Pool) *SummaryRepository {
-	return &SummaryRepository{db: db}
+// NewSummaryRepository returns a repository that keeps at most maxVersions
+// summary versions per file (0 for no limit). The extracted source text of a
+// summary is only saved when storeSourceText is set. cache is the file cache
+// to invalidate when a file's summary columns change.
+func NewSummaryRepository(db *pgxpool.Pool, cache *FileCache, maxVersions int, storeSourceText bool) *SummaryRepository {
+	return &SummaryRepository{db: db, cache: cache, maxVersions: maxVersions, storeSourceText: storeSourceText}
+}
+
+// MaxVersions is the number of versions kept per file; 0 means no limit
+func (r *SummaryRepository) MaxVersions() int {
+	return r.maxVersions
+}
+
+// StoresSourceText reports whether new summaries keep their source text
+func (r *SummaryRepository) StoresSourceText() bool {
+	return r.storeSourceText
 }
 
 // SummaryCreate is used for creating new summaries from AI callback
@@ -32,19 +50,28 @@ type SummaryCreate struct {
 	CompletionTokens     *int
 	ProcessingDurationMs *int
 	Language             string
+	SourceText           *string // Text extracted by the AI service; dropped unless storage is enabled
 }
 
 func (r *SummaryRepository) Create(ctx context.Context, summary *SummaryCreate) error {
+	defer r.cache.invalidate(summary.FileID)
+
 	// Default language to English if not specified
 	lang := summary.Language
 	if lang == "" {
 		lang = "en"
 	}
 
+	var sourceText *string
+	if r.storeSourceText {
+		sourceText = summary.SourceText
+	}
+
 	query := `
 		INSERT INTO summaries (file_id, title, content, style, custom_instructions, model_used,
-		                       prompt_tokens, completion_tokens, processing_duration_ms, language, is_current)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, true)
+		                       prompt_tokens, completion_tokens, processing_duration_ms, language,
+		                       source_text, version, is_current)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, true)
 		RETURNING id
 	`
 
@@ -56,8 +83,23 @@ func (r *SummaryRepository) Create(ctx context.Context, summary *SummaryCreate)
 	}
 	defer tx.Rollback(ctx)
 
-	// Update existing summaries to not be current
-	_, err = tx.Exec(ctx, "UPDATE summaries SET is_current = false WHERE file_id = $1", summary.FileID)
+	// Serialize creates per file. The version is numbered from MAX(version),
+	// and two concurrent callbacks would otherwise both miss each other's row:
+	// same version, and a clash on the current-summary index.
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1::text))", summary.FileID); err != nil {
+		return err
+	}
+
+	// Demote the previous current version so exactly one row stays current
+	_, err = tx.Exec(ctx, "UPDATE summaries SET is_current = false WHERE file_id = $1 AND is_current", summary.FileID)
+	if err != nil {
+		return err
+	}
+
+	// Number the version here rather than leaving it to the column default;
+	// the insert trigger, where installed, computes the same value
+	var version int
+	err = tx.QueryRow(ctx, "SELECT COALESCE(MAX(version), 0) + 1 FROM summaries WHERE file_id = $1", summary.FileID).Scan(&version)
 	if err != nil {
 		return err
 	}
@@ -65,13 +107,165 @@ func (r *SummaryRepository) Create(ctx context.Context, summary *SummaryCreate)
 	err = tx.QueryRow(ctx, query,
 		summary.FileID, summary.Title, summary.Content, summary.Style,
 		summary.CustomInstructions, summary.ModelUsed, summary.PromptTokens,
-		summary.CompletionTokens, summary.ProcessingDurationMs, lang,
+		summary.CompletionTokens, summary.ProcessingDurationMs, lang, sourceText, version,
 	).Scan(&id)
 
 	if err != nil {
 		return err
 	}
 
+	// Keep the listing flag in step with the summaries table
+	_, err = tx.Exec(ctx, "UPDATE files SET has_summary = true WHERE id = $1 AND NOT has_summary", summary.FileID)
+	if err != nil {
+		return err
+	}
+
+	// Prune the oldest versions beyond the limit; the new row is current and
+	// always kept
+	if r.maxVersions > 0 {
+		_, err = tx.Exec(ctx, `
+			DELETE FROM summaries
+			WHERE id IN (
+				SELECT id FROM summaries
+				WHERE file_id = $1 AND NOT is_current
+				ORDER BY version DESC
+				OFFSET $2
+			)
+		`, summary.FileID, r.maxVersions-1)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// CopyToFile clones every summary version of srcFileID onto dstFileID, along
+// with the file's summary cache columns, and returns how many were copied.
+// The copies are numbered 1..n in source version order, which is also how the
+// insert trigger numbers them; pruning can leave gaps in the source versions,
+// so the source's current version is matched to its copy by rank.
+func (r *SummaryRepository) CopyToFile(ctx context.Context, srcFileID, dstFileID uuid.UUID) (int64, error) {
+	defer r.cache.invalidate(dstFileID)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1::text))", dstFileID); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(ctx, `
+		INSERT INTO summaries (file_id, title, content, style, custom_instructions, model_used,
+		                       prompt_tokens, completion_tokens, processing_started_at,
+		                       processing_completed_at, processing_duration_ms, language,
+		                       source_text, version, is_current, created_at)
+		SELECT $2, title, content, style, custom_instructions, model_used,
+		       prompt_tokens, completion_tokens, processing_started_at,
+		       processing_completed_at, processing_duration_ms, language,
+		       source_text, ROW_NUMBER() OVER (ORDER BY version), false, created_at
+		FROM summaries
+		WHERE file_id = $1
+		ORDER BY version
+	`, srcFileID, dstFileID)
+	if err != nil {
+		return 0, err
+	}
+	if result.RowsAffected() == 0 {
+		return 0, nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE summaries d
+		SET is_current = true
+		FROM (
+			SELECT is_current, ROW_NUMBER() OVER (ORDER BY version) AS rank
+			FROM summaries
+			WHERE file_id = $1
+		) s
+		WHERE s.is_current AND d.file_id = $2 AND d.version = s.rank
+	`, srcFileID, dstFileID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE files d
+		SET has_summary = true,
+		    latest_summary_title = s.latest_summary_title,
+		    latest_summary = s.latest_summary,
+		    latest_summary_style = s.latest_summary_style,
+		    latest_summary_custom_instruction = s.latest_summary_custom_instruction,
+		    latest_summary_model = s.latest_summary_model,
+		    latest_summary_duration_ms = s.latest_summary_duration_ms,
+		    latest_summary_language = s.latest_summary_language
+		FROM files s
+		WHERE s.id = $1 AND d.id = $2
+	`, srcFileID, dstFileID)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), tx.Commit(ctx)
+}
+
+// SetCurrent makes an existing version the file's current summary and copies
+// it into the file's summary cache columns, which the insert trigger only
+// fills for new rows. Returns ErrSummaryNotFound when the version does not exist.
+func (r *SummaryRepository) SetCurrent(ctx context.Context, fileID uuid.UUID, version int) error {
+	defer r.cache.invalidate(fileID)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Same per-file lock as Create, so a summary saved meanwhile cannot end
+	// up current alongside the chosen version
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1::text))", fileID); err != nil {
+		return err
+	}
+
+	var exists bool
+	err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM summaries WHERE file_id = $1 AND version = $2)", fileID, version).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrSummaryNotFound
+	}
+
+	// Clear first: the current-summary index is unique per file and checked row by row
+	_, err = tx.Exec(ctx, "UPDATE summaries SET is_current = false WHERE file_id = $1 AND is_current", fileID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "UPDATE summaries SET is_current = true WHERE file_id = $1 AND version = $2", fileID, version)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE files f
+		SET latest_summary_title = s.title,
+		    latest_summary = s.content,
+		    latest_summary_style = s.style,
+		    latest_summary_custom_instruction = s.custom_instructions,
+		    latest_summary_model = s.model_used,
+		    latest_summary_duration_ms = s.processing_duration_ms,
+		    latest_summary_language = COALESCE(s.language, 'en'),
+		    updated_at = NOW()
+		FROM summaries s
+		WHERE f.id = $1 AND s.file_id = $1 AND s.version = $2
+	`, fileID, version)
+	if err != nil {
+		return err
+	}
+
 	return tx.Commit(ctx)
 }
 
@@ -102,6 +296,33 @@ func (r *SummaryRepository) GetCurrentByFileID(ctx context.Context, fileID uuid.
 	return summary, nil
 }
 
+func (r *SummaryRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Summary, error) {
+	query := `
+		SELECT id, file_id, title, content, style, custom_instructions, model_used,
+		       prompt_tokens, completion_tokens, processing_started_at, processing_completed_at,
+		       processing_duration_ms, COALESCE(language, 'en') as language, version, is_current, created_at
+		FROM summaries
+		WHERE id = $1
+	`
+
+	summary := &models.Summary{}
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&summary.ID, &summary.FileID, &summary.Title, &summary.Content, &summary.Style,
+		&summary.CustomInstructions, &summary.ModelUsed, &summary.PromptTokens,
+		&summary.CompletionTokens, &summary.ProcessingStartedAt, &summary.ProcessingCompletedAt,
+		&summary.ProcessingDurationMs, &summary.Language, &summary.Version, &summary.IsCurrent, &summary.CreatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrSummaryNotFound
+		}
+		return nil, err
+	}
+
+	return summary, nil
+}
+
 func (r *SummaryRepository) GetByFileIDAndVersion(ctx context.Context, fileID uuid.UUID, version int) (*models.Summary, error) {
 	query := `
 		SELECT id, file_id, title, content, style, custom_instructions, model_used,
@@ -129,6 +350,36 @@ func (r *SummaryRepository) GetByFileIDAndVersion(ctx context.Context, fileID uu
 	return summary, nil
 }
 
+// GetSourceText returns the version number and stored source text of a
+// summary: the given version, or the current one when version is nil. The
+// text is nil when it was not stored for that version.
+func (r *SummaryRepository) GetSourceText(ctx context.Context, fileID uuid.UUID, version *int) (int, *string, error) {
+	query := `
+		SELECT version, source_text
+		FROM summaries
+		WHERE file_id = $1 AND (version = $2 OR ($2 IS NULL AND is_current))
+	`
+
+	var v int
+	var text *string
+	err := r.db.QueryRow(ctx, query, fileID, version).Scan(&v, &text)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil, ErrSummaryNotFound
+		}
+		return 0, nil, err
+	}
+
+	return v, text, nil
+}
+
+// GetLatestVersion returns the highest summary version for a file, or 0 if none exist
+func (r *SummaryRepository) GetLatestVersion(ctx context.Context, fileID uuid.UUID) (int, error) {
+	var version int
+	err := r.db.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM summaries WHERE file_id = $1`, fileID).Scan(&version)
+	return version, err
+}
+
 func (r *SummaryRepository) GetHistoryByFileID(ctx context.Context, fileID uuid.UUID) ([]*models.SummaryHistoryItem, error) {
 	query := `
 		SELECT id, version, title, style, custom_instructions, model_used,
@@ -182,3 +433,58 @@ func (r *SummaryRepository) GetBriefByFileID(ctx context.Context, fileID uuid.UU
 
 	return brief, nil
 }
+
+// GetBriefsByFileIDs returns the current summary brief of each file that has one
+func (r *SummaryRepository) GetBriefsByFileIDs(ctx context.Context, fileIDs []uuid.UUID) (map[uuid.UUID]*models.SummaryBrief, error) {
+	query := `
+		SELECT file_id, id, title, version, processing_duration_ms, created_at
+		FROM summaries
+		WHERE file_id = ANY($1) AND is_current = true
+	`
+
+	rows, err := r.db.Query(ctx, query, fileIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	briefs := make(map[uuid.UUID]*models.SummaryBrief)
+	for rows.Next() {
+		var fileID uuid.UUID
+		brief := &models.SummaryBrief{}
+		err := rows.Scan(
+			&fileID, &brief.ID, &brief.Title, &brief.Version, &brief.ProcessingDurationMs, &brief.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		briefs[fileID] = brief
+	}
+
+	return briefs, rows.Err()
+}
+
+// UpsertFeedback records userID's rating of a summary, replacing any earlier
+// one, and returns when it was saved
+func (r *SummaryRepository) UpsertFeedback(ctx context.Context, summaryID, userID uuid.UUID, rating int, comment *string) (time.Time, error) {
+	var updatedAt time.Time
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO summary_feedback (summary_id, user_id, rating, comment)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (summary_id, user_id)
+		DO UPDATE SET rating = EXCLUDED.rating, comment = EXCLUDED.comment, updated_at = NOW()
+		RETURNING updated_at
+	`, summaryID, userID, rating, comment).Scan(&updatedAt)
+	return updatedAt, err
+}
+
+// GetRating aggregates the feedback on a summary
+func (r *SummaryRepository) GetRating(ctx context.Context, summaryID uuid.UUID) (models.SummaryRating, error) {
+	var rating models.SummaryRating
+	err := r.db.QueryRow(ctx, `
+		SELECT COALESCE(AVG(rating), 0)::float8, COUNT(*)
+		FROM summary_feedback
+		WHERE summary_id = $1
+	`, summaryID).Scan(&rating.Average, &rating.Count)
+	return rating, err
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nextpdf/backend/internal/models"
+	"github.com/nextpdf/backend/internal/testdb"
+)
+
+// summaryVersions returns the file's versions in order and the ones flagged current
+func summaryVersions(t *testing.T, pool *pgxpool.Pool, fileID uuid.UUID) (versions, current []int) {
+	t.Helper()
+
+	rows, err := pool.Query(context.Background(),
+		"SELECT version, is_current FROM summaries WHERE file_id = $1 ORDER BY version", fileID)
+	if err != nil {
+		t.Fatalf("query summaries: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var isCurrent bool
+		if err := rows.Scan(&version, &isCurrent); err != nil {
+			t.Fatalf("scan summary: %v", err)
+		}
+		versions = append(versions, version)
+		if isCurrent {
+			current = append(current, version)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("query summaries: %v", err)
+	}
+	return versions, current
+}
+
+func createSummary(t *testing.T, repo *SummaryRepository, fileID uuid.UUID, content string) {
+	t.Helper()
+
+	err := repo.Create(context.Background(), &SummaryCreate{
+		FileID:  fileID,
+		Content: content,
+		Style:   models.StyleBulletPoints,
+	})
+	if err != nil {
+		t.Fatalf("create summary %q: %v", content, err)
+	}
+}
+
+func TestSummaryRegenerateNumbersVersions(t *testing.T) {
+	pool := testdb.New(t)
+	userID := testdb.CreateUser(t, pool)
+	fileID := testdb.CreateFile(t, pool, userID, nil)
+	repo := NewSummaryRepository(pool, nil, 0, false)
+
+	// The first summary, then two regenerations
+	for _, content := range []string{"first", "second", "third"} {
+		createSummary(t, repo, fileID, content)
+	}
+
+	versions, current := summaryVersions(t, pool, fileID)
+	if want := []int{1, 2, 3}; !slices.Equal(versions, want) {
+		t.Errorf("versions = %v, want %v", versions, want)
+	}
+	if want := []int{3}; !slices.Equal(current, want) {
+		t.Errorf("current = %v, want %v", current, want)
+	}
+
+	summary, err := repo.GetCurrentByFileID(context.Background(), fileID)
+	if err != nil {
+		t.Fatalf("get current: %v", err)
+	}
+	if summary.Version != 3 || summary.Content != "third" {
+		t.Errorf("current = v%d %q, want v3 %q", summary.Version, summary.Content, "third")
+	}
+}
+
+func TestSummaryCreatePrunesOldestVersions(t *testing.T) {
+	pool := testdb.New(t)
+	userID := testdb.CreateUser(t, pool)
+	fileID := testdb.CreateFile(t, pool, userID, nil)
+
+	const maxVersions = 3
+	repo := NewSummaryRepository(pool, nil, maxVersions, false)
+	for i := 1; i <= maxVersions+2; i++ {
+		createSummary(t, repo, fileID, fmt.Sprintf("v%d", i))
+	}
+
+	versions, current := summaryVersions(t, pool, fileID)
+	if want := []int{3, 4, 5}; !slices.Equal(versions, want) {
+		t.Errorf("versions = %v, want %v", versions, want)
+	}
+	if want := []int{maxVersions + 2}; !slices.Equal(current, want) {
+		t.Errorf("current = %v, want %v", current, want)
+	}
+
+	// Pruning leaves a gap before version 3; the copy must still pick v5 as current
+	dstID := testdb.CreateFile(t, pool, userID, nil)
+	copied, err := repo.CopyToFile(context.Background(), fileID, dstID)
+	if err != nil {
+		t.Fatalf("copy summaries: %v", err)
+	}
+	if copied != maxVersions {
+		t.Errorf("copied %d summaries, want %d", copied, maxVersions)
+	}
+
+	versions, current = summaryVersions(t, pool, dstID)
+	if want := []int{1, 2, 3}; !slices.Equal(versions, want) {
+		t.Errorf("copied versions = %v, want %v", versions, want)
+	}
+	if want := []int{3}; !slices.Equal(current, want) {
+		t.Fatalf("copied current = %v, want %v", current, want)
+	}
+
+	summary, err := repo.GetCurrentByFileID(context.Background(), dstID)
+	if err != nil {
+		t.Fatalf("get current copy: %v", err)
+	}
+	if summary.Content != "v5" {
+		t.Errorf("current copy content = %q, want %q", summary.Content, "v5")
+	}
+}
+
+func TestSummaryCreateConcurrentVersionsAreDistinct(t *testing.T) {
+	pool := testdb.New(t)
+	userID := testdb.CreateUser(t, pool)
+	fileID := testdb.CreateFile(t, pool, userID, nil)
+	repo := NewSummaryRepository(pool, nil, 0, false)
+
+	// Without the per-file lock these would read the same MAX(version)
+	const writers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- repo.Create(context.Background(), &SummaryCreate{
+				FileID:  fileID,
+				Content: fmt.Sprintf("w%d", i),
+				Style:   models.StyleBulletPoints,
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent create: %v", err)
+		}
+	}
+
+	versions, current := summaryVersions(t, pool, fileID)
+	if want := []int{1, 2, 3, 4, 5, 6, 7, 8}; !slices.Equal(versions, want) {
+		t.Errorf("versions = %v, want %v", versions, want)
+	}
+	if len(current) != 1 {
+		t.Errorf("current = %v, want exactly one", current)
+	}
+}
@@ -27,26 +27,28 @@ func NewTokenRepository(db *pgxpool.Pool) *TokenRepository {
 
 func (r *TokenRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
 	query := `
-		INSERT INTO refresh_tokens (user_id, token_hash, device_info, ip_address, expires_at)
-		VALUES ($1, $2, $3, $4::inet, $5)
+		INSERT INTO refresh_tokens (user_id, token_hash, family_id, device_info, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, $5::inet, $6)
 		RETURNING id, created_at
 	`
 
 	return r.db.QueryRow(ctx, query,
-		token.UserID, token.TokenHash, token.DeviceInfo, token.IPAddress, token.ExpiresAt,
+		token.UserID, token.TokenHash, token.FamilyID, token.DeviceInfo, token.IPAddress, token.ExpiresAt,
 	).Scan(&token.ID, &token.CreatedAt)
 }
 
+// GetRefreshTokenByHash returns a valid token. A revoked token is returned
+// together with ErrTokenRevoked so callers can inspect its family for reuse.
 func (r *TokenRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
 	query := `
-		SELECT id, user_id, token_hash, device_info, ip_address::text, expires_at, revoked_at, created_at
+		SELECT id, user_id, token_hash, family_id, device_info, ip_address::text, expires_at, revoked_at, created_at
 		FROM refresh_tokens
 		WHERE token_hash = $1
 	`
 
 	token := &models.RefreshToken{}
 	err := r.db.QueryRow(ctx, query, tokenHash).Scan(
-		&token.ID, &token.UserID, &token.TokenHash, &token.DeviceInfo,
+		&token.ID, &token.UserID, &token.TokenHash, &token.FamilyID, &token.DeviceInfo,
 		&token.IPAddress, &token.ExpiresAt, &token.RevokedAt, &token.CreatedAt,
 	)
 
@@ -58,7 +60,7 @@ func (r *TokenRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash s
 	}
 
 	if token.RevokedAt != nil {
-		return nil, ErrTokenRevoked
+		return token, ErrTokenRevoked
 	}
 
 	if token.ExpiresAt.Before(time.Now()) {
@@ -87,6 +89,20 @@ func (r *TokenRepository) RevokeRefreshToken(ctx context.Context, tokenHash stri
 	return nil
 }
 
+// IsFamilyActive reports whether any token of the family is still usable
+func (r *TokenRepository) IsFamilyActive(ctx context.Context, familyID uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM refresh_tokens
+			WHERE family_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		)
+	`
+
+	var active bool
+	err := r.db.QueryRow(ctx, query, familyID).Scan(&active)
+	return active, err
+}
+
 func (r *TokenRepository) RevokeAllUserTokens(ctx context.Context, userID uuid.UUID) (int64, error) {
 	query := `
 		UPDATE refresh_tokens
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TwoFactorChallengeRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTwoFactorChallengeRepository(db *pgxpool.Pool) *TwoFactorChallengeRepository {
+	return &TwoFactorChallengeRepository{db: db}
+}
+
+// Create records a new challenge and drops the user's expired ones
+func (r *TwoFactorChallengeRepository) Create(ctx context.Context, id, userID uuid.UUID, expiresAt time.Time) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM two_factor_challenges WHERE user_id = $1 AND expires_at < NOW()`, userID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO two_factor_challenges (id, user_id, expires_at)
+		VALUES ($1, $2, $3)
+	`
+	_, err := r.db.Exec(ctx, query, id, userID, expiresAt)
+	return err
+}
+
+// IsActive reports whether the challenge can still be redeemed
+func (r *TwoFactorChallengeRepository) IsActive(ctx context.Context, id, userID uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM two_factor_challenges
+			WHERE id = $1 AND user_id = $2 AND used_at IS NULL AND expires_at > NOW()
+		)
+	`
+
+	var active bool
+	err := r.db.QueryRow(ctx, query, id, userID).Scan(&active)
+	return active, err
+}
+
+// RecordFailure counts a wrong code against the challenge; the attempt that
+// reaches maxAttempts burns it
+func (r *TwoFactorChallengeRepository) RecordFailure(ctx context.Context, id uuid.UUID, maxAttempts int) error {
+	query := `
+		UPDATE two_factor_challenges
+		SET failed_attempts = failed_attempts + 1,
+		    used_at = CASE WHEN failed_attempts + 1 >= $2 THEN NOW() ELSE used_at END
+		WHERE id = $1 AND used_at IS NULL
+	`
+
+	_, err := r.db.Exec(ctx, query, id, maxAttempts)
+	return err
+}
+
+// Consume redeems the challenge. Marking happens in the same statement as the
+// check, so only one of several concurrent verifications wins.
+func (r *TwoFactorChallengeRepository) Consume(ctx context.Context, id, userID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE two_factor_challenges
+		SET used_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND used_at IS NULL AND expires_at > NOW()
+	`
+
+	result, err := r.db.Exec(ctx, query, id, userID)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
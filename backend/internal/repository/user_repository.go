@@ -14,14 +14,20 @@ var (
 	ErrUserNotFound    = errors.New("user not found")
 	ErrEmailExists     = errors.New("email already exists")
 	ErrAccountDisabled = errors.New("account is disabled")
+	// ErrOwnsSharedWorkspace is returned when deleting a user who still owns
+	// a workspace with other members
+	ErrOwnsSharedWorkspace = errors.New("user owns a workspace with other members")
 )
 
 type UserRepository struct {
-	db *pgxpool.Pool
+	db    *pgxpool.Pool
+	cache *FileCache
 }
 
-func NewUserRepository(db *pgxpool.Pool) *UserRepository {
-	return &UserRepository{db: db}
+// NewUserRepository returns a user repository; cache is the file cache to
+// invalidate when deleting a user deletes their files
+func NewUserRepository(db *pgxpool.Pool, cache *FileCache) *UserRepository {
+	return &UserRepository{db: db, cache: cache}
 }
 
 func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
@@ -47,7 +53,7 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
 	query := `
 		SELECT id, email, password_hash, full_name, avatar_url, is_active, 
-		       email_verified_at, created_at, updated_at
+		       email_verified_at, totp_enabled, created_at, updated_at
 		FROM users
 		WHERE id = $1
 	`
@@ -55,7 +61,7 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Use
 	user := &models.User{}
 	err := r.db.QueryRow(ctx, query, id).Scan(
 		&user.ID, &user.Email, &user.PasswordHash, &user.FullName,
-		&user.AvatarURL, &user.IsActive, &user.EmailVerifiedAt,
+		&user.AvatarURL, &user.IsActive, &user.EmailVerifiedAt, &user.TOTPEnabled,
 		&user.CreatedAt, &user.UpdatedAt,
 	)
 
@@ -72,7 +78,7 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Use
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	query := `
 		SELECT id, email, password_hash, full_name, avatar_url, is_active, 
-		       email_verified_at, created_at, updated_at
+		       email_verified_at, totp_enabled, created_at, updated_at
 		FROM users
 		WHERE email = $1
 	`
@@ -80,7 +86,7 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.
 	user := &models.User{}
 	err := r.db.QueryRow(ctx, query, email).Scan(
 		&user.ID, &user.Email, &user.PasswordHash, &user.FullName,
-		&user.AvatarURL, &user.IsActive, &user.EmailVerifiedAt,
+		&user.AvatarURL, &user.IsActive, &user.EmailVerifiedAt, &user.TOTPEnabled,
 		&user.CreatedAt, &user.UpdatedAt,
 	)
 
@@ -115,6 +121,79 @@ func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
 	return nil
 }
 
+// Delete removes the user in one transaction, cascading to their own files,
+// folders, tokens and the workspaces only they belong to. A user who owns a
+// workspace with other members gets ErrOwnsSharedWorkspace and nothing is
+// deleted. Files other people left in the deleted workspaces move back to
+// their owners' libraries. It returns the storage paths of the user's files
+// so the caller can remove the objects once the rows are gone.
+func (r *UserRepository) Delete(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Locking the owned workspaces blocks anyone joining until the delete commits
+	var shared bool
+	err = tx.QueryRow(ctx, `
+		WITH owned AS (
+			SELECT id FROM workspaces WHERE owner_id = $1 FOR UPDATE
+		)
+		SELECT EXISTS (
+			SELECT 1 FROM workspace_members
+			WHERE workspace_id IN (SELECT id FROM owned) AND user_id <> $1
+		)
+	`, userID).Scan(&shared)
+	if err != nil {
+		return nil, err
+	}
+	if shared {
+		return nil, ErrOwnsSharedWorkspace
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE files SET workspace_id = NULL
+		WHERE workspace_id IN (SELECT id FROM workspaces WHERE owner_id = $1)
+		  AND user_id <> $1
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `SELECT storage_path FROM files WHERE user_id = $1 FOR UPDATE`, userID)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	if result.RowsAffected() == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	r.cache.invalidateAll()
+	return paths, nil
+}
+
 func (r *UserRepository) UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash string) error {
 	query := `
 		UPDATE users
@@ -169,3 +248,119 @@ func containsHelper(s, substr string) bool {
 	}
 	return false
 }
+
+// MarkEmailVerified records the verification time unless one is already set
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, userID uuid.UUID) error {
+	query := `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+		WHERE id = $1
+	`
+
+	result, err := r.db.Exec(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// GetTOTPSecret returns the user's TOTP secret, or nil if 2FA was never set up
+func (r *UserRepository) GetTOTPSecret(ctx context.Context, userID uuid.UUID) (*string, error) {
+	var secret *string
+	err := r.db.QueryRow(ctx, `SELECT totp_secret FROM users WHERE id = $1`, userID).Scan(&secret)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return secret, nil
+}
+
+// SetTOTPSecret stores a new pending secret; 2FA stays off until EnableTOTP
+func (r *UserRepository) SetTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) error {
+	query := `
+		UPDATE users
+		SET totp_secret = $2, totp_enabled = FALSE, totp_last_step = NULL, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	result, err := r.db.Exec(ctx, query, userID, secret)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// EnableTOTP turns on 2FA and replaces the recovery codes in one transaction
+func (r *UserRepository) EnableTOTP(ctx context.Context, userID uuid.UUID, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE users
+		SET totp_enabled = TRUE, totp_last_step = $2, updated_at = NOW()
+		WHERE id = $1
+	`, userID, step)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM totp_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, hash := range recoveryCodeHashes {
+		_, err := tx.Exec(ctx, `INSERT INTO totp_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RecordTOTPStep stores the step of an accepted code. It reports false when
+// the step is not newer than the last one, meaning the code was already used.
+func (r *UserRepository) RecordTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	query := `
+		UPDATE users
+		SET totp_last_step = $2
+		WHERE id = $1 AND (totp_last_step IS NULL OR totp_last_step < $2)
+	`
+
+	result, err := r.db.Exec(ctx, query, userID, step)
+	if err != nil {
+		return false, err
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// UseRecoveryCode redeems an unused recovery code, reporting whether one matched
+func (r *UserRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	query := `
+		UPDATE totp_recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	result, err := r.db.Exec(ctx, query, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	return result.RowsAffected() > 0, nil
+}
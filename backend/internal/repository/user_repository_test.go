@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nextpdf/backend/internal/models"
+	"github.com/nextpdf/backend/internal/testdb"
+)
+
+func TestDeleteUserKeepsOtherMembersData(t *testing.T) {
+	pool := testdb.New(t)
+	ctx := context.Background()
+	users := NewUserRepository(pool, nil)
+	workspaces := NewWorkspaceRepository(pool)
+
+	ownerID := testdb.CreateUser(t, pool)
+	memberID := testdb.CreateUser(t, pool)
+	workspace := &models.Workspace{Name: "Team", InviteCode: uuid.NewString()[:8], OwnerID: ownerID}
+	if err := workspaces.Create(ctx, workspace); err != nil {
+		t.Fatalf("create workspace: %v", err)
+	}
+	for userID, role := range map[uuid.UUID]string{ownerID: models.WorkspaceRoleOwner, memberID: models.WorkspaceRoleMember} {
+		if err := workspaces.AddMember(ctx, &models.WorkspaceMember{WorkspaceID: workspace.ID, UserID: userID, Role: role}); err != nil {
+			t.Fatalf("add member: %v", err)
+		}
+	}
+
+	ownFile := testdb.CreateFile(t, pool, ownerID, nil)
+	memberFile := testdb.CreateFile(t, pool, memberID, nil)
+	if _, err := pool.Exec(ctx, "UPDATE files SET workspace_id = $1 WHERE id = ANY($2)", workspace.ID, []uuid.UUID{ownFile, memberFile}); err != nil {
+		t.Fatalf("share files: %v", err)
+	}
+
+	// A workspace with another member blocks the deletion entirely
+	if _, err := users.Delete(ctx, ownerID); !errors.Is(err, ErrOwnsSharedWorkspace) {
+		t.Fatalf("delete shared owner = %v, want ErrOwnsSharedWorkspace", err)
+	}
+	if _, err := users.GetByID(ctx, ownerID); err != nil {
+		t.Fatalf("owner gone after a refused delete: %v", err)
+	}
+
+	// Once alone, the owner's files go; the former member's file stays theirs
+	if err := workspaces.RemoveMember(ctx, workspace.ID, memberID); err != nil {
+		t.Fatalf("remove member: %v", err)
+	}
+	paths, err := users.Delete(ctx, ownerID)
+	if err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if len(paths) != 1 || !strings.HasPrefix(paths[0], ownerID.String()+"/") {
+		t.Errorf("paths = %v, want only the owner's file", paths)
+	}
+
+	var workspaceID *uuid.UUID
+	if err := pool.QueryRow(ctx, "SELECT workspace_id FROM files WHERE id = $1", memberFile).Scan(&workspaceID); err != nil {
+		t.Fatalf("member file deleted with the owner: %v", err)
+	}
+	if workspaceID != nil {
+		t.Errorf("member file still in deleted workspace %s", workspaceID)
+	}
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nextpdf/backend/internal/models"
+)
+
+type VerificationTokenRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewVerificationTokenRepository(db *pgxpool.Pool) *VerificationTokenRepository {
+	return &VerificationTokenRepository{db: db}
+}
+
+func (r *VerificationTokenRepository) Create(ctx context.Context, token *models.EmailVerificationToken) error {
+	query := `
+		INSERT INTO email_verification_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+
+	return r.db.QueryRow(ctx, query, token.UserID, token.TokenHash, token.ExpiresAt).
+		Scan(&token.ID, &token.CreatedAt)
+}
+
+// Consume marks an unused token as used and returns it. Marking happens in
+// the same statement as the lookup, so a token can be redeemed only once.
+func (r *VerificationTokenRepository) Consume(ctx context.Context, tokenHash string) (*models.EmailVerificationToken, error) {
+	query := `
+		UPDATE email_verification_tokens
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL
+		RETURNING id, user_id, token_hash, expires_at, used_at, created_at
+	`
+
+	token := &models.EmailVerificationToken{}
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(
+		&token.ID, &token.UserID, &token.TokenHash, &token.ExpiresAt, &token.UsedAt, &token.CreatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTokenNotFound
+		}
+		return nil, err
+	}
+
+	if token.ExpiresAt.Before(time.Now()) {
+		return nil, ErrTokenExpired
+	}
+
+	return token, nil
+}
+
+// InvalidateForUser retires every outstanding token so only the newest link works
+func (r *VerificationTokenRepository) InvalidateForUser(ctx context.Context, userID uuid.UUID) error {
+	query := `
+		UPDATE email_verification_tokens
+		SET used_at = NOW()
+		WHERE user_id = $1 AND used_at IS NULL
+	`
+
+	_, err := r.db.Exec(ctx, query, userID)
+	return err
+}
+
+// LatestCreatedAt returns when the user's newest token was issued, or nil if none exist
+func (r *VerificationTokenRepository) LatestCreatedAt(ctx context.Context, userID uuid.UUID) (*time.Time, error) {
+	var createdAt *time.Time
+	err := r.db.QueryRow(ctx, `SELECT MAX(created_at) FROM email_verification_tokens WHERE user_id = $1`, userID).
+		Scan(&createdAt)
+	return createdAt, err
+}
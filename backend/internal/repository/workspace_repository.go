@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -14,6 +15,10 @@ var (
 	ErrWorkspaceNotFound = errors.New("workspace not found")
 	ErrInviteCodeInvalid = errors.New("invite code invalid")
 	ErrAlreadyMember     = errors.New("user is already a member of this workspace")
+	ErrMemberNotFound    = errors.New("workspace member not found")
+	ErrInviteNotFound    = errors.New("workspace invite not found")
+	ErrInviteExpired     = errors.New("workspace invite has expired")
+	ErrInviteExhausted   = errors.New("workspace invite has no uses left")
 )
 
 type WorkspaceRepository struct {
@@ -44,12 +49,14 @@ func (r *WorkspaceRepository) Create(ctx context.Context, workspace *models.Work
 func (r *WorkspaceRepository) UpdateRow(ctx context.Context, workspace *models.Workspace) error {
 	query := `
 		UPDATE workspaces
-		SET name = $2, updated_at = NOW()
+		SET name = $2, default_style = $3, default_language = $4, updated_at = NOW()
 		WHERE id = $1
 		RETURNING updated_at
 	`
 
-	err := r.db.QueryRow(ctx, query, workspace.ID, workspace.Name).Scan(&workspace.UpdatedAt)
+	err := r.db.QueryRow(ctx, query,
+		workspace.ID, workspace.Name, workspace.DefaultStyle, workspace.DefaultLanguage,
+	).Scan(&workspace.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return ErrWorkspaceNotFound
@@ -62,14 +69,14 @@ func (r *WorkspaceRepository) UpdateRow(ctx context.Context, workspace *models.W
 
 func (r *WorkspaceRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Workspace, error) {
 	query := `
-		SELECT id, name, invite_code, owner_id, created_at, updated_at
+		SELECT id, name, invite_code, owner_id, default_style, default_language, created_at, updated_at
 		FROM workspaces
 		WHERE id = $1
 	`
 
 	ws := &models.Workspace{}
 	err := r.db.QueryRow(ctx, query, id).Scan(
-		&ws.ID, &ws.Name, &ws.InviteCode, &ws.OwnerID, &ws.CreatedAt, &ws.UpdatedAt,
+		&ws.ID, &ws.Name, &ws.InviteCode, &ws.OwnerID, &ws.DefaultStyle, &ws.DefaultLanguage, &ws.CreatedAt, &ws.UpdatedAt,
 	)
 
 	if err != nil {
@@ -84,14 +91,14 @@ func (r *WorkspaceRepository) GetByID(ctx context.Context, id uuid.UUID) (*model
 
 func (r *WorkspaceRepository) GetByInviteCode(ctx context.Context, code string) (*models.Workspace, error) {
 	query := `
-		SELECT id, name, invite_code, owner_id, created_at, updated_at
+		SELECT id, name, invite_code, owner_id, default_style, default_language, created_at, updated_at
 		FROM workspaces
 		WHERE invite_code = $1
 	`
 
 	ws := &models.Workspace{}
 	err := r.db.QueryRow(ctx, query, code).Scan(
-		&ws.ID, &ws.Name, &ws.InviteCode, &ws.OwnerID, &ws.CreatedAt, &ws.UpdatedAt,
+		&ws.ID, &ws.Name, &ws.InviteCode, &ws.OwnerID, &ws.DefaultStyle, &ws.DefaultLanguage, &ws.CreatedAt, &ws.UpdatedAt,
 	)
 
 	if err != nil {
@@ -148,7 +155,7 @@ func (r *WorkspaceRepository) GetMember(ctx context.Context, workspaceID, userID
 
 func (r *WorkspaceRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]*models.WorkspaceResponse, error) {
 	query := `
-		SELECT w.id, w.name, w.invite_code, wm.role, w.owner_id, w.created_at
+		SELECT w.id, w.name, w.invite_code, wm.role, w.owner_id, w.default_style, w.default_language, w.created_at
 		FROM workspaces w
 		JOIN workspace_members wm ON w.id = wm.workspace_id
 		WHERE wm.user_id = $1
@@ -165,7 +172,7 @@ func (r *WorkspaceRepository) ListByUserID(ctx context.Context, userID uuid.UUID
 	for rows.Next() {
 		var w models.WorkspaceResponse
 		var ownerID uuid.UUID
-		err := rows.Scan(&w.ID, &w.Name, &w.InviteCode, &w.Role, &ownerID, &w.CreatedAt)
+		err := rows.Scan(&w.ID, &w.Name, &w.InviteCode, &w.Role, &ownerID, &w.DefaultStyle, &w.DefaultLanguage, &w.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -182,3 +189,197 @@ func (r *WorkspaceRepository) GetMemberCount(ctx context.Context, workspaceID uu
 	err := r.db.QueryRow(ctx, query, workspaceID).Scan(&count)
 	return count, err
 }
+
+// ListMembers returns the workspace's members with their profiles, owner first
+func (r *WorkspaceRepository) ListMembers(ctx context.Context, workspaceID uuid.UUID) ([]*models.WorkspaceMemberResponse, error) {
+	query := `
+		SELECT u.id, u.email, u.full_name, u.avatar_url, wm.role, wm.joined_at
+		FROM workspace_members wm
+		JOIN users u ON u.id = wm.user_id
+		WHERE wm.workspace_id = $1
+		ORDER BY (wm.role = 'owner') DESC, wm.joined_at ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []*models.WorkspaceMemberResponse{}
+	for rows.Next() {
+		m := &models.WorkspaceMemberResponse{}
+		err := rows.Scan(&m.UserID, &m.Email, &m.FullName, &m.AvatarURL, &m.Role, &m.JoinedAt)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+
+	return members, rows.Err()
+}
+
+// UpdateMemberRole sets a member's role and returns the updated membership
+func (r *WorkspaceRepository) UpdateMemberRole(ctx context.Context, workspaceID, userID uuid.UUID, role string) (*models.WorkspaceMember, error) {
+	query := `
+		UPDATE workspace_members
+		SET role = $3
+		WHERE workspace_id = $1 AND user_id = $2
+		RETURNING id, workspace_id, user_id, role, joined_at
+	`
+
+	m := &models.WorkspaceMember{}
+	err := r.db.QueryRow(ctx, query, workspaceID, userID, role).Scan(
+		&m.ID, &m.WorkspaceID, &m.UserID, &m.Role, &m.JoinedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMemberNotFound
+		}
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// TransferOwnership hands the workspace from fromID to toID, who must already
+// be a member. The new owner's membership becomes "owner" and the previous
+// owner stays on as an admin. The workspace row is locked for the whole
+// transaction so two transfers cannot interleave.
+func (r *WorkspaceRepository) TransferOwnership(ctx context.Context, workspaceID, fromID, toID uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var ownerID uuid.UUID
+	err = tx.QueryRow(ctx, "SELECT owner_id FROM workspaces WHERE id = $1 FOR UPDATE", workspaceID).Scan(&ownerID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrWorkspaceNotFound
+		}
+		return err
+	}
+	if ownerID != fromID {
+		return ErrWorkspaceNotFound
+	}
+
+	result, err := tx.Exec(ctx, `
+		UPDATE workspace_members SET role = $3
+		WHERE workspace_id = $1 AND user_id = $2
+	`, workspaceID, toID, models.WorkspaceRoleOwner)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrMemberNotFound
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE workspace_members SET role = $3
+		WHERE workspace_id = $1 AND user_id = $2
+	`, workspaceID, fromID, models.WorkspaceRoleAdmin); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE workspaces SET owner_id = $2, updated_at = NOW()
+		WHERE id = $1
+	`, workspaceID, toID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *WorkspaceRepository) RemoveMember(ctx context.Context, workspaceID, userID uuid.UUID) error {
+	query := `DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2`
+
+	result, err := r.db.Exec(ctx, query, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrMemberNotFound
+	}
+
+	return nil
+}
+
+func (r *WorkspaceRepository) CreateInvite(ctx context.Context, invite *models.WorkspaceInvite) error {
+	query := `
+		INSERT INTO workspace_invites (workspace_id, created_by, token_hash, expires_at, max_uses)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, use_count, created_at
+	`
+
+	return r.db.QueryRow(ctx, query, invite.WorkspaceID, invite.CreatedBy, invite.TokenHash, invite.ExpiresAt, invite.MaxUses).
+		Scan(&invite.ID, &invite.UseCount, &invite.CreatedAt)
+}
+
+// JoinByInvite redeems an invite for userID. The invite row stays locked while
+// expiry and remaining uses are checked, the member is added and the use is
+// counted, so concurrent joins cannot exceed max_uses. An existing member
+// gets ErrAlreadyMember and does not use up the invite.
+func (r *WorkspaceRepository) JoinByInvite(ctx context.Context, tokenHash string, userID uuid.UUID) (*models.Workspace, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var invite models.WorkspaceInvite
+	err = tx.QueryRow(ctx, `
+		SELECT id, workspace_id, expires_at, max_uses, use_count
+		FROM workspace_invites
+		WHERE token_hash = $1
+		FOR UPDATE
+	`, tokenHash).Scan(&invite.ID, &invite.WorkspaceID, &invite.ExpiresAt, &invite.MaxUses, &invite.UseCount)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInviteNotFound
+		}
+		return nil, err
+	}
+
+	if invite.ExpiresAt != nil && !invite.ExpiresAt.After(time.Now()) {
+		return nil, ErrInviteExpired
+	}
+	if invite.MaxUses != nil && invite.UseCount >= *invite.MaxUses {
+		return nil, ErrInviteExhausted
+	}
+
+	result, err := tx.Exec(ctx, `
+		INSERT INTO workspace_members (workspace_id, user_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (workspace_id, user_id) DO NOTHING
+	`, invite.WorkspaceID, userID, models.WorkspaceRoleMember)
+	if err != nil {
+		return nil, err
+	}
+	if result.RowsAffected() == 0 {
+		return nil, ErrAlreadyMember
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE workspace_invites SET use_count = use_count + 1 WHERE id = $1", invite.ID); err != nil {
+		return nil, err
+	}
+
+	ws := &models.Workspace{}
+	err = tx.QueryRow(ctx, `
+		SELECT id, name, invite_code, owner_id, default_style, default_language, created_at, updated_at
+		FROM workspaces
+		WHERE id = $1
+	`, invite.WorkspaceID).Scan(&ws.ID, &ws.Name, &ws.InviteCode, &ws.OwnerID, &ws.DefaultStyle, &ws.DefaultLanguage, &ws.CreatedAt, &ws.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return ws, nil
+}
@@ -6,13 +6,15 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
-	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/gofiber/websocket/v2"
 	"github.com/nextpdf/backend/internal/config"
 	"github.com/nextpdf/backend/internal/database"
 	"github.com/nextpdf/backend/internal/handler"
 	"github.com/nextpdf/backend/internal/infrastructure"
 	"github.com/nextpdf/backend/internal/middleware"
 	"github.com/nextpdf/backend/internal/models"
+	"github.com/nextpdf/backend/internal/netguard"
 	"github.com/nextpdf/backend/internal/repository"
 	"github.com/nextpdf/backend/internal/service"
 	"github.com/nextpdf/backend/internal/storage"
@@ -24,38 +26,61 @@ func New(cfg *config.Config, db *database.DB, store *storage.Storage) *fiber.App
 	})
 
 	// Global middleware
-	app.Use(recover.New())
+	app.Use(requestid.New())
+	app.Use(middleware.RecoverMiddleware())
 	app.Use(logger.New())
+	app.Use(middleware.SecurityHeaders(cfg.Security, cfg.Server.IsProduction()))
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     cfg.CORSOrigins,
 		AllowMethods:     "GET,POST,PUT,PATCH,DELETE,OPTIONS",
 		AllowHeaders:     "Origin,Content-Type,Accept,Authorization,X-Requested-With",
 		AllowCredentials: true,
-		ExposeHeaders:    "X-RateLimit-Limit,X-RateLimit-Remaining,X-RateLimit-Reset,Content-Disposition",
+		ExposeHeaders:    "X-RateLimit-Limit,X-RateLimit-Remaining,X-RateLimit-Reset,Content-Disposition,X-Request-ID,X-Summary-Max-Versions",
 	}))
+	// Coarse per-IP ceiling; per-user and per-route buckets are attached to routes below
 	app.Use(middleware.RateLimitMiddleware(cfg.RateLimit))
 
 	// Initialize repositories
-	userRepo := repository.NewUserRepository(db.Pool)
+	// Shared by every repository that writes to files, so each can invalidate it
+	fileCache := repository.NewFileCache(cfg.Upload.MetadataCacheTTL)
+	userRepo := repository.NewUserRepository(db.Pool, fileCache)
 	tokenRepo := repository.NewTokenRepository(db.Pool)
 	sessionRepo := repository.NewSessionRepository(db.Pool)
-	folderRepo := repository.NewFolderRepository(db.Pool)
-	fileRepo := repository.NewFileRepository(db.Pool)
+	verificationRepo := repository.NewVerificationTokenRepository(db.Pool)
+	resetRepo := repository.NewPasswordResetRepository(db.Pool)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(db.Pool)
+	challengeRepo := repository.NewTwoFactorChallengeRepository(db.Pool)
+	folderRepo := repository.NewFolderRepository(db.Pool, fileCache)
+	fileRepo := repository.NewFileRepository(db.Pool, fileCache)
 	pendingUploadRepo := repository.NewPendingUploadRepository(db.Pool)
-	summaryRepo := repository.NewSummaryRepository(db.Pool)
+	summaryRepo := repository.NewSummaryRepository(db.Pool, fileCache, cfg.Summary.MaxVersions, cfg.Summary.StoreSourceText)
 
 	jobRepo := repository.NewProcessingJobRepository(db.Pool)
+	statsRepo := repository.NewStatsRepository(db.Pool)
 	workspaceRepo := repository.NewWorkspaceRepository(db.Pool)
+	fileShareRepo := repository.NewFileShareRepository(db.Pool)
+	customStyleRepo := repository.NewCustomStyleRepository(db.Pool)
 
 	// Initialize services
 	workspaceService := service.NewWorkspaceService(workspaceRepo)
-	authService := service.NewAuthService(userRepo, tokenRepo, sessionRepo, workspaceService, cfg.JWT)
-	userService := service.NewUserService(userRepo, sessionRepo)
-	folderService := service.NewFolderService(folderRepo, fileRepo, store)
-	fileService := service.NewFileService(fileRepo, folderRepo, pendingUploadRepo, summaryRepo, store, cfg.Upload)
-	aiClient := service.NewAIClient()
-	summaryService := service.NewSummaryService(summaryRepo, fileRepo, jobRepo, aiClient)
+	mailer := infrastructure.NewMailer(cfg.Mail)
+	authService := service.NewAuthService(userRepo, tokenRepo, sessionRepo, verificationRepo, resetRepo, loginAttemptRepo, challengeRepo, workspaceService, mailer, cfg.JWT, cfg.Mail, cfg.Lockout)
+	userService := service.NewUserService(userRepo, sessionRepo, store)
+	folderService := service.NewFolderService(folderRepo, fileRepo, workspaceService, store, cfg.Folder)
+	aiLimiter := service.NewAILimiter(cfg.AI.MaxConcurrency, cfg.AI.QueueTimeout)
+	aiTransport := service.NewAITransport(cfg.AI)
+	aiClient := service.NewAIClient(cfg.AI, aiLimiter, aiTransport)
+	languages := service.NewLanguages(cfg.Summary.Languages)
+	summaryService := service.NewSummaryService(summaryRepo, fileRepo, workspaceRepo, jobRepo, statsRepo, aiClient, store, languages, customStyleRepo)
+	fileService := service.NewFileService(fileRepo, folderRepo, workspaceRepo, fileShareRepo, pendingUploadRepo, summaryRepo, summaryService, store, infrastructure.NewScanner(cfg.Scan), netguard.New(cfg.Outbound), cfg.Upload)
 	uploadService := service.NewUploadService(userRepo, pendingUploadRepo, store)
+	maintenanceService := service.NewMaintenanceService(fileRepo, store)
+	stopTrashPurge := maintenanceService.StartTrashPurge(cfg.Upload.TrashRetention)
+	app.Hooks().OnShutdown(func() error {
+		stopTrashPurge()
+		return nil
+	})
+	guestMetricsService := service.NewGuestMetricsService(statsRepo)
 
 	// Initialize infrastructure
 	rabbitMQ, err := infrastructure.NewRabbitMQClient(cfg.RabbitMQURL)
@@ -73,13 +98,22 @@ func New(cfg *config.Config, db *database.DB, store *storage.Storage) *fiber.App
 	authHandler := handler.NewAuthHandler(authService)
 	userHandler := handler.NewUserHandler(userService)
 	folderHandler := handler.NewFolderHandler(folderService, workspaceService)
-	fileHandler := handler.NewFileHandler(fileService, workspaceService, rabbitMQ)
+	fileHandler := handler.NewFileHandler(fileService, workspaceService, rabbitMQ, cfg.AI, aiLimiter, aiTransport, languages)
+	app.Hooks().OnShutdown(fileHandler.DrainStreamSaves)
+	app.Hooks().OnShutdown(fileService.DrainPageCounts)
 	summaryHandler := handler.NewSummaryHandler(summaryService)
 	uploadHandler := handler.NewUploadHandler(uploadService)
-	workspaceHandler := handler.NewWorkspaceHandler(workspaceService)
+	workspaceHandler := handler.NewWorkspaceHandler(workspaceService, languages)
 
 	// Auth middleware
 	authMiddleware := middleware.AuthMiddleware(authService)
+	// The only routes EventSource and WebSocket clients reach with ?token=
+	streamAuthMiddleware := middleware.AuthMiddleware(authService, "/summarize-stream", "/summarize-ws", "/events")
+
+	// Per-user buckets; each limiter instance is one shared budget
+	userLimit := middleware.RateLimitByUser(cfg.RateLimit.User)
+	generateLimit := middleware.RateLimitByUser(cfg.RateLimit.Generate)
+	guestSummarizeLimit := middleware.RateLimitByUser(cfg.RateLimit.GuestSummarize)
 
 	// Routes
 	api := app.Group("/api/v1")
@@ -94,58 +128,121 @@ func New(cfg *config.Config, db *database.DB, store *storage.Storage) *fiber.App
 	auth.Post("/register", authHandler.Register)
 	auth.Post("/login", authHandler.Login)
 	auth.Post("/refresh", authHandler.Refresh)
-	auth.Post("/logout", authMiddleware, authHandler.Logout)
-	auth.Post("/logout-all", authMiddleware, authHandler.LogoutAll)
-	auth.Get("/sessions", authMiddleware, userHandler.GetSessions)
-	auth.Delete("/sessions/:session_id", authMiddleware, userHandler.RevokeSession)
+	auth.Post("/logout", authMiddleware, userLimit, authHandler.Logout)
+	auth.Post("/logout-all", authMiddleware, userLimit, authHandler.LogoutAll)
+	auth.Post("/stream-token", authMiddleware, userLimit, authHandler.StreamToken)
+	auth.Post("/verify-email", authHandler.VerifyEmail)
+	auth.Post("/resend-verification", authMiddleware, userLimit, authHandler.ResendVerification)
+	auth.Post("/forgot-password", authHandler.ForgotPassword)
+	auth.Post("/reset-password", authHandler.ResetPassword)
+	auth.Post("/2fa/verify", authHandler.VerifyTwoFactor)
+	auth.Get("/sessions", authMiddleware, userLimit, userHandler.GetSessions)
+	auth.Delete("/sessions/:session_id", authMiddleware, userLimit, userHandler.RevokeSession)
 
 	// Workspace routes (protected)
-	workspaces := api.Group("/workspaces", authMiddleware)
+	workspaces := api.Group("/workspaces", authMiddleware, userLimit)
 	workspaces.Post("/", workspaceHandler.Create)
 	workspaces.Post("/join", workspaceHandler.Join)
+	workspaces.Post("/join-by-invite", workspaceHandler.JoinByInvite)
 	workspaces.Get("/", workspaceHandler.List)
+	workspaces.Get("/:id", workspaceHandler.Get)
 	workspaces.Get("/:id/members", workspaceHandler.GetMembers)
+	workspaces.Delete("/:id/members/:user_id", workspaceHandler.RemoveMember)
+	workspaces.Patch("/:id/members/:user_id/role", workspaceHandler.UpdateMemberRole)
+	workspaces.Post("/:id/transfer", workspaceHandler.TransferOwnership)
+	workspaces.Post("/:id/leave", workspaceHandler.Leave)
+	workspaces.Post("/:id/invites", workspaceHandler.CreateInvite)
+	workspaces.Get("/:id/folders/tree", folderHandler.GetWorkspaceTree)
 	workspaces.Patch("/:id", workspaceHandler.Update)
 
 	// User routes (protected)
-	api.Get("/me", authMiddleware, userHandler.GetMe)
-	api.Patch("/me", authMiddleware, userHandler.UpdateMe)
-	api.Patch("/me/password", authMiddleware, userHandler.ChangePassword)
+	api.Get("/me", authMiddleware, userLimit, userHandler.GetMe)
+	api.Patch("/me", authMiddleware, userLimit, userHandler.UpdateMe)
+	api.Delete("/me", authMiddleware, userLimit, userHandler.DeleteMe)
+	api.Patch("/me/password", authMiddleware, userLimit, userHandler.ChangePassword)
+	api.Post("/me/2fa/setup", authMiddleware, userLimit, authHandler.SetupTwoFactor)
+	api.Post("/me/2fa/enable", authMiddleware, userLimit, authHandler.EnableTwoFactor)
+	api.Get("/me/usage", authMiddleware, userLimit, fileHandler.GetUsage)
+	api.Get("/me/stats/summaries-timeseries", authMiddleware, userLimit, summaryHandler.GetTimeseries)
 
 	// Folder routes (protected)
-	folders := api.Group("/folders", authMiddleware)
+	folders := api.Group("/folders", authMiddleware, userLimit)
 	folders.Get("/tree", folderHandler.GetTree)
 	folders.Post("/", folderHandler.Create)
+	folders.Patch("/reorder", folderHandler.Reorder)
+	folders.Get("/:id/breadcrumbs", folderHandler.GetBreadcrumbs)
+	folders.Get("/:id/summary-stats", folderHandler.GetSummaryStats)
 	folders.Put("/:id", folderHandler.Update)
 	folders.Patch("/:id/move", folderHandler.Move)
+	folders.Post("/:id/duplicate", folderHandler.Duplicate)
 	folders.Delete("/:id", folderHandler.Delete)
 
 	// File routes (protected)
-	files := api.Group("/files", authMiddleware)
+	files := api.Group("/files", streamAuthMiddleware, userLimit)
 	files.Get("/export", fileHandler.Export)
 	files.Get("/", fileHandler.List)
+	files.Get("/trash", fileHandler.ListTrash)
+	files.Get("/search", fileHandler.Search)
 	files.Get("/:id", fileHandler.GetByID)
 	files.Patch("/:id/move", fileHandler.Move)
 	files.Patch("/:id/rename", fileHandler.Rename)
+	files.Post("/:id/copy", fileHandler.Copy)
+	files.Patch("/:id/star", fileHandler.Star)
+	files.Patch("/:id/unstar", fileHandler.Unstar)
 	files.Delete("/:id", fileHandler.Delete)
+	files.Post("/:id/restore", fileHandler.Restore)
+	files.Delete("/:id/purge", fileHandler.Purge)
+	files.Post("/batch-get", fileHandler.BatchGet)
+	files.Post("/bulk-move", fileHandler.BulkMove)
 	files.Post("/upload/presign", fileHandler.Presign)
 	files.Post("/upload/confirm", fileHandler.ConfirmUpload)
-	files.Post("/:id/summarize-stream", fileHandler.SummarizeStream)
-	files.Post("/:id/summarize-async", fileHandler.SummarizeAsync)
+	files.Post("/upload/multipart/init", fileHandler.InitMultipartUpload)
+	files.Post("/upload/multipart/part-url", fileHandler.GetMultipartPartURL)
+	files.Post("/upload/multipart/complete", fileHandler.CompleteMultipartUpload)
+	files.Post("/import-urls", fileHandler.ImportURLs)
+	files.Get("/uploads/pending", fileHandler.ListPendingUploads)
+	files.Post("/page-count/backfill", fileHandler.BackfillPageCounts)
+	files.Delete("/uploads/:upload_id", fileHandler.AbandonUpload)
+	files.Post("/:id/summarize-stream", generateLimit, fileHandler.SummarizeStream)
+	files.Post("/:id/summarize-async", generateLimit, fileHandler.SummarizeAsync)
+	files.Get("/:id/summarize-ws", generateLimit, fileHandler.SummarizeWSUpgrade, websocket.New(fileHandler.SummarizeWS))
 	files.Get("/:id/events", fileHandler.SubscribeEvents)
 	files.Get("/:id/download", fileHandler.GetDownloadURL)
+	files.Get("/:id/bundle", fileHandler.Bundle)
+	files.Get("/:id/checksum", fileHandler.GetChecksum)
+	files.Get("/:id/workspaces", fileHandler.ListWorkspaces)
+	files.Post("/:id/workspaces", fileHandler.ShareToWorkspace)
+	files.Delete("/:id/workspaces/:workspace_id", fileHandler.UnshareFromWorkspace)
+	files.Get("/:id/estimate", summaryHandler.Estimate)
+	files.Get("/:id/summaries/latest", summaryHandler.GetLatest)
 
 	// Summary routes (protected)
-	summaries := api.Group("/summaries", authMiddleware)
+	summaries := api.Group("/summaries", authMiddleware, userLimit)
 	summaries.Get("/:file_id", summaryHandler.GetByFileID)
+	summaries.Get("/:file_id/latest", summaryHandler.GetLatest)
 	summaries.Get("/:file_id/history", summaryHandler.GetHistory)
-	summaries.Post("/:file_id/generate", summaryHandler.Generate)
+	summaries.Get("/:file_id/source-text", summaryHandler.GetSourceText)
+	summaries.Get("/:file_id/export", summaryHandler.Export)
+	summaries.Get("/:file_id/diff", summaryHandler.Diff)
+	summaries.Post("/:file_id/versions/:version/set-current", summaryHandler.SetCurrentVersion)
+	summaries.Post("/:file_id/feedback", summaryHandler.SubmitFeedback)
+	summaries.Post("/:file_id/generate", generateLimit, summaryHandler.Generate)
+	summaries.Post("/:file_id/resume", generateLimit, summaryHandler.Resume)
 
-	// Summary styles (protected)
-	api.Get("/summary-styles", authMiddleware, summaryHandler.GetStyles)
+	// Summary styles: public for the landing page, authenticated alias kept for existing clients
+	api.Get("/styles", summaryHandler.GetStyles)
+	api.Get("/summary-styles", authMiddleware, userLimit, summaryHandler.GetStyles)
+	customStyles := api.Group("/summary-styles/custom", authMiddleware, userLimit)
+	customStyles.Get("/", summaryHandler.ListCustomStyles)
+	customStyles.Post("/", summaryHandler.CreateCustomStyle)
+	customStyles.Patch("/:id", summaryHandler.UpdateCustomStyle)
+	customStyles.Delete("/:id", summaryHandler.DeleteCustomStyle)
+	api.Get("/summary-models", authMiddleware, userLimit, summaryHandler.GetModels)
+	// Public too, since guest summaries take the same languages
+	api.Get("/summary-languages", summaryHandler.GetLanguages)
 
 	// Upload routes (protected) - Avatar
-	uploads := api.Group("/uploads", authMiddleware)
+	uploads := api.Group("/uploads", authMiddleware, userLimit)
 	uploads.Post("/avatar/presign", uploadHandler.AvatarPresign)
 	uploads.Post("/avatar/confirm", uploadHandler.AvatarConfirm)
 
@@ -154,11 +251,20 @@ func New(cfg *config.Config, db *database.DB, store *storage.Storage) *fiber.App
 	internal := api.Group("/internal")
 	internal.Post("/summaries/callback", internalHandler.SummaryCallback)
 
+	// Admin routes (protected, restricted to ADMIN_EMAILS)
+	adminHandler := handler.NewAdminHandler(summaryService, fileService, maintenanceService, guestMetricsService)
+	admin := api.Group("/admin", authMiddleware, userLimit, middleware.AdminMiddleware(userService, cfg.AdminEmails))
+	admin.Get("/summaries/:id/debug", adminHandler.GetSummaryDebug)
+	admin.Post("/storage/reconcile", adminHandler.ReconcileStorage)
+	admin.Post("/files/page-count/backfill", adminHandler.BackfillPageCounts)
+	admin.Get("/guest-metrics", adminHandler.GetGuestMetrics)
+	admin.Get("/cache/files", adminHandler.GetFileCacheStats)
+
 	// Guest routes (public - for trying the service without auth)
-	guestHandler := handler.NewGuestHandler()
+	guestHandler := handler.NewGuestHandler(cfg.AI, aiLimiter, aiTransport, guestMetricsService, languages)
 	guest := api.Group("/guest")
-	guest.Post("/summarize", guestHandler.Summarize)
-	guest.Post("/summarize-stream", guestHandler.SummarizeStream)
+	guest.Post("/summarize", guestSummarizeLimit, guestHandler.Summarize)
+	guest.Post("/summarize-stream", guestSummarizeLimit, guestHandler.SummarizeStream)
 
 	return app
 }
@@ -5,35 +5,59 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
-	"os"
-	"time"
+	"net/textproto"
 
 	"github.com/google/uuid"
+	"github.com/nextpdf/backend/internal/config"
 	"github.com/nextpdf/backend/internal/models"
 )
 
 type AIClient struct {
 	baseURL    string
 	httpClient *http.Client
+	// syncClient is used for /summarize-sync, which blocks until the summary is done
+	syncClient *http.Client
+	limiter    *AILimiter
+	models     []string
 }
 
-func NewAIClient() *AIClient {
-	baseURL := os.Getenv("AI_SERVICE_URL")
-	if baseURL == "" {
-		baseURL = "http://localhost:8000"
-	}
-
+func NewAIClient(cfg config.AIConfig, limiter *AILimiter, transport http.RoundTripper) *AIClient {
 	return &AIClient{
-		baseURL: baseURL,
+		baseURL: cfg.ServiceURL,
+		limiter: limiter,
+		models:  cfg.Models,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Transport: transport,
+			Timeout:   cfg.RequestTimeout,
+		},
+		syncClient: &http.Client{
+			Transport: transport,
+			Timeout:   cfg.StreamTimeout,
 		},
 	}
 }
 
-// RequestSummary sends a request to the AI service to generate a summary
-func (c *AIClient) RequestSummary(ctx context.Context, fileID uuid.UUID, storagePath string, style models.SummaryStyle, customInstructions *string, language string) error {
+// Models returns the configured models a summary request may pick
+func (c *AIClient) Models() []string {
+	return c.models
+}
+
+// AllowsModel reports whether model is on the configured allowlist
+func (c *AIClient) AllowsModel(model string) bool {
+	for _, m := range c.models {
+		if m == model {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestSummary sends a request to the AI service to generate a summary.
+// An empty model leaves the choice to the AI service.
+func (c *AIClient) RequestSummary(ctx context.Context, fileID uuid.UUID, storagePath string, style models.SummaryStyle, customInstructions *string, language, model, continueFrom string, includeSourceText bool, hints DocumentHints) error {
 	// Default to English if not specified
 	if language == "" {
 		language = "en"
@@ -45,6 +69,11 @@ func (c *AIClient) RequestSummary(ctx context.Context, fileID uuid.UUID, storage
 		Style:              string(style),
 		CustomInstructions: customInstructions,
 		Language:           language,
+		TitleHint:          hints.Title,
+		LanguageHint:       hints.Language,
+		Model:              model,
+		ContinueFrom:       continueFrom,
+		IncludeSourceText:  includeSourceText,
 	}
 
 	jsonData, err := json.Marshal(request)
@@ -59,6 +88,11 @@ func (c *AIClient) RequestSummary(ctx context.Context, fileID uuid.UUID, storage
 
 	req.Header.Set("Content-Type", "application/json")
 
+	if err := c.limiter.Acquire(ctx); err != nil {
+		return err
+	}
+	defer c.limiter.Release()
+
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to send request to AI service: %w", err)
@@ -72,6 +106,67 @@ func (c *AIClient) RequestSummary(ctx context.Context, fileID uuid.UUID, storage
 	return nil
 }
 
+// SummarizeSync runs a summary synchronously and returns the AI service's raw
+// JSON response. Nothing is persisted, which makes it suitable for dry runs.
+func (c *AIClient) SummarizeSync(ctx context.Context, filename string, content io.Reader, style models.SummaryStyle, customInstructions *string, language, model string, hints DocumentHints) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	partHeader := make(textproto.MIMEHeader)
+	partHeader.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, filename))
+	partHeader.Set("Content-Type", "application/pdf")
+
+	part, err := writer.CreatePart(partHeader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create multipart request: %w", err)
+	}
+	if _, err := io.Copy(part, content); err != nil {
+		return nil, fmt.Errorf("failed to write file content: %w", err)
+	}
+
+	_ = writer.WriteField("style", string(style))
+	_ = writer.WriteField("language", language)
+	if customInstructions != nil && *customInstructions != "" {
+		_ = writer.WriteField("custom_instructions", *customInstructions)
+	}
+	if model != "" {
+		_ = writer.WriteField("model", model)
+	}
+	writeHintFields(writer, hints)
+	writer.Close()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/summarize-sync", &buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	if err := c.limiter.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer c.limiter.Release()
+
+	resp, err := c.syncClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request to AI service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read AI service response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %d", ErrAIServiceStatus, resp.StatusCode)
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("AI service returned invalid JSON")
+	}
+
+	return body, nil
+}
+
 // HealthCheck checks if the AI service is healthy
 func (c *AIClient) HealthCheck(ctx context.Context) error {
 	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/health", nil)
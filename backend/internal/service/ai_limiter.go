@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrAIBusy = errors.New("AI service is at capacity, please retry shortly")
+
+// AILimiter bounds the number of concurrent outbound requests to the AI
+// service. Callers wait up to queueTimeout for a slot before giving up.
+type AILimiter struct {
+	slots        chan struct{}
+	queueTimeout time.Duration
+}
+
+func NewAILimiter(maxConcurrency int, queueTimeout time.Duration) *AILimiter {
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+	return &AILimiter{
+		slots:        make(chan struct{}, maxConcurrency),
+		queueTimeout: queueTimeout,
+	}
+}
+
+// Acquire takes a slot, returning ErrAIBusy if none frees up in time.
+// Every successful Acquire must be paired with Release.
+func (l *AILimiter) Acquire(ctx context.Context) error {
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return ErrAIBusy
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *AILimiter) Release() {
+	<-l.slots
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/nextpdf/backend/internal/models"
+)
+
+var ErrAIServiceStatus = errors.New("AI service returned an unexpected status")
+
+// AIStreamEventType identifies the kind of event emitted by the AI service stream
+type AIStreamEventType string
+
+const (
+	AIStreamLog    AIStreamEventType = "log"
+	AIStreamToken  AIStreamEventType = "token"
+	AIStreamError  AIStreamEventType = "error"
+	AIStreamResult AIStreamEventType = "result"
+	// AIStreamSource carries the extracted text when it was asked for; it is
+	// kept with the result and not relayed to clients
+	AIStreamSource AIStreamEventType = "source_text"
+)
+
+// AIStreamEvent is a single parsed SSE event from /summarize-stream.
+// Data holds the original JSON payload so handlers can relay it unchanged.
+type AIStreamEvent struct {
+	Type       AIStreamEventType
+	Data       []byte
+	Log        string
+	Token      string
+	Error      string
+	Result     *models.SummaryCallbackRequest
+	SourceText string
+}
+
+// AIStreamRequest describes a PDF to summarize over the streaming endpoint
+type AIStreamRequest struct {
+	Filename           string
+	Content            io.Reader
+	Style              string
+	Language           string
+	CustomInstructions string
+	Hints              DocumentHints
+	// IncludeSourceText asks for an AIStreamSource event with the extracted text
+	IncludeSourceText bool
+}
+
+// AIStreamClient talks to the AI service's SSE summarization endpoint
+type AIStreamClient struct {
+	baseURL    string
+	httpClient *http.Client
+	limiter    *AILimiter
+}
+
+func NewAIStreamClient(baseURL string, timeout time.Duration, limiter *AILimiter, transport http.RoundTripper) *AIStreamClient {
+	return &AIStreamClient{
+		baseURL:    baseURL,
+		httpClient: &http.Client{Transport: transport, Timeout: timeout},
+		limiter:    limiter,
+	}
+}
+
+// Stream posts the PDF and returns a channel of typed events. The channel is
+// closed when the AI service ends the stream or ctx is cancelled. A limiter
+// slot is held for the lifetime of the stream.
+func (c *AIStreamClient) Stream(ctx context.Context, r AIStreamRequest) (<-chan AIStreamEvent, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	_ = writer.WriteField("style", r.Style)
+	_ = writer.WriteField("language", r.Language)
+	if r.CustomInstructions != "" {
+		_ = writer.WriteField("custom_instructions", r.CustomInstructions)
+	}
+	writeHintFields(writer, r.Hints)
+	if r.IncludeSourceText {
+		_ = writer.WriteField("include_source_text", "true")
+	}
+
+	// The AI service validates the part's Content-Type, so set it explicitly
+	partHeader := make(textproto.MIMEHeader)
+	partHeader.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, r.Filename))
+	partHeader.Set("Content-Type", "application/pdf")
+
+	part, err := writer.CreatePart(partHeader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create multipart request: %w", err)
+	}
+	if _, err := io.Copy(part, r.Content); err != nil {
+		return nil, fmt.Errorf("failed to write file content: %w", err)
+	}
+	writer.Close()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/summarize-stream", &buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	if err := c.limiter.Acquire(ctx); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		c.limiter.Release()
+		return nil, fmt.Errorf("failed to connect to AI service: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		c.limiter.Release()
+		return nil, fmt.Errorf("%w: %d", ErrAIServiceStatus, resp.StatusCode)
+	}
+
+	events := make(chan AIStreamEvent)
+	go func() {
+		defer c.limiter.Release()
+		defer close(events)
+		defer resp.Body.Close()
+
+		readSSE(resp.Body, func(name string, data []byte) bool {
+			event, ok := parseAIStreamEvent(name, data)
+			if !ok {
+				return true
+			}
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	return events, nil
+}
+
+// readSSE splits an event stream into (event name, data) pairs, joining
+// multi-line data fields as the SSE spec requires. emit returns false to stop.
+func readSSE(r io.Reader, emit func(name string, data []byte) bool) {
+	reader := bufio.NewReader(r)
+	var name string
+	var data []string
+
+	flush := func() bool {
+		if len(data) == 0 {
+			name = ""
+			return true
+		}
+		payload := strings.Join(data, "\n")
+		eventName := name
+		name, data = "", data[:0]
+		return emit(eventName, []byte(payload))
+	}
+
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case line == "":
+			if !flush() {
+				return
+			}
+		case strings.HasPrefix(line, "event:"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+
+		if err != nil {
+			flush()
+			return
+		}
+	}
+}
+
+// parseAIStreamEvent classifies a payload by its SSE event name when present,
+// otherwise by its top-level keys. It never matches on substrings, so a token
+// or log line that merely mentions "result" is not mistaken for the result.
+func parseAIStreamEvent(name string, data []byte) (AIStreamEvent, bool) {
+	var payload struct {
+		Log        *string         `json:"log"`
+		Token      *string         `json:"token"`
+		Error      *string         `json:"error"`
+		Result     json.RawMessage `json:"result"`
+		SourceText *string         `json:"source_text"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return AIStreamEvent{}, false
+	}
+
+	// An explicit "event: result" may carry the summary as the whole payload
+	if AIStreamEventType(name) == AIStreamResult && len(payload.Result) == 0 {
+		payload.Result = data
+	}
+
+	event := AIStreamEvent{Data: data}
+	switch {
+	case payload.Error != nil:
+		event.Type = AIStreamError
+		event.Error = *payload.Error
+	case len(payload.Result) > 0 && payload.Result[0] == '{':
+		var result models.SummaryCallbackRequest
+		if err := json.Unmarshal(payload.Result, &result); err != nil || result.Content == "" {
+			return AIStreamEvent{}, false
+		}
+		event.Type = AIStreamResult
+		event.Result = &result
+	case payload.SourceText != nil:
+		event.Type = AIStreamSource
+		event.SourceText = *payload.SourceText
+	case payload.Token != nil:
+		event.Type = AIStreamToken
+		event.Token = *payload.Token
+	case payload.Log != nil:
+		event.Type = AIStreamLog
+		event.Log = *payload.Log
+	default:
+		return AIStreamEvent{}, false
+	}
+
+	return event, true
+}
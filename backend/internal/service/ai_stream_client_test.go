@@ -0,0 +1,60 @@
+package service
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseAIStreamEventIgnoresResultInText(t *testing.T) {
+	stream := strings.Join([]string{
+		`data: {"log": "Waiting for the result of extraction"}`,
+		``,
+		`data: {"token": "The \"result\": {\"content\": \"x\"} of the survey"}`,
+		``,
+		`data: {"token": "result"}`,
+		``,
+		`event: result`,
+		`data: {"content": "Final summary", "title": "Survey"}`,
+		``,
+	}, "\n")
+
+	var types []AIStreamEventType
+	var results int
+	readSSE(strings.NewReader(stream), func(name string, data []byte) bool {
+		event, ok := parseAIStreamEvent(name, data)
+		if !ok {
+			t.Fatalf("event %s %s was not parsed", name, data)
+		}
+		types = append(types, event.Type)
+		if event.Type == AIStreamResult {
+			results++
+			if event.Result.Content != "Final summary" {
+				t.Errorf("result content = %q", event.Result.Content)
+			}
+		}
+		return true
+	})
+
+	want := []AIStreamEventType{AIStreamLog, AIStreamToken, AIStreamToken, AIStreamResult}
+	if !slices.Equal(types, want) {
+		t.Errorf("types = %v, want %v", types, want)
+	}
+	if results != 1 {
+		t.Errorf("%d results, want 1", results)
+	}
+}
+
+func TestParseAIStreamEventResultKey(t *testing.T) {
+	event, ok := parseAIStreamEvent("", []byte(`{"result": {"content": "Summary"}}`))
+	if !ok || event.Type != AIStreamResult || event.Result.Content != "Summary" {
+		t.Errorf("result key = %v %+v", ok, event)
+	}
+
+	// A "result" that is not an object, or has no content, is not a result
+	for _, data := range []string{`{"result": "done"}`, `{"result": {"content": ""}}`} {
+		if event, ok := parseAIStreamEvent("", []byte(data)); ok && event.Type == AIStreamResult {
+			t.Errorf("%s parsed as a result", data)
+		}
+	}
+}
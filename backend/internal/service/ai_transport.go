@@ -0,0 +1,117 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/nextpdf/backend/internal/config"
+)
+
+// aiRetryBaseDelay is the first backoff step; each further retry doubles it
+const aiRetryBaseDelay = 200 * time.Millisecond
+
+// NewAITransport builds the round-tripper shared by every HTTP client that
+// talks to the AI service, so connections are pooled in one place. Idempotent
+// requests are retried on network errors and gateway-style statuses; every
+// other request, summarization POSTs included, only when the connection could
+// not be made, since the AI service then never saw it.
+func NewAITransport(cfg config.AIConfig) http.RoundTripper {
+	base := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   cfg.DialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:   true,
+		MaxIdleConns:        cfg.MaxIdleConnsPerHost * 2,
+		MaxIdleConnsPerHost: cfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:     cfg.IdleConnTimeout,
+		TLSHandshakeTimeout: cfg.DialTimeout,
+	}
+
+	return &retryTransport{base: base, maxRetries: cfg.MaxRetries}
+}
+
+// retryTransport retries requests that are safe to repeat, and any request
+// whose connection failed before it was sent
+type retryTransport struct {
+	base       http.RoundTripper
+	maxRetries int
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.maxRetries <= 0 || !hasReplayableBody(req) {
+		return t.base.RoundTrip(req)
+	}
+	idempotent := isIdempotentRequest(req)
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if attempt >= t.maxRetries {
+			return resp, err
+		}
+		if idempotent && !shouldRetry(resp, err) || !idempotent && !isDialError(err) {
+			return resp, err
+		}
+		if resp != nil {
+			// Drain so the connection goes back to the pool
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		timer := time.NewTimer(aiRetryBaseDelay << attempt)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// hasReplayableBody reports whether the request can be sent again: it has no
+// body or one that GetBody recreates
+func hasReplayableBody(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// isIdempotentRequest mirrors net/http's own rule: safe methods, or an
+// explicit Idempotency-Key
+func isIdempotentRequest(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// isDialError reports whether err is a failure to connect, so nothing of the
+// request reached the server. net/http already resends requests that fail on
+// a reused connection before anything was written.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
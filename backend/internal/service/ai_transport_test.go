@@ -0,0 +1,89 @@
+package service
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// scriptedTransport answers each round trip with the next scripted outcome
+// and records the bodies it was sent
+type scriptedTransport struct {
+	outcomes []func() (*http.Response, error)
+	bodies   []string
+}
+
+func (s *scriptedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		body, _ := io.ReadAll(req.Body)
+		s.bodies = append(s.bodies, string(body))
+	}
+	next := s.outcomes[0]
+	if len(s.outcomes) > 1 {
+		s.outcomes = s.outcomes[1:]
+	}
+	return next()
+}
+
+func dialFailure() (*http.Response, error) {
+	return nil, &net.OpError{Op: "dial", Net: "tcp", Err: io.ErrUnexpectedEOF}
+}
+
+func readFailure() (*http.Response, error) {
+	return nil, &net.OpError{Op: "read", Net: "tcp", Err: io.ErrUnexpectedEOF}
+}
+
+func respondWith(code int) func() (*http.Response, error) {
+	return func() (*http.Response, error) {
+		return &http.Response{StatusCode: code, Body: io.NopCloser(strings.NewReader(""))}, nil
+	}
+}
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		outcomes []func() (*http.Response, error)
+		attempts int
+		want     int
+	}{
+		{"post retried after dial error", http.MethodPost, []func() (*http.Response, error){dialFailure, respondWith(http.StatusOK)}, 2, http.StatusOK},
+		{"post not retried after read error", http.MethodPost, []func() (*http.Response, error){readFailure, respondWith(http.StatusOK)}, 1, 0},
+		{"post not retried on 503", http.MethodPost, []func() (*http.Response, error){respondWith(http.StatusServiceUnavailable), respondWith(http.StatusOK)}, 1, http.StatusServiceUnavailable},
+		{"get retried on 503", http.MethodGet, []func() (*http.Response, error){respondWith(http.StatusServiceUnavailable), respondWith(http.StatusOK)}, 2, http.StatusOK},
+		{"retries are bounded", http.MethodPost, []func() (*http.Response, error){dialFailure}, 3, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := &scriptedTransport{outcomes: tt.outcomes}
+			transport := &retryTransport{base: base, maxRetries: 2}
+
+			var body io.Reader
+			if tt.method == http.MethodPost {
+				body = bytes.NewBufferString(`{"file_id":"x"}`)
+			}
+			req, _ := http.NewRequest(tt.method, "http://ai.local/summarize", body)
+
+			resp, err := transport.RoundTrip(req)
+			got := 0
+			if err == nil {
+				got = resp.StatusCode
+			}
+			if got != tt.want {
+				t.Errorf("status %d (err %v), want %d", got, err, tt.want)
+			}
+			if attempts := len(base.bodies); tt.method == http.MethodPost && attempts != tt.attempts {
+				t.Errorf("%d attempts, want %d", attempts, tt.attempts)
+			}
+			for _, b := range base.bodies {
+				if b != `{"file_id":"x"}` {
+					t.Errorf("retry sent body %q", b)
+				}
+			}
+		})
+	}
+}
@@ -5,44 +5,108 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
+	"log"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/nextpdf/backend/internal/config"
+	"github.com/nextpdf/backend/internal/infrastructure"
 	"github.com/nextpdf/backend/internal/models"
 	"github.com/nextpdf/backend/internal/repository"
 	"golang.org/x/crypto/bcrypt"
 )
 
 var (
-	ErrInvalidCredentials = errors.New("invalid email or password")
-	ErrAccountDisabled    = errors.New("account is disabled")
-	ErrInvalidToken       = errors.New("invalid token")
-	ErrTokenExpired       = errors.New("token has expired")
+	ErrInvalidCredentials   = errors.New("invalid email or password")
+	ErrAccountDisabled      = errors.New("account is disabled")
+	ErrInvalidToken         = errors.New("invalid token")
+	ErrTokenExpired         = errors.New("token has expired")
+	ErrTokenReuse           = errors.New("refresh token reuse detected")
+	ErrAccountLocked        = errors.New("too many failed login attempts")
+	ErrEmailAlreadyVerified = errors.New("email is already verified")
+	ErrVerificationCooldown = errors.New("a verification email was sent recently")
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotSetUp    = errors.New("two-factor authentication has not been set up")
 )
 
+const (
+	verificationTokenTTL  = 24 * time.Hour
+	passwordResetTokenTTL = time.Hour
+	twoFactorChallengeTTL = 5 * time.Minute
+	// Wrong codes one login challenge tolerates before it is burned
+	maxChallengeAttempts = 5
+
+	// A token presented again this soon after rotation is treated as a benign
+	// race (two tabs refreshing at once) rather than theft
+	refreshReuseGrace = 10 * time.Second
+
+	totpIssuer = "NextPDF"
+	// JWT "typ" of 2FA challenges, which must never pass as access tokens
+	challengeTokenType = "2fa_challenge"
+	// JWT "typ" of stream tokens, only accepted in the query string of
+	// streaming endpoints and never as access tokens
+	streamTokenType = "stream"
+	streamTokenTTL  = 60 * time.Second
+	// Minimum gap between verification emails to the same user
+	verificationResendCooldown = time.Minute
+)
+
+// AccountLockedError is returned while an email is locked out. It matches
+// ErrAccountLocked with errors.Is and carries when the lockout ends.
+type AccountLockedError struct {
+	Until time.Time
+}
+
+func (e *AccountLockedError) Error() string { return ErrAccountLocked.Error() }
+
+func (e *AccountLockedError) Unwrap() error { return ErrAccountLocked }
+
 type AuthService struct {
 	userRepo         *repository.UserRepository
 	tokenRepo        *repository.TokenRepository
 	sessionRepo      *repository.SessionRepository
+	verificationRepo *repository.VerificationTokenRepository
+	resetRepo        *repository.PasswordResetRepository
+	loginAttemptRepo *repository.LoginAttemptRepository
+	challengeRepo    *repository.TwoFactorChallengeRepository
 	workspaceService *WorkspaceService
+	mailer           infrastructure.Mailer
 	jwtConfig        config.JWTConfig
+	mailConfig       config.MailConfig
+	lockoutConfig    config.LockoutConfig
 }
 
 func NewAuthService(
 	userRepo *repository.UserRepository,
 	tokenRepo *repository.TokenRepository,
 	sessionRepo *repository.SessionRepository,
+	verificationRepo *repository.VerificationTokenRepository,
+	resetRepo *repository.PasswordResetRepository,
+	loginAttemptRepo *repository.LoginAttemptRepository,
+	challengeRepo *repository.TwoFactorChallengeRepository,
 	workspaceService *WorkspaceService,
+	mailer infrastructure.Mailer,
 	jwtConfig config.JWTConfig,
+	mailConfig config.MailConfig,
+	lockoutConfig config.LockoutConfig,
 ) *AuthService {
 	return &AuthService{
 		userRepo:         userRepo,
 		tokenRepo:        tokenRepo,
 		sessionRepo:      sessionRepo,
+		verificationRepo: verificationRepo,
+		resetRepo:        resetRepo,
+		loginAttemptRepo: loginAttemptRepo,
+		challengeRepo:    challengeRepo,
 		workspaceService: workspaceService,
+		mailer:           mailer,
 		jwtConfig:        jwtConfig,
+		mailConfig:       mailConfig,
+		lockoutConfig:    lockoutConfig,
 	}
 }
 
@@ -78,15 +142,179 @@ func (s *AuthService) Register(ctx context.Context, req *models.RegisterRequest)
 		return nil, err
 	}
 
+	// The account is usable either way; the user can ask for another email
+	if err := s.sendVerificationEmail(ctx, user); err != nil {
+		log.Printf("Failed to send verification email to user %s: %v", user.ID, err)
+	}
+
 	return user, nil
 }
 
+// SendVerificationEmail issues a fresh verification link, retiring older ones
+func (s *AuthService) SendVerificationEmail(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+
+	lastSent, err := s.verificationRepo.LatestCreatedAt(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if lastSent != nil && time.Since(*lastSent) < verificationResendCooldown {
+		return ErrVerificationCooldown
+	}
+
+	return s.sendVerificationEmail(ctx, user)
+}
+
+func (s *AuthService) sendVerificationEmail(ctx context.Context, user *models.User) error {
+	if err := s.verificationRepo.InvalidateForUser(ctx, user.ID); err != nil {
+		return err
+	}
+
+	token := uuid.New().String()
+	record := &models.EmailVerificationToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(verificationTokenTTL),
+	}
+	if err := s.verificationRepo.Create(ctx, record); err != nil {
+		return err
+	}
+
+	link := strings.TrimRight(s.mailConfig.AppURL, "/") + "/verify-email?token=" + url.QueryEscape(token)
+	body := "Welcome to NextPDF!\n\n" +
+		"Confirm your email address by opening the link below:\n\n" +
+		link + "\n\n" +
+		"The link expires in 24 hours. If you did not create an account, you can ignore this email.\n"
+
+	return s.mailer.Send(ctx, user.Email, "Verify your NextPDF email address", body)
+}
+
+// VerifyEmail redeems a verification token and marks the owner's email verified
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) error {
+	record, err := s.verificationRepo.Consume(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrTokenNotFound) {
+			return ErrInvalidToken
+		}
+		if errors.Is(err, repository.ErrTokenExpired) {
+			return ErrTokenExpired
+		}
+		return err
+	}
+
+	return s.userRepo.MarkEmailVerified(ctx, record.UserID)
+}
+
+// RequestPasswordReset emails a reset link if the address belongs to an active
+// account. It reports success either way so callers cannot probe for accounts.
+func (s *AuthService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+	if !user.IsActive {
+		return nil
+	}
+
+	if err := s.resetRepo.InvalidateForUser(ctx, user.ID); err != nil {
+		return err
+	}
+
+	token := uuid.New().String()
+	record := &models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(passwordResetTokenTTL),
+	}
+	if err := s.resetRepo.Create(ctx, record); err != nil {
+		return err
+	}
+
+	link := strings.TrimRight(s.mailConfig.AppURL, "/") + "/reset-password?token=" + url.QueryEscape(token)
+	body := "We received a request to reset your NextPDF password.\n\n" +
+		"Choose a new password by opening the link below:\n\n" +
+		link + "\n\n" +
+		"The link expires in 1 hour. If you did not ask for a reset, you can ignore this email.\n"
+
+	// Sent in the background so response time doesn't reveal whether the account exists
+	go func() {
+		sendCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := s.mailer.Send(sendCtx, user.Email, "Reset your NextPDF password", body); err != nil {
+			log.Printf("Failed to send password reset email to user %s: %v", user.ID, err)
+		}
+	}()
+
+	return nil
+}
+
+// ResetPassword redeems a reset token, sets the new password and signs the
+// user out everywhere by revoking all refresh tokens
+func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	record, err := s.resetRepo.Consume(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrTokenNotFound) {
+			return ErrInvalidToken
+		}
+		if errors.Is(err, repository.ErrTokenExpired) {
+			return ErrTokenExpired
+		}
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, record.UserID, string(hashedPassword)); err != nil {
+		return err
+	}
+
+	if err := s.resetRepo.InvalidateForUser(ctx, record.UserID); err != nil {
+		return err
+	}
+
+	// Proving control of the mailbox lifts any login lockout
+	user, err := s.userRepo.GetByID(ctx, record.UserID)
+	if err != nil {
+		return err
+	}
+	if err := s.loginAttemptRepo.Clear(ctx, lockoutKey(user.Email)); err != nil {
+		return err
+	}
+
+	_, err = s.tokenRepo.RevokeAllUserTokens(ctx, record.UserID)
+	return err
+}
+
 func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest, deviceInfo, ipAddress string) (*models.LoginResponse, string, error) {
+	key := lockoutKey(req.Email)
+	if s.lockoutConfig.MaxAttempts > 0 {
+		lockedUntil, err := s.loginAttemptRepo.GetLockedUntil(ctx, key)
+		if err != nil {
+			return nil, "", err
+		}
+		if lockedUntil != nil {
+			return nil, "", &AccountLockedError{Until: *lockedUntil}
+		}
+	}
+
 	// Get user by email
 	user, err := s.userRepo.GetByEmail(ctx, req.Email)
 	if err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
-			return nil, "", ErrInvalidCredentials
+			return nil, "", s.loginFailed(ctx, key)
 		}
 		return nil, "", err
 	}
@@ -98,9 +326,54 @@ func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest, devic
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		return nil, "", ErrInvalidCredentials
+		return nil, "", s.loginFailed(ctx, key)
+	}
+
+	if err := s.loginAttemptRepo.Clear(ctx, key); err != nil {
+		return nil, "", err
+	}
+
+	// With 2FA the password alone only earns a challenge for /auth/2fa/verify
+	if user.TOTPEnabled {
+		challenge, err := s.generateChallengeToken(ctx, user)
+		if err != nil {
+			return nil, "", err
+		}
+		return &models.LoginResponse{
+			ExpiresIn:         int(twoFactorChallengeTTL.Seconds()),
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+		}, "", nil
+	}
+
+	return s.issueSession(ctx, user, deviceInfo, ipAddress)
+}
+
+// lockoutKey normalizes an email so case variants share one failure counter
+func lockoutKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// loginFailed records a failed attempt and returns the error for the caller:
+// ErrInvalidCredentials, or an AccountLockedError once the limit is reached
+func (s *AuthService) loginFailed(ctx context.Context, key string) error {
+	if s.lockoutConfig.MaxAttempts <= 0 {
+		return ErrInvalidCredentials
 	}
 
+	lockedUntil, err := s.loginAttemptRepo.RecordFailure(ctx, key, s.lockoutConfig.MaxAttempts, s.lockoutConfig.Window)
+	if err != nil {
+		return err
+	}
+	if lockedUntil != nil {
+		return &AccountLockedError{Until: *lockedUntil}
+	}
+
+	return ErrInvalidCredentials
+}
+
+// issueSession creates a refresh token and session for a fully authenticated user
+func (s *AuthService) issueSession(ctx context.Context, user *models.User, deviceInfo, ipAddress string) (*models.LoginResponse, string, error) {
 	// Generate tokens
 	accessToken, err := s.generateAccessToken(user)
 	if err != nil {
@@ -112,10 +385,11 @@ func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest, devic
 		return nil, "", err
 	}
 
-	// Store refresh token
+	// Store refresh token; each login starts a new rotation family
 	tokenRecord := &models.RefreshToken{
 		UserID:     user.ID,
 		TokenHash:  refreshTokenHash,
+		FamilyID:   uuid.New(),
 		DeviceInfo: &deviceInfo,
 		IPAddress:  &ipAddress,
 		ExpiresAt:  time.Now().Add(s.jwtConfig.RefreshExpiryDays),
@@ -145,6 +419,195 @@ func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest, devic
 	}, refreshToken, nil
 }
 
+// VerifyTwoFactor exchanges a login challenge plus a TOTP or recovery code
+// for a real session. A challenge is redeemed at most once and burned after
+// maxChallengeAttempts wrong codes; wrong codes also count towards a per-user
+// lockout that a fresh password login does not reset.
+func (s *AuthService) VerifyTwoFactor(ctx context.Context, req *models.TwoFactorVerifyRequest, deviceInfo, ipAddress string) (*models.LoginResponse, string, error) {
+	userID, challengeID, err := s.parseChallengeToken(req.ChallengeToken)
+	if err != nil {
+		return nil, "", err
+	}
+
+	active, err := s.challengeRepo.IsActive(ctx, challengeID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !active {
+		return nil, "", ErrInvalidToken
+	}
+
+	key := twoFactorLockoutKey(userID)
+	if s.lockoutConfig.MaxAttempts > 0 {
+		lockedUntil, err := s.loginAttemptRepo.GetLockedUntil(ctx, key)
+		if err != nil {
+			return nil, "", err
+		}
+		if lockedUntil != nil {
+			return nil, "", &AccountLockedError{Until: *lockedUntil}
+		}
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, "", ErrInvalidToken
+		}
+		return nil, "", err
+	}
+	if !user.IsActive {
+		return nil, "", ErrAccountDisabled
+	}
+	if !user.TOTPEnabled {
+		return nil, "", ErrInvalidToken
+	}
+
+	if req.RecoveryCode != "" {
+		ok, err := s.userRepo.UseRecoveryCode(ctx, user.ID, hashToken(normalizeRecoveryCode(req.RecoveryCode)))
+		if err != nil {
+			return nil, "", err
+		}
+		if !ok {
+			return nil, "", s.twoFactorFailed(ctx, challengeID, key)
+		}
+	} else if err := s.checkTOTP(ctx, user.ID, req.Code); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			return nil, "", s.twoFactorFailed(ctx, challengeID, key)
+		}
+		return nil, "", err
+	}
+
+	consumed, err := s.challengeRepo.Consume(ctx, challengeID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !consumed {
+		return nil, "", ErrInvalidToken
+	}
+	if err := s.loginAttemptRepo.Clear(ctx, key); err != nil {
+		return nil, "", err
+	}
+
+	return s.issueSession(ctx, user, deviceInfo, ipAddress)
+}
+
+// twoFactorLockoutKey is the login_attempts key for a user's wrong second-factor
+// codes, kept apart from the email key that a correct password clears
+func twoFactorLockoutKey(userID uuid.UUID) string {
+	return "2fa:" + userID.String()
+}
+
+// twoFactorFailed counts a wrong code against the challenge and the user, and
+// returns ErrInvalidTwoFactorCode or, once the user is locked, an AccountLockedError
+func (s *AuthService) twoFactorFailed(ctx context.Context, challengeID uuid.UUID, key string) error {
+	if err := s.challengeRepo.RecordFailure(ctx, challengeID, maxChallengeAttempts); err != nil {
+		return err
+	}
+	if s.lockoutConfig.MaxAttempts <= 0 {
+		return ErrInvalidTwoFactorCode
+	}
+
+	lockedUntil, err := s.loginAttemptRepo.RecordFailure(ctx, key, s.lockoutConfig.MaxAttempts, s.lockoutConfig.Window)
+	if err != nil {
+		return err
+	}
+	if lockedUntil != nil {
+		return &AccountLockedError{Until: *lockedUntil}
+	}
+	return ErrInvalidTwoFactorCode
+}
+
+// SetupTwoFactor generates a new secret for the user to add to an authenticator
+// app. 2FA is not active until EnableTwoFactor confirms a code.
+func (s *AuthService) SetupTwoFactor(ctx context.Context, userID uuid.UUID) (*models.TwoFactorSetupResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.SetTOTPSecret(ctx, userID, secret); err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorSetupResponse{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(totpIssuer, user.Email, secret),
+	}, nil
+}
+
+// EnableTwoFactor turns 2FA on once the user proves their app produces valid
+// codes, returning recovery codes that are shown only this once
+func (s *AuthService) EnableTwoFactor(ctx context.Context, userID uuid.UUID, code string) (*models.TwoFactorEnableResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := s.userRepo.GetTOTPSecret(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, ErrTwoFactorNotSetUp
+	}
+
+	step, ok := validateTOTP(*secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = hashToken(c)
+	}
+
+	if err := s.userRepo.EnableTOTP(ctx, userID, step, hashes); err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorEnableResponse{RecoveryCodes: codes}, nil
+}
+
+// checkTOTP validates a code and rejects reuse of one that was already accepted
+func (s *AuthService) checkTOTP(ctx context.Context, userID uuid.UUID, code string) error {
+	secret, err := s.userRepo.GetTOTPSecret(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if secret == nil {
+		return ErrTwoFactorNotSetUp
+	}
+
+	step, ok := validateTOTP(*secret, code, time.Now())
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	fresh, err := s.userRepo.RecordTOTPStep(ctx, userID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
 func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*models.RefreshResponse, string, error) {
 	// Hash the provided token
 	tokenHash := hashToken(refreshToken)
@@ -152,7 +615,10 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*m
 	// Get token from database
 	tokenRecord, err := s.tokenRepo.GetRefreshTokenByHash(ctx, tokenHash)
 	if err != nil {
-		if errors.Is(err, repository.ErrTokenNotFound) || errors.Is(err, repository.ErrTokenRevoked) {
+		if errors.Is(err, repository.ErrTokenRevoked) {
+			return nil, "", s.checkTokenReuse(ctx, tokenRecord)
+		}
+		if errors.Is(err, repository.ErrTokenNotFound) {
 			return nil, "", ErrInvalidToken
 		}
 		if errors.Is(err, repository.ErrTokenExpired) {
@@ -191,6 +657,7 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*m
 	newTokenRecord := &models.RefreshToken{
 		UserID:     user.ID,
 		TokenHash:  newRefreshTokenHash,
+		FamilyID:   tokenRecord.FamilyID,
 		DeviceInfo: tokenRecord.DeviceInfo,
 		IPAddress:  tokenRecord.IPAddress,
 		ExpiresAt:  time.Now().Add(s.jwtConfig.RefreshExpiryDays),
@@ -207,6 +674,31 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*m
 	}, newRefreshToken, nil
 }
 
+// checkTokenReuse handles a revoked refresh token being presented. If its
+// family is still active, the token was rotated out and then replayed, so a
+// copy is in someone else's hands: every session of the user is revoked.
+func (s *AuthService) checkTokenReuse(ctx context.Context, token *models.RefreshToken) error {
+	if token.RevokedAt != nil && time.Since(*token.RevokedAt) < refreshReuseGrace {
+		return ErrInvalidToken
+	}
+
+	active, err := s.tokenRepo.IsFamilyActive(ctx, token.FamilyID)
+	if err != nil {
+		return err
+	}
+	if !active {
+		// Logged out or fully expired family; nothing left to protect
+		return ErrInvalidToken
+	}
+
+	if _, err := s.tokenRepo.RevokeAllUserTokens(ctx, token.UserID); err != nil {
+		return err
+	}
+	log.Printf("Refresh token reuse detected for user %s (family %s); revoked all sessions", token.UserID, token.FamilyID)
+
+	return ErrTokenReuse
+}
+
 func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
 	tokenHash := hashToken(refreshToken)
 	return s.tokenRepo.RevokeRefreshToken(ctx, tokenHash)
@@ -240,6 +732,10 @@ func (s *AuthService) ValidateAccessToken(tokenString string) (*models.TokenClai
 		return nil, ErrInvalidToken
 	}
 
+	if typ, _ := claims["typ"].(string); typ == challengeTokenType || typ == streamTokenType {
+		return nil, ErrInvalidToken
+	}
+
 	userIDStr, ok := claims["sub"].(string)
 	if !ok {
 		return nil, ErrInvalidToken
@@ -270,6 +766,119 @@ func (s *AuthService) generateAccessToken(user *models.User) (string, error) {
 	return token.SignedString([]byte(s.jwtConfig.AccessSecret))
 }
 
+// IssueStreamToken mints a short-lived token that EventSource and WebSocket
+// clients put in ?token=, so the access token never ends up in a URL
+func (s *AuthService) IssueStreamToken(userID uuid.UUID, email string) (*models.StreamTokenResponse, error) {
+	claims := jwt.MapClaims{
+		"sub":   userID.String(),
+		"email": email,
+		"typ":   streamTokenType,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(streamTokenTTL).Unix(),
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.jwtConfig.AccessSecret))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.StreamTokenResponse{
+		StreamToken: token,
+		ExpiresIn:   int(streamTokenTTL.Seconds()),
+	}, nil
+}
+
+// ValidateStreamToken accepts only tokens from IssueStreamToken
+func (s *AuthService) ValidateStreamToken(tokenString string) (*models.TokenClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrInvalidToken
+		}
+		return []byte(s.jwtConfig.AccessSecret), nil
+	})
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrTokenExpired
+		}
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	if typ, _ := claims["typ"].(string); typ != streamTokenType {
+		return nil, ErrInvalidToken
+	}
+
+	sub, _ := claims["sub"].(string)
+	userID, err := uuid.Parse(sub)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	email, _ := claims["email"].(string)
+
+	return &models.TokenClaims{UserID: userID, Email: email}, nil
+}
+
+// generateChallengeToken records a challenge and signs a token naming it in jti
+func (s *AuthService) generateChallengeToken(ctx context.Context, user *models.User) (string, error) {
+	challengeID := uuid.New()
+	expiresAt := time.Now().Add(twoFactorChallengeTTL)
+	if err := s.challengeRepo.Create(ctx, challengeID, user.ID, expiresAt); err != nil {
+		return "", err
+	}
+
+	claims := jwt.MapClaims{
+		"sub": user.ID.String(),
+		"jti": challengeID.String(),
+		"typ": challengeTokenType,
+		"iat": time.Now().Unix(),
+		"exp": expiresAt.Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(s.jwtConfig.AccessSecret))
+}
+
+// parseChallengeToken returns the user and the challenge ID of a challenge token
+func (s *AuthService) parseChallengeToken(tokenString string) (uuid.UUID, uuid.UUID, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrInvalidToken
+		}
+		return []byte(s.jwtConfig.AccessSecret), nil
+	})
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return uuid.Nil, uuid.Nil, ErrTokenExpired
+		}
+		return uuid.Nil, uuid.Nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return uuid.Nil, uuid.Nil, ErrInvalidToken
+	}
+	if typ, _ := claims["typ"].(string); typ != challengeTokenType {
+		return uuid.Nil, uuid.Nil, ErrInvalidToken
+	}
+
+	sub, _ := claims["sub"].(string)
+	userID, err := uuid.Parse(sub)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, ErrInvalidToken
+	}
+	jti, _ := claims["jti"].(string)
+	challengeID, err := uuid.Parse(jti)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, ErrInvalidToken
+	}
+
+	return userID, challengeID, nil
+}
+
 func (s *AuthService) generateRefreshToken() (string, string, error) {
 	tokenID := uuid.New().String()
 	tokenHash := hashToken(tokenID)
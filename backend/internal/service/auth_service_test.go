@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nextpdf/backend/internal/config"
+	"github.com/nextpdf/backend/internal/repository"
+	"github.com/nextpdf/backend/internal/testdb"
+)
+
+func TestRevokedSessionCannotRefresh(t *testing.T) {
+	pool := testdb.New(t)
+	ctx := context.Background()
+
+	userRepo := repository.NewUserRepository(pool, nil)
+	tokenRepo := repository.NewTokenRepository(pool)
+	sessionRepo := repository.NewSessionRepository(pool)
+	authService := NewAuthService(userRepo, tokenRepo, sessionRepo, nil, nil, nil, nil, nil, nil,
+		config.JWTConfig{AccessSecret: "test-secret", AccessExpiryMins: time.Minute, RefreshExpiryDays: time.Hour},
+		config.MailConfig{}, config.LockoutConfig{})
+	userService := NewUserService(userRepo, sessionRepo, nil)
+
+	user, err := userRepo.GetByID(ctx, testdb.CreateUser(t, pool))
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+
+	// Two devices; the first one is revoked from the sessions list
+	_, revokedToken, err := authService.issueSession(ctx, user, "laptop", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("issue session: %v", err)
+	}
+	_, keptToken, err := authService.issueSession(ctx, user, "phone", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("issue session: %v", err)
+	}
+
+	sessions, err := sessionRepo.GetByUserID(ctx, user.ID, nil)
+	if err != nil {
+		t.Fatalf("list sessions: %v", err)
+	}
+	revoked := false
+	for _, session := range sessions {
+		if session.UserAgent != nil && *session.UserAgent == "laptop" {
+			if err := userService.RevokeSession(ctx, user.ID, session.ID); err != nil {
+				t.Fatalf("revoke session: %v", err)
+			}
+			revoked = true
+		}
+	}
+	if !revoked {
+		t.Fatal("laptop session not listed")
+	}
+
+	// The handler answers ErrInvalidToken with 401 INVALID_TOKEN
+	if _, _, err := authService.RefreshToken(ctx, revokedToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("refresh with revoked session token = %v, want ErrInvalidToken", err)
+	}
+	if _, _, err := authService.RefreshToken(ctx, keptToken); err != nil {
+		t.Errorf("refresh with the other session's token: %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/nextpdf/backend/internal/models"
+)
+
+const (
+	// MaxCustomInstructionsLength bounds the instructions sent with a summary
+	// request, including those a custom style expands into
+	MaxCustomInstructionsLength = 500
+
+	// maxCustomStyles caps the custom styles one user can save
+	maxCustomStyles = 50
+)
+
+var (
+	// ErrCustomStyleLimit is returned when a user already has maxCustomStyles styles
+	ErrCustomStyleLimit = errors.New("custom summary style limit reached")
+	// ErrInstructionsTooLong is returned when a custom style's instructions and
+	// the request's own custom_instructions together exceed the limit
+	ErrInstructionsTooLong = errors.New("custom instructions are too long")
+)
+
+// ListCustomStyles returns the user's custom styles by name
+func (s *SummaryService) ListCustomStyles(ctx context.Context, userID uuid.UUID) ([]*models.CustomSummaryStyle, error) {
+	styles, err := s.customStyleRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if styles == nil {
+		styles = []*models.CustomSummaryStyle{}
+	}
+	return styles, nil
+}
+
+// CreateCustomStyle saves a custom style. req.Name and req.Instructions must
+// be set; the handler validates their length.
+func (s *SummaryService) CreateCustomStyle(ctx context.Context, userID uuid.UUID, req *models.CustomSummaryStyleRequest) (*models.CustomSummaryStyle, error) {
+	style := &models.CustomSummaryStyle{
+		UserID:       userID,
+		Name:         strings.TrimSpace(*req.Name),
+		BaseStyle:    models.StyleBulletPoints,
+		Instructions: strings.TrimSpace(*req.Instructions),
+	}
+	if req.BaseStyle != nil {
+		style.BaseStyle = *req.BaseStyle
+	}
+	if !style.BaseStyle.IsBuiltIn() {
+		return nil, ErrInvalidStyle
+	}
+
+	count, err := s.customStyleRepo.CountByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxCustomStyles {
+		return nil, ErrCustomStyleLimit
+	}
+
+	if err := s.customStyleRepo.Create(ctx, style); err != nil {
+		return nil, err
+	}
+	return style, nil
+}
+
+// UpdateCustomStyle changes the fields of a custom style that req sets
+func (s *SummaryService) UpdateCustomStyle(ctx context.Context, userID, styleID uuid.UUID, req *models.CustomSummaryStyleRequest) (*models.CustomSummaryStyle, error) {
+	style, err := s.customStyleRepo.GetByID(ctx, styleID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		style.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.BaseStyle != nil {
+		if !req.BaseStyle.IsBuiltIn() {
+			return nil, ErrInvalidStyle
+		}
+		style.BaseStyle = *req.BaseStyle
+	}
+	if req.Instructions != nil {
+		style.Instructions = strings.TrimSpace(*req.Instructions)
+	}
+
+	if err := s.customStyleRepo.Update(ctx, style); err != nil {
+		return nil, err
+	}
+	return style, nil
+}
+
+// DeleteCustomStyle removes a custom style. Summaries made with it keep its
+// instructions, which were copied into their custom_instructions.
+func (s *SummaryService) DeleteCustomStyle(ctx context.Context, userID, styleID uuid.UUID) error {
+	return s.customStyleRepo.Delete(ctx, styleID, userID)
+}
+
+// expandCustomStyle turns a request for StyleCustom into the custom style's
+// base style, with its instructions ahead of any the request carries
+func (s *SummaryService) expandCustomStyle(ctx context.Context, userID uuid.UUID, req *models.GenerateSummaryRequest) error {
+	if req.Style != models.StyleCustom {
+		return nil
+	}
+	if req.CustomStyleID == nil {
+		return ErrInvalidStyle
+	}
+
+	style, err := s.customStyleRepo.GetByID(ctx, *req.CustomStyleID, userID)
+	if err != nil {
+		return err
+	}
+
+	instructions := style.Instructions
+	if req.CustomInstructions != nil {
+		if extra := strings.TrimSpace(*req.CustomInstructions); extra != "" {
+			instructions += "\n" + extra
+		}
+	}
+	if utf8.RuneCountInString(instructions) > MaxCustomInstructionsLength {
+		return ErrInstructionsTooLong
+	}
+
+	req.Style = style.BaseStyle
+	req.CustomInstructions = &instructions
+	return nil
+}
+
+// customStyleInfos lists the user's custom styles as GET /summary-styles entries
+func (s *SummaryService) customStyleInfos(ctx context.Context, userID uuid.UUID) ([]models.SummaryStyleInfo, error) {
+	styles, err := s.customStyleRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]models.SummaryStyleInfo, 0, len(styles))
+	for _, style := range styles {
+		id := style.ID
+		infos = append(infos, models.SummaryStyleInfo{
+			ID:            models.StyleCustom,
+			Name:          style.Name,
+			Description:   style.Instructions,
+			CustomStyleID: &id,
+		})
+	}
+	return infos, nil
+}
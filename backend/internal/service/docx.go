@@ -0,0 +1,304 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// A DOCX file is a ZIP of WordprocessingML parts. writeDOCX emits the few
+// parts Word needs and maps the markdown subset handled by markdownToHTML
+// onto built-in styles: headings, bullet and numbered lists, quotes, code
+// blocks, rules, and bold, italic and code runs. Links become "text (url)".
+
+const (
+	docxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>` +
+		`<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>` +
+		`<Override PartName="/word/numbering.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.numbering+xml"/>` +
+		`<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/>` +
+		`</Types>`
+
+	docxPackageRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties" Target="docProps/core.xml"/>` +
+		`</Relationships>`
+
+	docxDocumentRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/numbering" Target="numbering.xml"/>` +
+		`</Relationships>`
+
+	docxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` +
+		`<w:docDefaults><w:rPrDefault><w:rPr><w:rFonts w:ascii="Calibri" w:hAnsi="Calibri" w:cs="Calibri"/><w:sz w:val="22"/></w:rPr></w:rPrDefault>` +
+		`<w:pPrDefault><w:pPr><w:spacing w:after="160" w:line="276" w:lineRule="auto"/></w:pPr></w:pPrDefault></w:docDefaults>` +
+		`<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/></w:style>` +
+		`<w:style w:type="paragraph" w:styleId="Title"><w:name w:val="Title"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:spacing w:after="240"/></w:pPr><w:rPr><w:b/><w:sz w:val="48"/></w:rPr></w:style>` +
+		`<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:spacing w:before="360" w:after="120"/><w:outlineLvl w:val="0"/></w:pPr><w:rPr><w:b/><w:sz w:val="36"/></w:rPr></w:style>` +
+		`<w:style w:type="paragraph" w:styleId="Heading2"><w:name w:val="heading 2"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:spacing w:before="240" w:after="120"/><w:outlineLvl w:val="1"/></w:pPr><w:rPr><w:b/><w:sz w:val="30"/></w:rPr></w:style>` +
+		`<w:style w:type="paragraph" w:styleId="Heading3"><w:name w:val="heading 3"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:spacing w:before="240" w:after="80"/><w:outlineLvl w:val="2"/></w:pPr><w:rPr><w:b/><w:sz w:val="26"/></w:rPr></w:style>` +
+		`<w:style w:type="paragraph" w:styleId="Heading4"><w:name w:val="heading 4"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:outlineLvl w:val="3"/></w:pPr><w:rPr><w:b/><w:sz w:val="24"/></w:rPr></w:style>` +
+		`<w:style w:type="paragraph" w:styleId="Heading5"><w:name w:val="heading 5"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:outlineLvl w:val="4"/></w:pPr><w:rPr><w:b/><w:i/></w:rPr></w:style>` +
+		`<w:style w:type="paragraph" w:styleId="Heading6"><w:name w:val="heading 6"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:outlineLvl w:val="5"/></w:pPr><w:rPr><w:i/></w:rPr></w:style>` +
+		`<w:style w:type="paragraph" w:styleId="ListParagraph"><w:name w:val="List Paragraph"/><w:basedOn w:val="Normal"/><w:pPr><w:spacing w:after="60"/><w:ind w:left="720"/></w:pPr></w:style>` +
+		`<w:style w:type="paragraph" w:styleId="Quote"><w:name w:val="Quote"/><w:basedOn w:val="Normal"/><w:pPr><w:ind w:left="720"/></w:pPr><w:rPr><w:i/><w:color w:val="595959"/></w:rPr></w:style>` +
+		`<w:style w:type="paragraph" w:styleId="Code"><w:name w:val="Code"/><w:basedOn w:val="Normal"/><w:pPr><w:spacing w:after="0" w:line="240" w:lineRule="auto"/></w:pPr><w:rPr><w:rFonts w:ascii="Consolas" w:hAnsi="Consolas" w:cs="Consolas"/><w:sz w:val="20"/></w:rPr></w:style>` +
+		`</w:styles>`
+
+	// docxBulletNumID is the one numbering instance shared by bullet lists;
+	// each numbered list gets its own instance so it restarts at 1
+	docxBulletNumID = 1
+)
+
+// docxRun is a piece of paragraph text with one set of formatting
+type docxRun struct {
+	Text   string
+	Bold   bool
+	Italic bool
+	Code   bool
+}
+
+// writeDOCX writes title (when not empty) and the markdown content as a DOCX document
+func writeDOCX(w io.Writer, title, md string, created time.Time) error {
+	body, orderedLists := docxBody(title, md)
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", docxContentTypes},
+		{"_rels/.rels", docxPackageRels},
+		{"word/_rels/document.xml.rels", docxDocumentRels},
+		{"word/document.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` + body +
+			`<w:sectPr><w:pgSz w:w="11906" w:h="16838"/><w:pgMar w:top="1440" w:right="1440" w:bottom="1440" w:left="1440" w:header="708" w:footer="708" w:gutter="0"/></w:sectPr>` +
+			`</w:body></w:document>`},
+		{"word/styles.xml", docxStyles},
+		{"word/numbering.xml", docxNumbering(orderedLists)},
+		{"docProps/core.xml", docxCoreProperties(title, created)},
+	}
+
+	zw := zip.NewWriter(w)
+	for _, part := range parts {
+		pw, err := zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(pw, part.content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// docxBody renders the document paragraphs and reports how many numbered
+// lists they use
+func docxBody(title, md string) (string, int) {
+	var out strings.Builder
+	var paragraph []string
+	listTag := ""
+	orderedLists := 0
+	inCode := false
+
+	if title = strings.TrimSpace(title); title != "" {
+		out.WriteString(docxParagraph(`<w:pStyle w:val="Title"/>`, []docxRun{{Text: title}}))
+	}
+
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			out.WriteString(docxParagraph("", docxInlineRuns(strings.Join(paragraph, " "))))
+			paragraph = nil
+		}
+	}
+	// listItem writes one item, starting a new numbering for each numbered list
+	listItem := func(tag, text string) {
+		numID := docxBulletNumID
+		if tag == "ol" {
+			if listTag != "ol" {
+				orderedLists++
+			}
+			numID = docxBulletNumID + orderedLists
+		}
+		listTag = tag
+		props := fmt.Sprintf(`<w:pStyle w:val="ListParagraph"/><w:numPr><w:ilvl w:val="0"/><w:numId w:val="%d"/></w:numPr>`, numID)
+		out.WriteString(docxParagraph(props, docxInlineRuns(text)))
+	}
+
+	for _, raw := range strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n") {
+		line := strings.TrimSpace(raw)
+
+		if strings.HasPrefix(line, "```") {
+			flushParagraph()
+			listTag = ""
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			out.WriteString(docxParagraph(`<w:pStyle w:val="Code"/>`, []docxRun{{Text: strings.TrimRight(raw, " \t")}}))
+			continue
+		}
+
+		switch {
+		case line == "":
+			flushParagraph()
+			listTag = ""
+		case rulePattern.MatchString(line):
+			flushParagraph()
+			listTag = ""
+			out.WriteString(docxParagraph(`<w:pBdr><w:bottom w:val="single" w:sz="6" w:space="1" w:color="auto"/></w:pBdr>`, nil))
+		case headingPattern.MatchString(line):
+			flushParagraph()
+			listTag = ""
+			m := headingPattern.FindStringSubmatch(line)
+			out.WriteString(docxParagraph(fmt.Sprintf(`<w:pStyle w:val="Heading%d"/>`, len(m[1])), docxInlineRuns(m[2])))
+		case bulletPattern.MatchString(line):
+			flushParagraph()
+			listItem("ul", bulletPattern.FindStringSubmatch(line)[1])
+		case orderedPattern.MatchString(line):
+			flushParagraph()
+			listItem("ol", orderedPattern.FindStringSubmatch(line)[1])
+		case strings.HasPrefix(line, ">"):
+			flushParagraph()
+			listTag = ""
+			out.WriteString(docxParagraph(`<w:pStyle w:val="Quote"/>`, docxInlineRuns(strings.TrimSpace(strings.TrimPrefix(line, ">")))))
+		default:
+			listTag = ""
+			paragraph = append(paragraph, line)
+		}
+	}
+	flushParagraph()
+
+	return out.String(), orderedLists
+}
+
+// docxParagraph renders one paragraph with the given paragraph properties
+func docxParagraph(props string, runs []docxRun) string {
+	var p strings.Builder
+	p.WriteString("<w:p>")
+	if props != "" {
+		p.WriteString("<w:pPr>" + props + "</w:pPr>")
+	}
+	for _, run := range runs {
+		if run.Text == "" {
+			continue
+		}
+		p.WriteString("<w:r>")
+		if run.Bold || run.Italic || run.Code {
+			p.WriteString("<w:rPr>")
+			if run.Code {
+				p.WriteString(`<w:rFonts w:ascii="Consolas" w:hAnsi="Consolas" w:cs="Consolas"/>`)
+			}
+			if run.Bold {
+				p.WriteString("<w:b/>")
+			}
+			if run.Italic {
+				p.WriteString("<w:i/>")
+			}
+			p.WriteString("</w:rPr>")
+		}
+		p.WriteString(`<w:t xml:space="preserve">` + xmlText(run.Text) + "</w:t></w:r>")
+	}
+	p.WriteString("</w:p>")
+	return p.String()
+}
+
+// docxInlineRuns splits a line into runs the way renderInline marks it up.
+// Code spans are split out first so emphasis markers inside them are left alone.
+func docxInlineRuns(text string) []docxRun {
+	parts := strings.Split(text, "`")
+	var runs []docxRun
+	for i, part := range parts {
+		// Odd segments sit between backticks; an unclosed trailing one is literal
+		if i%2 == 1 && i < len(parts)-1 {
+			runs = append(runs, docxRun{Text: part, Code: true})
+			continue
+		}
+		if i%2 == 1 {
+			part = "`" + part
+		}
+		runs = append(runs, docxStrongRuns(linkPattern.ReplaceAllString(part, "$1 ($2)"))...)
+	}
+	return runs
+}
+
+func docxStrongRuns(text string) []docxRun {
+	var runs []docxRun
+	last := 0
+	for _, m := range strongPattern.FindAllStringSubmatchIndex(text, -1) {
+		runs = append(runs, docxEmphasisRuns(text[last:m[0]], false)...)
+		inner := ""
+		if m[2] >= 0 {
+			inner = text[m[2]:m[3]]
+		} else {
+			inner = text[m[4]:m[5]]
+		}
+		runs = append(runs, docxEmphasisRuns(inner, true)...)
+		last = m[1]
+	}
+	return append(runs, docxEmphasisRuns(text[last:], false)...)
+}
+
+func docxEmphasisRuns(text string, bold bool) []docxRun {
+	var runs []docxRun
+	last := 0
+	for _, m := range emphasisPattern.FindAllStringSubmatchIndex(text, -1) {
+		runs = append(runs, docxRun{Text: text[last:m[0]], Bold: bold})
+		inner := ""
+		if m[2] >= 0 {
+			inner = text[m[2]:m[3]]
+		} else {
+			inner = text[m[4]:m[5]]
+		}
+		runs = append(runs, docxRun{Text: inner, Bold: bold, Italic: true})
+		last = m[1]
+	}
+	return append(runs, docxRun{Text: text[last:], Bold: bold})
+}
+
+// docxNumbering defines a bullet list and one restarting decimal list per
+// numbered list in the document
+func docxNumbering(orderedLists int) string {
+	var n strings.Builder
+	n.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:numbering xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">`)
+	n.WriteString(`<w:abstractNum w:abstractNumId="0"><w:multiLevelType w:val="singleLevel"/>` +
+		`<w:lvl w:ilvl="0"><w:start w:val="1"/><w:numFmt w:val="bullet"/><w:lvlText w:val="•"/><w:lvlJc w:val="left"/>` +
+		`<w:pPr><w:ind w:left="720" w:hanging="360"/></w:pPr></w:lvl></w:abstractNum>`)
+	n.WriteString(`<w:abstractNum w:abstractNumId="1"><w:multiLevelType w:val="singleLevel"/>` +
+		`<w:lvl w:ilvl="0"><w:start w:val="1"/><w:numFmt w:val="decimal"/><w:lvlText w:val="%1."/><w:lvlJc w:val="left"/>` +
+		`<w:pPr><w:ind w:left="720" w:hanging="360"/></w:pPr></w:lvl></w:abstractNum>`)
+	fmt.Fprintf(&n, `<w:num w:numId="%d"><w:abstractNumId w:val="0"/></w:num>`, docxBulletNumID)
+	for i := 1; i <= orderedLists; i++ {
+		fmt.Fprintf(&n, `<w:num w:numId="%d"><w:abstractNumId w:val="1"/>`+
+			`<w:lvlOverride w:ilvl="0"><w:startOverride w:val="1"/></w:lvlOverride></w:num>`, docxBulletNumID+i)
+	}
+	n.WriteString(`</w:numbering>`)
+	return n.String()
+}
+
+func docxCoreProperties(title string, created time.Time) string {
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" ` +
+		`xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/" ` +
+		`xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+		`<dc:title>` + xmlText(title) + `</dc:title>` +
+		`<dcterms:created xsi:type="dcterms:W3CDTF">` + created.UTC().Format(time.RFC3339) + `</dcterms:created>` +
+		`</cp:coreProperties>`
+}
+
+// xmlText escapes s for XML character data; characters XML cannot carry
+// become U+FFFD
+func xmlText(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
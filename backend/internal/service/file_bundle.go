@@ -0,0 +1,128 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nextpdf/backend/internal/models"
+	"github.com/nextpdf/backend/internal/repository"
+)
+
+// FileBundle is a file opened for GET /files/:id/bundle together with its
+// current summary. Summary is nil when the file has not been summarized.
+type FileBundle struct {
+	File    *models.File
+	Summary *models.Summary
+	Content io.ReadCloser
+}
+
+// bundleMetadata is written to metadata.json inside the bundle
+type bundleMetadata struct {
+	FileID           uuid.UUID              `json:"file_id"`
+	OriginalFilename string                 `json:"original_filename"`
+	MimeType         string                 `json:"mime_type"`
+	FileSize         int64                  `json:"file_size"`
+	PageCount        *int                   `json:"page_count"`
+	UploadedAt       time.Time              `json:"uploaded_at"`
+	Summary          *bundleSummaryMetadata `json:"summary"`
+	BundledAt        time.Time              `json:"bundled_at"`
+}
+
+type bundleSummaryMetadata struct {
+	Version            int                 `json:"version"`
+	Title              *string             `json:"title"`
+	Style              models.SummaryStyle `json:"style"`
+	CustomInstructions *string             `json:"custom_instructions"`
+	Language           string              `json:"language"`
+	ModelUsed          *string             `json:"model_used"`
+	CreatedAt          time.Time           `json:"created_at"`
+}
+
+// OpenBundle checks ownership and opens the stored PDF and current summary.
+// The caller must close Content.
+func (s *FileService) OpenBundle(ctx context.Context, userID, fileID uuid.UUID) (*FileBundle, error) {
+	content, file, err := s.GetFileContent(ctx, userID, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := s.summaryRepo.GetCurrentByFileID(ctx, fileID)
+	if err != nil && !errors.Is(err, repository.ErrSummaryNotFound) {
+		content.Close()
+		return nil, err
+	}
+
+	return &FileBundle{File: file, Summary: summary, Content: content}, nil
+}
+
+// WriteZip streams the bundle as a ZIP archive: the original PDF under its
+// own name, summary.md when there is a summary, and metadata.json
+func (b *FileBundle) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	// The PDF is already compressed, so store it as is
+	pdf, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     path.Base(b.File.OriginalFilename),
+		Method:   zip.Store,
+		Modified: b.File.UploadedAt,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(pdf, b.Content); err != nil {
+		return err
+	}
+
+	meta := bundleMetadata{
+		FileID:           b.File.ID,
+		OriginalFilename: b.File.OriginalFilename,
+		MimeType:         b.File.MimeType,
+		FileSize:         b.File.FileSize,
+		PageCount:        b.File.PageCount,
+		UploadedAt:       b.File.UploadedAt,
+		BundledAt:        time.Now(),
+	}
+
+	if b.Summary != nil {
+		md, err := zw.Create("summary.md")
+		if err != nil {
+			return err
+		}
+		if b.Summary.Title != nil && *b.Summary.Title != "" {
+			if _, err := io.WriteString(md, "# "+*b.Summary.Title+"\n\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(md, b.Summary.Content+"\n"); err != nil {
+			return err
+		}
+
+		meta.Summary = &bundleSummaryMetadata{
+			Version:            b.Summary.Version,
+			Title:              b.Summary.Title,
+			Style:              b.Summary.Style,
+			CustomInstructions: b.Summary.CustomInstructions,
+			Language:           b.Summary.Language,
+			ModelUsed:          b.Summary.ModelUsed,
+			CreatedAt:          b.Summary.CreatedAt,
+		}
+	}
+
+	mw, err := zw.Create("metadata.json")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(mw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(meta); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
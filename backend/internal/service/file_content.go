@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/ledongthuc/pdf"
+	"github.com/nextpdf/backend/internal/models"
+	"github.com/nextpdf/backend/internal/repository"
+)
+
+// maxIndexedTextBytes bounds the text kept per file for content search, well
+// under the 1 MB PostgreSQL allows for a tsvector
+const maxIndexedTextBytes = 256 << 10
+
+// pdfPlainTextAt extracts the text of every page, up to maxBytes. Pages that
+// fail to parse are skipped; a malformed file, which can make the PDF library
+// panic, yields whatever was read before it.
+func pdfPlainTextAt(src io.ReaderAt, size int64, maxBytes int) (text string) {
+	var sb strings.Builder
+	defer func() {
+		if recover() != nil {
+			text = sb.String()
+		}
+	}()
+
+	reader, err := pdf.NewReader(src, size)
+	if err != nil {
+		return ""
+	}
+
+	for i := 1; i <= reader.NumPage() && sb.Len() < maxBytes; i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		pageText, err := page.GetPlainText(nil)
+		if err != nil {
+			continue
+		}
+		sb.WriteString(pageText)
+		sb.WriteString("\n")
+	}
+
+	text = sb.String()
+	if len(text) > maxBytes {
+		text = text[:maxBytes]
+	}
+	return text
+}
+
+// indexContent extracts a stored file's text for content search. Scanned
+// PDFs without a text layer are not indexed. Failures are only logged; the
+// file is just not found by GET /files/search.
+func (s *FileService) indexContent(ctx context.Context, fileID uuid.UUID, src io.ReaderAt, size int64) {
+	// PostgreSQL text cannot hold NUL bytes or invalid UTF-8, which a cut
+	// through a multi-byte character leaves at the end
+	text := strings.ToValidUTF8(strings.ReplaceAll(pdfPlainTextAt(src, size, maxIndexedTextBytes), "\x00", ""), "")
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+
+	if err := s.fileRepo.SaveContent(ctx, fileID, text); err != nil {
+		log.Printf("Failed to index text of file %s: %v", fileID, err)
+	}
+}
+
+// SearchContent finds files whose extracted text matches params.Search, with
+// a highlighted snippet of each match
+func (s *FileService) SearchContent(ctx context.Context, params repository.FileListParams) ([]*models.FileSearchResult, int64, error) {
+	results, totalCount, err := s.fileRepo.FullTextSearch(ctx, params)
+	if err != nil {
+		return nil, 0, err
+	}
+	if results == nil {
+		results = []*models.FileSearchResult{}
+	}
+	return results, totalCount, nil
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nextpdf/backend/internal/models"
+	"github.com/nextpdf/backend/internal/netguard"
+)
+
+const (
+	// MaxImportURLs caps the URLs accepted by one POST /files/import-urls
+	MaxImportURLs = 10
+
+	// maxImportRedirects bounds the redirects followed for one URL
+	maxImportRedirects = 5
+
+	// maxImportFilenameLength bounds a filename taken from the remote server
+	maxImportFilenameLength = 200
+)
+
+var (
+	// ErrImportFetch is returned when the remote server cannot be reached or
+	// does not answer 200 OK
+	ErrImportFetch = errors.New("failed to fetch import URL")
+	// ErrImportNotPDF is returned when the downloaded content is not a PDF
+	ErrImportNotPDF = errors.New("imported content is not a PDF")
+	// ErrImportTooLarge is returned when the remote file exceeds the upload size limit
+	ErrImportTooLarge = errors.New("imported file exceeds the maximum upload size")
+)
+
+// ImportFromURL downloads a publicly accessible PDF and stores it as if it had
+// been uploaded and confirmed, so it gets the same filename policy, size
+// limit, malware scan, checksum and page count. Only FolderID and WorkspaceID
+// are read from req.
+func (s *FileService) ImportFromURL(ctx context.Context, userID uuid.UUID, rawURL string, req *models.ImportURLsRequest) (*models.File, error) {
+	target, err := s.urlGuard.CheckURL(ctx, rawURL)
+	if err != nil {
+		if netguard.IsRefused(err) {
+			return nil, err
+		}
+		log.Printf("URL import failed: %v", err)
+		return nil, ErrImportFetch
+	}
+
+	content, filename, err := s.fetchImport(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	pendingUpload, err := s.newPendingUpload(ctx, userID, &models.PresignRequest{
+		Filename:    filename,
+		FileSize:    int64(len(content)),
+		ContentType: "application/pdf",
+		FolderID:    req.FolderID,
+		WorkspaceID: req.WorkspaceID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	pendingUpload.ExpiresAt = time.Now().Add(s.storage.PresignExpiry())
+
+	if err := s.pendingUploadRepo.Create(ctx, pendingUpload); err != nil {
+		return nil, err
+	}
+
+	if err := s.storage.PutObject(ctx, s.storage.BucketUploads(), pendingUpload.StoragePath,
+		bytes.NewReader(content), int64(len(content)), pendingUpload.ContentType); err != nil {
+		_ = s.pendingUploadRepo.Delete(ctx, pendingUpload.ID)
+		return nil, err
+	}
+
+	return s.ConfirmUpload(ctx, userID, pendingUpload.ID)
+}
+
+// fetchImport downloads target, enforcing the upload size limit while reading,
+// and returns the content with the filename to store it under
+func (s *FileService) fetchImport(ctx context.Context, target *url.URL) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, "", netguard.ErrInvalidURL
+	}
+	req.Header.Set("Accept", "application/pdf")
+
+	resp, err := s.importClient.Do(req)
+	if err != nil {
+		if netguard.IsRefused(err) {
+			return nil, "", err
+		}
+		log.Printf("URL import of %s failed: %v", target.Redacted(), err)
+		return nil, "", ErrImportFetch
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("%w: remote server returned status %d", ErrImportFetch, resp.StatusCode)
+	}
+
+	maxSize := s.uploadConfig.MaxFileSizeMB * 1024 * 1024
+	if resp.ContentLength > maxSize {
+		return nil, "", ErrImportTooLarge
+	}
+
+	// Read one byte past the limit to tell a file at the limit from a larger one
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		log.Printf("URL import of %s failed while reading: %v", target.Redacted(), err)
+		return nil, "", ErrImportFetch
+	}
+	if int64(len(content)) > maxSize {
+		return nil, "", ErrImportTooLarge
+	}
+	if !bytes.HasPrefix(content, []byte("%PDF-")) {
+		return nil, "", ErrImportNotPDF
+	}
+
+	// The final URL after redirects names the file better than the one given
+	return content, importFilename(resp.Header.Get("Content-Disposition"), resp.Request.URL), nil
+}
+
+// importFilename names an imported file from the Content-Disposition header,
+// falling back to the last segment of the URL path
+func importFilename(disposition string, source *url.URL) string {
+	name := ""
+	if _, params, err := mime.ParseMediaType(disposition); err == nil {
+		name = params["filename"]
+	}
+	if name == "" {
+		name = path.Base(source.Path)
+	}
+
+	name = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '-'
+		}
+		return r
+	}, name))
+	if name == "" || name == "." || name == "-" {
+		name = "import"
+	}
+
+	if !strings.EqualFold(path.Ext(name), ".pdf") {
+		name += ".pdf"
+	}
+	if runes := []rune(name); len(runes) > maxImportFilenameLength {
+		name = string(runes[:maxImportFilenameLength-len(".pdf")]) + ".pdf"
+	}
+	return name
+}
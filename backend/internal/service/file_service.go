@@ -3,48 +3,102 @@ package service
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/csv"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net/http"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/ledongthuc/pdf"
 	"github.com/nextpdf/backend/internal/config"
+	"github.com/nextpdf/backend/internal/infrastructure"
 	"github.com/nextpdf/backend/internal/models"
+	"github.com/nextpdf/backend/internal/netguard"
 	"github.com/nextpdf/backend/internal/repository"
 	"github.com/nextpdf/backend/internal/storage"
 )
 
+var (
+	// ErrBlockedFilename is returned when a filename matches an upload policy pattern
+	ErrBlockedFilename = errors.New("filename is blocked by upload policy")
+	// ErrWorkspaceMismatch is returned when a file would move to a folder of another workspace
+	ErrWorkspaceMismatch = errors.New("target folder is not in the file's workspace")
+	// ErrUploadMismatch is returned when a confirmed object differs from its presigned upload
+	ErrUploadMismatch = errors.New("uploaded file does not match the presigned upload")
+	// ErrNotMultipartUpload is returned for multipart calls on a single-request upload
+	ErrNotMultipartUpload = errors.New("upload was not started as a multipart upload")
+	// ErrInvalidPartNumber is returned for a part number outside the upload's parts
+	ErrInvalidPartNumber = errors.New("part number is out of range")
+	// ErrFileRejected is returned when the malware scan flags an upload
+	ErrFileRejected = errors.New("file was rejected by the malware scan")
+	// ErrScanUnavailable is returned when an upload could not be scanned; the
+	// upload is kept so confirming can be retried
+	ErrScanUnavailable = errors.New("malware scanner is unavailable")
+)
+
+const (
+	// maxConcurrentPageCounts caps the page counts run after confirmed uploads
+	maxConcurrentPageCounts = 4
+
+	// pageCountTimeout bounds one background page count and the shutdown wait
+	pageCountTimeout = 30 * time.Second
+)
+
 type FileService struct {
 	fileRepo          *repository.FileRepository
 	folderRepo        *repository.FolderRepository
+	workspaceRepo     *repository.WorkspaceRepository
+	fileShareRepo     *repository.FileShareRepository
 	pendingUploadRepo *repository.PendingUploadRepository
 	summaryRepo       *repository.SummaryRepository
+	summaryService    *SummaryService
 	storage           *storage.Storage
+	scanner           infrastructure.Scanner
+	urlGuard          *netguard.Guard
+	importClient      *http.Client
 	uploadConfig      config.UploadConfig
+	pageCounts        sync.WaitGroup
+	pageCountSlots    chan struct{}
 }
 
 func NewFileService(
 	fileRepo *repository.FileRepository,
 	folderRepo *repository.FolderRepository,
+	workspaceRepo *repository.WorkspaceRepository,
+	fileShareRepo *repository.FileShareRepository,
 	pendingUploadRepo *repository.PendingUploadRepository,
 	summaryRepo *repository.SummaryRepository,
+	summaryService *SummaryService,
 	storage *storage.Storage,
+	scanner infrastructure.Scanner,
+	urlGuard *netguard.Guard,
 	uploadConfig config.UploadConfig,
 ) *FileService {
 	return &FileService{
 		fileRepo:          fileRepo,
 		folderRepo:        folderRepo,
+		workspaceRepo:     workspaceRepo,
+		fileShareRepo:     fileShareRepo,
 		pendingUploadRepo: pendingUploadRepo,
 		summaryRepo:       summaryRepo,
+		summaryService:    summaryService,
 		storage:           storage,
+		scanner:           scanner,
+		urlGuard:          urlGuard,
+		importClient:      urlGuard.HTTPClient(uploadConfig.ImportTimeout, maxImportRedirects),
 		uploadConfig:      uploadConfig,
+		pageCountSlots:    make(chan struct{}, maxConcurrentPageCounts),
 	}
 }
 
@@ -53,17 +107,66 @@ func (s *FileService) GetFile(ctx context.Context, id uuid.UUID) (*models.File,
 }
 
 func (s *FileService) CreatePresignedUpload(ctx context.Context, userID uuid.UUID, req *models.PresignRequest) (*models.PresignResponse, error) {
+	pendingUpload, err := s.newPendingUpload(ctx, userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The POST policy pins the content type and exact size of the upload
+	presignedURL, fields, err := s.storage.GeneratePresignedPostPolicy(ctx, s.storage.BucketUploads(), pendingUpload.StoragePath, req.ContentType, req.FileSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create pending upload record
+	expiresAt := time.Now().Add(s.storage.PresignExpiry())
+	pendingUpload.ExpiresAt = expiresAt
+
+	if err := s.pendingUploadRepo.Create(ctx, pendingUpload); err != nil {
+		return nil, err
+	}
+
+	return &models.PresignResponse{
+		UploadID:     pendingUpload.ID,
+		PresignedURL: presignedURL.String(),
+		StoragePath:  pendingUpload.StoragePath,
+		ExpiresAt:    expiresAt,
+		Headers:      fields,
+	}, nil
+}
+
+// newPendingUpload validates an upload request and builds its pending upload
+// under a fresh storage path. The caller sets ExpiresAt and creates the row.
+func (s *FileService) newPendingUpload(ctx context.Context, userID uuid.UUID, req *models.PresignRequest) (*models.PendingUpload, error) {
 	// Validate file type
 	if req.ContentType != "application/pdf" {
 		return nil, fmt.Errorf("only PDF files are allowed")
 	}
 
+	if err := s.checkFilenamePolicy(req.Filename); err != nil {
+		return nil, err
+	}
+
 	// Validate file size
 	maxSize := s.uploadConfig.MaxFileSizeMB * 1024 * 1024
 	if req.FileSize > maxSize {
 		return nil, fmt.Errorf("file size exceeds maximum limit of %d MB", s.uploadConfig.MaxFileSizeMB)
 	}
 
+	// A workspace that does not exist, or that the user is not in, would tag
+	// the file to a workspace where nobody can see it
+	if req.WorkspaceID != nil {
+		if _, err := s.workspaceRepo.GetByID(ctx, *req.WorkspaceID); err != nil {
+			return nil, err
+		}
+		if _, err := s.workspaceRepo.GetMember(ctx, *req.WorkspaceID, userID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, repository.ErrWorkspaceNotFound
+			}
+			return nil, err
+		}
+	}
+
 	// Validate folder if provided
 	if req.FolderID != nil {
 		folder, err := s.folderRepo.GetByID(ctx, *req.FolderID)
@@ -75,6 +178,21 @@ func (s *FileService) CreatePresignedUpload(ctx context.Context, userID uuid.UUI
 		}
 	}
 
+	// Validate the automatic summary settings now rather than at confirm time
+	var summaryStyle *models.SummaryStyle
+	var summaryLanguage *string
+	if req.AutoSummarize {
+		if req.SummaryStyle != "" {
+			if !req.SummaryStyle.IsBuiltIn() {
+				return nil, ErrInvalidStyle
+			}
+			summaryStyle = &req.SummaryStyle
+		}
+		if req.SummaryLanguage != "" {
+			summaryLanguage = &req.SummaryLanguage
+		}
+	}
+
 	// Generate storage path
 	fileID := uuid.New()
 	ext := filepath.Ext(req.Filename)
@@ -83,88 +201,350 @@ func (s *FileService) CreatePresignedUpload(ctx context.Context, userID uuid.UUI
 	}
 	storagePath := fmt.Sprintf("users/%s/files/%s%s", userID.String(), fileID.String(), ext)
 
-	// Generate presigned URL
-	presignedURL, err := s.storage.GeneratePresignedPutURL(ctx, s.storage.BucketUploads(), storagePath, req.ContentType, req.FileSize)
+	return &models.PendingUpload{
+		UserID:          userID,
+		WorkspaceID:     req.WorkspaceID,
+		FolderID:        req.FolderID,
+		Filename:        req.Filename,
+		FileSize:        req.FileSize,
+		ContentType:     req.ContentType,
+		StoragePath:     storagePath,
+		AutoSummarize:   req.AutoSummarize,
+		SummaryStyle:    summaryStyle,
+		SummaryLanguage: summaryLanguage,
+	}, nil
+}
+
+// InitMultipartUpload starts a resumable upload for a large PDF. It takes the
+// same request and checks as CreatePresignedUpload, but the client uploads
+// parts of PartSize bytes and the upload stays open for MultipartExpiry.
+func (s *FileService) InitMultipartUpload(ctx context.Context, userID uuid.UUID, req *models.PresignRequest) (*models.MultipartInitResponse, error) {
+	pendingUpload, err := s.newPendingUpload(ctx, userID, req)
 	if err != nil {
 		return nil, err
 	}
 
-	// Create pending upload record
-	expiresAt := time.Now().Add(s.storage.PresignExpiry())
-	pendingUpload := &models.PendingUpload{
-		UserID:      userID,
-		WorkspaceID: req.WorkspaceID,
-		FolderID:    req.FolderID,
-		Filename:    req.Filename,
-		FileSize:    req.FileSize,
-		ContentType: req.ContentType,
-		StoragePath: storagePath,
-		ExpiresAt:   expiresAt,
+	multipartID, err := s.storage.NewMultipartUpload(ctx, s.storage.BucketUploads(), pendingUpload.StoragePath, req.ContentType)
+	if err != nil {
+		return nil, err
 	}
+	pendingUpload.MultipartUploadID = &multipartID
+	pendingUpload.ExpiresAt = time.Now().Add(s.uploadConfig.MultipartExpiry)
 
 	if err := s.pendingUploadRepo.Create(ctx, pendingUpload); err != nil {
+		_ = s.storage.AbortMultipartUpload(ctx, s.storage.BucketUploads(), pendingUpload.StoragePath, multipartID)
 		return nil, err
 	}
 
-	return &models.PresignResponse{
-		UploadID:     pendingUpload.ID,
+	partSize := s.uploadConfig.MultipartPartSizeMB * 1024 * 1024
+	return &models.MultipartInitResponse{
+		UploadID:    pendingUpload.ID,
+		PartSize:    partSize,
+		PartCount:   int((req.FileSize + partSize - 1) / partSize),
+		StoragePath: pendingUpload.StoragePath,
+		ExpiresAt:   pendingUpload.ExpiresAt,
+	}, nil
+}
+
+// GetMultipartPartURL presigns the upload of one part of a resumable upload.
+// Part numbers run from 1 to the part count returned by InitMultipartUpload.
+func (s *FileService) GetMultipartPartURL(ctx context.Context, userID uuid.UUID, req *models.MultipartPartURLRequest) (*models.MultipartPartURLResponse, error) {
+	pendingUpload, err := s.getMultipartUpload(ctx, userID, req.UploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	partSize := s.uploadConfig.MultipartPartSizeMB * 1024 * 1024
+	partCount := int((pendingUpload.FileSize + partSize - 1) / partSize)
+	if req.PartNumber < 1 || req.PartNumber > partCount {
+		return nil, ErrInvalidPartNumber
+	}
+
+	presignedURL, err := s.storage.PresignPartURL(ctx, s.storage.BucketUploads(), pendingUpload.StoragePath, *pendingUpload.MultipartUploadID, req.PartNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MultipartPartURLResponse{
+		PartNumber:   req.PartNumber,
 		PresignedURL: presignedURL.String(),
-		StoragePath:  storagePath,
-		ExpiresAt:    expiresAt,
-		Headers: map[string]string{
-			"Content-Type":   req.ContentType,
-			"Content-Length": fmt.Sprintf("%d", req.FileSize),
-		},
+		ExpiresAt:    time.Now().Add(s.storage.PresignExpiry()),
 	}, nil
 }
 
-func (s *FileService) ConfirmUpload(ctx context.Context, userID uuid.UUID, uploadID uuid.UUID) (*models.File, error) {
-	// Get pending upload
-	pendingUpload, err := s.pendingUploadRepo.GetByID(ctx, uploadID)
+// CompleteMultipartUpload assembles the uploaded parts and then confirms the
+// upload like ConfirmUpload. While parts are missing it returns
+// storage.ErrIncompleteUpload and the upload stays open.
+func (s *FileService) CompleteMultipartUpload(ctx context.Context, userID, uploadID uuid.UUID) (*models.File, error) {
+	pendingUpload, err := s.getMultipartUpload(ctx, userID, uploadID)
 	if err != nil {
 		return nil, err
 	}
 
+	err = s.storage.CompleteMultipartUpload(ctx, s.storage.BucketUploads(), pendingUpload.StoragePath, *pendingUpload.MultipartUploadID, pendingUpload.FileSize)
+	if err != nil {
+		// A retried complete finds the upload already assembled
+		if !errors.Is(err, storage.ErrObjectNotFound) {
+			return nil, err
+		}
+		if exists, existsErr := s.storage.ObjectExists(ctx, s.storage.BucketUploads(), pendingUpload.StoragePath); existsErr != nil || !exists {
+			return nil, err
+		}
+	}
+
+	return s.ConfirmUpload(ctx, userID, uploadID)
+}
+
+// getMultipartUpload returns one of the user's open multipart uploads
+func (s *FileService) getMultipartUpload(ctx context.Context, userID, uploadID uuid.UUID) (*models.PendingUpload, error) {
+	pendingUpload, err := s.pendingUploadRepo.GetByID(ctx, uploadID)
+	if err != nil {
+		return nil, err
+	}
 	if pendingUpload.UserID != userID {
 		return nil, repository.ErrUploadNotFound
 	}
+	if pendingUpload.MultipartUploadID == nil {
+		return nil, ErrNotMultipartUpload
+	}
+	return pendingUpload, nil
+}
 
-	// Verify file exists in storage
-	exists, err := s.storage.ObjectExists(ctx, s.storage.BucketUploads(), pendingUpload.StoragePath)
+// GetFileHints samples a stored PDF for its title and language, returning
+// no hints if the file cannot be read
+func (s *FileService) GetFileHints(ctx context.Context, userID, fileID uuid.UUID) DocumentHints {
+	content, _, err := s.GetFileContent(ctx, userID, fileID)
+	if err != nil {
+		return DocumentHints{}
+	}
+	defer content.Close()
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return DocumentHints{}
+	}
+	return ExtractPDFHints(data)
+}
+
+// GetChecksum returns the SHA-256 of a file's stored object, computing it by
+// streaming the object through the hasher on first request and caching it
+func (s *FileService) GetChecksum(ctx context.Context, userID, fileID uuid.UUID) (*models.FileChecksumResponse, error) {
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.UserID != userID {
+		return nil, repository.ErrFileNotFound
+	}
+
+	response := &models.FileChecksumResponse{FileID: fileID, Algorithm: "sha256"}
+
+	cached, err := s.fileRepo.GetChecksum(ctx, fileID)
 	if err != nil {
 		return nil, err
 	}
-	if !exists {
-		return nil, fmt.Errorf("file not found in storage")
+	if cached != nil {
+		response.Checksum = *cached
+		response.Cached = true
+		return response, nil
 	}
 
-	// Count pages
-	var pageCount *int
-	if strings.HasPrefix(pendingUpload.ContentType, "application/pdf") {
-		log.Printf("Analyzing PDF for page count: %s", pendingUpload.StoragePath)
-		obj, err := s.storage.GetObject(ctx, s.storage.BucketUploads(), pendingUpload.StoragePath)
+	obj, err := s.storage.GetObject(ctx, s.storage.BucketFiles(), file.StoragePath)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, obj); err != nil {
+		return nil, fmt.Errorf("failed to read object for checksum: %w", err)
+	}
+	response.Checksum = hex.EncodeToString(hasher.Sum(nil))
+
+	if err := s.fileRepo.SetChecksum(ctx, fileID, response.Checksum); err != nil {
+		log.Printf("Failed to cache checksum for file %s: %v", fileID, err)
+	}
+
+	return response, nil
+}
+
+// countPages returns the page count of a stored PDF. The object is read with
+// ranged requests, so only the trailer, cross-reference data and page tree
+// are fetched rather than the whole file.
+func (s *FileService) countPages(ctx context.Context, bucket, storagePath string) (int, error) {
+	obj, size, err := s.storage.OpenObject(ctx, bucket, storagePath)
+	if err != nil {
+		return 0, err
+	}
+	defer obj.Close()
+
+	return countPDFPagesAt(obj, size)
+}
+
+// CountPDFPages returns the page count of a PDF held in memory
+func CountPDFPages(data []byte) (int, error) {
+	return countPDFPagesAt(bytes.NewReader(data), int64(len(data)))
+}
+
+// countPDFPagesAt returns the page count of a PDF of the given size. A panic
+// in the PDF library on a malformed file is reported as an error.
+func countPDFPagesAt(src io.ReaderAt, size int64) (pages int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pages, err = 0, fmt.Errorf("failed to parse PDF: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(src, size)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create PDF reader: %w", err)
+	}
+
+	pc := reader.NumPage()
+	if pc <= 0 {
+		return 0, fmt.Errorf("PDF reports no pages")
+	}
+	return pc, nil
+}
+
+// BackfillPageCounts counts pages for up to limit PDFs with a NULL page_count.
+// userID scopes the run to one user's library; nil covers all users (admin).
+// Files that still cannot be parsed stay NULL and are reported as failed.
+func (s *FileService) BackfillPageCounts(ctx context.Context, userID *uuid.UUID, limit int) (*models.PageCountBackfillReport, error) {
+	// Fetch one extra row to learn whether another run is needed
+	files, err := s.fileRepo.ListMissingPageCount(ctx, userID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.PageCountBackfillReport{}
+	if len(files) > limit {
+		report.HasMore = true
+		files = files[:limit]
+	}
+
+	for i, file := range files {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		report.Scanned++
+
+		pc, err := s.countPages(ctx, s.storage.BucketFiles(), file.StoragePath)
 		if err == nil {
-			defer obj.Close()
-			data, err := io.ReadAll(obj)
-			if err == nil {
-				reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
-				if err == nil {
-					pc := reader.NumPage()
-					log.Printf("Page count for %s: %d", pendingUpload.StoragePath, pc)
-					if pc > 0 {
-						pageCount = &pc
-					}
-				} else {
-					log.Printf("Failed to create PDF reader: %v", err)
-				}
-			} else {
-				log.Printf("Failed to read object data: %v", err)
-			}
+			err = s.fileRepo.UpdatePageCount(ctx, file.ID, pc)
+		}
+		if err != nil {
+			log.Printf("Page count backfill failed for file %s: %v", file.ID, err)
+			report.Failed++
 		} else {
-			log.Printf("Failed to get object for page count: %v", err)
+			report.Updated++
+		}
+
+		if (i+1)%25 == 0 {
+			log.Printf("Page count backfill progress: %d/%d (updated %d, failed %d)", i+1, len(files), report.Updated, report.Failed)
 		}
-	} else {
-		log.Printf("Skipping page count for content type: %s", pendingUpload.ContentType)
+	}
+
+	return report, nil
+}
+
+// GetStorageUsage reports how much of the quota a user has consumed. It never
+// blocks uploads; QuotaWarning lets clients nudge users before they run out.
+func (s *FileService) GetStorageUsage(ctx context.Context, userID uuid.UUID) (*models.StorageUsageResponse, error) {
+	used, err := s.fileRepo.GetTotalSizeByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &models.StorageUsageResponse{UsedBytes: used}
+	if s.uploadConfig.StorageQuotaMB <= 0 {
+		return usage, nil
+	}
+
+	usage.QuotaBytes = s.uploadConfig.StorageQuotaMB * 1024 * 1024
+	usage.UsedPercent = float64(used) / float64(usage.QuotaBytes) * 100
+	usage.QuotaWarning = usage.UsedPercent >= float64(s.uploadConfig.QuotaWarningPercent)
+
+	return usage, nil
+}
+
+// ListPendingUploads returns the user's in-progress uploads so they can be confirmed or abandoned
+func (s *FileService) ListPendingUploads(ctx context.Context, userID uuid.UUID) ([]*models.PendingUploadResponse, error) {
+	uploads, err := s.pendingUploadRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	responses := make([]*models.PendingUploadResponse, 0, len(uploads))
+	for _, u := range uploads {
+		responses = append(responses, &models.PendingUploadResponse{
+			UploadID:    u.ID,
+			Filename:    u.Filename,
+			FileSize:    u.FileSize,
+			ContentType: u.ContentType,
+			FolderID:    u.FolderID,
+			WorkspaceID: u.WorkspaceID,
+			ExpiresAt:   u.ExpiresAt,
+			IsExpired:   u.ExpiresAt.Before(now),
+			CreatedAt:   u.CreatedAt,
+		})
+	}
+
+	return responses, nil
+}
+
+// AbandonUpload cancels a pending upload, removing its row and any object or
+// parts already uploaded
+func (s *FileService) AbandonUpload(ctx context.Context, userID, uploadID uuid.UUID) error {
+	upload, err := s.pendingUploadRepo.DeleteByUser(ctx, uploadID, userID)
+	if err != nil {
+		return err
+	}
+
+	if upload.MultipartUploadID != nil {
+		err := s.storage.AbortMultipartUpload(ctx, s.storage.BucketUploads(), upload.StoragePath, *upload.MultipartUploadID)
+		if err != nil && !errors.Is(err, storage.ErrObjectNotFound) {
+			log.Printf("Failed to abort abandoned multipart upload %s: %v", upload.StoragePath, err)
+		}
+	}
+
+	// The client may never have PUT the object; removing a missing key is a no-op
+	if err := s.storage.DeleteObject(ctx, s.storage.BucketUploads(), upload.StoragePath); err != nil {
+		log.Printf("Failed to delete abandoned upload object %s: %v", upload.StoragePath, err)
+	}
+
+	return nil
+}
+
+func (s *FileService) ConfirmUpload(ctx context.Context, userID uuid.UUID, uploadID uuid.UUID) (*models.File, error) {
+	// Get pending upload
+	pendingUpload, err := s.pendingUploadRepo.GetByID(ctx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	if pendingUpload.UserID != userID {
+		return nil, repository.ErrUploadNotFound
+	}
+
+	// Verify the object exists and is what was presigned. The POST policy
+	// already enforces this, so a mismatch means the object came another way.
+	info, err := s.storage.StatObject(ctx, s.storage.BucketUploads(), pendingUpload.StoragePath)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			return nil, fmt.Errorf("file not found in storage")
+		}
+		return nil, err
+	}
+	if info.Size != pendingUpload.FileSize || info.ContentType != pendingUpload.ContentType {
+		_ = s.storage.DeleteObject(ctx, s.storage.BucketUploads(), pendingUpload.StoragePath)
+		return nil, ErrUploadMismatch
+	}
+
+	scan, checksum, err := s.inspectUpload(ctx, pendingUpload)
+	if err != nil {
+		return nil, err
 	}
 
 	// Move file from uploads bucket to files bucket
@@ -191,17 +571,177 @@ func (s *FileService) ConfirmUpload(ctx context.Context, userID uuid.UUID, uploa
 		StoragePath:      pendingUpload.StoragePath,
 		MimeType:         pendingUpload.ContentType,
 		FileSize:         pendingUpload.FileSize,
-		PageCount:        pageCount,
+		ChecksumSHA256:   &checksum,
 		Status:           models.StatusUploaded,
 	}
+	if scan.Status != "" {
+		file.ScanStatus = &scan.Status
+	}
 
 	if err := s.fileRepo.Create(ctx, file); err != nil {
 		return nil, err
 	}
 
+	// Pages and metadata are read off the request path, so the confirmed file
+	// has neither until the background count saves them
+	if strings.HasPrefix(file.MimeType, "application/pdf") {
+		s.countPagesInBackground(file.ID, file.StoragePath)
+	}
+
 	// Delete pending upload
 	_ = s.pendingUploadRepo.Delete(ctx, uploadID)
 
+	if pendingUpload.AutoSummarize {
+		s.autoSummarize(ctx, userID, file, pendingUpload)
+	}
+
+	return file, nil
+}
+
+// inspectUpload reads an upload once before it is moved to the files bucket,
+// running the malware scan and computing its SHA-256 along the way. An
+// infected upload is deleted along with its pending row.
+func (s *FileService) inspectUpload(ctx context.Context, pendingUpload *models.PendingUpload) (infrastructure.ScanResult, string, error) {
+	content, err := s.storage.GetObject(ctx, s.storage.BucketUploads(), pendingUpload.StoragePath)
+	if err != nil {
+		return infrastructure.ScanResult{}, "", err
+	}
+	defer content.Close()
+
+	hasher := sha256.New()
+	tee := io.TeeReader(content, hasher)
+
+	result, err := s.scanner.Scan(ctx, pendingUpload.Filename, tee)
+	if err != nil {
+		log.Printf("Malware scan failed for upload %s: %v", pendingUpload.ID, err)
+		return infrastructure.ScanResult{}, "", ErrScanUnavailable
+	}
+
+	if result.Status == models.ScanInfected {
+		log.Printf("Upload %s by user %s rejected by malware scan: %s", pendingUpload.ID, pendingUpload.UserID, result.Signature)
+		_ = s.storage.DeleteObject(ctx, s.storage.BucketUploads(), pendingUpload.StoragePath)
+		_ = s.pendingUploadRepo.Delete(ctx, pendingUpload.ID)
+		return infrastructure.ScanResult{}, "", ErrFileRejected
+	}
+
+	// Hash whatever the scanner did not read (all of it without a scanner)
+	if _, err := io.Copy(io.Discard, tee); err != nil {
+		return infrastructure.ScanResult{}, "", fmt.Errorf("failed to read upload for checksum: %w", err)
+	}
+
+	return result, hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// DuplicateOf returns the oldest other file of the owner with the same
+// content as file, or nil when there is none
+func (s *FileService) DuplicateOf(ctx context.Context, file *models.File) (*uuid.UUID, error) {
+	if file.ChecksumSHA256 == nil {
+		return nil, nil
+	}
+
+	matches, err := s.fileRepo.GetByChecksum(ctx, file.UserID, *file.ChecksumSHA256)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range matches {
+		if m.ID != file.ID {
+			return &m.ID, nil
+		}
+	}
+	return nil, nil
+}
+
+// countPagesInBackground counts and saves a new file's pages, along with its
+// embedded metadata and its text for content search, with at most
+// maxConcurrentPageCounts counts running at once. A file whose count fails
+// keeps a NULL page count and is picked up by BackfillPageCounts; metadata
+// that cannot be read stays NULL.
+func (s *FileService) countPagesInBackground(fileID uuid.UUID, storagePath string) {
+	s.pageCounts.Add(1)
+	go func() {
+		defer s.pageCounts.Done()
+		s.pageCountSlots <- struct{}{}
+		defer func() { <-s.pageCountSlots }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), pageCountTimeout)
+		defer cancel()
+
+		obj, size, err := s.storage.OpenObject(ctx, s.storage.BucketFiles(), storagePath)
+		if err != nil {
+			log.Printf("Failed to count pages for file %s: %v", fileID, err)
+			return
+		}
+		defer obj.Close()
+
+		if meta := pdfMetadataAt(obj, size); meta != (models.PDFMetadata{}) {
+			if err := s.fileRepo.UpdatePDFMetadata(ctx, fileID, meta); err != nil {
+				log.Printf("Failed to save PDF metadata for file %s: %v", fileID, err)
+			}
+		}
+
+		pc, err := countPDFPagesAt(obj, size)
+		if err == nil {
+			err = s.fileRepo.UpdatePageCount(ctx, fileID, pc)
+		}
+		if err != nil {
+			log.Printf("Failed to count pages for file %s: %v", fileID, err)
+		} else {
+			log.Printf("Page count for file %s: %d", fileID, pc)
+		}
+
+		s.indexContent(ctx, fileID, obj, size)
+	}()
+}
+
+// DrainPageCounts waits for background page counts in flight. It is
+// registered as a shutdown hook, which runs before the database is closed.
+func (s *FileService) DrainPageCounts() error {
+	done := make(chan struct{})
+	go func() {
+		s.pageCounts.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(pageCountTimeout):
+		log.Printf("WARNING: background page counts still running at shutdown; affected files keep a NULL page count")
+	}
+	return nil
+}
+
+// autoSummarize queues the summary requested at presign time. The upload has
+// already succeeded, so a failure is only logged and the file stays uploaded.
+func (s *FileService) autoSummarize(ctx context.Context, userID uuid.UUID, file *models.File, upload *models.PendingUpload) {
+	if s.summaryService == nil {
+		return
+	}
+
+	req := &models.GenerateSummaryRequest{}
+	if upload.SummaryStyle != nil {
+		req.Style = *upload.SummaryStyle
+	}
+	if upload.SummaryLanguage != nil {
+		req.Language = *upload.SummaryLanguage
+	}
+
+	if _, err := s.summaryService.Generate(ctx, userID, file.ID, req); err != nil {
+		log.Printf("Auto summarize failed for file %s: %v", file.ID, err)
+		return
+	}
+	file.Status = models.StatusProcessing
+}
+
+// GetOwnedFile returns the file when userID owns it. Unlike GetByID it neither
+// presigns a download URL nor loads the folder.
+func (s *FileService) GetOwnedFile(ctx context.Context, userID, fileID uuid.UUID) (*models.File, error) {
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.UserID != userID {
+		return nil, repository.ErrFileNotFound
+	}
 	return file, nil
 }
 
@@ -221,23 +761,7 @@ func (s *FileService) GetByID(ctx context.Context, userID, fileID uuid.UUID) (*m
 		return nil, err
 	}
 
-	response := &models.FileDetailResponse{
-		ID:               file.ID,
-		Filename:         file.Filename,
-		OriginalFilename: file.OriginalFilename,
-		FolderID:         file.FolderID,
-		StoragePath:      file.StoragePath,
-		MimeType:         file.MimeType,
-		FileSize:         file.FileSize,
-		PageCount:        file.PageCount,
-		Status:           file.Status,
-		ErrorMessage:     file.ErrorMessage,
-		UploadedAt:       file.UploadedAt,
-		ProcessedAt:      file.ProcessedAt,
-		CreatedAt:        file.CreatedAt,
-		UpdatedAt:        file.UpdatedAt,
-		DownloadURL:      downloadURL.String(),
-	}
+	response := newFileDetailResponse(file, downloadURL.String())
 
 	// Get folder info if exists
 	if file.FolderID != nil {
@@ -259,6 +783,84 @@ func (s *FileService) GetByID(ctx context.Context, userID, fileID uuid.UUID) (*m
 	return response, nil
 }
 
+// MaxBatchGetFiles caps how many files a single batch-get may request
+const MaxBatchGetFiles = 100
+
+// MaxBulkMoveFiles caps how many files a single bulk move may touch
+const MaxBulkMoveFiles = 500
+
+// BatchGet returns details for the requested files keyed by ID. Files that
+// don't exist or belong to someone else are silently left out.
+func (s *FileService) BatchGet(ctx context.Context, userID uuid.UUID, fileIDs []uuid.UUID) (map[uuid.UUID]*models.FileDetailResponse, error) {
+	files, err := s.fileRepo.GetByIDsForUser(ctx, userID, fileIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[uuid.UUID]*models.FileDetailResponse, len(files))
+	if len(files) == 0 {
+		return result, nil
+	}
+
+	ids := make([]uuid.UUID, len(files))
+	for i, f := range files {
+		ids[i] = f.ID
+	}
+	briefs, err := s.summaryRepo.GetBriefsByFileIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	folders := make(map[uuid.UUID]*models.FolderInfo)
+	for _, file := range files {
+		downloadURL, err := s.storage.GeneratePresignedGetURL(ctx, s.storage.BucketFiles(), file.StoragePath, time.Hour)
+		if err != nil {
+			return nil, err
+		}
+
+		response := newFileDetailResponse(file, downloadURL.String())
+		response.Summary = briefs[file.ID]
+
+		if file.FolderID != nil {
+			info, ok := folders[*file.FolderID]
+			if !ok {
+				if folder, err := s.folderRepo.GetByID(ctx, *file.FolderID); err == nil {
+					info = &models.FolderInfo{ID: folder.ID, Name: folder.Name}
+				}
+				folders[*file.FolderID] = info
+			}
+			response.Folder = info
+		}
+
+		result[file.ID] = response
+	}
+
+	return result, nil
+}
+
+func newFileDetailResponse(file *models.File, downloadURL string) *models.FileDetailResponse {
+	return &models.FileDetailResponse{
+		ID:                file.ID,
+		Filename:          file.Filename,
+		OriginalFilename:  file.OriginalFilename,
+		FolderID:          file.FolderID,
+		StoragePath:       file.StoragePath,
+		MimeType:          file.MimeType,
+		FileSize:          file.FileSize,
+		PageCount:         file.PageCount,
+		Status:            file.Status,
+		ErrorMessage:      file.ErrorMessage,
+		Starred:           file.Starred,
+		UploadedAt:        file.UploadedAt,
+		ProcessedAt:       file.ProcessedAt,
+		CreatedAt:         file.CreatedAt,
+		UpdatedAt:         file.UpdatedAt,
+		DownloadURL:       downloadURL,
+		PDFMetadata:       file.PDFMetadata,
+		SuggestedFilename: suggestedFilename(file),
+	}
+}
+
 func (s *FileService) List(ctx context.Context, params repository.FileListParams) ([]*models.FileResponse, int64, error) {
 	files, totalCount, err := s.fileRepo.List(ctx, params)
 	if err != nil {
@@ -276,29 +878,119 @@ func (s *FileService) List(ctx context.Context, params repository.FileListParams
 			PageCount:        f.PageCount,
 			Status:           f.Status,
 			HasSummary:       f.HasSummary,
+			Starred:          f.Starred,
 			UploadedAt:       f.UploadedAt,
 			ProcessedAt:      f.ProcessedAt,
+			DeletedAt:        f.DeletedAt,
 		})
 	}
 
 	return responses, totalCount, nil
 }
 
+// ListTrash returns the user's trashed files, most recently trashed first
+func (s *FileService) ListTrash(ctx context.Context, userID uuid.UUID) ([]*models.FileResponse, error) {
+	files, err := s.fileRepo.ListTrashed(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*models.FileResponse, 0, len(files))
+	for _, f := range files {
+		responses = append(responses, &models.FileResponse{
+			ID:               f.ID,
+			Filename:         f.Filename,
+			OriginalFilename: f.OriginalFilename,
+			FolderID:         f.FolderID,
+			FileSize:         f.FileSize,
+			PageCount:        f.PageCount,
+			Status:           f.Status,
+			HasSummary:       f.HasSummary,
+			Starred:          f.Starred,
+			MimeType:         f.MimeType,
+			UploadedAt:       f.UploadedAt,
+			ProcessedAt:      f.ProcessedAt,
+			DeletedAt:        f.DeletedAt,
+		})
+	}
+
+	return responses, nil
+}
+
 func (s *FileService) Move(ctx context.Context, userID, fileID uuid.UUID, folderID *uuid.UUID) error {
 	// Validate folder if provided
 	if folderID != nil {
-		folder, err := s.folderRepo.GetByID(ctx, *folderID)
+		file, err := s.fileRepo.GetByID(ctx, fileID)
 		if err != nil {
-			return repository.ErrFolderNotFound
+			return err
 		}
-		if folder.UserID != userID {
-			return repository.ErrFolderNotFound
+		if file.UserID != userID {
+			return repository.ErrFileNotFound
+		}
+		if err := s.checkMoveTarget(ctx, userID, *folderID, []*models.File{file}); err != nil {
+			return err
 		}
 	}
 
 	return s.fileRepo.Move(ctx, fileID, userID, folderID)
 }
 
+// BulkMove moves many files to one folder with a single update. The target
+// is checked once; IDs that are not the caller's files are skipped, which the
+// returned count reflects.
+func (s *FileService) BulkMove(ctx context.Context, userID uuid.UUID, fileIDs []uuid.UUID, folderID *uuid.UUID) (int64, error) {
+	if folderID != nil {
+		files, err := s.fileRepo.GetByIDsForUser(ctx, userID, fileIDs)
+		if err != nil {
+			return 0, err
+		}
+		if err := s.checkMoveTarget(ctx, userID, *folderID, files); err != nil {
+			return 0, err
+		}
+	}
+
+	return s.fileRepo.BulkMove(ctx, userID, fileIDs, folderID)
+}
+
+// checkMoveTarget verifies the user owns the target folder and that it can
+// hold every file: a workspace file must stay visible in its workspace, and a
+// personal file must not end up in a workspace folder
+func (s *FileService) checkMoveTarget(ctx context.Context, userID, folderID uuid.UUID, files []*models.File) error {
+	folder, err := s.folderRepo.GetByID(ctx, folderID)
+	if err != nil {
+		return repository.ErrFolderNotFound
+	}
+	if folder.UserID != userID {
+		return repository.ErrFolderNotFound
+	}
+
+	checked := make(map[uuid.UUID]bool)
+	checkedPersonal := false
+	for _, file := range files {
+		if file.WorkspaceID == nil {
+			if checkedPersonal {
+				continue
+			}
+			checkedPersonal = true
+		} else {
+			if checked[*file.WorkspaceID] {
+				continue
+			}
+			checked[*file.WorkspaceID] = true
+		}
+
+		matches, err := s.folderRepo.MatchesWorkspace(ctx, folderID, file.WorkspaceID)
+		if err != nil {
+			return err
+		}
+		if !matches {
+			return ErrWorkspaceMismatch
+		}
+	}
+
+	return nil
+}
+
 func (s *FileService) Rename(ctx context.Context, userID, fileID uuid.UUID, newName string) error {
 	file, err := s.fileRepo.GetByID(ctx, fileID)
 	if err != nil {
@@ -309,9 +1001,80 @@ func (s *FileService) Rename(ctx context.Context, userID, fileID uuid.UUID, newN
 		return repository.ErrFileNotFound
 	}
 
+	if err := s.checkFilenamePolicy(newName); err != nil {
+		return err
+	}
+
 	return s.fileRepo.Rename(ctx, fileID, userID, newName)
 }
 
+// checkFilenamePolicy rejects names matching any configured blocked pattern.
+// Matching is case-insensitive and applies to the base name only.
+func (s *FileService) checkFilenamePolicy(name string) error {
+	base := strings.ToLower(path.Base(strings.ReplaceAll(name, "\\", "/")))
+	for _, pattern := range s.uploadConfig.BlockedFilenamePatterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), base); ok {
+			return fmt.Errorf("%w: matches %q", ErrBlockedFilename, pattern)
+		}
+	}
+	return nil
+}
+
+// Copy duplicates a file next to the original: a fresh object in the files
+// bucket and a new row named "<name> (copy)<ext>". Summaries are only cloned
+// when copySummaries is set; the copy then starts out completed.
+func (s *FileService) Copy(ctx context.Context, userID, fileID uuid.UUID, copySummaries bool) (*models.FileResponse, error) {
+	src, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	if src.UserID != userID {
+		return nil, repository.ErrFileNotFound
+	}
+
+	nameExt := filepath.Ext(src.OriginalFilename)
+	name := strings.TrimSuffix(src.OriginalFilename, nameExt) + " (copy)" + nameExt
+
+	file, err := copyStoredFile(ctx, s.fileRepo, s.storage, userID, src, src.FolderID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	hasSummary := false
+	if copySummaries {
+		copied, err := s.summaryRepo.CopyToFile(ctx, src.ID, file.ID)
+		if err != nil {
+			// The copy itself is usable; only its history is missing
+			log.Printf("Copy %s: failed to copy summaries to %s: %v", src.ID, file.ID, err)
+		} else if copied > 0 {
+			hasSummary = true
+			if err := s.fileRepo.UpdateStatus(ctx, file.ID, models.StatusCompleted, nil); err == nil {
+				file.Status = models.StatusCompleted
+			}
+		}
+	}
+
+	return &models.FileResponse{
+		ID:               file.ID,
+		Filename:         file.Filename,
+		OriginalFilename: file.OriginalFilename,
+		FolderID:         file.FolderID,
+		FileSize:         file.FileSize,
+		PageCount:        file.PageCount,
+		Status:           file.Status,
+		HasSummary:       hasSummary,
+		MimeType:         file.MimeType,
+		UploadedAt:       file.UploadedAt,
+	}, nil
+}
+
+// SetStarred adds a file to or removes it from the owner's favorites
+func (s *FileService) SetStarred(ctx context.Context, userID, fileID uuid.UUID, starred bool) error {
+	return s.fileRepo.SetStarred(ctx, fileID, userID, starred)
+}
+
+// Delete moves a file to the trash; Purge removes it for good
 func (s *FileService) Delete(ctx context.Context, userID, fileID uuid.UUID) error {
 	file, err := s.fileRepo.GetByID(ctx, fileID)
 	if err != nil {
@@ -326,11 +1089,30 @@ func (s *FileService) Delete(ctx context.Context, userID, fileID uuid.UUID) erro
 		return repository.ErrFileNotFound
 	}
 
-	// Delete from storage
-	_ = s.storage.DeleteObject(ctx, s.storage.BucketFiles(), file.StoragePath)
+	// The object stays in storage until the file is purged
+	return s.fileRepo.Trash(ctx, fileID, userID)
+}
+
+// Restore takes a file out of the trash. If its folder was deleted meanwhile,
+// the file comes back to the root.
+func (s *FileService) Restore(ctx context.Context, userID, fileID uuid.UUID) error {
+	return s.fileRepo.Restore(ctx, fileID, userID)
+}
 
-	// Delete from database (cascades to summaries)
-	return s.fileRepo.Delete(ctx, fileID, userID)
+// Purge permanently deletes a trashed file and its stored object. The row
+// goes first: if the object cannot be deleted afterwards it is only an orphan,
+// logged here and removed by the storage reconcile, never a row pointing at a
+// missing object.
+func (s *FileService) Purge(ctx context.Context, userID, fileID uuid.UUID) error {
+	storagePath, err := s.fileRepo.DeletePurged(ctx, fileID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.storage.DeleteObject(ctx, s.storage.BucketFiles(), storagePath); err != nil {
+		log.Printf("Purge %s: object %s left for reconcile: %v", fileID, storagePath, err)
+	}
+	return nil
 }
 
 func (s *FileService) GetDownloadURL(ctx context.Context, userID, fileID uuid.UUID, expiresIn time.Duration) (string, string, error) {
@@ -379,10 +1161,15 @@ func (s *FileService) SaveStreamSummary(ctx context.Context, userID, fileID uuid
 		return repository.ErrFileNotFound
 	}
 
-	// 2. Create summary
+	// 2. Create summary, deriving a title if the AI omitted one
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = fallbackSummaryTitle(req.Content, file.OriginalFilename)
+	}
+
 	summary := &repository.SummaryCreate{
 		FileID:               fileID,
-		Title:                &req.Title,
+		Title:                &title,
 		Content:              req.Content,
 		Style:                req.Style,
 		CustomInstructions:   req.CustomInstructions,
@@ -391,6 +1178,7 @@ func (s *FileService) SaveStreamSummary(ctx context.Context, userID, fileID uuid
 		CompletionTokens:     &req.CompletionTokens,
 		ProcessingDurationMs: &req.ProcessingDurationMs,
 		Language:             req.Language,
+		SourceText:           nonEmpty(req.SourceText),
 	}
 
 	if err := s.summaryRepo.Create(ctx, summary); err != nil {
@@ -398,7 +1186,73 @@ func (s *FileService) SaveStreamSummary(ctx context.Context, userID, fileID uuid
 	}
 
 	// 3. CRITICAL: Update file status to completed so GetByFileID returns the summary
-	return s.fileRepo.UpdateStatus(ctx, fileID, models.StatusCompleted, nil)
+	if err := s.fileRepo.UpdateStatus(ctx, fileID, models.StatusCompleted, nil); err != nil {
+		return err
+	}
+
+	if err := s.fileRepo.SetPartialSummary(ctx, fileID, nil); err != nil {
+		log.Printf("Failed to clear partial summary of file %s: %v", fileID, err)
+	}
+	return nil
+}
+
+// CacheStats reports the file metadata cache
+func (s *FileService) CacheStats() models.FileCacheStats {
+	return s.fileRepo.CacheStats()
+}
+
+// StoresSourceText reports whether summaries keep the text they were made from
+func (s *FileService) StoresSourceText() bool {
+	return s.summaryRepo.StoresSourceText()
+}
+
+// SavePartialSummary keeps the text a summary stream produced before it
+// ended without a result, so POST /summaries/:file_id/resume can continue it
+func (s *FileService) SavePartialSummary(ctx context.Context, fileID uuid.UUID, content string) error {
+	return s.fileRepo.SetPartialSummary(ctx, fileID, &content)
+}
+
+// copyStoredFile copies src's object in the files bucket and creates a row
+// for the copy, owned by userID, in folderID and named name. The extracted
+// text comes along so the copy is searchable straight away. The new object is
+// removed again if its row cannot be created.
+func copyStoredFile(ctx context.Context, fileRepo *repository.FileRepository, store *storage.Storage, userID uuid.UUID, src *models.File, folderID *uuid.UUID, name string) (*models.File, error) {
+	ext := filepath.Ext(src.StoragePath)
+	if ext == "" {
+		ext = ".pdf"
+	}
+	storagePath := fmt.Sprintf("users/%s/files/%s%s", userID.String(), uuid.New().String(), ext)
+
+	if err := store.CopyObject(ctx,
+		store.BucketFiles(), src.StoragePath,
+		store.BucketFiles(), storagePath,
+	); err != nil {
+		return nil, err
+	}
+
+	file := &models.File{
+		UserID:           userID,
+		WorkspaceID:      src.WorkspaceID,
+		FolderID:         folderID,
+		Filename:         generateSafeFilename(name),
+		OriginalFilename: name,
+		StoragePath:      storagePath,
+		MimeType:         src.MimeType,
+		FileSize:         src.FileSize,
+		PageCount:        src.PageCount,
+		Status:           models.StatusUploaded,
+	}
+
+	if err := fileRepo.Create(ctx, file); err != nil {
+		_ = store.DeleteObject(ctx, store.BucketFiles(), storagePath)
+		return nil, err
+	}
+
+	if err := fileRepo.CopyContent(ctx, src.ID, file.ID); err != nil {
+		log.Printf("Copy %s: failed to copy extracted text to %s: %v", src.ID, file.ID, err)
+	}
+
+	return file, nil
 }
 
 func generateSafeFilename(filename string) string {
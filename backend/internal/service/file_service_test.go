@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nextpdf/backend/internal/config"
+	"github.com/nextpdf/backend/internal/models"
+	"github.com/nextpdf/backend/internal/repository"
+	"github.com/nextpdf/backend/internal/testdb"
+)
+
+func TestPresignRejectsUnknownWorkspace(t *testing.T) {
+	pool := testdb.New(t)
+	ctx := context.Background()
+
+	workspaceRepo := repository.NewWorkspaceRepository(pool)
+	svc := &FileService{
+		fileRepo:          repository.NewFileRepository(pool, nil),
+		folderRepo:        repository.NewFolderRepository(pool, nil),
+		workspaceRepo:     workspaceRepo,
+		pendingUploadRepo: repository.NewPendingUploadRepository(pool),
+		uploadConfig:      config.UploadConfig{MaxFileSizeMB: 10},
+	}
+
+	userID := testdb.CreateUser(t, pool)
+	foreign, err := NewWorkspaceService(workspaceRepo).CreateWorkspace(ctx, testdb.CreateUser(t, pool), "Other team")
+	if err != nil {
+		t.Fatalf("create workspace: %v", err)
+	}
+
+	for name, workspaceID := range map[string]uuid.UUID{"random": uuid.New(), "foreign": foreign.ID} {
+		_, err := svc.CreatePresignedUpload(ctx, userID, &models.PresignRequest{
+			Filename:    "report.pdf",
+			ContentType: "application/pdf",
+			FileSize:    1024,
+			WorkspaceID: &workspaceID,
+		})
+		if !errors.Is(err, repository.ErrWorkspaceNotFound) {
+			t.Errorf("%s workspace: err = %v, want ErrWorkspaceNotFound", name, err)
+		}
+	}
+
+	var pending int
+	if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM pending_uploads WHERE user_id = $1", userID).Scan(&pending); err != nil {
+		t.Fatalf("count pending uploads: %v", err)
+	}
+	if pending != 0 {
+		t.Errorf("%d pending upload(s) created for a rejected workspace", pending)
+	}
+}
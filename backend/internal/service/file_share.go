@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/nextpdf/backend/internal/models"
+	"github.com/nextpdf/backend/internal/repository"
+)
+
+// ListWorkspaces returns the workspaces a file appears in: its home
+// workspace first, if it has one, then the workspaces it is shared into.
+// Only the owner may list them.
+func (s *FileService) ListWorkspaces(ctx context.Context, userID, fileID uuid.UUID) ([]*models.FileWorkspaceShare, error) {
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.UserID != userID {
+		return nil, repository.ErrFileNotFound
+	}
+
+	workspaces := []*models.FileWorkspaceShare{}
+	if file.WorkspaceID != nil {
+		home, err := s.workspaceRepo.GetByID(ctx, *file.WorkspaceID)
+		if err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, &models.FileWorkspaceShare{WorkspaceID: home.ID, WorkspaceName: home.Name, Home: true})
+	}
+
+	shares, err := s.fileShareRepo.ListByFile(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	return append(workspaces, shares...), nil
+}
+
+// ShareToWorkspace shares the owner's file into another workspace the owner
+// belongs to. The home workspace counts as already shared.
+func (s *FileService) ShareToWorkspace(ctx context.Context, userID, fileID, workspaceID uuid.UUID) error {
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return err
+	}
+	if file.UserID != userID {
+		return repository.ErrFileNotFound
+	}
+
+	if _, err := s.workspaceRepo.GetByID(ctx, workspaceID); err != nil {
+		return err
+	}
+	if _, err := s.workspaceRepo.GetMember(ctx, workspaceID, userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repository.ErrWorkspaceNotFound
+		}
+		return err
+	}
+
+	if file.WorkspaceID != nil && *file.WorkspaceID == workspaceID {
+		return repository.ErrAlreadyShared
+	}
+	return s.fileShareRepo.Create(ctx, fileID, workspaceID, userID)
+}
+
+// UnshareFromWorkspace removes the owner's file from a workspace it was
+// shared into. The home workspace cannot be unshared this way; move the file
+// instead.
+func (s *FileService) UnshareFromWorkspace(ctx context.Context, userID, fileID, workspaceID uuid.UUID) error {
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return err
+	}
+	if file.UserID != userID {
+		return repository.ErrFileNotFound
+	}
+	return s.fileShareRepo.Delete(ctx, fileID, workspaceID)
+}
@@ -2,29 +2,63 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/nextpdf/backend/internal/config"
 	"github.com/nextpdf/backend/internal/models"
 	"github.com/nextpdf/backend/internal/repository"
 	"github.com/nextpdf/backend/internal/storage"
 )
 
+var (
+	ErrFolderLimitExceeded  = errors.New("folder limit exceeded")
+	ErrTargetFolderNotFound = errors.New("target folder not found")
+)
+
 type FolderService struct {
-	folderRepo *repository.FolderRepository
-	fileRepo   *repository.FileRepository
-	storage    *storage.Storage
+	folderRepo       *repository.FolderRepository
+	fileRepo         *repository.FileRepository
+	workspaceService *WorkspaceService
+	storage          *storage.Storage
+	config           config.FolderConfig
 }
 
 func NewFolderService(
 	folderRepo *repository.FolderRepository,
 	fileRepo *repository.FileRepository,
+	workspaceService *WorkspaceService,
 	storage *storage.Storage,
+	cfg config.FolderConfig,
 ) *FolderService {
 	return &FolderService{
-		folderRepo: folderRepo,
-		fileRepo:   fileRepo,
-		storage:    storage,
+		folderRepo:       folderRepo,
+		fileRepo:         fileRepo,
+		workspaceService: workspaceService,
+		storage:          storage,
+		config:           cfg,
+	}
+}
+
+// checkFolderLimit fails with ErrFolderLimitExceeded if adding n folders
+// would take the user past the configured cap
+func (s *FolderService) checkFolderLimit(ctx context.Context, userID uuid.UUID, n int) error {
+	if s.config.MaxPerUser <= 0 {
+		return nil
+	}
+
+	count, err := s.folderRepo.CountByUser(ctx, userID)
+	if err != nil {
+		return err
 	}
+	if count+n > s.config.MaxPerUser {
+		return ErrFolderLimitExceeded
+	}
+
+	return nil
 }
 
 func (s *FolderService) Create(ctx context.Context, userID uuid.UUID, req *models.CreateFolderRequest) (*models.Folder, error) {
@@ -39,6 +73,14 @@ func (s *FolderService) Create(ctx context.Context, userID uuid.UUID, req *model
 		}
 	}
 
+	if err := s.checkFolderLimit(ctx, userID, 1); err != nil {
+		return nil, err
+	}
+
+	if req.OnConflict == models.ConflictRename {
+		return s.createWithUniqueName(ctx, userID, req.ParentID, req.Name)
+	}
+
 	folder := &models.Folder{
 		UserID:   userID,
 		ParentID: req.ParentID,
@@ -52,85 +94,62 @@ func (s *FolderService) Create(ctx context.Context, userID uuid.UUID, req *model
 	return folder, nil
 }
 
-func (s *FolderService) GetTree(ctx context.Context, userID uuid.UUID, includeFiles, includeCounts bool) ([]*models.FolderTreeNode, error) {
-	folders, err := s.folderRepo.GetByUserID(ctx, userID)
-	if err != nil {
-		return nil, err
-	}
+// Attempts before giving up when concurrent creates keep taking the chosen name
+const maxRenameAttempts = 5
 
-	// Build tree structure
-	nodeMap := make(map[uuid.UUID]*models.FolderTreeNode)
-	var rootNodes []*models.FolderTreeNode
-
-	// Create nodes
-	for _, f := range folders {
-		node := &models.FolderTreeNode{
-			ID:        f.ID,
-			Name:      f.Name,
-			ParentID:  f.ParentID,
-			Depth:     f.Depth,
-			SortOrder: f.SortOrder,
-			CreatedAt: f.CreatedAt,
-			Children:  []*models.FolderTreeNode{},
+// createWithUniqueName creates the folder under the first free suffixed name
+func (s *FolderService) createWithUniqueName(ctx context.Context, userID uuid.UUID, parentID *uuid.UUID, name string) (*models.Folder, error) {
+	var err error
+	for attempt := 0; attempt < maxRenameAttempts; attempt++ {
+		folder := &models.Folder{UserID: userID, ParentID: parentID}
+		if folder.Name, err = s.uniqueName(ctx, userID, parentID, name); err != nil {
+			return nil, err
 		}
 
-		if includeCounts {
-			node.FileCount = f.FileCount
-			node.TotalSize = f.TotalSize
+		err = s.folderRepo.Create(ctx, folder)
+		if err == nil {
+			return folder, nil
 		}
-
-		nodeMap[f.ID] = node
-	}
-
-	// Build hierarchy
-	for _, f := range folders {
-		node := nodeMap[f.ID]
-		if f.ParentID == nil {
-			rootNodes = append(rootNodes, node)
-		} else {
-			if parent, ok := nodeMap[*f.ParentID]; ok {
-				parent.Children = append(parent.Children, node)
-			}
+		if !errors.Is(err, repository.ErrFolderExists) {
+			return nil, err
 		}
 	}
+	return nil, err
+}
 
-	// Include files if requested
-	if includeFiles {
-		for _, node := range nodeMap {
-			files, err := s.fileRepo.GetByFolderID(ctx, node.ID)
-			if err != nil {
-				return nil, err
-			}
-			for _, f := range files {
-				node.Files = append(node.Files, &models.FileResponse{
-					ID:               f.ID,
-					Filename:         f.Filename,
-					OriginalFilename: f.OriginalFilename,
-					FolderID:         f.FolderID,
-					FileSize:         f.FileSize,
-					PageCount:        f.PageCount,
-					Status:           f.Status,
-					UploadedAt:       f.UploadedAt,
-					ProcessedAt:      f.ProcessedAt,
-				})
-			}
-		}
+func (s *FolderService) GetTree(ctx context.Context, userID uuid.UUID, includeFiles, includeCounts bool) ([]*models.FolderTreeNode, error) {
+	folders, err := s.folderRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
 	}
 
-	return rootNodes, nil
+	return s.buildTree(ctx, folders, includeFiles, includeCounts)
 }
 
-// GetTreeByWorkspaceID returns the folder tree for all members of a workspace.
 func (s *FolderService) GetTreeByWorkspaceID(ctx context.Context, workspaceID uuid.UUID, includeFiles, includeCounts bool) ([]*models.FolderTreeNode, error) {
 	folders, err := s.folderRepo.GetByWorkspaceID(ctx, workspaceID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Build tree structure (same logic as GetTree)
+	return s.buildTree(ctx, folders, includeFiles, includeCounts)
+}
+
+// GetWorkspaceTree returns a workspace's shared folder tree to one of its members
+func (s *FolderService) GetWorkspaceTree(ctx context.Context, userID, workspaceID uuid.UUID, includeFiles, includeCounts bool) ([]*models.FolderTreeNode, error) {
+	if _, err := s.workspaceService.VerifyMemberAccess(ctx, workspaceID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.GetTreeByWorkspaceID(ctx, workspaceID, includeFiles, includeCounts)
+}
+
+func (s *FolderService) buildTree(ctx context.Context, folders []*models.FolderWithCounts, includeFiles, includeCounts bool) ([]*models.FolderTreeNode, error) {
+	// Build tree structure
 	nodeMap := make(map[uuid.UUID]*models.FolderTreeNode)
 	var rootNodes []*models.FolderTreeNode
 
+	// Create nodes
 	for _, f := range folders {
 		node := &models.FolderTreeNode{
 			ID:        f.ID,
@@ -150,6 +169,7 @@ func (s *FolderService) GetTreeByWorkspaceID(ctx context.Context, workspaceID uu
 		nodeMap[f.ID] = node
 	}
 
+	// Build hierarchy
 	for _, f := range folders {
 		node := nodeMap[f.ID]
 		if f.ParentID == nil {
@@ -161,13 +181,25 @@ func (s *FolderService) GetTreeByWorkspaceID(ctx context.Context, workspaceID uu
 		}
 	}
 
+	if includeCounts {
+		for _, node := range rootNodes {
+			rollUpCounts(node)
+		}
+	}
+
+	// Include files if requested, loading all of them in one query
 	if includeFiles {
-		for _, node := range nodeMap {
-			files, err := s.fileRepo.GetByFolderID(ctx, node.ID)
-			if err != nil {
-				return nil, err
-			}
-			for _, f := range files {
+		folderIDs := make([]uuid.UUID, 0, len(nodeMap))
+		for id := range nodeMap {
+			folderIDs = append(folderIDs, id)
+		}
+
+		files, err := s.fileRepo.GetByFolderIDs(ctx, folderIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			if node, ok := nodeMap[*f.FolderID]; ok {
 				node.Files = append(node.Files, &models.FileResponse{
 					ID:               f.ID,
 					Filename:         f.Filename,
@@ -186,6 +218,17 @@ func (s *FolderService) GetTreeByWorkspaceID(ctx context.Context, workspaceID uu
 	return rootNodes, nil
 }
 
+// rollUpCounts fills the recursive totals of a node and its subtree
+func rollUpCounts(node *models.FolderTreeNode) {
+	node.TotalFileCount = node.FileCount
+	node.TotalSizeRecursive = node.TotalSize
+	for _, child := range node.Children {
+		rollUpCounts(child)
+		node.TotalFileCount += child.TotalFileCount
+		node.TotalSizeRecursive += child.TotalSizeRecursive
+	}
+}
+
 func (s *FolderService) Update(ctx context.Context, userID, folderID uuid.UUID, req *models.UpdateFolderRequest) (*models.Folder, error) {
 	folder, err := s.folderRepo.GetByID(ctx, folderID)
 	if err != nil {
@@ -220,33 +263,253 @@ func (s *FolderService) Move(ctx context.Context, userID, folderID uuid.UUID, re
 	return s.folderRepo.Move(ctx, folderID, userID, req.ParentID, req.SortOrder)
 }
 
-func (s *FolderService) Delete(ctx context.Context, userID, folderID uuid.UUID) error {
+// Reorder arranges the sibling folders under req.ParentID in the given order
+func (s *FolderService) Reorder(ctx context.Context, userID uuid.UUID, req *models.ReorderFoldersRequest) error {
+	seen := make(map[uuid.UUID]bool, len(req.OrderedIDs))
+	for _, id := range req.OrderedIDs {
+		if seen[id] {
+			return repository.ErrInvalidReorder
+		}
+		seen[id] = true
+	}
+
+	if req.ParentID != nil {
+		parent, err := s.folderRepo.GetByID(ctx, *req.ParentID)
+		if err != nil {
+			return err
+		}
+		if parent.UserID != userID {
+			return repository.ErrFolderNotFound
+		}
+	}
+
+	return s.folderRepo.Reorder(ctx, userID, req.ParentID, req.OrderedIDs)
+}
+
+// Delete removes a folder with its subfolders and their files. The rows go
+// first, in one transaction; the stored objects are deleted afterwards, and
+// any that fail are only orphans, logged with their paths and removed by the
+// storage reconcile. It returns how many objects were left behind.
+func (s *FolderService) Delete(ctx context.Context, userID, folderID uuid.UUID) (int, error) {
 	folder, err := s.folderRepo.GetByID(ctx, folderID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
+	if folder.UserID != userID {
+		return 0, repository.ErrFolderNotFound
+	}
+
+	paths, err := s.folderRepo.Delete(ctx, folderID, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.deleteObjects(ctx, folderID, paths), nil
+}
+
+// deleteObjects removes the stored objects of a deleted folder's files and
+// returns how many are left for the storage reconcile
+func (s *FolderService) deleteObjects(ctx context.Context, folderID uuid.UUID, paths []string) int {
+	var failed []string
+	for i, path := range paths {
+		err := s.storage.DeleteObject(ctx, s.storage.BucketFiles(), path)
+		if errors.Is(err, storage.ErrStorageUnavailable) {
+			// No point trying the rest; they all wait for the reconcile
+			log.Printf("Folder delete %s: storage unavailable: %v", folderID, err)
+			failed = append(failed, paths[i:]...)
+			break
+		}
+		if err != nil {
+			log.Printf("Folder delete %s: failed to delete object %s: %v", folderID, path, err)
+			failed = append(failed, path)
+		}
+	}
+
+	if len(failed) > 0 {
+		log.Printf("Folder delete %s: %d of %d object(s) left in storage: %v", folderID, len(failed), len(paths), failed)
+	}
+	return len(failed)
+}
+
+// BatchReorder applies many drag-and-drop changes at once: each item gives a
+// folder's parent and sort order. Either every change is applied or none.
+func (s *FolderService) BatchReorder(ctx context.Context, userID uuid.UUID, items []models.FolderReorderItem) ([]*models.Folder, error) {
+	seen := make(map[uuid.UUID]bool, len(items))
+	for _, item := range items {
+		if seen[item.ID] || item.SortOrder < 0 {
+			return nil, repository.ErrInvalidReorder
+		}
+		seen[item.ID] = true
+	}
+
+	return s.folderRepo.BatchReorder(ctx, userID, items)
+}
+
+// DeleteAndReassign is the non-destructive alternative to Delete: the files
+// are moved into targetID before the folder goes away, and no storage object
+// is removed. See FolderRepository.DeleteAndReassign for keepSubfolders.
+func (s *FolderService) DeleteAndReassign(ctx context.Context, userID, folderID, targetID uuid.UUID, keepSubfolders bool) error {
+	folder, err := s.folderRepo.GetByID(ctx, folderID)
+	if err != nil {
+		return err
+	}
 	if folder.UserID != userID {
 		return repository.ErrFolderNotFound
 	}
 
-	// Get all descendant folder IDs for file cleanup
-	descendantIDs, err := s.folderRepo.GetDescendantIDs(ctx, folderID)
+	target, err := s.folderRepo.GetByID(ctx, targetID)
 	if err != nil {
+		if errors.Is(err, repository.ErrFolderNotFound) {
+			return ErrTargetFolderNotFound
+		}
 		return err
 	}
+	if target.UserID != userID {
+		return ErrTargetFolderNotFound
+	}
+
+	return s.folderRepo.DeleteAndReassign(ctx, folderID, userID, targetID, keepSubfolders)
+}
+
+// Duplicate copies a folder and its whole subtree next to the original.
+// When includeFiles is set, every file is copied to a fresh storage object;
+// summaries are not carried over.
+func (s *FolderService) Duplicate(ctx context.Context, userID, folderID uuid.UUID, includeFiles bool) (*models.Folder, error) {
+	folder, err := s.folderRepo.GetByID(ctx, folderID)
+	if err != nil {
+		return nil, err
+	}
 
-	// Delete files from storage for all folders
-	for _, fID := range descendantIDs {
-		files, err := s.fileRepo.GetByFolderID(ctx, fID)
+	if folder.UserID != userID {
+		return nil, repository.ErrFolderNotFound
+	}
+
+	// The copy adds one folder per folder in the subtree
+	subtree, err := s.folderRepo.GetDescendantIDs(ctx, folderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkFolderLimit(ctx, userID, len(subtree)); err != nil {
+		return nil, err
+	}
+
+	name, err := s.uniqueName(ctx, userID, folder.ParentID, folder.Name+" (copy)")
+	if err != nil {
+		return nil, err
+	}
+
+	dst, err := s.duplicateTree(ctx, userID, folder, folder.ParentID, name, includeFiles)
+	if err != nil {
+		if dst != nil {
+			s.discardCopy(userID, dst.ID)
+		}
+		return nil, err
+	}
+	return dst, nil
+}
+
+// discardCopy removes a partly duplicated subtree, with the file rows and
+// objects copied into it, so a failed duplicate leaves nothing behind
+func (s *FolderService) discardCopy(userID, folderID uuid.UUID) {
+	// The request context may be what failed the copy
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	paths, err := s.folderRepo.Delete(ctx, folderID, userID)
+	if err != nil {
+		log.Printf("Folder duplicate: failed to remove partial copy %s: %v", folderID, err)
+		return
+	}
+	s.deleteObjects(ctx, folderID, paths)
+}
+
+// GetBreadcrumbs returns the folders from the root down to folderID, for
+// navigation above a file list
+func (s *FolderService) GetBreadcrumbs(ctx context.Context, userID, folderID uuid.UUID) ([]models.FolderBreadcrumb, error) {
+	ancestors, err := s.folderRepo.GetAncestors(ctx, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The last entry is the folder itself
+	if ancestors[len(ancestors)-1].UserID != userID {
+		return nil, repository.ErrFolderNotFound
+	}
+
+	breadcrumbs := make([]models.FolderBreadcrumb, 0, len(ancestors))
+	for _, f := range ancestors {
+		breadcrumbs = append(breadcrumbs, models.FolderBreadcrumb{ID: f.ID, Name: f.Name})
+	}
+
+	return breadcrumbs, nil
+}
+
+// GetSummaryStats checks ownership and returns how much of a folder, and
+// optionally its subtree, has been summarized
+func (s *FolderService) GetSummaryStats(ctx context.Context, userID, folderID uuid.UUID, includeSubfolders bool) (*models.FolderSummaryStats, error) {
+	folder, err := s.folderRepo.GetByID(ctx, folderID)
+	if err != nil {
+		return nil, err
+	}
+	if folder.UserID != userID {
+		return nil, repository.ErrFolderNotFound
+	}
+
+	return s.folderRepo.GetSummaryStats(ctx, folderID, includeSubfolders)
+}
+
+// duplicateTree copies src under parentID as name. Once the copy's folder
+// exists it is returned even with an error, so the caller can remove it.
+func (s *FolderService) duplicateTree(ctx context.Context, userID uuid.UUID, src *models.Folder, parentID *uuid.UUID, name string, includeFiles bool) (*models.Folder, error) {
+	dst := &models.Folder{
+		UserID:    userID,
+		ParentID:  parentID,
+		Name:      name,
+		SortOrder: src.SortOrder,
+	}
+
+	if err := s.folderRepo.Create(ctx, dst); err != nil {
+		return nil, err
+	}
+
+	if includeFiles {
+		files, err := s.fileRepo.GetByFolderID(ctx, src.ID)
 		if err != nil {
-			return err
+			return dst, err
 		}
 		for _, f := range files {
-			_ = s.storage.DeleteObject(ctx, s.storage.BucketFiles(), f.StoragePath)
+			if _, err := copyStoredFile(ctx, s.fileRepo, s.storage, userID, f, &dst.ID, f.OriginalFilename); err != nil {
+				return dst, err
+			}
+		}
+	}
+
+	children, err := s.folderRepo.GetChildren(ctx, src.ID)
+	if err != nil {
+		return dst, err
+	}
+	for _, child := range children {
+		if _, err := s.duplicateTree(ctx, userID, child, &dst.ID, child.Name, includeFiles); err != nil {
+			return dst, err
 		}
 	}
 
-	// Delete folder (cascades to files and subfolders)
-	return s.folderRepo.Delete(ctx, folderID, userID)
+	return dst, nil
+}
+
+// uniqueName returns name, or name suffixed with an incrementing counter
+// ("Reports (2)") if a sibling with that name already exists.
+func (s *FolderService) uniqueName(ctx context.Context, userID uuid.UUID, parentID *uuid.UUID, name string) (string, error) {
+	candidate := name
+	for i := 2; ; i++ {
+		exists, err := s.folderRepo.NameExists(ctx, userID, parentID, candidate)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s (%d)", name, i)
+	}
 }
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nextpdf/backend/internal/config"
+	"github.com/nextpdf/backend/internal/models"
+	"github.com/nextpdf/backend/internal/repository"
+	"github.com/nextpdf/backend/internal/testdb"
+)
+
+// fileQueryCounter counts the statements that read from the files table
+type fileQueryCounter struct {
+	n atomic.Int32
+}
+
+var fromFiles = regexp.MustCompile(`(?i)\bFROM\s+files\b`)
+
+func (c *fileQueryCounter) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if fromFiles.MatchString(data.SQL) {
+		c.n.Add(1)
+	}
+	return ctx
+}
+
+func (c *fileQueryCounter) TraceQueryEnd(context.Context, *pgx.Conn, pgx.TraceQueryEndData) {}
+
+func TestGetTreeLoadsFilesInOneQuery(t *testing.T) {
+	pool := testdb.New(t)
+	ctx := context.Background()
+	userID := testdb.CreateUser(t, pool)
+
+	folderRepo := repository.NewFolderRepository(pool, nil)
+	var parent *models.Folder
+	for _, name := range []string{"a", "b", "c", "d"} {
+		folder := &models.Folder{UserID: userID, Name: name}
+		if parent != nil {
+			folder.ParentID = &parent.ID
+		}
+		if err := folderRepo.Create(ctx, folder); err != nil {
+			t.Fatalf("create folder: %v", err)
+		}
+		testdb.CreateFile(t, pool, userID, &folder.ID)
+		testdb.CreateFile(t, pool, userID, &folder.ID)
+		parent = folder
+	}
+
+	// A second pool on the same schema whose statements are counted
+	counter := &fileQueryCounter{}
+	cfg := pool.Config()
+	cfg.ConnConfig.Tracer = counter
+	traced, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("connect traced pool: %v", err)
+	}
+	defer traced.Close()
+
+	svc := NewFolderService(repository.NewFolderRepository(traced, nil), repository.NewFileRepository(traced, nil), nil, nil, config.FolderConfig{})
+	tree, err := svc.GetTree(ctx, userID, true, false)
+	if err != nil {
+		t.Fatalf("GetTree: %v", err)
+	}
+
+	if got := counter.n.Load(); got != 1 {
+		t.Errorf("GetTree issued %d file queries, want 1", got)
+	}
+
+	files := 0
+	for nodes := tree; len(nodes) > 0; nodes = nodes[0].Children {
+		if len(nodes) != 1 {
+			t.Fatalf("level has %d folders, want 1", len(nodes))
+		}
+		files += len(nodes[0].Files)
+	}
+	if files != 8 {
+		t.Errorf("tree holds %d files, want 8", files)
+	}
+}
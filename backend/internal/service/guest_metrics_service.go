@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/nextpdf/backend/internal/models"
+	"github.com/nextpdf/backend/internal/repository"
+)
+
+const (
+	// guestMetricWriteTimeout bounds the background insert of one guest metric
+	guestMetricWriteTimeout = 5 * time.Second
+
+	// guestMetricWorkers caps the metrics recorded at once; each holds its PDF
+	// in memory while the pages are counted
+	guestMetricWorkers = 4
+)
+
+// GuestMetricsService records anonymous guest summary metrics and aggregates
+// them for admins
+type GuestMetricsService struct {
+	statsRepo *repository.StatsRepository
+	slots     chan struct{}
+}
+
+func NewGuestMetricsService(statsRepo *repository.StatsRepository) *GuestMetricsService {
+	return &GuestMetricsService{
+		statsRepo: statsRepo,
+		slots:     make(chan struct{}, guestMetricWorkers),
+	}
+}
+
+// Record stores a metric in the background, counting the pages of pdfData
+// first. Metrics are best effort: when every worker is busy the metric is
+// dropped, and a failure is only logged and never reaches the guest.
+func (s *GuestMetricsService) Record(metric models.GuestSummaryMetric, pdfData []byte) {
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		log.Printf("Guest summary metric dropped: %d recordings in progress", guestMetricWorkers)
+		return
+	}
+
+	go func() {
+		defer func() { <-s.slots }()
+
+		if pages, err := CountPDFPages(pdfData); err == nil {
+			metric.PageCount = &pages
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), guestMetricWriteTimeout)
+		defer cancel()
+
+		if err := s.statsRepo.RecordGuestSummary(ctx, &metric); err != nil {
+			log.Printf("Failed to record guest summary metric: %v", err)
+		}
+	}()
+}
+
+// Summary aggregates the guest summaries of the last days days
+func (s *GuestMetricsService) Summary(ctx context.Context, days int) (*models.GuestMetricsResponse, error) {
+	from := time.Now().UTC().AddDate(0, 0, -days)
+
+	stats, err := s.statsRepo.GuestSummaryStats(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	stats.From = from.Format(time.RFC3339)
+
+	return stats, nil
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+
+	"github.com/ledongthuc/pdf"
+	"github.com/nextpdf/backend/internal/models"
+)
+
+// LanguageAuto asks the backend to detect the document language before summarizing
+const LanguageAuto = "auto"
+
+const (
+	defaultLanguage = "en"
+
+	// Only the first pages are sampled; that is plenty for stopword statistics
+	languageSamplePages = 3
+	languageSampleChars = 8000
+
+	// A guess needs this many stopword hits and this lead over the runner-up
+	minLanguageHits   = 8
+	minLanguageMargin = 1.5
+)
+
+// defaultLanguages is the summary language list when SUPPORTED_LANGUAGES is unset
+var defaultLanguages = []string{"en:English", "id:Indonesian"}
+
+// Languages is the allow-list of summary languages. Adding a language is a
+// matter of listing it in SUPPORTED_LANGUAGES; the AI service writes in any
+// language it is given the code of.
+type Languages struct {
+	list  []models.SummaryLanguage
+	codes map[string]bool
+}
+
+// NewLanguages parses "code:Name" entries, such as "en:English"; a bare code
+// is also its name. Codes are lowercased and duplicates dropped.
+func NewLanguages(entries []string) *Languages {
+	if len(entries) == 0 {
+		entries = defaultLanguages
+	}
+
+	l := &Languages{list: []models.SummaryLanguage{}, codes: make(map[string]bool)}
+	for _, entry := range entries {
+		code, name, _ := strings.Cut(entry, ":")
+		code = strings.ToLower(strings.TrimSpace(code))
+		if code == "" || code == LanguageAuto || l.codes[code] {
+			continue
+		}
+		if name = strings.TrimSpace(name); name == "" {
+			name = code
+		}
+		l.codes[code] = true
+		l.list = append(l.list, models.SummaryLanguage{Code: code, Name: name})
+	}
+	return l
+}
+
+// List returns the supported languages in configured order
+func (l *Languages) List() []models.SummaryLanguage {
+	return l.list
+}
+
+// Has reports whether code is a supported language
+func (l *Languages) Has(code string) bool {
+	return l.codes[code]
+}
+
+// Allows reports whether code may be requested for a summary: a supported
+// language, "auto", or empty for the default
+func (l *Languages) Allows(code string) bool {
+	return code == "" || code == LanguageAuto || l.codes[code]
+}
+
+// Codes lists the supported codes for error messages, e.g. "en, id"
+func (l *Languages) Codes() string {
+	codes := make([]string, len(l.list))
+	for i, lang := range l.list {
+		codes[i] = lang.Code
+	}
+	return strings.Join(codes, ", ")
+}
+
+// Default is the language of requests that name none, or ask for "auto" on a
+// document in no supported language: English when it is configured, else the
+// first configured language
+func (l *Languages) Default() string {
+	if l.codes[defaultLanguage] || len(l.list) == 0 {
+		return defaultLanguage
+	}
+	return l.list[0].Code
+}
+
+// Resolve picks the summary language for a request. "auto" becomes the
+// detected document language, but only a configured one; anything unresolved
+// becomes the default.
+func (l *Languages) Resolve(requested string, hints DocumentHints) string {
+	switch requested {
+	case "":
+		return l.Default()
+	case LanguageAuto:
+		if l.codes[hints.Language] {
+			return hints.Language
+		}
+		return l.Default()
+	}
+	return requested
+}
+
+// languageStopwords holds frequent function words for each supported summary language
+var languageStopwords = map[string]map[string]bool{
+	"en": wordSet("the and of to in is that for it with as on are this be by was from or an at which not have"),
+	"id": wordSet("yang dan di ini itu dengan untuk dari dalam tidak akan pada juga ke adalah atau karena oleh sebagai bisa telah"),
+}
+
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+// DetectLanguage guesses the language of text by counting stopwords. It
+// reports false when the sample is too small or too ambiguous to trust. The
+// guess is only a document hint; Languages.Resolve decides whether it may be
+// used as the summary language.
+func DetectLanguage(text string) (string, bool) {
+	scores := make(map[string]int, len(languageStopwords))
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, w := range words {
+		for lang, stopwords := range languageStopwords {
+			if stopwords[w] {
+				scores[lang]++
+			}
+		}
+	}
+
+	best, bestScore, runnerUp := "", 0, 0
+	for lang, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore, runnerUp = lang, score, bestScore
+		case score > runnerUp:
+			runnerUp = score
+		}
+	}
+
+	if bestScore < minLanguageHits || float64(bestScore) < float64(runnerUp)*minLanguageMargin {
+		return defaultLanguage, false
+	}
+	return best, true
+}
+
+// pdfTextSample extracts up to languageSampleChars of plain text. The PDF
+// library can panic on malformed files, which is treated as no text.
+func pdfTextSample(data []byte) (sample string) {
+	defer func() {
+		if recover() != nil {
+			sample = ""
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	for i := 1; i <= reader.NumPage() && i <= languageSamplePages; i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		text, err := page.GetPlainText(nil)
+		if err != nil {
+			continue
+		}
+		sb.WriteString(text)
+		sb.WriteString(" ")
+		if sb.Len() >= languageSampleChars {
+			break
+		}
+	}
+
+	return sb.String()
+}
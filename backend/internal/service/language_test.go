@@ -0,0 +1,69 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLanguagesResolve(t *testing.T) {
+	indonesian := DocumentHints{Language: "id"}
+
+	tests := []struct {
+		name      string
+		entries   []string
+		requested string
+		hints     DocumentHints
+		want      string
+	}{
+		{"empty uses default", nil, "", DocumentHints{}, "en"},
+		{"explicit code", nil, "id", DocumentHints{}, "id"},
+		{"auto uses detected language", nil, LanguageAuto, indonesian, "id"},
+		{"auto without detection", nil, LanguageAuto, DocumentHints{}, "en"},
+		{"auto ignores unconfigured detection", []string{"en:English", "fr:French"}, LanguageAuto, indonesian, "en"},
+		{"default without English", []string{"fr:French", "de:German"}, "", DocumentHints{}, "fr"},
+		{"auto falls back without English", []string{"fr:French"}, LanguageAuto, indonesian, "fr"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewLanguages(tt.entries).Resolve(tt.requested, tt.hints); got != tt.want {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.requested, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewLanguages(t *testing.T) {
+	languages := NewLanguages([]string{" EN : English ", "fr", "en:Duplicate", "auto:Auto", ""})
+
+	if got, want := languages.Codes(), "en, fr"; got != want {
+		t.Errorf("Codes() = %q, want %q", got, want)
+	}
+	if list := languages.List(); list[1].Name != "fr" {
+		t.Errorf("bare code name = %q, want %q", list[1].Name, "fr")
+	}
+	for _, code := range []string{"", LanguageAuto, "en", "fr"} {
+		if !languages.Allows(code) {
+			t.Errorf("Allows(%q) = false", code)
+		}
+	}
+	if languages.Allows("id") || languages.Has(LanguageAuto) {
+		t.Error("unconfigured code or auto accepted as a language")
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	english := strings.Repeat("the report is based on the results of the survey and it shows that ", 3)
+	if lang, ok := DetectLanguage(english); !ok || lang != "en" {
+		t.Errorf("DetectLanguage(english) = %q, %v", lang, ok)
+	}
+
+	indonesian := strings.Repeat("laporan ini adalah hasil dari survei yang dilakukan dengan tim untuk perusahaan ", 3)
+	if lang, ok := DetectLanguage(indonesian); !ok || lang != "id" {
+		t.Errorf("DetectLanguage(indonesian) = %q, %v", lang, ok)
+	}
+
+	if _, ok := DetectLanguage("too short"); ok {
+		t.Error("DetectLanguage trusted a tiny sample")
+	}
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/nextpdf/backend/internal/models"
+	"github.com/nextpdf/backend/internal/repository"
+	"github.com/nextpdf/backend/internal/storage"
+)
+
+// orphanGracePeriod skips recently written objects, which may belong to an
+// upload confirm or copy whose row has not been inserted yet
+const orphanGracePeriod = time.Hour
+
+const (
+	// trashPurgeInterval is how often expired trash is looked for
+	trashPurgeInterval = time.Hour
+	// trashPurgeBatch bounds how many files one query hands to the purge
+	trashPurgeBatch = 100
+)
+
+// MaintenanceService runs operator tasks that repair drift between the
+// database and object storage
+type MaintenanceService struct {
+	fileRepo *repository.FileRepository
+	storage  *storage.Storage
+}
+
+func NewMaintenanceService(fileRepo *repository.FileRepository, storage *storage.Storage) *MaintenanceService {
+	return &MaintenanceService{
+		fileRepo: fileRepo,
+		storage:  storage,
+	}
+}
+
+// ReconcileStorage reports objects in the files bucket with no matching row and
+// rows whose object is missing. Orphaned objects are deleted when deleteOrphans
+// is set; rows are only reported, since removing them would lose summaries.
+func (s *MaintenanceService) ReconcileStorage(ctx context.Context, deleteOrphans bool) (*models.StorageReconcileReport, error) {
+	paths, err := s.fileRepo.ListStoragePaths(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.StorageReconcileReport{
+		RowsScanned:    len(paths),
+		OrphanObjects:  []string{},
+		MissingObjects: []models.MissingObject{},
+	}
+
+	seen := make(map[string]bool, len(paths))
+	cutoff := time.Now().Add(-orphanGracePeriod)
+
+	err = s.storage.ListObjects(ctx, s.storage.BucketFiles(), func(key string, lastModified time.Time) error {
+		report.ObjectsScanned++
+		if _, ok := paths[key]; ok {
+			seen[key] = true
+			return nil
+		}
+		if lastModified.After(cutoff) {
+			return nil
+		}
+
+		report.OrphanObjects = append(report.OrphanObjects, key)
+		if deleteOrphans {
+			if err := s.storage.DeleteObject(ctx, s.storage.BucketFiles(), key); err != nil {
+				log.Printf("Reconcile: failed to delete orphan %s: %v", key, err)
+				return nil
+			}
+			report.DeletedObjects++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for path, id := range paths {
+		if !seen[path] {
+			report.MissingObjects = append(report.MissingObjects, models.MissingObject{FileID: id, StoragePath: path})
+		}
+	}
+
+	log.Printf("Reconcile: %d objects, %d rows, %d orphans (%d deleted), %d missing",
+		report.ObjectsScanned, report.RowsScanned, len(report.OrphanObjects), report.DeletedObjects, len(report.MissingObjects))
+
+	return report, nil
+}
+
+// PurgeTrash permanently deletes files trashed before cutoff together with
+// their objects. As in FileService.Purge the row goes first and a failed
+// object delete leaves an orphan for ReconcileStorage. The run stops early
+// when storage is down, leaving the remaining files for the next run.
+func (s *MaintenanceService) PurgeTrash(ctx context.Context, cutoff time.Time) (int, error) {
+	purged := 0
+	for {
+		files, err := s.fileRepo.ListTrashedBefore(ctx, cutoff, trashPurgeBatch)
+		if err != nil {
+			return purged, err
+		}
+
+		for _, file := range files {
+			storagePath, err := s.fileRepo.DeletePurged(ctx, file.ID, file.UserID)
+			if errors.Is(err, repository.ErrFileNotFound) {
+				continue // Restored or purged since it was listed
+			}
+			if err != nil {
+				return purged, err
+			}
+			purged++
+
+			if err := s.storage.DeleteObject(ctx, s.storage.BucketFiles(), storagePath); err != nil {
+				log.Printf("Trash purge: object %s left for reconcile: %v", storagePath, err)
+				if errors.Is(err, storage.ErrStorageUnavailable) {
+					return purged, err
+				}
+			}
+		}
+
+		if len(files) < trashPurgeBatch {
+			return purged, nil
+		}
+	}
+}
+
+// StartTrashPurge purges files older than retention now and then every
+// trashPurgeInterval until the returned stop function is called. A retention
+// of 0 disables the job.
+func (s *MaintenanceService) StartTrashPurge(retention time.Duration) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if retention <= 0 {
+		return cancel
+	}
+
+	go func() {
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+
+		for {
+			purged, err := s.PurgeTrash(ctx, time.Now().Add(-retention))
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Trash purge: failed after %d file(s): %v", purged, err)
+			} else if purged > 0 {
+				log.Printf("Trash purge: deleted %d file(s) trashed more than %s ago", purged, retention)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return cancel
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/nextpdf/backend/internal/models"
+)
+
+// Summaries are stored as the markdown the AI returns. These helpers render
+// the subset the AI actually produces (headings, lists, emphasis, code, links,
+// quotes, rules) so clients don't each need a markdown renderer.
+//
+// HTML output is safe by construction: every piece of source text is escaped
+// before any tag is emitted, so raw HTML in the content is never passed through.
+
+var (
+	headingPattern     = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	bulletPattern      = regexp.MustCompile(`^[-*+•]\s+(.*)$`)
+	orderedPattern     = regexp.MustCompile(`^\d+[.)]\s+(.*)$`)
+	rulePattern        = regexp.MustCompile(`^(-{3,}|\*{3,}|_{3,})$`)
+	strongPattern      = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	emphasisPattern    = regexp.MustCompile(`\*([^*\s][^*]*?)\*|\b_([^_\s][^_]*?)_\b`)
+	linkPattern        = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	strongEmphasisTrim = strings.NewReplacer("**", "", "__", "", "`", "")
+)
+
+// RenderSummaryContent converts markdown content into the requested format
+func RenderSummaryContent(content string, format models.SummaryFormat) string {
+	switch format {
+	case models.FormatHTML:
+		return markdownToHTML(content)
+	case models.FormatPlain:
+		return markdownToPlain(content)
+	}
+	return content
+}
+
+func markdownToHTML(md string) string {
+	var out strings.Builder
+	var paragraph []string
+	listTag := ""
+	inCode := false
+
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			out.WriteString("<p>" + renderInline(strings.Join(paragraph, " ")) + "</p>\n")
+			paragraph = nil
+		}
+	}
+	closeList := func() {
+		if listTag != "" {
+			out.WriteString("</" + listTag + ">\n")
+			listTag = ""
+		}
+	}
+	openList := func(tag string) {
+		if listTag != tag {
+			closeList()
+			out.WriteString("<" + tag + ">\n")
+			listTag = tag
+		}
+	}
+
+	for _, raw := range strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n") {
+		line := strings.TrimSpace(raw)
+
+		if strings.HasPrefix(line, "```") {
+			flushParagraph()
+			closeList()
+			if inCode {
+				out.WriteString("</code></pre>\n")
+			} else {
+				out.WriteString("<pre><code>")
+			}
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			out.WriteString(html.EscapeString(raw) + "\n")
+			continue
+		}
+
+		switch {
+		case line == "":
+			flushParagraph()
+			closeList()
+		case rulePattern.MatchString(line):
+			flushParagraph()
+			closeList()
+			out.WriteString("<hr>\n")
+		case headingPattern.MatchString(line):
+			flushParagraph()
+			closeList()
+			m := headingPattern.FindStringSubmatch(line)
+			level := string(rune('0' + len(m[1])))
+			out.WriteString("<h" + level + ">" + renderInline(m[2]) + "</h" + level + ">\n")
+		case bulletPattern.MatchString(line):
+			flushParagraph()
+			openList("ul")
+			out.WriteString("<li>" + renderInline(bulletPattern.FindStringSubmatch(line)[1]) + "</li>\n")
+		case orderedPattern.MatchString(line):
+			flushParagraph()
+			openList("ol")
+			out.WriteString("<li>" + renderInline(orderedPattern.FindStringSubmatch(line)[1]) + "</li>\n")
+		case strings.HasPrefix(line, ">"):
+			flushParagraph()
+			closeList()
+			out.WriteString("<blockquote>" + renderInline(strings.TrimSpace(strings.TrimPrefix(line, ">"))) + "</blockquote>\n")
+		default:
+			closeList()
+			paragraph = append(paragraph, line)
+		}
+	}
+
+	flushParagraph()
+	closeList()
+	if inCode {
+		out.WriteString("</code></pre>\n")
+	}
+
+	return strings.TrimRight(out.String(), "\n")
+}
+
+// renderInline escapes text and applies inline markup. Code spans are split
+// out first so emphasis markers inside them are left alone.
+func renderInline(text string) string {
+	parts := strings.Split(text, "`")
+	var out strings.Builder
+	for i, part := range parts {
+		escaped := html.EscapeString(part)
+		// Odd segments sit between backticks; an unclosed trailing one is literal
+		if i%2 == 1 && i < len(parts)-1 {
+			out.WriteString("<code>" + escaped + "</code>")
+			continue
+		}
+		if i%2 == 1 {
+			out.WriteString("`")
+		}
+		out.WriteString(renderEmphasis(escaped))
+	}
+	return out.String()
+}
+
+func renderEmphasis(escaped string) string {
+	escaped = linkPattern.ReplaceAllStringFunc(escaped, func(m string) string {
+		sub := linkPattern.FindStringSubmatch(m)
+		href := sub[2]
+		// Only plain web links; this also rules out javascript: and data: URLs
+		if !strings.HasPrefix(href, "http://") && !strings.HasPrefix(href, "https://") {
+			return sub[1]
+		}
+		return `<a href="` + href + `" rel="nofollow noopener noreferrer">` + sub[1] + `</a>`
+	})
+	escaped = strongPattern.ReplaceAllString(escaped, "<strong>$1$2</strong>")
+	return emphasisPattern.ReplaceAllString(escaped, "<em>$1$2</em>")
+}
+
+func markdownToPlain(md string) string {
+	var lines []string
+	for _, raw := range strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n") {
+		line := strings.TrimRight(raw, " \t")
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, "```"), rulePattern.MatchString(trimmed):
+			continue
+		case headingPattern.MatchString(trimmed):
+			line = headingPattern.FindStringSubmatch(trimmed)[2]
+		case bulletPattern.MatchString(trimmed):
+			line = "- " + bulletPattern.FindStringSubmatch(trimmed)[1]
+		case strings.HasPrefix(trimmed, ">"):
+			line = strings.TrimSpace(strings.TrimPrefix(trimmed, ">"))
+		}
+
+		line = linkPattern.ReplaceAllString(line, "$1 ($2)")
+		line = strongEmphasisTrim.Replace(line)
+		line = emphasisPattern.ReplaceAllString(line, "$1$2")
+		lines = append(lines, line)
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
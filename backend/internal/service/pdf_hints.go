@@ -0,0 +1,56 @@
+package service
+
+import (
+	"bytes"
+	"mime/multipart"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// maxTitleHintLength keeps odd metadata from bloating the AI prompt
+const maxTitleHintLength = 300
+
+// DocumentHints carries what the backend learned while opening a PDF. The AI
+// service uses them for a better title and to know the source language; empty
+// fields mean nothing trustworthy was found.
+type DocumentHints struct {
+	Title    string
+	Language string
+}
+
+// ExtractPDFHints reads the metadata title and detects the text language of a
+// PDF. The language is only set when detection is confident.
+func ExtractPDFHints(data []byte) DocumentHints {
+	hints := DocumentHints{Title: pdfTitle(data)}
+	if lang, ok := DetectLanguage(pdfTextSample(data)); ok {
+		hints.Language = lang
+	}
+	return hints
+}
+
+// pdfTitle returns the cleaned Title entry of the document info dictionary.
+// Like pdfTextSample, a panic in the PDF library means no title.
+func pdfTitle(data []byte) (title string) {
+	defer func() {
+		if recover() != nil {
+			title = ""
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return ""
+	}
+
+	return pdfInfoText(reader.Trailer().Key("Info"), "Title", maxTitleHintLength)
+}
+
+// writeHintFields adds the non-empty hints to a multipart request to the AI service
+func writeHintFields(writer *multipart.Writer, hints DocumentHints) {
+	if hints.Title != "" {
+		_ = writer.WriteField("title_hint", hints.Title)
+	}
+	if hints.Language != "" {
+		_ = writer.WriteField("language_hint", hints.Language)
+	}
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ledongthuc/pdf"
+	"github.com/nextpdf/backend/internal/models"
+)
+
+// maxPDFMetadataLength bounds the stored Title and Author entries
+const maxPDFMetadataLength = 300
+
+// pdfMetadataAt reads Title, Author and CreationDate from the document info
+// dictionary. Encrypted or malformed files, and entries that are missing or
+// unparsable, leave the matching fields nil.
+func pdfMetadataAt(src io.ReaderAt, size int64) (meta models.PDFMetadata) {
+	defer func() {
+		if recover() != nil {
+			meta = models.PDFMetadata{}
+		}
+	}()
+
+	reader, err := pdf.NewReader(src, size)
+	if err != nil {
+		return models.PDFMetadata{}
+	}
+
+	info := reader.Trailer().Key("Info")
+	if title := pdfInfoText(info, "Title", maxPDFMetadataLength); title != "" {
+		meta.Title = &title
+	}
+	if author := pdfInfoText(info, "Author", maxPDFMetadataLength); author != "" {
+		meta.Author = &author
+	}
+	if created, ok := parsePDFDate(info.Key("CreationDate").Text()); ok {
+		meta.CreatedAt = &created
+	}
+	return meta
+}
+
+// pdfInfoText returns an info dictionary entry with whitespace and control
+// characters collapsed, cut to maxLen runes
+func pdfInfoText(info pdf.Value, key string, maxLen int) string {
+	text := strings.Join(strings.FieldsFunc(info.Key(key).Text(), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r)
+	}), " ")
+	if runes := []rune(text); len(runes) > maxLen {
+		text = string(runes[:maxLen])
+	}
+	return text
+}
+
+// parsePDFDate parses a PDF date string, "D:YYYYMMDDHHmmSSOHH'mm'", where
+// everything after the year is optional and a missing offset means UTC
+func parsePDFDate(s string) (time.Time, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "D:")
+
+	digits := 0
+	for digits < len(s) && digits < 14 && s[digits] >= '0' && s[digits] <= '9' {
+		digits++
+	}
+	if digits < 4 || digits%2 != 0 {
+		return time.Time{}, false
+	}
+
+	// Fill the missing parts with their defaults: January 1st, midnight
+	stamp := s[:digits] + "0101000000"[digits-4:]
+	t, err := time.Parse("20060102150405", stamp)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	offset := strings.ReplaceAll(s[digits:], "'", "")
+	if len(offset) >= 3 && (offset[0] == '+' || offset[0] == '-') {
+		tz, err := time.Parse("-0700", (offset + "00")[:5])
+		if err == nil {
+			_, secs := tz.Zone()
+			t = t.Add(-time.Duration(secs) * time.Second)
+		}
+	}
+	return t.UTC(), true
+}
+
+// suggestedFilename proposes the PDF's embedded title as a display name, or
+// nil when there is no title or it already matches the current name
+func suggestedFilename(file *models.File) *string {
+	if file.PDFMetadata.Title == nil {
+		return nil
+	}
+
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '-'
+		}
+		return r
+	}, strings.TrimSpace(*file.PDFMetadata.Title))
+	if name == "" {
+		return nil
+	}
+
+	name += ".pdf"
+	current := strings.TrimSuffix(file.OriginalFilename, filepath.Ext(file.OriginalFilename)) + ".pdf"
+	if strings.EqualFold(name, current) {
+		return nil
+	}
+	return &name
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/nextpdf/backend/internal/models"
+	"github.com/nextpdf/backend/internal/repository"
+)
+
+// ErrDiffVersionNotFound is returned when either side of a diff does not exist
+var ErrDiffVersionNotFound = errors.New("summary version to diff not found")
+
+// maxDiffCells bounds the LCS table built for the lines left after trimming
+// the common prefix and suffix; beyond it the middle is reported as replaced
+const maxDiffCells = 4_000_000
+
+// DiffVersions compares the content of two summary versions line by line
+func (s *SummaryService) DiffVersions(ctx context.Context, userID, fileID uuid.UUID, fromVersion, toVersion int) (*models.SummaryDiffResponse, error) {
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.UserID != userID {
+		return nil, repository.ErrFileNotFound
+	}
+
+	from, err := s.summaryRepo.GetByFileIDAndVersion(ctx, fileID, fromVersion)
+	if err != nil {
+		if errors.Is(err, repository.ErrSummaryNotFound) {
+			return nil, ErrDiffVersionNotFound
+		}
+		return nil, err
+	}
+	to, err := s.summaryRepo.GetByFileIDAndVersion(ctx, fileID, toVersion)
+	if err != nil {
+		if errors.Is(err, repository.ErrSummaryNotFound) {
+			return nil, ErrDiffVersionNotFound
+		}
+		return nil, err
+	}
+
+	resp := &models.SummaryDiffResponse{
+		FileID:      fileID,
+		FromVersion: fromVersion,
+		ToVersion:   toVersion,
+		Segments:    diffLines(splitLines(from.Content), splitLines(to.Content)),
+	}
+	for _, seg := range resp.Segments {
+		switch seg.Op {
+		case models.DiffAdded:
+			resp.Added += len(seg.Lines)
+		case models.DiffRemoved:
+			resp.Removed += len(seg.Lines)
+		}
+	}
+	return resp, nil
+}
+
+// splitLines splits text into lines, treating CRLF like LF
+func splitLines(text string) []string {
+	text = strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// diffLines returns the segments turning a into b, using the longest common
+// subsequence of lines. Within a changed run, removals come before additions.
+func diffLines(a, b []string) []models.SummaryDiffSegment {
+	segments := []models.SummaryDiffSegment{}
+	appendLine := func(op, line string) {
+		if n := len(segments); n > 0 && segments[n-1].Op == op {
+			segments[n-1].Lines = append(segments[n-1].Lines, line)
+			return
+		}
+		segments = append(segments, models.SummaryDiffSegment{Op: op, Lines: []string{line}})
+	}
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	for _, line := range a[:prefix] {
+		appendLine(models.DiffUnchanged, line)
+	}
+
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(midA)*len(midB) > maxDiffCells {
+		for _, line := range midA {
+			appendLine(models.DiffRemoved, line)
+		}
+		for _, line := range midB {
+			appendLine(models.DiffAdded, line)
+		}
+	} else {
+		// lcs[i][j] is the LCS length of midA[i:] and midB[j:]
+		lcs := make([][]int, len(midA)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(midB)+1)
+		}
+		for i := len(midA) - 1; i >= 0; i-- {
+			for j := len(midB) - 1; j >= 0; j-- {
+				if midA[i] == midB[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+
+		i, j := 0, 0
+		for i < len(midA) || j < len(midB) {
+			switch {
+			case i < len(midA) && j < len(midB) && midA[i] == midB[j]:
+				appendLine(models.DiffUnchanged, midA[i])
+				i++
+				j++
+			case i < len(midA) && (j == len(midB) || lcs[i+1][j] >= lcs[i][j+1]):
+				appendLine(models.DiffRemoved, midA[i])
+				i++
+			default:
+				appendLine(models.DiffAdded, midB[j])
+				j++
+			}
+		}
+	}
+
+	for _, line := range a[len(a)-suffix:] {
+		appendLine(models.DiffUnchanged, line)
+	}
+	return segments
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/nextpdf/backend/internal/models"
+	"github.com/nextpdf/backend/internal/repository"
+)
+
+// Summary export formats
+const (
+	// ExportFormatDOCX is a Word document built from the summary markdown
+	ExportFormatDOCX = "docx"
+	// ExportFormatMarkdown is the summary markdown under a title heading
+	ExportFormatMarkdown = "md"
+	// ExportFormatPDF is a PDF rendered from the summary markdown
+	ExportFormatPDF = "pdf"
+)
+
+// ErrInvalidExportFormat is returned for a summary export format that is not supported
+var ErrInvalidExportFormat = errors.New("invalid summary export format")
+
+// ErrPDFUnsupportedText is returned when a summary holds characters the
+// standard PDF fonts cannot show, such as non-Latin scripts
+var ErrPDFUnsupportedText = errors.New("summary has characters the PDF fonts cannot show")
+
+// SummaryExport is a summary version prepared for download by
+// GET /summaries/:file_id/export
+type SummaryExport struct {
+	File    *models.File
+	Summary *models.Summary
+	Format  string
+}
+
+// ContentType is the media type of the exported document
+func (e *SummaryExport) ContentType() string {
+	switch e.Format {
+	case ExportFormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	case ExportFormatPDF:
+		return "application/pdf"
+	default:
+		return "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	}
+}
+
+// Write renders the summary, with its title as the heading, in the export format
+func (e *SummaryExport) Write(w io.Writer) error {
+	title := ""
+	if e.Summary.Title != nil {
+		title = *e.Summary.Title
+	}
+
+	switch e.Format {
+	case ExportFormatMarkdown:
+		if title = strings.TrimSpace(title); title != "" {
+			title = "# " + title + "\n\n"
+		}
+		_, err := io.WriteString(w, title+strings.TrimSpace(e.Summary.Content)+"\n")
+		return err
+	case ExportFormatPDF:
+		return writePDF(w, title, e.Summary.Content, e.Summary.CreatedAt)
+	default:
+		return writeDOCX(w, title, e.Summary.Content, e.Summary.CreatedAt)
+	}
+}
+
+// OpenExport checks ownership and loads the summary version to export: the
+// given version, or the current one when version is nil
+func (s *SummaryService) OpenExport(ctx context.Context, userID, fileID uuid.UUID, version *int, format string) (*SummaryExport, error) {
+	switch format {
+	case ExportFormatDOCX, ExportFormatMarkdown, ExportFormatPDF:
+	default:
+		return nil, ErrInvalidExportFormat
+	}
+
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.UserID != userID {
+		return nil, repository.ErrFileNotFound
+	}
+
+	var summary *models.Summary
+	if version != nil {
+		summary, err = s.summaryRepo.GetByFileIDAndVersion(ctx, fileID, *version)
+	} else {
+		summary, err = s.summaryRepo.GetCurrentByFileID(ctx, fileID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &SummaryExport{File: file, Summary: summary, Format: format}, nil
+}
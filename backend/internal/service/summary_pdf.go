@@ -0,0 +1,467 @@
+package service
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// writePDF lays the same markdown subset as writeDOCX out on A4 pages using
+// the standard Helvetica and Courier fonts, which every viewer has, so no
+// font is embedded. Text is WinAnsi encoded, so a summary with characters
+// outside it is refused with ErrPDFUnsupportedText rather than printed as
+// "?". Wrapping uses the Helvetica metrics, so bold text is measured with a
+// margin rather than exactly.
+
+const (
+	pdfPageWidth  = 595.0
+	pdfPageHeight = 842.0
+	pdfMargin     = 56.0
+	pdfTextWidth  = pdfPageWidth - 2*pdfMargin
+
+	pdfBodySize = 11.0
+	pdfCodeSize = 9.5
+	pdfListGap  = 16.0
+)
+
+// Font resource names, in the order they are written to the file
+var pdfFonts = []struct{ key, base string }{
+	{"F1", "Helvetica"},
+	{"F2", "Helvetica-Bold"},
+	{"F3", "Helvetica-Oblique"},
+	{"F4", "Helvetica-BoldOblique"},
+	{"F5", "Courier"},
+}
+
+// pdfHeadingSizes is the font size of heading levels 1 to 6
+var pdfHeadingSizes = [6]float64{18, 15, 13, 12, 11, 11}
+
+// helveticaWidths holds the advance widths of ASCII 32-126 in 1/1000 em
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// winAnsiExtra maps the characters WinAnsiEncoding places in 0x80-0x9F
+var winAnsiExtra = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E, '‘': 0x91,
+	'’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98,
+	'™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// pdfWord is a word set in one font, with whether a space precedes it
+type pdfWord struct {
+	text  string
+	font  string
+	space bool
+}
+
+// pdfDoc collects page content streams while laying out text top to bottom
+type pdfDoc struct {
+	pages []*bytes.Buffer
+	y     float64
+}
+
+// writePDF writes title (when not empty) and the markdown content as a PDF document
+func writePDF(w io.Writer, title, md string, created time.Time) error {
+	for _, r := range title + md {
+		if !pdfEncodable(r) && r != '\n' && r != '\r' && r != '\t' {
+			return fmt.Errorf("%w: %q", ErrPDFUnsupportedText, r)
+		}
+	}
+
+	doc := &pdfDoc{}
+	doc.newPage()
+
+	if title = strings.TrimSpace(title); title != "" {
+		doc.paragraph(pdfWords([]docxRun{{Text: title, Bold: true}}), 20, "", 0, 0)
+		doc.y -= 12
+	}
+
+	var paragraph []string
+	listTag := ""
+	listNumber := 0
+	inCode := false
+
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			doc.paragraph(pdfWords(docxInlineRuns(strings.Join(paragraph, " "))), pdfBodySize, "", 0, 0)
+			doc.y -= 6
+			paragraph = nil
+		}
+	}
+	endList := func() {
+		if listTag != "" {
+			doc.y -= 6
+		}
+		listTag = ""
+	}
+	listItem := func(tag, text string) {
+		if tag != listTag {
+			listNumber = 0
+		}
+		listTag = tag
+		listNumber++
+		marker := "•"
+		if tag == "ol" {
+			marker = fmt.Sprintf("%d.", listNumber)
+		}
+		doc.paragraph(pdfWords(docxInlineRuns(text)), pdfBodySize, marker, pdfListGap, 0)
+	}
+
+	for _, raw := range strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n") {
+		line := strings.TrimSpace(raw)
+
+		if strings.HasPrefix(line, "```") {
+			flushParagraph()
+			endList()
+			if inCode {
+				doc.y -= 6
+			}
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			doc.codeLine(strings.TrimRight(strings.ReplaceAll(raw, "\t", "    "), " "))
+			continue
+		}
+
+		switch {
+		case line == "":
+			flushParagraph()
+			endList()
+		case rulePattern.MatchString(line):
+			flushParagraph()
+			endList()
+			doc.rule()
+		case headingPattern.MatchString(line):
+			flushParagraph()
+			endList()
+			m := headingPattern.FindStringSubmatch(line)
+			runs := docxInlineRuns(m[2])
+			for i := range runs {
+				runs[i].Bold = true
+			}
+			doc.y -= 6
+			doc.paragraph(pdfWords(runs), pdfHeadingSizes[len(m[1])-1], "", 0, 0)
+			doc.y -= 4
+		case bulletPattern.MatchString(line):
+			flushParagraph()
+			listItem("ul", bulletPattern.FindStringSubmatch(line)[1])
+		case orderedPattern.MatchString(line):
+			flushParagraph()
+			listItem("ol", orderedPattern.FindStringSubmatch(line)[1])
+		case strings.HasPrefix(line, ">"):
+			flushParagraph()
+			endList()
+			runs := docxInlineRuns(strings.TrimSpace(strings.TrimPrefix(line, ">")))
+			for i := range runs {
+				runs[i].Italic = !runs[i].Code
+			}
+			doc.paragraph(pdfWords(runs), pdfBodySize, "", pdfListGap, 0.35)
+			doc.y -= 6
+		default:
+			endList()
+			paragraph = append(paragraph, line)
+		}
+	}
+	flushParagraph()
+
+	return doc.write(w, title, created)
+}
+
+// newPage starts a page with the cursor at the top margin
+func (d *pdfDoc) newPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = pdfPageHeight - pdfMargin
+}
+
+// advance moves the cursor down by height, breaking the page when it would
+// run into the bottom margin, and returns the content stream to draw into
+func (d *pdfDoc) advance(height float64) *bytes.Buffer {
+	if d.y-height < pdfMargin {
+		d.newPage()
+	}
+	d.y -= height
+	return d.pages[len(d.pages)-1]
+}
+
+// paragraph wraps words to the text width, less indent, and draws marker (a
+// list bullet or number) in the indent of the first line. gray sets the text
+// color, 0 being black.
+func (d *pdfDoc) paragraph(words []pdfWord, size float64, marker string, indent, gray float64) {
+	if len(words) == 0 {
+		return
+	}
+	leading := size * 1.35
+	maxWidth := pdfTextWidth - indent
+
+	var line []pdfWord
+	lineWidth := 0.0
+	first := true
+	flush := func() {
+		page := d.advance(leading)
+		if gray > 0 {
+			fmt.Fprintf(page, "%.2f g\n", gray)
+		}
+		if first && marker != "" {
+			pdfText(page, "F1", size, pdfMargin, d.y, marker)
+		}
+		// Consecutive words in one font are drawn as one string so viewers
+		// extract and select them as a phrase
+		x := pdfMargin + indent
+		for start := 0; start < len(line); {
+			font := line[start].font
+			text := line[start].text
+			end := start + 1
+			for ; end < len(line) && line[end].font == font; end++ {
+				if line[end].space {
+					text += " "
+				}
+				text += line[end].text
+			}
+			if start > 0 && line[start].space {
+				x += pdfTextWidthOf(font, " ", size)
+			}
+			pdfText(page, font, size, x, d.y, text)
+			x += pdfTextWidthOf(font, text, size)
+			start = end
+		}
+		if gray > 0 {
+			page.WriteString("0 g\n")
+		}
+		line, lineWidth, first = nil, 0, false
+	}
+
+	for _, word := range words {
+		for _, piece := range pdfSplitWord(word, size, maxWidth) {
+			width := pdfTextWidthOf(piece.font, piece.text, size)
+			if len(line) > 0 && piece.space {
+				width += pdfTextWidthOf(piece.font, " ", size)
+			}
+			if len(line) > 0 && lineWidth+width > maxWidth {
+				flush()
+				width = pdfTextWidthOf(piece.font, piece.text, size)
+			}
+			line = append(line, piece)
+			lineWidth += width
+		}
+	}
+	flush()
+}
+
+// codeLine draws one line of a code block in Courier, keeping its indentation
+// and breaking it by characters when it is wider than the page
+func (d *pdfDoc) codeLine(text string) {
+	maxChars := int(pdfTextWidth / pdfTextWidthOf("F5", " ", pdfCodeSize))
+	runes := []rune(text)
+	for {
+		n := min(len(runes), maxChars)
+		page := d.advance(pdfCodeSize * 1.3)
+		if n > 0 {
+			pdfText(page, "F5", pdfCodeSize, pdfMargin, d.y, string(runes[:n]))
+		}
+		runes = runes[n:]
+		if len(runes) == 0 {
+			return
+		}
+	}
+}
+
+// rule draws a horizontal line across the text width
+func (d *pdfDoc) rule() {
+	page := d.advance(12)
+	fmt.Fprintf(page, "0.5 w %.2f %.2f m %.2f %.2f l S\n", pdfMargin, d.y+4, pdfPageWidth-pdfMargin, d.y+4)
+}
+
+// write serializes the pages with the catalog, fonts, info dictionary and
+// cross-reference table
+func (d *pdfDoc) write(w io.Writer, title string, created time.Time) error {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// Objects 1-2 are the catalog and page tree, then fonts, then the info
+	// dictionary, then a page and its content stream for each page
+	fontBase := 3
+	infoID := fontBase + len(pdfFonts)
+	firstPage := infoID + 1
+
+	out.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+
+	fonts := make([]string, len(pdfFonts))
+	for i, f := range pdfFonts {
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", f.base))
+		fonts[i] = fmt.Sprintf("/%s %d 0 R", f.key, fontBase+i)
+	}
+
+	info := fmt.Sprintf("<< /Producer (NextPDF) /CreationDate (D:%s) ", created.UTC().Format("20060102150405Z"))
+	if title != "" {
+		info += "/Title " + pdfUTF16String(title) + " "
+	}
+	object(info + ">>")
+
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << %s >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, strings.Join(fonts, " "), firstPage+2*i+1))
+
+		var stream bytes.Buffer
+		zw := zlib.NewWriter(&stream)
+		if _, err := zw.Write(page.Bytes()); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", stream.Len(), stream.Bytes()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, infoID, xref)
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// pdfWords splits runs into words, each in the font matching its formatting.
+// A word is marked as spaced from the previous one when whitespace separated
+// them, including across run boundaries.
+func pdfWords(runs []docxRun) []pdfWord {
+	words := []pdfWord{}
+	space := false
+	for _, run := range runs {
+		font := "F1"
+		switch {
+		case run.Code:
+			font = "F5"
+		case run.Bold && run.Italic:
+			font = "F4"
+		case run.Bold:
+			font = "F2"
+		case run.Italic:
+			font = "F3"
+		}
+
+		for i, piece := range strings.Split(run.Text, " ") {
+			if i > 0 {
+				space = true
+			}
+			if piece == "" {
+				continue
+			}
+			words = append(words, pdfWord{text: piece, font: font, space: space && len(words) > 0})
+			space = false
+		}
+	}
+	return words
+}
+
+// pdfSplitWord breaks a word wider than maxWidth into pieces that fit
+func pdfSplitWord(word pdfWord, size, maxWidth float64) []pdfWord {
+	if pdfTextWidthOf(word.font, word.text, size) <= maxWidth {
+		return []pdfWord{word}
+	}
+
+	var pieces []pdfWord
+	runes := []rune(word.text)
+	start := 0
+	for i := 1; i <= len(runes); i++ {
+		if i == len(runes) || pdfTextWidthOf(word.font, string(runes[start:i+1]), size) > maxWidth {
+			pieces = append(pieces, pdfWord{text: string(runes[start:i]), font: word.font, space: start == 0 && word.space})
+			start = i
+		}
+	}
+	return pieces
+}
+
+// pdfTextWidthOf measures text in points. Bold Helvetica is about 5% wider
+// than regular, which is added so bold lines never overflow.
+func pdfTextWidthOf(font, text string, size float64) float64 {
+	if font == "F5" {
+		return float64(len([]rune(text))) * 0.6 * size
+	}
+
+	units := 0
+	for _, r := range text {
+		if r >= 32 && r <= 126 {
+			units += helveticaWidths[r-32]
+		} else {
+			units += 556
+		}
+	}
+	width := float64(units) * size / 1000
+	if font == "F2" || font == "F4" {
+		width *= 1.06
+	}
+	return width
+}
+
+// pdfText draws text with its baseline starting at (x, y)
+func pdfText(page *bytes.Buffer, font string, size, x, y float64, text string) {
+	fmt.Fprintf(page, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfEscape(text))
+}
+
+// pdfEscape encodes text as WinAnsi and escapes it for a literal string
+func pdfEscape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r >= 32 && r <= 126, r >= 0xA0 && r <= 0xFF:
+			b.WriteByte(byte(r))
+		default:
+			if c, ok := winAnsiExtra[r]; ok {
+				b.WriteByte(c)
+			} else {
+				b.WriteByte('?')
+			}
+		}
+	}
+	return b.String()
+}
+
+// pdfEncodable reports whether r has a WinAnsi code the standard fonts can show
+func pdfEncodable(r rune) bool {
+	if r >= 32 && r <= 126 || r >= 0xA0 && r <= 0xFF {
+		return true
+	}
+	_, ok := winAnsiExtra[r]
+	return ok
+}
+
+// pdfUTF16String encodes text as a UTF-16BE hex string, which document
+// metadata such as the title may use to hold any character
+func pdfUTF16String(text string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(text)) {
+		fmt.Fprintf(&b, "%04X", u)
+	}
+	b.WriteString(">")
+	return b.String()
+}
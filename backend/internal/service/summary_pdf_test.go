@@ -0,0 +1,27 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWritePDFRejectsUnsupportedText(t *testing.T) {
+	var doc bytes.Buffer
+	if err := writePDF(&doc, "Résumé", "- “quoted” café – 5 €\n\n```\n\tcode\n```", time.Now()); err != nil {
+		t.Fatalf("WinAnsi text: %v", err)
+	}
+	if !bytes.HasPrefix(doc.Bytes(), []byte("%PDF-")) {
+		t.Fatal("output is not a PDF")
+	}
+
+	for _, text := range []string{"日本語の要約", "Ελληνικά", "emoji 🙂"} {
+		if err := writePDF(&bytes.Buffer{}, "", text, time.Now()); !errors.Is(err, ErrPDFUnsupportedText) {
+			t.Errorf("writePDF(%q) = %v, want ErrPDFUnsupportedText", text, err)
+		}
+	}
+	if err := writePDF(&bytes.Buffer{}, "Заголовок", "body", time.Now()); !errors.Is(err, ErrPDFUnsupportedText) {
+		t.Errorf("unsupported title = %v, want ErrPDFUnsupportedText", err)
+	}
+}
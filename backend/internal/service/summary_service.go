@@ -1,41 +1,81 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/nextpdf/backend/internal/models"
 	"github.com/nextpdf/backend/internal/repository"
+	"github.com/nextpdf/backend/internal/storage"
 )
 
 var (
 	ErrAlreadyProcessing = errors.New("a summary is already being generated for this file")
 	ErrInvalidStyle      = errors.New("invalid summary style")
+	ErrInvalidFormat     = errors.New("invalid summary format")
+	ErrPageCountUnknown  = errors.New("file page count is unknown")
+	ErrInvalidBucket     = errors.New("invalid timeseries bucket")
+	ErrInvalidRange      = errors.New("invalid timeseries range")
+	ErrUnknownModel      = errors.New("model is not available")
+	// ErrUnsupportedLanguage is returned for a language missing from SUPPORTED_LANGUAGES
+	ErrUnsupportedLanguage = errors.New("summary language is not supported")
+	// ErrSourceTextDisabled is returned when source text storage is turned off
+	ErrSourceTextDisabled = errors.New("summary source text is not stored")
+	// ErrSourceTextNotStored is returned for a version saved without its source text
+	ErrSourceTextNotStored = errors.New("no source text was stored for this summary")
 )
 
 type SummaryService struct {
-	summaryRepo *repository.SummaryRepository
-	fileRepo    *repository.FileRepository
-	jobRepo     *repository.ProcessingJobRepository
-	aiClient    *AIClient
+	summaryRepo     *repository.SummaryRepository
+	fileRepo        *repository.FileRepository
+	workspaceRepo   *repository.WorkspaceRepository
+	jobRepo         *repository.ProcessingJobRepository
+	statsRepo       *repository.StatsRepository
+	aiClient        *AIClient
+	storage         *storage.Storage
+	languages       *Languages
+	customStyleRepo *repository.CustomStyleRepository
 }
 
 func NewSummaryService(
 	summaryRepo *repository.SummaryRepository,
 	fileRepo *repository.FileRepository,
+	workspaceRepo *repository.WorkspaceRepository,
 	jobRepo *repository.ProcessingJobRepository,
+	statsRepo *repository.StatsRepository,
 	aiClient *AIClient,
+	storage *storage.Storage,
+	languages *Languages,
+	customStyleRepo *repository.CustomStyleRepository,
 ) *SummaryService {
 	return &SummaryService{
-		summaryRepo: summaryRepo,
-		fileRepo:    fileRepo,
-		jobRepo:     jobRepo,
-		aiClient:    aiClient,
+		summaryRepo:     summaryRepo,
+		fileRepo:        fileRepo,
+		workspaceRepo:   workspaceRepo,
+		jobRepo:         jobRepo,
+		statsRepo:       statsRepo,
+		aiClient:        aiClient,
+		storage:         storage,
+		languages:       languages,
+		customStyleRepo: customStyleRepo,
 	}
 }
 
-func (s *SummaryService) GetByFileID(ctx context.Context, userID, fileID uuid.UUID, version *int) (*models.SummaryResponse, *models.SummaryStatusResponse, error) {
+func (s *SummaryService) GetByFileID(ctx context.Context, userID, fileID uuid.UUID, version *int, format models.SummaryFormat) (*models.SummaryResponse, *models.SummaryStatusResponse, error) {
+	if format == "" {
+		format = models.FormatMarkdown
+	}
+	if !format.IsValid() {
+		return nil, nil, ErrInvalidFormat
+	}
+
 	// Verify file ownership
 	file, err := s.fileRepo.GetByID(ctx, fileID)
 	if err != nil {
@@ -100,11 +140,124 @@ func (s *SummaryService) GetByFileID(ctx context.Context, userID, fileID uuid.UU
 		return nil, nil, err
 	}
 
+	response, err := s.toSummaryResponse(ctx, summary, format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return response, nil, nil
+}
+
+// GetLatest returns the file's current summary, or ErrSummaryNotFound if it
+// has none yet. Unlike GetByFileID it never answers with a status object.
+func (s *SummaryService) GetLatest(ctx context.Context, userID, fileID uuid.UUID, format models.SummaryFormat) (*models.SummaryResponse, error) {
+	if format == "" {
+		format = models.FormatMarkdown
+	}
+	if !format.IsValid() {
+		return nil, ErrInvalidFormat
+	}
+
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.UserID != userID {
+		return nil, repository.ErrFileNotFound
+	}
+
+	summary, err := s.summaryRepo.GetCurrentByFileID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toSummaryResponse(ctx, summary, format)
+}
+
+// SetCurrentVersion rolls the file's current summary back (or forward) to an
+// existing version and returns it
+func (s *SummaryService) SetCurrentVersion(ctx context.Context, userID, fileID uuid.UUID, version int) (*models.SummaryResponse, error) {
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.UserID != userID {
+		return nil, repository.ErrFileNotFound
+	}
+
+	if err := s.summaryRepo.SetCurrent(ctx, fileID, version); err != nil {
+		return nil, err
+	}
+
+	summary, err := s.summaryRepo.GetCurrentByFileID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	return s.toSummaryResponse(ctx, summary, models.FormatMarkdown)
+}
+
+// SubmitFeedback rates a summary version, the current one when req.Version is
+// nil. A user has one feedback per summary; submitting again replaces it.
+func (s *SummaryService) SubmitFeedback(ctx context.Context, userID, fileID uuid.UUID, req *models.SummaryFeedbackRequest) (*models.SummaryFeedbackResponse, error) {
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.UserID != userID {
+		return nil, repository.ErrFileNotFound
+	}
+
+	var summary *models.Summary
+	if req.Version != nil {
+		summary, err = s.summaryRepo.GetByFileIDAndVersion(ctx, fileID, *req.Version)
+	} else {
+		summary, err = s.summaryRepo.GetCurrentByFileID(ctx, fileID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	comment := req.Comment
+	if comment != nil && strings.TrimSpace(*comment) == "" {
+		comment = nil
+	}
+	updatedAt, err := s.summaryRepo.UpsertFeedback(ctx, summary.ID, userID, req.Rating, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	rating, err := s.summaryRepo.GetRating(ctx, summary.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SummaryFeedbackResponse{
+		SummaryID: summary.ID,
+		Version:   summary.Version,
+		Rating:    req.Rating,
+		Comment:   comment,
+		UpdatedAt: updatedAt,
+		Summary:   rating,
+	}, nil
+}
+
+func (s *SummaryService) toSummaryResponse(ctx context.Context, summary *models.Summary, format models.SummaryFormat) (*models.SummaryResponse, error) {
+	// Lets a client viewing an old version point at the newer one
+	latestVersion, err := s.summaryRepo.GetLatestVersion(ctx, summary.FileID)
+	if err != nil {
+		return nil, err
+	}
+	rating, err := s.summaryRepo.GetRating(ctx, summary.ID)
+	if err != nil {
+		return nil, err
+	}
+
 	return &models.SummaryResponse{
 		ID:                    summary.ID,
 		FileID:                summary.FileID,
 		Title:                 summary.Title,
-		Content:               summary.Content,
+		Content:               RenderSummaryContent(summary.Content, format),
+		Format:                format,
 		Style:                 summary.Style,
 		CustomInstructions:    summary.CustomInstructions,
 		ModelUsed:             summary.ModelUsed,
@@ -115,9 +268,43 @@ func (s *SummaryService) GetByFileID(ctx context.Context, userID, fileID uuid.UU
 		ProcessingDurationMs:  summary.ProcessingDurationMs,
 		Language:              summary.Language,
 		Version:               summary.Version,
+		LatestVersion:         latestVersion,
+		IsLatest:              summary.Version >= latestVersion,
 		IsCurrent:             summary.IsCurrent,
+		Rating:                rating,
 		CreatedAt:             summary.CreatedAt,
-	}, nil, nil
+	}, nil
+}
+
+// GetSourceText returns the text that was summarized for a version, or for
+// the current summary when version is nil
+func (s *SummaryService) GetSourceText(ctx context.Context, userID, fileID uuid.UUID, version *int) (*models.SummarySourceTextResponse, error) {
+	if !s.summaryRepo.StoresSourceText() {
+		return nil, ErrSourceTextDisabled
+	}
+
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.UserID != userID {
+		return nil, repository.ErrFileNotFound
+	}
+
+	v, text, err := s.summaryRepo.GetSourceText(ctx, fileID, version)
+	if err != nil {
+		return nil, err
+	}
+	if text == nil {
+		return nil, ErrSourceTextNotStored
+	}
+
+	return &models.SummarySourceTextResponse{FileID: fileID, Version: v, SourceText: *text}, nil
+}
+
+// MaxVersions is the number of summary versions kept per file; 0 means no limit
+func (s *SummaryService) MaxVersions() int {
+	return s.summaryRepo.MaxVersions()
 }
 
 func (s *SummaryService) GetHistory(ctx context.Context, userID, fileID uuid.UUID) ([]*models.SummaryHistoryItem, error) {
@@ -134,22 +321,200 @@ func (s *SummaryService) GetHistory(ctx context.Context, userID, fileID uuid.UUI
 	return s.summaryRepo.GetHistoryByFileID(ctx, fileID)
 }
 
+// Estimates need this many past summaries before a tier of history is trusted;
+// below it the next, broader tier is tried and finally the defaults
+const minEstimateSamples = 5
+
+// Rough per-page costs used until there is enough history
+var defaultEstimate = repository.SummaryAverages{
+	DurationMsPerPage:       1500,
+	PromptTokensPerPage:     700,
+	CompletionTokensPerPage: 60,
+}
+
+// Estimate predicts duration and token usage for summarizing a file in the
+// given style, from past summaries of similarly sized files
+func (s *SummaryService) Estimate(ctx context.Context, userID, fileID uuid.UUID, style models.SummaryStyle) (*models.SummaryEstimateResponse, error) {
+	if style == "" {
+		style = models.StyleBulletPoints
+	}
+	if !style.IsBuiltIn() {
+		return nil, ErrInvalidStyle
+	}
+
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.UserID != userID {
+		return nil, repository.ErrFileNotFound
+	}
+	if file.PageCount == nil || *file.PageCount <= 0 {
+		return nil, ErrPageCountUnknown
+	}
+	pages := *file.PageCount
+
+	tiers := []struct {
+		basis              string
+		style              *models.SummaryStyle
+		minPages, maxPages int
+	}{
+		{"similar_pages", &style, pages / 2, pages * 2},
+		{"style", &style, 0, 0},
+		{"all", nil, 0, 0},
+	}
+
+	averages, basis := &defaultEstimate, "default"
+	for _, tier := range tiers {
+		avg, err := s.statsRepo.EstimateForPages(ctx, tier.style, tier.minPages, tier.maxPages)
+		if err != nil {
+			return nil, err
+		}
+		if avg.Samples >= minEstimateSamples {
+			averages, basis = avg, tier.basis
+			break
+		}
+	}
+
+	return &models.SummaryEstimateResponse{
+		FileID:                    fileID,
+		Style:                     style,
+		PageCount:                 pages,
+		EstimatedDurationMs:       int64(averages.DurationMsPerPage * float64(pages)),
+		EstimatedPromptTokens:     int64(averages.PromptTokensPerPage * float64(pages)),
+		EstimatedCompletionTokens: int64(averages.CompletionTokensPerPage * float64(pages)),
+		SampleSize:                averages.Samples,
+		Basis:                     basis,
+	}, nil
+}
+
+// Timeseries buckets and the widest range each may span, which bounds the
+// number of points returned
+var timeseriesMaxRange = map[string]time.Duration{
+	"day":   366 * 24 * time.Hour,
+	"week":  5 * 366 * 24 * time.Hour,
+	"month": 10 * 366 * 24 * time.Hour,
+}
+
+// defaultTimeseriesDays is the range used when from is omitted
+const defaultTimeseriesDays = 30
+
+const timeseriesDateLayout = "2006-01-02"
+
+// SummaryTimeseries counts the user's summaries per bucket between the from
+// and to dates, both inclusive and in UTC. Zero dates default to the last
+// defaultTimeseriesDays days.
+func (s *SummaryService) SummaryTimeseries(ctx context.Context, userID uuid.UUID, from, to time.Time, bucket string) (*models.SummaryTimeseriesResponse, error) {
+	if bucket == "" {
+		bucket = "day"
+	}
+	maxRange, ok := timeseriesMaxRange[bucket]
+	if !ok {
+		return nil, ErrInvalidBucket
+	}
+
+	if to.IsZero() {
+		to = time.Now().UTC().Truncate(24 * time.Hour)
+	}
+	if from.IsZero() {
+		from = to.AddDate(0, 0, -(defaultTimeseriesDays - 1))
+	}
+	if to.Before(from) || to.Sub(from) > maxRange {
+		return nil, ErrInvalidRange
+	}
+
+	// The to date is inclusive, so count through the end of that day
+	buckets, err := s.statsRepo.SummaryTimeseries(ctx, userID, bucket, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.SummaryTimeseriesResponse{
+		Bucket: bucket,
+		From:   from.Format(timeseriesDateLayout),
+		To:     to.Format(timeseriesDateLayout),
+		Points: make([]models.TimeseriesPoint, 0, len(buckets)),
+	}
+	for _, b := range buckets {
+		response.Points = append(response.Points, models.TimeseriesPoint{
+			Date:  b.Start.Format(timeseriesDateLayout),
+			Count: b.Count,
+		})
+		response.Total += b.Count
+	}
+
+	return response, nil
+}
+
 func (s *SummaryService) Generate(ctx context.Context, userID, fileID uuid.UUID, req *models.GenerateSummaryRequest) (*models.GenerateSummaryResponse, error) {
+	// Verify file ownership
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	if file.UserID != userID {
+		return nil, repository.ErrFileNotFound
+	}
+
 	// Validate style
+	s.applyWorkspaceDefaults(ctx, file, req)
 	if !req.Style.IsValid() {
 		return nil, ErrInvalidStyle
 	}
+	if !s.allowsModel(req.Model) {
+		return nil, ErrUnknownModel
+	}
+	if !s.languages.Allows(req.Language) {
+		return nil, ErrUnsupportedLanguage
+	}
+	if err := s.expandCustomStyle(ctx, userID, req); err != nil {
+		return nil, err
+	}
 
-	// Verify file ownership
+	return s.start(ctx, file, req, "")
+}
+
+// Resume continues the partial output of an interrupted summary attempt
+// instead of starting over. Without a stored partial summary, or when the AI
+// service cannot continue it, the summary is regenerated in full.
+func (s *SummaryService) Resume(ctx context.Context, userID, fileID uuid.UUID, req *models.GenerateSummaryRequest) (*models.GenerateSummaryResponse, error) {
 	file, err := s.fileRepo.GetByID(ctx, fileID)
 	if err != nil {
 		return nil, err
 	}
-
 	if file.UserID != userID {
 		return nil, repository.ErrFileNotFound
 	}
 
+	partial, err := s.fileRepo.GetPartialSummary(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.applyWorkspaceDefaults(ctx, file, req)
+	if !req.Style.IsValid() {
+		return nil, ErrInvalidStyle
+	}
+	if !s.allowsModel(req.Model) {
+		return nil, ErrUnknownModel
+	}
+	if !s.languages.Allows(req.Language) {
+		return nil, ErrUnsupportedLanguage
+	}
+	if err := s.expandCustomStyle(ctx, userID, req); err != nil {
+		return nil, err
+	}
+
+	return s.start(ctx, file, req, partial)
+}
+
+// start queues a summary job and requests it from the AI service in the
+// background. A non-empty continueFrom asks the AI service to continue that
+// partial summary, falling back to a full run if the request fails.
+func (s *SummaryService) start(ctx context.Context, file *models.File, req *models.GenerateSummaryRequest, continueFrom string) (*models.GenerateSummaryResponse, error) {
+	fileID := file.ID
+
 	// Check checks removed to allow multiple/concurrent summaries and recovery from stuck state
 	// if file.Status == models.StatusProcessing || file.Status == models.StatusPending {
 	// 	return nil, ErrAlreadyProcessing
@@ -181,30 +546,215 @@ func (s *SummaryService) Generate(ctx context.Context, userID, fileID uuid.UUID,
 	}
 
 	// Call AI service asynchronously
+	includeSourceText := s.summaryRepo.StoresSourceText()
 	go func() {
-		if s.aiClient != nil {
-			_ = s.aiClient.RequestSummary(context.Background(), fileID, file.StoragePath, req.Style, req.CustomInstructions, req.Language)
+		hints := s.documentHints(context.Background(), file.StoragePath)
+		language := s.languages.Resolve(req.Language, hints)
+		if s.aiClient == nil {
+			return
+		}
+		err := s.aiClient.RequestSummary(context.Background(), fileID, file.StoragePath, req.Style, req.CustomInstructions, language, req.Model, continueFrom, includeSourceText, hints)
+		if err != nil && continueFrom != "" {
+			log.Printf("AI service could not resume summary for file %s, regenerating: %v", fileID, err)
+			_ = s.aiClient.RequestSummary(context.Background(), fileID, file.StoragePath, req.Style, req.CustomInstructions, language, req.Model, "", includeSourceText, hints)
 		}
 	}()
 
+	message := "Summary generation started. Check status at GET /summaries/{file_id}"
+	if continueFrom != "" {
+		message = "Resuming the interrupted summary. Check status at GET /summaries/{file_id}"
+	}
+
 	return &models.GenerateSummaryResponse{
 		FileID:             fileID,
 		Status:             "processing",
 		JobID:              job.ID,
 		Style:              req.Style,
 		CustomInstructions: req.CustomInstructions,
-		Message:            "Summary generation started. Check status at GET /summaries/{file_id}",
+		Resumed:            continueFrom != "",
+		Message:            message,
 	}, nil
 }
 
-func (s *SummaryService) GetStyles() []models.SummaryStyleInfo {
-	return models.GetSummaryStyles()
+// applyWorkspaceDefaults fills an omitted style or language from the file's
+// workspace settings. A style that is still missing becomes bullet_points;
+// an empty language is resolved later like any other request.
+func (s *SummaryService) applyWorkspaceDefaults(ctx context.Context, file *models.File, req *models.GenerateSummaryRequest) {
+	if file.WorkspaceID != nil && (req.Style == "" || req.Language == "") {
+		workspace, err := s.workspaceRepo.GetByID(ctx, *file.WorkspaceID)
+		if err != nil {
+			log.Printf("Failed to load summary defaults of workspace %s: %v", *file.WorkspaceID, err)
+		} else {
+			if req.Style == "" && workspace.DefaultStyle != nil {
+				req.Style = *workspace.DefaultStyle
+			}
+			if req.Language == "" && workspace.DefaultLanguage != nil {
+				req.Language = *workspace.DefaultLanguage
+			}
+		}
+	}
+
+	if req.Style == "" {
+		req.Style = models.StyleBulletPoints
+	}
+}
+
+// GenerateEphemeral summarizes a file synchronously and returns the result
+// without saving it: no version is added and the file status is untouched
+func (s *SummaryService) GenerateEphemeral(ctx context.Context, userID, fileID uuid.UUID, req *models.GenerateSummaryRequest) (*models.EphemeralSummaryResponse, error) {
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.UserID != userID {
+		return nil, repository.ErrFileNotFound
+	}
+
+	s.applyWorkspaceDefaults(ctx, file, req)
+	if !req.Style.IsValid() {
+		return nil, ErrInvalidStyle
+	}
+	if !s.allowsModel(req.Model) {
+		return nil, ErrUnknownModel
+	}
+	if !s.languages.Allows(req.Language) {
+		return nil, ErrUnsupportedLanguage
+	}
+	if err := s.expandCustomStyle(ctx, userID, req); err != nil {
+		return nil, err
+	}
+
+	data, err := s.readFile(ctx, file.StoragePath)
+	if err != nil {
+		return nil, err
+	}
+	hints := ExtractPDFHints(data)
+
+	raw, err := s.aiClient.SummarizeSync(ctx, file.OriginalFilename, bytes.NewReader(data), req.Style, req.CustomInstructions, s.languages.Resolve(req.Language, hints), req.Model, hints)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.EphemeralSummaryResponse{
+		FileID:    fileID,
+		Ephemeral: true,
+		Summary:   raw,
+	}, nil
+}
+
+// GetDebugInfo returns the stored generation parameters of a summary. With
+// dryRun set, the same request is replayed against the AI service and its raw
+// response is attached without saving anything. Admin use only: no ownership check.
+func (s *SummaryService) GetDebugInfo(ctx context.Context, summaryID uuid.UUID, dryRun bool) (*models.SummaryDebugResponse, error) {
+	summary, err := s.summaryRepo.GetByID(ctx, summaryID)
+	if err != nil {
+		return nil, err
+	}
+
+	debug := &models.SummaryDebugResponse{
+		SummaryID:            summary.ID,
+		FileID:               summary.FileID,
+		Version:              summary.Version,
+		Style:                summary.Style,
+		CustomInstructions:   summary.CustomInstructions,
+		Language:             summary.Language,
+		ModelUsed:            summary.ModelUsed,
+		PromptTokens:         summary.PromptTokens,
+		CompletionTokens:     summary.CompletionTokens,
+		ProcessingDurationMs: summary.ProcessingDurationMs,
+		CreatedAt:            summary.CreatedAt,
+	}
+
+	if !dryRun {
+		return debug, nil
+	}
+
+	file, err := s.fileRepo.GetByID(ctx, summary.FileID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.readFile(ctx, file.StoragePath)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := s.aiClient.SummarizeSync(ctx, file.OriginalFilename, bytes.NewReader(data), summary.Style, summary.CustomInstructions, summary.Language, "", ExtractPDFHints(data))
+	if err != nil {
+		return nil, err
+	}
+	debug.DryRun = raw
+
+	return debug, nil
+}
+
+// readFile downloads a stored file into memory
+func (s *SummaryService) readFile(ctx context.Context, storagePath string) ([]byte, error) {
+	content, err := s.storage.GetObject(ctx, s.storage.BucketFiles(), storagePath)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	return io.ReadAll(content)
+}
+
+// documentHints extracts hints from the stored PDF; a failed download just
+// means no hints
+func (s *SummaryService) documentHints(ctx context.Context, storagePath string) DocumentHints {
+	data, err := s.readFile(ctx, storagePath)
+	if err != nil {
+		return DocumentHints{}
+	}
+	return ExtractPDFHints(data)
+}
+
+// GetStyles lists the built-in styles followed, for a signed-in user (userID
+// not uuid.Nil), by their custom styles
+func (s *SummaryService) GetStyles(ctx context.Context, userID uuid.UUID) ([]models.SummaryStyleInfo, error) {
+	styles := models.GetSummaryStyles()
+	if userID == uuid.Nil {
+		return styles, nil
+	}
+
+	custom, err := s.customStyleInfos(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return append(styles, custom...), nil
+}
+
+// GetLanguages lists the languages a summary request may pick
+func (s *SummaryService) GetLanguages() []models.SummaryLanguage {
+	return s.languages.List()
+}
+
+// GetModels lists the models a summary request may pick
+func (s *SummaryService) GetModels() *models.SummaryModelsResponse {
+	response := &models.SummaryModelsResponse{Models: []string{}}
+	if s.aiClient != nil {
+		response.Models = append(response.Models, s.aiClient.Models()...)
+	}
+	return response
+}
+
+// allowsModel accepts an empty model (the AI service default) or one from
+// the configured allowlist
+func (s *SummaryService) allowsModel(model string) bool {
+	return model == "" || (s.aiClient != nil && s.aiClient.AllowsModel(model))
 }
 
 // ProcessCallback processes the callback from AI service when summary is complete
 func (s *SummaryService) ProcessCallback(ctx context.Context, fileID uuid.UUID, req *models.SummaryCallbackRequest) error {
 	// Create summary
-	title := req.Title
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		var filename string
+		if file, err := s.fileRepo.GetByID(ctx, fileID); err == nil {
+			filename = file.OriginalFilename
+		}
+		title = fallbackSummaryTitle(req.Content, filename)
+	}
 	modelUsed := req.ModelUsed
 	promptTokens := req.PromptTokens
 	completionTokens := req.CompletionTokens
@@ -221,6 +771,7 @@ func (s *SummaryService) ProcessCallback(ctx context.Context, fileID uuid.UUID,
 		CompletionTokens:     &completionTokens,
 		ProcessingDurationMs: &durationMs,
 		Language:             req.Language,
+		SourceText:           nonEmpty(req.SourceText),
 	}
 
 	if err := s.summaryRepo.Create(ctx, summary); err != nil {
@@ -232,10 +783,53 @@ func (s *SummaryService) ProcessCallback(ctx context.Context, fileID uuid.UUID,
 		return err
 	}
 
+	if err := s.fileRepo.SetPartialSummary(ctx, fileID, nil); err != nil {
+		log.Printf("Failed to clear partial summary of file %s: %v", fileID, err)
+	}
+
 	return nil
 }
 
-// ProcessErrorCallback processes the callback from AI service when summary fails
-func (s *SummaryService) ProcessErrorCallback(ctx context.Context, fileID uuid.UUID, errorMessage string) error {
+// nonEmpty returns nil for an empty string, so it is stored as NULL
+func nonEmpty(v string) *string {
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
+// maxFallbackTitleLength bounds titles derived from summary content
+const maxFallbackTitleLength = 80
+
+// fallbackSummaryTitle derives a title for summaries the AI returned without
+// one: the first non-empty line of content stripped of markdown markers, or
+// failing that the original filename without its extension
+func fallbackSummaryTitle(content, originalFilename string) string {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#*->•_ \t"))
+		line = strings.TrimSpace(strings.TrimRight(line, "*_:"))
+		if line == "" {
+			continue
+		}
+		if runes := []rune(line); len(runes) > maxFallbackTitleLength {
+			line = strings.TrimSpace(string(runes[:maxFallbackTitleLength])) + "…"
+		}
+		return line
+	}
+
+	if name := strings.TrimSuffix(originalFilename, filepath.Ext(originalFilename)); name != "" {
+		return name
+	}
+	return "Untitled summary"
+}
+
+// ProcessErrorCallback processes the callback from AI service when summary
+// fails. Content the AI service produced before failing is kept for Resume.
+func (s *SummaryService) ProcessErrorCallback(ctx context.Context, fileID uuid.UUID, errorMessage, partialContent string) error {
+	if partialContent != "" {
+		if err := s.fileRepo.SetPartialSummary(ctx, fileID, &partialContent); err != nil {
+			log.Printf("Failed to save partial summary of file %s: %v", fileID, err)
+		}
+	}
 	return s.fileRepo.UpdateStatus(ctx, fileID, models.StatusFailed, &errorMessage)
 }
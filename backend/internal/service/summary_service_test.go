@@ -0,0 +1,45 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestFallbackSummaryTitle(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		filename string
+		want     string
+	}{
+		{"heading", "\n\n## Key Findings\n- one", "report.pdf", "Key Findings"},
+		{"bold label", "**Overview:**\nText", "report.pdf", "Overview"},
+		{"bullet", "  - First point\n- Second", "report.pdf", "First point"},
+		{"markers only", "---\n***\n", "Annual Report 2024.pdf", "Annual Report 2024"},
+		{"empty content", "", "notes.pdf", "notes"},
+		{"no filename", "   \n", "", "Untitled summary"},
+		{"extension only", "", ".pdf", "Untitled summary"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fallbackSummaryTitle(tt.content, tt.filename); got != tt.want {
+				t.Errorf("fallbackSummaryTitle(%q, %q) = %q, want %q", tt.content, tt.filename, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFallbackSummaryTitleTruncatesRunes(t *testing.T) {
+	got := fallbackSummaryTitle(strings.Repeat("é", maxFallbackTitleLength+20), "")
+	if !utf8.ValidString(got) {
+		t.Fatalf("title %q is not valid UTF-8", got)
+	}
+	if n := utf8.RuneCountInString(got); n != maxFallbackTitleLength+1 {
+		t.Errorf("title has %d runes, want %d and an ellipsis", n, maxFallbackTitleLength)
+	}
+	if !strings.HasSuffix(got, "…") {
+		t.Errorf("title %q does not end with an ellipsis", got)
+	}
+}
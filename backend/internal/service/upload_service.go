@@ -54,8 +54,8 @@ func (s *UploadService) CreateAvatarPresignedUpload(ctx context.Context, userID
 	uploadID := uuid.New()
 	storagePath := fmt.Sprintf("avatars/%s/%s%s", userID.String(), uploadID.String(), ext)
 
-	// Generate presigned URL
-	presignedURL, err := s.storage.GeneratePresignedPutURL(ctx, s.storage.BucketAvatars(), storagePath, req.ContentType, req.FileSize)
+	// The POST policy pins the content type and exact size of the upload
+	presignedURL, fields, err := s.storage.GeneratePresignedPostPolicy(ctx, s.storage.BucketAvatars(), storagePath, req.ContentType, req.FileSize)
 	if err != nil {
 		return nil, err
 	}
@@ -79,6 +79,7 @@ func (s *UploadService) CreateAvatarPresignedUpload(ctx context.Context, userID
 		UploadID:     pendingUpload.ID,
 		PresignedURL: presignedURL.String(),
 		ExpiresAt:    expiresAt,
+		Headers:      fields,
 	}, nil
 }
 
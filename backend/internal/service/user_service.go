@@ -3,10 +3,14 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/nextpdf/backend/internal/models"
 	"github.com/nextpdf/backend/internal/repository"
+	"github.com/nextpdf/backend/internal/storage"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -15,12 +19,18 @@ var ErrInvalidPassword = errors.New("current password is incorrect")
 type UserService struct {
 	userRepo    *repository.UserRepository
 	sessionRepo *repository.SessionRepository
+	storage     *storage.Storage
 }
 
-func NewUserService(userRepo *repository.UserRepository, sessionRepo *repository.SessionRepository) *UserService {
+func NewUserService(
+	userRepo *repository.UserRepository,
+	sessionRepo *repository.SessionRepository,
+	storage *storage.Storage,
+) *UserService {
 	return &UserService{
 		userRepo:    userRepo,
 		sessionRepo: sessionRepo,
+		storage:     storage,
 	}
 }
 
@@ -82,5 +92,60 @@ func (s *UserService) RevokeSession(ctx context.Context, userID, sessionID uuid.
 		return repository.ErrSessionNotFound
 	}
 
-	return s.sessionRepo.Delete(ctx, sessionID)
+	return s.sessionRepo.Revoke(ctx, sessionID)
+}
+
+// DeleteAccount permanently deletes the user after checking their password.
+// The rows go first, in one transaction; stored objects are then removed
+// best-effort: a failed deletion is logged and left for the storage
+// reconciler rather than failing a deletion that already happened.
+// Deleting an account that no longer exists succeeds; an owner of a workspace
+// with other members gets repository.ErrOwnsSharedWorkspace.
+func (s *UserService) DeleteAccount(ctx context.Context, userID uuid.UUID, password string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return ErrInvalidPassword
+	}
+
+	// Refresh tokens and sessions go with the user row
+	paths, err := s.userRepo.Delete(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	failed := 0
+	for _, path := range paths {
+		if err := s.storage.DeleteObject(ctx, s.storage.BucketFiles(), path); err != nil {
+			log.Printf("Account deletion for user %s: failed to delete %s: %v", userID, path, err)
+			failed++
+		}
+	}
+
+	avatarPrefix := fmt.Sprintf("avatars/%s/", userID.String())
+	err = s.storage.ListObjectsWithPrefix(ctx, s.storage.BucketAvatars(), avatarPrefix, func(key string, _ time.Time) error {
+		if err := s.storage.DeleteObject(ctx, s.storage.BucketAvatars(), key); err != nil {
+			log.Printf("Account deletion for user %s: failed to delete avatar %s: %v", userID, key, err)
+			failed++
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Account deletion for user %s: failed to list avatars: %v", userID, err)
+	}
+
+	if failed > 0 {
+		log.Printf("Account deletion for user %s: %d storage object(s) left behind", userID, failed)
+	}
+
+	return nil
 }
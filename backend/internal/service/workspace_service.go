@@ -6,8 +6,10 @@ import (
 	"encoding/hex"
 	"errors"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/nextpdf/backend/internal/models"
 	"github.com/nextpdf/backend/internal/repository"
 )
@@ -16,6 +18,19 @@ var (
 	ErrWorkspaceNotFound = repository.ErrWorkspaceNotFound
 	ErrInviteCodeInvalid = repository.ErrInviteCodeInvalid
 	ErrAlreadyMember     = repository.ErrAlreadyMember
+	ErrNotMember         = errors.New("user is not a member of this workspace")
+	ErrMemberNotFound    = repository.ErrMemberNotFound
+	ErrNotOwner          = errors.New("only the workspace owner can do this")
+	ErrCannotRemoveOwner = errors.New("the workspace owner cannot be removed")
+	ErrOwnerCannotLeave  = errors.New("the workspace owner must transfer ownership before leaving")
+	ErrAlreadyOwner      = errors.New("the user already owns this workspace")
+	ErrInvalidRole       = errors.New("role must be admin or member")
+	ErrCannotChangeOwner = errors.New("the workspace owner's role cannot be changed")
+	ErrInsufficientRole  = errors.New("admins can only remove members")
+	ErrNotManager        = errors.New("only the workspace owner or an admin can do this")
+	ErrInviteNotFound    = repository.ErrInviteNotFound
+	ErrInviteExpired     = repository.ErrInviteExpired
+	ErrInviteExhausted   = repository.ErrInviteExhausted
 )
 
 type WorkspaceService struct {
@@ -47,7 +62,7 @@ func (s *WorkspaceService) CreateWorkspace(ctx context.Context, userID uuid.UUID
 	member := &models.WorkspaceMember{
 		WorkspaceID: workspace.ID,
 		UserID:      userID,
-		Role:        "owner",
+		Role:        models.WorkspaceRoleOwner,
 	}
 
 	if err := s.repo.AddMember(ctx, member); err != nil {
@@ -68,7 +83,7 @@ func (s *WorkspaceService) JoinWorkspace(ctx context.Context, userID uuid.UUID,
 	member := &models.WorkspaceMember{
 		WorkspaceID: workspace.ID,
 		UserID:      userID,
-		Role:        "member",
+		Role:        models.WorkspaceRoleMember,
 	}
 
 	if err := s.repo.AddMember(ctx, member); err != nil {
@@ -78,17 +93,47 @@ func (s *WorkspaceService) JoinWorkspace(ctx context.Context, userID uuid.UUID,
 	return workspace, nil
 }
 
-func (s *WorkspaceService) UpdateWorkspace(ctx context.Context, userID, workspaceID uuid.UUID, name string) (*models.Workspace, error) {
+// UpdateWorkspace applies the fields present in req. Renaming is reserved to
+// the owner; the summary defaults may also be changed by admins.
+func (s *WorkspaceService) UpdateWorkspace(ctx context.Context, userID, workspaceID uuid.UUID, req *models.UpdateWorkspaceRequest) (*models.Workspace, error) {
 	workspace, err := s.repo.GetByID(ctx, workspaceID)
 	if err != nil {
 		return nil, err
 	}
 
 	if workspace.OwnerID != userID {
-		return nil, errors.New("FORBIDDEN: Only the owner can update the workspace")
+		if req.Name != nil {
+			return nil, ErrNotOwner
+		}
+		caller, err := s.VerifyMemberAccess(ctx, workspaceID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if caller.Role != models.WorkspaceRoleAdmin {
+			return nil, ErrNotManager
+		}
+	}
+
+	if req.DefaultStyle != nil {
+		if *req.DefaultStyle == "" {
+			workspace.DefaultStyle = nil
+		} else if !req.DefaultStyle.IsBuiltIn() {
+			return nil, ErrInvalidStyle
+		} else {
+			workspace.DefaultStyle = req.DefaultStyle
+		}
+	}
+	if req.DefaultLanguage != nil {
+		if *req.DefaultLanguage == "" {
+			workspace.DefaultLanguage = nil
+		} else {
+			workspace.DefaultLanguage = req.DefaultLanguage
+		}
+	}
+	if req.Name != nil {
+		workspace.Name = *req.Name
 	}
 
-	workspace.Name = name
 	if err := s.repo.UpdateRow(ctx, workspace); err != nil {
 		return nil, err
 	}
@@ -96,6 +141,32 @@ func (s *WorkspaceService) UpdateWorkspace(ctx context.Context, userID, workspac
 	return workspace, nil
 }
 
+// GetWorkspaceDetail returns a workspace to one of its members, with their
+// role and the member count. The invite code is only shown to managers.
+func (s *WorkspaceService) GetWorkspaceDetail(ctx context.Context, userID, workspaceID uuid.UUID) (*models.WorkspaceResponse, error) {
+	member, err := s.VerifyMemberAccess(ctx, workspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	workspace, err := s.repo.GetByID(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := workspace.ToResponse(member.Role)
+	response.IsOwner = workspace.OwnerID == userID
+	if member.Role != models.WorkspaceRoleOwner && member.Role != models.WorkspaceRoleAdmin {
+		response.InviteCode = ""
+	}
+
+	if count, err := s.repo.GetMemberCount(ctx, workspaceID); err == nil {
+		response.MemberCount = count
+	}
+
+	return response, nil
+}
+
 func (s *WorkspaceService) GetUserWorkspaces(ctx context.Context, userID uuid.UUID) ([]*models.WorkspaceResponse, error) {
 	return s.repo.ListByUserID(ctx, userID)
 }
@@ -105,7 +176,171 @@ func (s *WorkspaceService) GetWorkspace(ctx context.Context, workspaceID uuid.UU
 }
 
 func (s *WorkspaceService) VerifyMemberAccess(ctx context.Context, workspaceID, userID uuid.UUID) (*models.WorkspaceMember, error) {
-	return s.repo.GetMember(ctx, workspaceID, userID)
+	member, err := s.repo.GetMember(ctx, workspaceID, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotMember
+	}
+	return member, err
+}
+
+// GetMembers lists a workspace's members to one of its members
+func (s *WorkspaceService) GetMembers(ctx context.Context, userID, workspaceID uuid.UUID) (*models.WorkspaceMembersResponse, error) {
+	if _, err := s.VerifyMemberAccess(ctx, workspaceID, userID); err != nil {
+		return nil, err
+	}
+
+	members, err := s.repo.ListMembers(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.repo.GetMemberCount(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.WorkspaceMembersResponse{
+		Members:     members,
+		MemberCount: count,
+	}, nil
+}
+
+// RemoveMember lets the owner remove anyone else, and admins remove plain
+// members. Files the member put in the workspace stay there.
+func (s *WorkspaceService) RemoveMember(ctx context.Context, callerID, workspaceID, memberID uuid.UUID) error {
+	workspace, err := s.repo.GetByID(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	// Only the owner and admins may remove anyone, so check the caller first
+	isOwner := workspace.OwnerID == callerID
+	if !isOwner {
+		caller, err := s.VerifyMemberAccess(ctx, workspaceID, callerID)
+		if errors.Is(err, ErrNotMember) {
+			return ErrNotOwner
+		}
+		if err != nil {
+			return err
+		}
+		if caller.Role != models.WorkspaceRoleAdmin {
+			return ErrNotOwner
+		}
+	}
+
+	if memberID == workspace.OwnerID {
+		return ErrCannotRemoveOwner
+	}
+
+	if !isOwner {
+		target, err := s.repo.GetMember(ctx, workspaceID, memberID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrMemberNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if target.Role != models.WorkspaceRoleMember {
+			return ErrInsufficientRole
+		}
+	}
+
+	return s.repo.RemoveMember(ctx, workspaceID, memberID)
+}
+
+// ChangeMemberRole lets the owner promote a member to admin or demote an
+// admin back to member. Ownership cannot be granted or taken this way.
+func (s *WorkspaceService) ChangeMemberRole(ctx context.Context, callerID, workspaceID, memberID uuid.UUID, role string) (*models.WorkspaceMember, error) {
+	if role != models.WorkspaceRoleAdmin && role != models.WorkspaceRoleMember {
+		return nil, ErrInvalidRole
+	}
+
+	workspace, err := s.repo.GetByID(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if workspace.OwnerID != callerID {
+		return nil, ErrNotOwner
+	}
+	if memberID == workspace.OwnerID {
+		return nil, ErrCannotChangeOwner
+	}
+
+	return s.repo.UpdateMemberRole(ctx, workspaceID, memberID, role)
+}
+
+// TransferOwnership makes another member the owner of the workspace. Only the
+// current owner can do this; they stay on as an admin and may then leave.
+func (s *WorkspaceService) TransferOwnership(ctx context.Context, callerID, workspaceID, newOwnerID uuid.UUID) error {
+	workspace, err := s.repo.GetByID(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	if workspace.OwnerID != callerID {
+		return ErrNotOwner
+	}
+	if newOwnerID == callerID {
+		return ErrAlreadyOwner
+	}
+
+	return s.repo.TransferOwnership(ctx, workspaceID, callerID, newOwnerID)
+}
+
+// LeaveWorkspace removes the caller's own membership. Their files stay in the
+// workspace. The owner has to transfer ownership first (TransferOwnership).
+func (s *WorkspaceService) LeaveWorkspace(ctx context.Context, userID, workspaceID uuid.UUID) error {
+	workspace, err := s.repo.GetByID(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	if workspace.OwnerID == userID {
+		return ErrOwnerCannotLeave
+	}
+
+	err = s.repo.RemoveMember(ctx, workspaceID, userID)
+	if errors.Is(err, repository.ErrMemberNotFound) {
+		return ErrNotMember
+	}
+	return err
+}
+
+// CreateInvite makes an invite link for the workspace. Owners and admins may
+// create them; expiresAt and maxUses are optional limits.
+func (s *WorkspaceService) CreateInvite(ctx context.Context, callerID, workspaceID uuid.UUID, expiresAt *time.Time, maxUses *int) (*models.WorkspaceInviteResponse, error) {
+	caller, err := s.VerifyMemberAccess(ctx, workspaceID, callerID)
+	if err != nil {
+		return nil, err
+	}
+	if caller.Role != models.WorkspaceRoleOwner && caller.Role != models.WorkspaceRoleAdmin {
+		return nil, ErrNotManager
+	}
+
+	token, err := generateInviteToken()
+	if err != nil {
+		return nil, err
+	}
+
+	invite := &models.WorkspaceInvite{
+		WorkspaceID: workspaceID,
+		CreatedBy:   callerID,
+		TokenHash:   hashToken(token),
+		ExpiresAt:   expiresAt,
+		MaxUses:     maxUses,
+	}
+	if err := s.repo.CreateInvite(ctx, invite); err != nil {
+		return nil, err
+	}
+
+	return &models.WorkspaceInviteResponse{WorkspaceInvite: invite, Token: token}, nil
+}
+
+// JoinByInvite redeems an invite link. Expiry, remaining uses and the use
+// counter are handled atomically by the repository.
+func (s *WorkspaceService) JoinByInvite(ctx context.Context, userID uuid.UUID, token string) (*models.Workspace, error) {
+	return s.repo.JoinByInvite(ctx, hashToken(strings.TrimSpace(token)), userID)
 }
 
 func generateInviteCode() (string, error) {
@@ -115,3 +350,12 @@ func generateInviteCode() (string, error) {
 	}
 	return strings.ToUpper(hex.EncodeToString(bytes)), nil
 }
+
+// generateInviteToken returns a 32-character hex token for invite links
+func generateInviteToken() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nextpdf/backend/internal/models"
+	"github.com/nextpdf/backend/internal/repository"
+	"github.com/nextpdf/backend/internal/testdb"
+)
+
+// newTestWorkspace creates a workspace owned by a new user and adds one
+// user per role given, returning the workspace and the users in that order
+func newTestWorkspace(t *testing.T, pool *pgxpool.Pool, svc *WorkspaceService, roles ...string) (*models.Workspace, uuid.UUID, []uuid.UUID) {
+	t.Helper()
+	ctx := context.Background()
+
+	ownerID := testdb.CreateUser(t, pool)
+	workspace, err := svc.CreateWorkspace(ctx, ownerID, "Team")
+	if err != nil {
+		t.Fatalf("create workspace: %v", err)
+	}
+
+	users := make([]uuid.UUID, len(roles))
+	for i, role := range roles {
+		users[i] = testdb.CreateUser(t, pool)
+		err := svc.repo.AddMember(ctx, &models.WorkspaceMember{WorkspaceID: workspace.ID, UserID: users[i], Role: role})
+		if err != nil {
+			t.Fatalf("add %s: %v", role, err)
+		}
+	}
+	return workspace, ownerID, users
+}
+
+func TestRemoveMemberChecksCallerFirst(t *testing.T) {
+	pool := testdb.New(t)
+	svc := NewWorkspaceService(repository.NewWorkspaceRepository(pool))
+	ctx := context.Background()
+
+	workspace, ownerID, users := newTestWorkspace(t, pool, svc, models.WorkspaceRoleMember, models.WorkspaceRoleAdmin)
+	member, admin := users[0], users[1]
+	outsider := testdb.CreateUser(t, pool)
+
+	// Callers without the right to remove anyone must not learn who the owner is
+	if err := svc.RemoveMember(ctx, outsider, workspace.ID, ownerID); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("outsider removing owner = %v, want ErrNotOwner", err)
+	}
+	if err := svc.RemoveMember(ctx, member, workspace.ID, ownerID); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("member removing owner = %v, want ErrNotOwner", err)
+	}
+	if err := svc.RemoveMember(ctx, admin, workspace.ID, ownerID); !errors.Is(err, ErrCannotRemoveOwner) {
+		t.Errorf("admin removing owner = %v, want ErrCannotRemoveOwner", err)
+	}
+
+	if err := svc.RemoveMember(ctx, admin, workspace.ID, member); err != nil {
+		t.Fatalf("admin removing member: %v", err)
+	}
+	if _, err := svc.VerifyMemberAccess(ctx, workspace.ID, member); !errors.Is(err, ErrNotMember) {
+		t.Errorf("removed member access = %v, want ErrNotMember", err)
+	}
+}
+
+func TestLeaveWorkspace(t *testing.T) {
+	pool := testdb.New(t)
+	svc := NewWorkspaceService(repository.NewWorkspaceRepository(pool))
+	ctx := context.Background()
+
+	workspace, ownerID, users := newTestWorkspace(t, pool, svc, models.WorkspaceRoleMember)
+	member := users[0]
+
+	if err := svc.LeaveWorkspace(ctx, ownerID, workspace.ID); !errors.Is(err, ErrOwnerCannotLeave) {
+		t.Errorf("owner leaving = %v, want ErrOwnerCannotLeave", err)
+	}
+	if _, err := svc.VerifyMemberAccess(ctx, workspace.ID, ownerID); err != nil {
+		t.Errorf("owner lost access after a refused leave: %v", err)
+	}
+
+	if err := svc.LeaveWorkspace(ctx, member, workspace.ID); err != nil {
+		t.Fatalf("member leaving: %v", err)
+	}
+	if _, err := svc.VerifyMemberAccess(ctx, workspace.ID, member); !errors.Is(err, ErrNotMember) {
+		t.Errorf("access after leaving = %v, want ErrNotMember", err)
+	}
+	if err := svc.LeaveWorkspace(ctx, member, workspace.ID); !errors.Is(err, ErrNotMember) {
+		t.Errorf("leaving twice = %v, want ErrNotMember", err)
+	}
+}
+
+func TestTransferOwnership(t *testing.T) {
+	pool := testdb.New(t)
+	svc := NewWorkspaceService(repository.NewWorkspaceRepository(pool))
+	ctx := context.Background()
+
+	workspace, ownerID, users := newTestWorkspace(t, pool, svc, models.WorkspaceRoleMember, models.WorkspaceRoleAdmin)
+	member, admin := users[0], users[1]
+	outsider := testdb.CreateUser(t, pool)
+
+	if err := svc.TransferOwnership(ctx, admin, workspace.ID, member); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("admin transferring = %v, want ErrNotOwner", err)
+	}
+	if err := svc.TransferOwnership(ctx, ownerID, workspace.ID, ownerID); !errors.Is(err, ErrAlreadyOwner) {
+		t.Errorf("transfer to self = %v, want ErrAlreadyOwner", err)
+	}
+	if err := svc.TransferOwnership(ctx, ownerID, workspace.ID, outsider); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("transfer to outsider = %v, want ErrMemberNotFound", err)
+	}
+
+	if err := svc.TransferOwnership(ctx, ownerID, workspace.ID, member); err != nil {
+		t.Fatalf("transfer to member: %v", err)
+	}
+
+	updated, err := svc.repo.GetByID(ctx, workspace.ID)
+	if err != nil {
+		t.Fatalf("get workspace: %v", err)
+	}
+	if updated.OwnerID != member {
+		t.Errorf("owner = %s, want %s", updated.OwnerID, member)
+	}
+	newOwner, err := svc.VerifyMemberAccess(ctx, workspace.ID, member)
+	if err != nil || newOwner.Role != models.WorkspaceRoleOwner {
+		t.Errorf("new owner membership = %+v, %v, want role owner", newOwner, err)
+	}
+	previous, err := svc.VerifyMemberAccess(ctx, workspace.ID, ownerID)
+	if err != nil || previous.Role != models.WorkspaceRoleAdmin {
+		t.Errorf("previous owner membership = %+v, %v, want role admin", previous, err)
+	}
+
+	// The previous owner is now free to leave
+	if err := svc.LeaveWorkspace(ctx, ownerID, workspace.ID); err != nil {
+		t.Errorf("previous owner leaving: %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/minio/minio-go/v7"
+)
+
+var (
+	ErrObjectNotFound      = errors.New("storage object not found")
+	ErrStorageUnavailable  = errors.New("storage temporarily unavailable")
+	ErrStorageAccessDenied = errors.New("storage access denied")
+	// ErrIncompleteUpload is returned when a multipart upload is completed
+	// before its parts add up to the expected size
+	ErrIncompleteUpload = errors.New("multipart upload is missing parts")
+)
+
+// classifyError wraps a MinIO error in one of the sentinel errors above so
+// callers can tell a missing object from an outage without inspecting codes.
+// Unrecognized errors are returned unchanged.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	resp := minio.ToErrorResponse(err)
+	switch resp.Code {
+	case "NoSuchKey", "NoSuchBucket", "NoSuchUpload":
+		return fmt.Errorf("%w: %v", ErrObjectNotFound, err)
+	case "AccessDenied", "InvalidAccessKeyId", "SignatureDoesNotMatch":
+		return fmt.Errorf("%w: %v", ErrStorageAccessDenied, err)
+	case "SlowDown", "ServiceUnavailable", "RequestTimeout", "XMinioServerNotInitialized":
+		return fmt.Errorf("%w: %v", ErrStorageUnavailable, err)
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("%w: %v", ErrStorageUnavailable, err)
+	}
+
+	// Connection refused, DNS failures, and timeouts all surface as net errors
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %v", ErrStorageUnavailable, err)
+	}
+
+	return err
+}
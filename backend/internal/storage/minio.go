@@ -2,9 +2,13 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/minio/minio-go/v7"
@@ -19,9 +23,19 @@ type Storage struct {
 }
 
 func New(cfg config.MinIOConfig) (*Storage, error) {
+	// Both clients must agree on region and addressing or presigned URLs
+	// carry signatures the backend rejects. Without MINIO_PATH_STYLE the
+	// client keeps its own choice (virtual-hosted for AWS, path otherwise).
+	bucketLookup := minio.BucketLookupAuto
+	if cfg.PathStyle {
+		bucketLookup = minio.BucketLookupPath
+	}
+
 	client, err := minio.New(cfg.Endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
-		Secure: cfg.UseSSL,
+		Creds:        credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure:       cfg.UseSSL,
+		Region:       cfg.Region,
+		BucketLookup: bucketLookup,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create minio client: %w", err)
@@ -35,9 +49,10 @@ func New(cfg config.MinIOConfig) (*Storage, error) {
 	}
 
 	presignClient, err := minio.New(presignEndpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
-		Secure: cfg.UseSSL,
-		Region: "us-east-1",
+		Creds:        credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure:       cfg.UseSSL,
+		Region:       cfg.Region,
+		BucketLookup: bucketLookup,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create minio presign client: %w", err)
@@ -50,18 +65,41 @@ func New(cfg config.MinIOConfig) (*Storage, error) {
 	}, nil
 }
 
+// EnsureBuckets creates any missing buckets. Connectivity failures are retried
+// with exponential backoff so the API can start alongside a MinIO container
+// that is still booting; credential and permission failures fail immediately.
 func (s *Storage) EnsureBuckets(ctx context.Context) error {
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		err := s.ensureBuckets(ctx)
+		if err == nil || !errors.Is(err, ErrStorageUnavailable) || attempt >= s.cfg.StartupRetries {
+			return err
+		}
+
+		log.Printf("MinIO not ready (attempt %d/%d), retrying in %s: %v", attempt, s.cfg.StartupRetries, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+func (s *Storage) ensureBuckets(ctx context.Context) error {
 	buckets := []string{s.cfg.BucketFiles, s.cfg.BucketAvatars, s.cfg.BucketUploads}
 
 	for _, bucket := range buckets {
 		exists, err := s.client.BucketExists(ctx, bucket)
 		if err != nil {
-			return fmt.Errorf("failed to check bucket %s: %w", bucket, err)
+			return s.startupError("check bucket "+bucket, err)
 		}
 
 		if !exists {
-			if err := s.client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{}); err != nil {
-				return fmt.Errorf("failed to create bucket %s: %w", bucket, err)
+			if err := s.client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{Region: s.cfg.Region}); err != nil {
+				return s.startupError("create bucket "+bucket, err)
 			}
 		}
 	}
@@ -69,22 +107,129 @@ func (s *Storage) EnsureBuckets(ctx context.Context) error {
 	return nil
 }
 
-func (s *Storage) GeneratePresignedPutURL(ctx context.Context, bucket, objectName, contentType string, size int64) (*url.URL, error) {
+// startupError turns a raw MinIO error into an actionable message naming the
+// likely misconfiguration, keeping the classified sentinel in the chain
+func (s *Storage) startupError(op string, err error) error {
+	code := minio.ToErrorResponse(err).Code
+	err = classifyError(err)
+
+	switch {
+	case code == "InvalidAccessKeyId" || code == "SignatureDoesNotMatch":
+		return fmt.Errorf("failed to %s: MinIO rejected the credentials, check MINIO_ACCESS_KEY and MINIO_SECRET_KEY: %w", op, err)
+	case errors.Is(err, ErrStorageAccessDenied):
+		return fmt.Errorf("failed to %s: the MinIO user lacks permission for this bucket: %w", op, err)
+	case errors.Is(err, ErrStorageUnavailable):
+		return fmt.Errorf("failed to %s: cannot reach MinIO at %s, check MINIO_ENDPOINT and MINIO_USE_SSL: %w", op, s.cfg.Endpoint, err)
+	}
+	return fmt.Errorf("failed to %s: %w", op, err)
+}
+
+// ObjectInfo is the part of an object's metadata the services check
+type ObjectInfo struct {
+	Size        int64
+	ContentType string
+}
+
+// GeneratePresignedPostPolicy returns a browser form upload URL and the form
+// fields to send with the file. Unlike a presigned PUT, the policy makes the
+// storage server reject any other content type or size.
+func (s *Storage) GeneratePresignedPostPolicy(ctx context.Context, bucket, objectName, contentType string, size int64) (*url.URL, map[string]string, error) {
+	policy := minio.NewPostPolicy()
+	if err := policy.SetBucket(bucket); err != nil {
+		return nil, nil, err
+	}
+	if err := policy.SetKey(objectName); err != nil {
+		return nil, nil, err
+	}
+	if err := policy.SetContentType(contentType); err != nil {
+		return nil, nil, err
+	}
+	if err := policy.SetContentLengthRange(size, size); err != nil {
+		return nil, nil, err
+	}
+	if err := policy.SetExpires(time.Now().UTC().Add(s.cfg.PresignExpiryMin)); err != nil {
+		return nil, nil, err
+	}
+
+	// Use presignClient to sign for the public endpoint
+	u, fields, err := s.presignClient.PresignedPostPolicy(ctx, policy)
+	if err != nil {
+		return nil, nil, classifyError(err)
+	}
+	return u, fields, nil
+}
+
+// NewMultipartUpload starts a multipart upload for a resumable client upload
+// and returns its upload ID. The content type is fixed here, for all parts.
+func (s *Storage) NewMultipartUpload(ctx context.Context, bucket, objectName, contentType string) (string, error) {
+	core := minio.Core{Client: s.client}
+	uploadID, err := core.NewMultipartUpload(ctx, bucket, objectName, minio.PutObjectOptions{ContentType: contentType})
+	return uploadID, classifyError(err)
+}
+
+// PresignPartURL returns a URL the client can PUT one part of a multipart
+// upload to. A failed part is retried by uploading it again.
+func (s *Storage) PresignPartURL(ctx context.Context, bucket, objectName, uploadID string, partNumber int) (*url.URL, error) {
+	params := make(url.Values)
+	params.Set("uploadId", uploadID)
+	params.Set("partNumber", strconv.Itoa(partNumber))
+
 	// Use presignClient to generate URL with public endpoint and correct signature
-	return s.presignClient.PresignedPutObject(ctx, bucket, objectName, s.cfg.PresignExpiryMin)
+	u, err := s.presignClient.Presign(ctx, http.MethodPut, bucket, objectName, s.cfg.PresignExpiryMin, params)
+	return u, classifyError(err)
+}
+
+// CompleteMultipartUpload assembles the uploaded parts into the object. It
+// lists the parts itself, so the client does not have to collect ETags, and
+// returns ErrIncompleteUpload without completing while the parts do not add
+// up to size, so the client can still upload what is missing.
+func (s *Storage) CompleteMultipartUpload(ctx context.Context, bucket, objectName, uploadID string, size int64) error {
+	core := minio.Core{Client: s.client}
+
+	var parts []minio.CompletePart
+	var total int64
+	marker := 0
+	for {
+		result, err := core.ListObjectParts(ctx, bucket, objectName, uploadID, marker, 1000)
+		if err != nil {
+			return classifyError(err)
+		}
+		for _, part := range result.ObjectParts {
+			parts = append(parts, minio.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag})
+			total += part.Size
+		}
+		if !result.IsTruncated {
+			break
+		}
+		marker = result.NextPartNumberMarker
+	}
+
+	if len(parts) == 0 || total != size {
+		return fmt.Errorf("%w: %d of %d bytes uploaded", ErrIncompleteUpload, total, size)
+	}
+
+	_, err := core.CompleteMultipartUpload(ctx, bucket, objectName, uploadID, parts, minio.PutObjectOptions{})
+	return classifyError(err)
+}
+
+// AbortMultipartUpload discards a multipart upload and its uploaded parts
+func (s *Storage) AbortMultipartUpload(ctx context.Context, bucket, objectName, uploadID string) error {
+	core := minio.Core{Client: s.client}
+	return classifyError(core.AbortMultipartUpload(ctx, bucket, objectName, uploadID))
 }
 
 func (s *Storage) GeneratePresignedGetURL(ctx context.Context, bucket, objectName string, expiry time.Duration) (*url.URL, error) {
 	reqParams := make(url.Values)
 	// Use presignClient to generate URL with public endpoint and correct signature
-	return s.presignClient.PresignedGetObject(ctx, bucket, objectName, expiry, reqParams)
+	u, err := s.presignClient.PresignedGetObject(ctx, bucket, objectName, expiry, reqParams)
+	return u, classifyError(err)
 }
 
 func (s *Storage) ObjectExists(ctx context.Context, bucket, objectName string) (bool, error) {
 	_, err := s.client.StatObject(ctx, bucket, objectName, minio.StatObjectOptions{})
 	if err != nil {
-		errResp := minio.ToErrorResponse(err)
-		if errResp.Code == "NoSuchKey" {
+		err = classifyError(err)
+		if errors.Is(err, ErrObjectNotFound) {
 			return false, nil
 		}
 		return false, err
@@ -92,12 +237,55 @@ func (s *Storage) ObjectExists(ctx context.Context, bucket, objectName string) (
 	return true, nil
 }
 
+// StatObject returns the size and content type of a stored object
+func (s *Storage) StatObject(ctx context.Context, bucket, objectName string) (*ObjectInfo, error) {
+	info, err := s.client.StatObject(ctx, bucket, objectName, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	return &ObjectInfo{Size: info.Size, ContentType: info.ContentType}, nil
+}
+
 func (s *Storage) DeleteObject(ctx context.Context, bucket, objectName string) error {
-	return s.client.RemoveObject(ctx, bucket, objectName, minio.RemoveObjectOptions{})
+	return classifyError(s.client.RemoveObject(ctx, bucket, objectName, minio.RemoveObjectOptions{}))
+}
+
+// ObjectReader reads parts of a stored object without downloading all of it
+type ObjectReader interface {
+	io.ReaderAt
+	io.Closer
+}
+
+// OpenObject opens an object for random access and returns its size. Each
+// ReadAt away from the current position becomes a new ranged GET, so a parser
+// that seeks only fetches the parts it reads.
+func (s *Storage) OpenObject(ctx context.Context, bucket, objectName string) (ObjectReader, int64, error) {
+	obj, err := s.client.GetObject(ctx, bucket, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, 0, classifyError(err)
+	}
+	info, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		return nil, 0, classifyError(err)
+	}
+	return obj, info.Size, nil
 }
 
 func (s *Storage) GetObject(ctx context.Context, bucket, objectName string) (io.ReadCloser, error) {
-	return s.client.GetObject(ctx, bucket, objectName, minio.GetObjectOptions{})
+	// minio.GetObject is lazy and only fails on first read, so errors here are
+	// limited to request construction; read errors are not classified
+	obj, err := s.client.GetObject(ctx, bucket, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	return obj, nil
+}
+
+// PutObject uploads size bytes from reader as objectName
+func (s *Storage) PutObject(ctx context.Context, bucket, objectName string, reader io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, bucket, objectName, reader, size, minio.PutObjectOptions{ContentType: contentType})
+	return classifyError(err)
 }
 
 func (s *Storage) CopyObject(ctx context.Context, srcBucket, srcObject, dstBucket, dstObject string) error {
@@ -110,7 +298,26 @@ func (s *Storage) CopyObject(ctx context.Context, srcBucket, srcObject, dstBucke
 		Object: dstObject,
 	}
 	_, err := s.client.CopyObject(ctx, dst, src)
-	return err
+	return classifyError(err)
+}
+
+// ListObjects calls fn for every object in bucket, stopping at the first error
+func (s *Storage) ListObjects(ctx context.Context, bucket string, fn func(key string, lastModified time.Time) error) error {
+	return s.ListObjectsWithPrefix(ctx, bucket, "", fn)
+}
+
+// ListObjectsWithPrefix is ListObjects restricted to keys starting with prefix
+func (s *Storage) ListObjectsWithPrefix(ctx context.Context, bucket, prefix string, fn func(key string, lastModified time.Time) error) error {
+	opts := minio.ListObjectsOptions{Prefix: prefix, Recursive: true}
+	for obj := range s.client.ListObjects(ctx, bucket, opts) {
+		if obj.Err != nil {
+			return classifyError(obj.Err)
+		}
+		if err := fn(obj.Key, obj.LastModified); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
 }
 
 func (s *Storage) BucketFiles() string {
@@ -0,0 +1,130 @@
+// Package testdb gives database tests a Postgres schema loaded from
+// db/schema.sql. Tests using it are skipped unless TEST_DATABASE_URL points
+// at a database the tests may create schemas in.
+package testdb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// setupLockID serializes schema setup across test binaries, which would
+// otherwise race to install the extensions
+const setupLockID = 4_242_001
+
+// New returns a pool whose search_path is a fresh schema holding the full
+// database schema. The schema is dropped when the test ends.
+func New(t testing.TB) *pgxpool.Pool {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	ctx := context.Background()
+
+	_, file, _, _ := runtime.Caller(0)
+	schemaSQL, err := os.ReadFile(filepath.Join(filepath.Dir(file), "..", "..", "db", "schema.sql"))
+	if err != nil {
+		t.Fatalf("read schema: %v", err)
+	}
+
+	conn, err := pgx.Connect(ctx, url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", setupLockID); err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	setup := fmt.Sprintf(`
+		CREATE EXTENSION IF NOT EXISTS "uuid-ossp" SCHEMA public;
+		CREATE EXTENSION IF NOT EXISTS "pgcrypto" SCHEMA public;
+		CREATE EXTENSION IF NOT EXISTS pg_trgm SCHEMA public;
+		CREATE SCHEMA %[1]s;
+		SET search_path TO %[1]s, public;
+	`, schema)
+	_, err = conn.Exec(ctx, setup)
+	if err == nil {
+		_, err = conn.Exec(ctx, string(schemaSQL))
+	}
+	if _, unlockErr := conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", setupLockID); unlockErr != nil && err == nil {
+		err = unlockErr
+	}
+	if err != nil {
+		t.Fatalf("load schema: %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn, err := pgx.Connect(ctx, url)
+		if err != nil {
+			t.Logf("drop schema %s: %v", schema, err)
+			return
+		}
+		defer conn.Close(ctx)
+		if _, err := conn.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE"); err != nil {
+			t.Logf("drop schema %s: %v", schema, err)
+		}
+	})
+
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		t.Fatalf("parse url: %v", err)
+	}
+	config.ConnConfig.RuntimeParams["search_path"] = schema + ", public"
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatalf("connect pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	return pool
+}
+
+// CreateUser inserts a user and returns its ID
+func CreateUser(t testing.TB, pool *pgxpool.Pool) uuid.UUID {
+	t.Helper()
+
+	var id uuid.UUID
+	err := pool.QueryRow(context.Background(), `
+		INSERT INTO users (email, password_hash, full_name)
+		VALUES ($1, 'x', 'Test User')
+		RETURNING id
+	`, uuid.NewString()+"@example.com").Scan(&id)
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return id
+}
+
+// CreateFile inserts a file owned by userID, in folderID or the root when nil,
+// and returns its ID
+func CreateFile(t testing.TB, pool *pgxpool.Pool, userID uuid.UUID, folderID *uuid.UUID) uuid.UUID {
+	t.Helper()
+
+	name := uuid.NewString() + ".pdf"
+	var id uuid.UUID
+	err := pool.QueryRow(context.Background(), `
+		INSERT INTO files (user_id, folder_id, filename, original_filename, storage_path, file_size)
+		VALUES ($1, $2, $3, $3, $4, 1024)
+		RETURNING id
+	`, userID, folderID, name, userID.String()+"/"+name).Scan(&id)
+	if err != nil {
+		t.Fatalf("create file: %v", err)
+	}
+	return id
+}
@@ -150,10 +150,13 @@ export function SummaryPanel({ file }: SummaryPanelProps) {
 
       // 2. Connect to Event Stream (RabbitMQ Bridge)
       const baseUrl = (process.env.NEXT_PUBLIC_API_URL || "http://localhost:8080").replace(/\/api\/v1\/?$/, "").replace(/\/$/, "");
-      const token = api.getAccessToken();
+      const tokenRes = await api.getStreamToken();
+      if (tokenRes.error || !tokenRes.data) {
+        throw new Error(tokenRes.error?.message || "Failed to authorize event stream");
+      }
 
-      // Use query param for token (Middleware updated to support this)
-      eventSource = new EventSource(`${baseUrl}/api/v1/files/${file.id}/events?token=${token}`);
+      // EventSource cannot set headers, so a short-lived stream token goes in the URL
+      eventSource = new EventSource(`${baseUrl}/api/v1/files/${file.id}/events?token=${encodeURIComponent(tokenRes.data.stream_token)}`);
 
       eventSource.onopen = () => {
         setStreamLogs(prev => [...prev, "Connected to event stream..."]);
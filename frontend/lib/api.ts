@@ -131,6 +131,14 @@ class ApiClient {
     return response;
   }
 
+  // Short-lived token for EventSource/WebSocket URLs, which cannot send headers
+  async getStreamToken() {
+    return this.request<{
+      stream_token: string;
+      expires_in: number;
+    }>('/auth/stream-token', { method: 'POST' });
+  }
+
   async logoutAll() {
     const response = await this.request('/auth/logout-all', { method: 'POST' });
     this.setAccessToken(null);
@@ -241,6 +249,21 @@ class ApiClient {
     return this.request<FileItem[]>(`/files${query ? `?${query}` : ''}`);
   }
 
+  async searchFileContents(q: string, params?: {
+    folder_id?: string;
+    workspace_id?: string;
+    page?: number;
+    limit?: number;
+  }) {
+    const searchParams = new URLSearchParams({ q });
+    if (params?.folder_id) searchParams.set('folder_id', params.folder_id);
+    if (params?.workspace_id) searchParams.set('workspace_id', params.workspace_id);
+    if (params?.page) searchParams.set('page', params.page.toString());
+    if (params?.limit) searchParams.set('limit', params.limit.toString());
+
+    return this.request<FileSearchResult[]>(`/files/search?${searchParams.toString()}`);
+  }
+
   async getFile(id: string) {
     return this.request<FileItem>(`/files/${id}`);
   }
@@ -355,6 +378,43 @@ class ApiClient {
     });
   }
 
+  async initMultipartUpload(filename: string, fileSize: number, contentType: string, folderId?: string | null, workspaceId?: string | null) {
+    return this.request<{
+      upload_id: string;
+      part_size: number;
+      part_count: number;
+      storage_path: string;
+      expires_at: string;
+    }>('/files/upload/multipart/init', {
+      method: 'POST',
+      body: JSON.stringify({
+        filename,
+        file_size: fileSize,
+        content_type: contentType,
+        folder_id: folderId,
+        workspace_id: workspaceId,
+      }),
+    });
+  }
+
+  async getMultipartPartUrl(uploadId: string, partNumber: number) {
+    return this.request<{
+      part_number: number;
+      presigned_url: string;
+      expires_at: string;
+    }>('/files/upload/multipart/part-url', {
+      method: 'POST',
+      body: JSON.stringify({ upload_id: uploadId, part_number: partNumber }),
+    });
+  }
+
+  async completeMultipartUpload(uploadId: string) {
+    return this.request<FileItem>('/files/upload/multipart/complete', {
+      method: 'POST',
+      body: JSON.stringify({ upload_id: uploadId }),
+    });
+  }
+
   async getDownloadUrl(fileId: string) {
     return this.request<{
       download_url: string;
@@ -369,6 +429,7 @@ class ApiClient {
       upload_id: string;
       presigned_url: string;
       expires_at: string;
+      headers: Record<string, string>;
     }>('/uploads/avatar/presign', {
       method: 'POST',
       body: JSON.stringify({ filename, file_size: fileSize, content_type: contentType }),
@@ -387,6 +448,28 @@ class ApiClient {
     return this.request<SummaryStyle[]>('/summary-styles');
   }
 
+  async getCustomStyles() {
+    return this.request<CustomSummaryStyle[]>('/summary-styles/custom');
+  }
+
+  async createCustomStyle(name: string, instructions: string, baseStyle?: string) {
+    return this.request<CustomSummaryStyle>('/summary-styles/custom', {
+      method: 'POST',
+      body: JSON.stringify({ name, instructions, base_style: baseStyle }),
+    });
+  }
+
+  async updateCustomStyle(id: string, data: { name?: string; instructions?: string; base_style?: string }) {
+    return this.request<CustomSummaryStyle>(`/summary-styles/custom/${id}`, {
+      method: 'PATCH',
+      body: JSON.stringify(data),
+    });
+  }
+
+  async deleteCustomStyle(id: string) {
+    return this.request(`/summary-styles/custom/${id}`, { method: 'DELETE' });
+  }
+
   async getSummary(fileId: string, version?: number) {
     const query = version ? `?version=${version}` : '';
     return this.request<Summary | { status: string; message: string }>(`/summaries/${fileId}${query}`);
@@ -396,6 +479,13 @@ class ApiClient {
     return this.request<SummaryHistoryItem[]>(`/summaries/${fileId}/history`);
   }
 
+  async submitSummaryFeedback(fileId: string, rating: number, comment?: string, version?: number) {
+    return this.request<SummaryFeedback>(`/summaries/${fileId}/feedback`, {
+      method: 'POST',
+      body: JSON.stringify({ rating, comment, version }),
+    });
+  }
+
   async generateSummary(fileId: string, style: string, customInstructions?: string, language: string = 'en') {
     return this.request<{
       file_id: string;
@@ -468,6 +558,13 @@ export interface FolderTreeItem {
   children: FolderTreeItem[];
 }
 
+// A match of GET /files/search. snippet is document text with the matches
+// wrapped in <mark> tags; escape everything else before rendering it as HTML.
+export interface FileSearchResult extends FileItem {
+  rank: number;
+  snippet: string;
+}
+
 export interface FileItem {
   id: string;
   filename: string;
@@ -481,6 +578,13 @@ export interface FileItem {
   uploaded_at: string;
   processed_at?: string;
   download_url?: string;
+  duplicate_of?: string;
+  pdf_metadata?: {
+    title: string | null;
+    author: string | null;
+    created_at: string | null;
+  };
+  suggested_filename?: string;
   summary?: {
     id: string;
     title: string;
@@ -495,6 +599,16 @@ export interface SummaryStyle {
   name: string;
   description: string;
   example_output: string;
+  custom_style_id?: string;
+}
+
+export interface CustomSummaryStyle {
+  id: string;
+  name: string;
+  base_style: string;
+  instructions: string;
+  created_at: string;
+  updated_at: string;
 }
 
 export interface Summary {
@@ -513,9 +627,24 @@ export interface Summary {
   language: string;
   version: number;
   is_current: boolean;
+  rating: SummaryRating;
   created_at: string;
 }
 
+export interface SummaryRating {
+  average: number;
+  count: number;
+}
+
+export interface SummaryFeedback {
+  summary_id: string;
+  version: number;
+  rating: number;
+  comment?: string;
+  updated_at: string;
+  summary_rating: SummaryRating;
+}
+
 export interface SummaryHistoryItem {
   id: string;
   version: number;
@@ -38,6 +38,44 @@ interface FileContextType {
 
 const FileContext = createContext<FileContextType | undefined>(undefined);
 
+// Files above this size use a resumable multipart upload
+const MULTIPART_THRESHOLD = 10 * 1024 * 1024;
+const PART_ATTEMPTS = 3;
+
+async function uploadMultipart(file: File, folderId?: string | null, workspaceId?: string | null) {
+  const init = await api.initMultipartUpload(file.name, file.size, file.type, folderId, workspaceId);
+  if (!init.data) {
+    return { success: false, error: init.error?.message || 'Failed to start upload' };
+  }
+
+  const { upload_id: uploadId, part_size: partSize, part_count: partCount } = init.data;
+  for (let part = 1; part <= partCount; part++) {
+    const chunk = file.slice((part - 1) * partSize, part * partSize);
+
+    // A dropped part is sent again; the parts already uploaded are kept
+    let uploaded = false;
+    for (let attempt = 0; attempt < PART_ATTEMPTS && !uploaded; attempt++) {
+      const partUrl = await api.getMultipartPartUrl(uploadId, part);
+      if (!partUrl.data) continue;
+      try {
+        const response = await fetch(partUrl.data.presigned_url, { method: 'PUT', body: chunk });
+        uploaded = response.ok;
+      } catch {
+        uploaded = false;
+      }
+    }
+    if (!uploaded) {
+      return { success: false, error: 'Failed to upload file' };
+    }
+  }
+
+  const complete = await api.completeMultipartUpload(uploadId);
+  if (!complete.data) {
+    return { success: false, error: complete.error?.message || 'Failed to confirm upload' };
+  }
+  return { success: true };
+}
+
 export function FileProvider({ children }: { children: React.ReactNode }) {
   const { currentWorkspace } = useWorkspace();
   const [folders, setFolders] = useState<FolderTreeItem[]>([]);
@@ -175,6 +213,14 @@ export function FileProvider({ children }: { children: React.ReactNode }) {
       return { success: false, error: 'File size exceeds 25 MB limit' };
     }
 
+    if (file.size > MULTIPART_THRESHOLD) {
+      const result = await uploadMultipart(file, folderId, currentWorkspace?.id);
+      if (result.success) {
+        await refreshFiles(folderId);
+      }
+      return result;
+    }
+
     // Get presigned URL
     const presignResponse = await api.getPresignedUploadUrl(
       file.name,
@@ -188,12 +234,17 @@ export function FileProvider({ children }: { children: React.ReactNode }) {
       return { success: false, error: presignResponse.error?.message || 'Failed to get upload URL' };
     }
 
-    // Upload to MinIO
+    // Upload to MinIO with the presigned POST policy; the file field must come last
     try {
+      const form = new FormData();
+      for (const [key, value] of Object.entries(presignResponse.data.headers)) {
+        form.append(key, value);
+      }
+      form.append('file', file);
+
       const uploadResponse = await fetch(presignResponse.data.presigned_url, {
-        method: 'PUT',
-        headers: presignResponse.data.headers,
-        body: file,
+        method: 'POST',
+        body: form,
       });
 
       if (!uploadResponse.ok) {